@@ -6,10 +6,19 @@ import (
 	"fmt"
 	"golang.org/x/sync/errgroup"
 	"log"
+	"main/internal/cluster"
 	"main/internal/config"
+	"main/internal/gateway"
+	"main/internal/gc"
+	"main/internal/integrity"
+	"main/internal/ipns"
 	jwtManager "main/internal/lib/jwt"
+	"main/internal/mfs"
+	"main/internal/monitor"
 	"main/internal/repository/postgresql"
 	"main/internal/server"
+	"main/internal/service"
+	"main/internal/storage"
 	rediscache "main/tools/pkg/cache/redis"
 	coreconfig "main/tools/pkg/core_config"
 	"main/tools/pkg/database"
@@ -18,6 +27,7 @@ import (
 	"os/signal"
 
 	"main/internal/handlers"
+	"main/internal/pinning"
 )
 
 func main() {
@@ -69,18 +79,99 @@ func main() {
 	tokenRepository := postgresql.NewUserTokenRepository(db, cfg.App.Debug)
 	roleRepository := postgresql.NewRoleRepository(db)
 	nftDataRepository := postgresql.NewNftDataRepository(db)
+	collectionRepository := postgresql.NewCollectionRepository(db)
+	pinRepository := postgresql.NewPinRepository(db)
+	pinReplicaRepository := postgresql.NewPinReplicaRepository(db)
+	directoryRepository := postgresql.NewDirectoryRepository(db)
+	contentCheckRepository := postgresql.NewContentCheckRepository(db)
+	ipnsRepository := postgresql.NewIpnsRepository(db)
+	orphanPinRepository := postgresql.NewOrphanPinRepository(db)
 	jwt := jwtManager.NewJWTManager(&cfg.JWT)
 
+	// реестр узлов Kubo: загрузки идут на доступный узел, чтения переключаются между узлами
+	kuboNodes := cluster.NewRegistry(&cfg.IPFS, logger)
+	usesKubo := cfg.Storage.Backend == storage.BackendKubo || cfg.Storage.Backend == ""
+	if usesKubo {
+		kuboNodes.Check(ctx)
+		go kuboNodes.Run(ctx)
+	}
+
+	// выбираем хранилище контента
+	contentStorage, err := storage.New(&cfg.Storage, kuboNodes, logger)
+	if err != nil {
+		log.Panic("storage initialization error ", err)
+	}
+
+	// репликатор копирует выполненные закрепления на удаленные сервисы закреплений
+	remoteTargets := make([]pinning.RemotePinner, 0, len(cfg.Replication.Targets))
+	for i := range cfg.Replication.Targets {
+		remoteTargets = append(remoteTargets, service.NewRemotePinClient(&cfg.Replication.Targets[i],
+			cfg.Replication.Timeout))
+	}
+	replicator := pinning.NewReplicator(pinReplicaRepository, remoteTargets, &cfg.Pinning, &cfg.Replication, logger)
+	go replicator.Run(ctx)
+
+	// фоновый воркер доводит закрепления до конца и сверяет их с хранилищем
+	pinWorker := pinning.NewWorker(pinRepository, contentStorage, replicator, &cfg.Pinning, logger)
+	go pinWorker.Run(ctx)
+
+	// публикация IPNS записей коллекций, истекающие записи переопубликовываются в фоне
+	ipnsPublisher := ipns.NewPublisher(contentStorage, ipnsRepository, &cfg.IPNS, logger)
+	go ipnsPublisher.Run(ctx)
+
+	// аудит пересчитывает хеши содержимого токенов и записывает расхождения в отчет
+	auditor := integrity.NewAuditor(integrity.NewVerifier(contentStorage), contentCheckRepository,
+		nftDataRepository, &cfg.Integrity, logger)
+	go auditor.Run(ctx)
+
+	// встроенный шлюз отдает содержимое хранилища через дисковый кеш,
+	// при включенной проверке содержимое сверяется с CID перед отдачей
+	var gatewayVerifier gateway.Verifier
+	if cfg.Integrity.VerifyGateway {
+		gatewayVerifier = auditor.ReadVerifier()
+	}
+	contentGateway, err := gateway.New(contentStorage, &cfg.Gateway, gatewayVerifier)
+	if err != nil {
+		log.Panic("gateway initialization error ", err)
+	}
+
+	// зеркало загрузок в файловом дереве узла Kubo
+	mirror := mfs.NewMirror(contentStorage, &cfg.MFS, logger)
+
+	// наблюдение за узлом Kubo, при заполнении репозитория выше порога в лог пишутся предупреждения
+	var nodeClient monitor.NodeClient
+	if usesKubo {
+		nodeClient = kuboNodes.Primary().Client
+	}
+	nodeMonitor := monitor.NewMonitor(nodeClient, &cfg.Monitor, logger)
+	go nodeMonitor.Run(ctx)
+
+	// сборка закреплений, на которые не ссылается ни одна запись базы
+	orphanCollector := gc.NewCollector(contentStorage, mirror, orphanPinRepository, &cfg.GC, logger)
+	go orphanCollector.Run(ctx)
+
 	logger.Info("Create server")
 
-	app := server.NewServer()
+	app := server.NewServer(&cfg.App)
 	logger.Info("Creating internal handlers")
 	authHandlers := handlers.NewAuthHandlers(logger, jwt, userRepository, tokenRepository, roleRepository, cacheClient, cfg.Secret)
-	kuboHandlers := handlers.NewKuboHandlers(logger)
-	nftDataHandlers := handlers.NewNftHandlers(logger, nftDataRepository)
+	kuboHandlers := handlers.NewKuboHandlers(logger, contentStorage, pinRepository, nftDataRepository, pinReplicaRepository,
+		collectionRepository, directoryRepository, mirror, pinWorker, cfg.IPFS.GatewayURL)
+	nftDataHandlers := handlers.NewNftHandlers(logger, nftDataRepository, collectionRepository, pinRepository,
+		contentStorage, contentGateway, mirror, pinWorker, cfg.IPFS.GatewayURL, &cfg.NFT)
+	collectionHandlers := handlers.NewCollectionHandlers(logger, collectionRepository, ipnsRepository,
+		ipnsPublisher, cfg.IPFS.GatewayURL, cfg.NFT.CollectionBaseURI)
+	pinningServiceHandlers := handlers.NewPinningServiceHandlers(logger, pinRepository, nftDataRepository,
+		contentStorage, pinWorker, pinDelegates(&cfg.Pinning, usesKubo, kuboNodes, logger))
+	gatewayHandlers := handlers.NewGatewayHandlers(logger, contentGateway)
+	integrityHandlers := handlers.NewIntegrityHandlers(logger, contentCheckRepository, auditor)
+	mfsHandlers := handlers.NewMfsHandlers(logger, mirror)
+	nodeHandlers := handlers.NewNodeHandlers(logger, nodeMonitor, kuboNodes, cfg.Monitor.MetricsToken)
+	gcHandlers := handlers.NewGcHandlers(logger, orphanCollector, &cfg.GC)
 
 	// добавляем роуты для экземпляра сервера
-	server.AddRoutes(app, authHandlers, kuboHandlers, nftDataHandlers, logger)
+	server.AddRoutes(app, authHandlers, kuboHandlers, nftDataHandlers, collectionHandlers,
+		pinningServiceHandlers, gatewayHandlers, integrityHandlers, mfsHandlers, nodeHandlers, gcHandlers, logger)
 
 	logger.Info("Service api gateway starts", "address", cfg.App.Addr)
 	if err = app.Listen(cfg.App.Addr); err != nil {
@@ -100,3 +191,23 @@ func main() {
 	logger.Info("api-gateway service was stopped")
 
 }
+
+// pinDelegates адреса узлов хранилища для клиентов Pinning Service API.
+// Если они не заданы в конфигурации, берутся из проверки доступных узлов Kubo.
+func pinDelegates(cfg *config.Pinning, usesKubo bool, nodes *cluster.Registry, logger *logger.Logger) []string {
+	if len(cfg.Delegates) > 0 {
+		return cfg.Delegates
+	}
+	if !usesKubo {
+		return nil
+	}
+
+	var delegates []string
+	for _, node := range nodes.Healthy() {
+		delegates = append(delegates, node.Addresses()...)
+	}
+	if len(delegates) == 0 {
+		logger.Warn("No kubo node is available to resolve pin delegates")
+	}
+	return delegates
+}
@@ -0,0 +1,220 @@
+// Package cluster ведет реестр узлов Kubo и их состояния: узлы периодически проверяются,
+// а узел, не ответивший на запрос, сразу считается недоступным до следующей успешной проверки.
+package cluster
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"main/internal/config"
+	"main/internal/models"
+	"main/internal/service"
+	"main/tools/pkg/logger"
+)
+
+// Node узел Kubo из реестра
+type Node struct {
+	Name   string
+	Client *service.KuboClient
+
+	mu        sync.Mutex
+	healthy   bool
+	err       string
+	peerId    string
+	addresses []string
+	checkedAt time.Time
+}
+
+// Healthy сообщает, доступен ли узел
+func (n *Node) Healthy() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.healthy
+}
+
+// Addresses возвращает адреса узла из последней успешной проверки
+func (n *Node) Addresses() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.addresses
+}
+
+// Registry реестр узлов Kubo. Первый узел конфигурации основной: пока он доступен,
+// он первым отвечает на чтения.
+type Registry struct {
+	nodes  []*Node
+	cfg    *config.IPFS
+	logger *logger.Logger
+	next   atomic.Uint64
+}
+
+// NewRegistry создает реестр узлов из cfg.Nodes. До первой проверки все узлы считаются доступными.
+func NewRegistry(cfg *config.IPFS, logger *logger.Logger) *Registry {
+	urls := cfg.Nodes()
+	nodes := make([]*Node, 0, len(urls))
+	for _, apiURL := range urls {
+		nodeCfg := *cfg
+		nodeCfg.APIURL = apiURL
+		nodes = append(nodes, &Node{Name: apiURL, Client: service.NewKuboClient(&nodeCfg), healthy: true})
+	}
+	return &Registry{
+		nodes:  nodes,
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+// Run проверяет узлы раз в cfg.HealthInterval, пока не будет отменен ctx.
+// Нулевой интервал отключает проверки.
+func (r *Registry) Run(ctx context.Context) {
+	if r.cfg.HealthInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(r.cfg.HealthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.Check(ctx)
+		}
+	}
+}
+
+// Check параллельно запрашивает идентификаторы всех узлов и обновляет их состояние
+func (r *Registry) Check(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, node := range r.nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id, err := node.Client.ID(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					r.setState(node, err)
+				}
+				return
+			}
+
+			node.mu.Lock()
+			node.peerId, node.addresses = id.ID, id.Addresses
+			node.mu.Unlock()
+			r.setState(node, nil)
+		}()
+	}
+	wg.Wait()
+}
+
+// Len возвращает число узлов
+func (r *Registry) Len() int {
+	return len(r.nodes)
+}
+
+// Primary возвращает основной узел
+func (r *Registry) Primary() *Node {
+	return r.nodes[0]
+}
+
+// Replicas возвращает число узлов, на которых закрепляется каждый CID
+func (r *Registry) Replicas() int {
+	return min(max(r.cfg.Replicas, 1), len(r.nodes))
+}
+
+// Nodes возвращает доступные узлы в порядке конфигурации, за ними недоступные:
+// при чтении они пробуются последними, ведь их состояние могло устареть
+func (r *Registry) Nodes() []*Node {
+	nodes := make([]*Node, 0, len(r.nodes))
+	var down []*Node
+	for _, node := range r.nodes {
+		if node.Healthy() {
+			nodes = append(nodes, node)
+		} else {
+			down = append(down, node)
+		}
+	}
+	return append(nodes, down...)
+}
+
+// Healthy возвращает доступные узлы в порядке конфигурации
+func (r *Registry) Healthy() []*Node {
+	var nodes []*Node
+	for _, node := range r.nodes {
+		if node.Healthy() {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// Pick выбирает узел для загрузки, доступные узлы выбираются по очереди.
+// Если доступных узлов нет, возвращается основной.
+func (r *Registry) Pick() *Node {
+	healthy := r.Healthy()
+	if len(healthy) == 0 {
+		return r.Primary()
+	}
+	return healthy[(r.next.Add(1)-1)%uint64(len(healthy))]
+}
+
+// Fail отмечает узел недоступным, если err означает, что узел не ответил.
+// Ошибки содержимого и таймауты не меняют состояние узла. Возвращает, был ли узел отмечен.
+func (r *Registry) Fail(node *Node, err error) bool {
+	if !IsNodeError(err) {
+		return false
+	}
+	r.setState(node, err)
+	return true
+}
+
+// States возвращает состояние всех узлов
+func (r *Registry) States() []models.KuboNode {
+	states := make([]models.KuboNode, 0, len(r.nodes))
+	for i, node := range r.nodes {
+		node.mu.Lock()
+		states = append(states, models.KuboNode{
+			Name:      node.Name,
+			Primary:   i == 0,
+			Healthy:   node.healthy,
+			Error:     node.err,
+			PeerId:    node.peerId,
+			Addresses: node.addresses,
+			CheckedAt: node.checkedAt,
+		})
+		node.mu.Unlock()
+	}
+	return states
+}
+
+// IsNodeError сообщает, что запрос не дошел до узла: соединение не установлено или разорвано.
+// Таймауты и отмена запроса узлом не считаются, закрепление может долго искать содержимое в сети.
+func IsNodeError(err error) bool {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return false
+	}
+	return !urlErr.Timeout() && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// setState записывает результат проверки узла и сообщает в лог о смене состояния
+func (r *Registry) setState(node *Node, err error) {
+	node.mu.Lock()
+	wasHealthy := node.healthy
+	node.healthy, node.err, node.checkedAt = err == nil, "", time.Now()
+	if err != nil {
+		node.err = err.Error()
+	}
+	node.mu.Unlock()
+
+	switch {
+	case wasHealthy && err != nil:
+		r.logger.Warn("Kubo node is down", "node", node.Name, "error", err)
+	case !wasHealthy && err == nil:
+		r.logger.Info("Kubo node is back", "node", node.Name)
+	}
+}
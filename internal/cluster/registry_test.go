@@ -0,0 +1,100 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"main/internal/config"
+	"main/tools/pkg/logger"
+)
+
+// newFakeNode запускает узел, отвечающий только на /id
+func newFakeNode(t *testing.T, peerId string) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/id" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"ID":%q,"Addresses":["/ip4/127.0.0.1/tcp/4001/p2p/%s"]}`, peerId, peerId)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestRegistry(cfg *config.IPFS) *Registry {
+	return NewRegistry(cfg, &logger.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+}
+
+func TestRegistryCheck(t *testing.T) {
+	first, second, third := newFakeNode(t, "peer-a"), newFakeNode(t, "peer-b"), newFakeNode(t, "peer-c")
+	r := newTestRegistry(&config.IPFS{NodeURLs: []string{first.URL, second.URL, third.URL}, Replicas: 5})
+
+	second.Close()
+	r.Check(context.Background())
+
+	states := r.States()
+	if !states[0].Primary || !states[0].Healthy || states[0].PeerId != "peer-a" || len(states[0].Addresses) != 1 {
+		t.Errorf("unexpected primary node state %+v", states[0])
+	}
+	if states[1].Healthy || states[1].Error == "" || states[1].CheckedAt.IsZero() {
+		t.Errorf("stopped node is not marked down: %+v", states[1])
+	}
+	if r.Replicas() != 3 {
+		t.Errorf("Replicas() = %d, expected the node count", r.Replicas())
+	}
+
+	// загрузки распределяются по доступным узлам, недоступные при чтении пробуются последними
+	var picked []string
+	for range 4 {
+		picked = append(picked, r.Pick().Name)
+	}
+	expected := []string{first.URL, third.URL, first.URL, third.URL}
+	if fmt.Sprint(picked) != fmt.Sprint(expected) {
+		t.Errorf("Pick() = %v, expected %v", picked, expected)
+	}
+	if nodes := r.Nodes(); nodes[2].Name != second.URL {
+		t.Errorf("Nodes() does not put the stopped node last")
+	}
+}
+
+func TestRegistrySingleNode(t *testing.T) {
+	r := newTestRegistry(&config.IPFS{APIURL: "http://127.0.0.1:5001/api/v0", Replicas: 2})
+
+	if r.Len() != 1 || r.Primary().Name != "http://127.0.0.1:5001/api/v0" || r.Replicas() != 1 {
+		t.Errorf("unexpected single node registry: %d nodes, replicas %d", r.Len(), r.Replicas())
+	}
+	// без доступных узлов загрузки идут на основной узел
+	r.Fail(r.Primary(), &urlError)
+	if r.Primary().Healthy() || r.Pick() != r.Primary() {
+		t.Error("Pick() expected the primary node when no node is healthy")
+	}
+}
+
+// urlError ошибка соединения, как ее возвращает http.Client
+var urlError = url.Error{Op: "Post", URL: "http://127.0.0.1:5001/api/v0/id", Err: errors.New("connection refused")}
+
+func TestIsNodeError(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{fmt.Errorf("request failed: %w", &urlError), true},
+		{&url.Error{Op: "Post", URL: "http://127.0.0.1:5001", Err: context.DeadlineExceeded}, false},
+		{&url.Error{Op: "Post", URL: "http://127.0.0.1:5001", Err: context.Canceled}, false},
+		{errors.New("Kubo API (cat) вернул ошибку: 500 Internal Server Error"), false},
+	}
+	for _, test := range tests {
+		if actual := IsNodeError(test.err); actual != test.expected {
+			t.Errorf("IsNodeError(%v) = %v, expected %v", test.err, actual, test.expected)
+		}
+	}
+}
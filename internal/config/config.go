@@ -1,14 +1,151 @@
 package config
 
-import coreconfig "main/tools/pkg/core_config"
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	coreconfig "main/tools/pkg/core_config"
+)
+
+// IPFS конфигурация подключения к узлу Kubo
+type IPFS struct {
+	APIURL       string        `envconfig:"IPFS_API_URL" default:"http://127.0.0.1:5001/api/v0"`         // Kubo RPC API base URL
+	GatewayURL   string        `envconfig:"IPFS_GATEWAY_URL" default:"http://127.0.0.1:9000/v1/ipfs/%s"` // gateway URL template, %s is replaced by CID
+	Timeout      time.Duration `envconfig:"IPFS_API_TIMEOUT" default:"30s"`                              // timeout of a single RPC call
+	AddTimeout   time.Duration `envconfig:"IPFS_ADD_TIMEOUT" default:"10m"`                              // timeout of a streaming upload
+	PinTimeout   time.Duration `envconfig:"IPFS_PIN_TIMEOUT" default:"10m"`                              // timeout of pin/add, which may fetch the DAG from the network
+	AuthUsername string        `envconfig:"IPFS_API_USERNAME"`                                           // Basic auth user from API.Authorizations
+	AuthPassword string        `envconfig:"IPFS_API_PASSWORD"`                                           // Basic auth password from API.Authorizations
+	AuthToken    string        `envconfig:"IPFS_API_TOKEN"`                                              // Bearer token from API.Authorizations
+	// NodeURLs are the Kubo RPC API base URLs of all nodes, the first one is the primary node
+	// keeping IPNS keys and the MFS mirror. Empty means the single node at APIURL.
+	NodeURLs       []string      `envconfig:"IPFS_NODE_URLS"`
+	Replicas       int           `envconfig:"IPFS_REPLICAS" default:"2"`          // nodes pinning every CID, capped by the number of nodes
+	HealthInterval time.Duration `envconfig:"IPFS_HEALTH_INTERVAL" default:"30s"` // how often the nodes are checked, 0 disables the checks
+}
+
+// Nodes возвращает адреса API всех узлов Kubo, основной узел первым
+func (i *IPFS) Nodes() []string {
+	if len(i.NodeURLs) == 0 {
+		return []string{i.APIURL}
+	}
+	return i.NodeURLs
+}
+
+// Storage конфигурация хранилища контента
+type Storage struct {
+	Backend     string `envconfig:"STORAGE_BACKEND" default:"kubo"`              // kubo, local or s3
+	LocalPath   string `envconfig:"STORAGE_LOCAL_PATH" default:"./data/storage"` // root directory of the local backend
+	S3Endpoint  string `envconfig:"STORAGE_S3_ENDPOINT"`                         // e.g. http://127.0.0.1:9000 for MinIO
+	S3Region    string `envconfig:"STORAGE_S3_REGION" default:"us-east-1"`
+	S3Bucket    string `envconfig:"STORAGE_S3_BUCKET"`
+	S3AccessKey string `envconfig:"STORAGE_S3_ACCESS_KEY"`
+	S3SecretKey string `envconfig:"STORAGE_S3_SECRET_KEY"`
+}
+
+// NFT параметры публикации токенов
+type NFT struct {
+	MetadataBaseURI   string `envconfig:"NFT_METADATA_BASE_URI" default:"http://127.0.0.1:9000/v1/api/nft"`           // base of tokenURI
+	CollectionBaseURI string `envconfig:"NFT_COLLECTION_BASE_URI" default:"http://127.0.0.1:9000/v1/api/collections"` // base of tokenURI inside collections
+}
+
+// Pinning параметры фоновой обработки закреплений
+type Pinning struct {
+	Workers           int           `envconfig:"PINNING_WORKERS" default:"4"`              // pins processed concurrently
+	PollInterval      time.Duration `envconfig:"PINNING_POLL_INTERVAL" default:"5s"`       // how often the queue is checked
+	ReconcileInterval time.Duration `envconfig:"PINNING_RECONCILE_INTERVAL" default:"10m"` // how often the pins table is compared with the storage
+	MaxAttempts       int           `envconfig:"PINNING_MAX_ATTEMPTS" default:"5"`         // attempts before the pin is failed
+	RetryDelay        time.Duration `envconfig:"PINNING_RETRY_DELAY" default:"30s"`        // delay before the first retry, doubled for every next one
+	MaxRetryDelay     time.Duration `envconfig:"PINNING_MAX_RETRY_DELAY" default:"1h"`     // upper bound of the retry delay
+	StaleAfter        time.Duration `envconfig:"PINNING_STALE_AFTER" default:"30m"`        // pinning status older than this is requeued
+	Delegates         []string      `envconfig:"PINNING_DELEGATES"`                        // multiaddrs returned to pinning service clients, the node addresses by default
+}
+
+// Gateway параметры встроенного шлюза /v1/ipfs
+type Gateway struct {
+	CacheDir     string        `envconfig:"GATEWAY_CACHE_DIR" default:"./data/gateway"` // directory of the on-disk cache
+	CacheSize    int64         `envconfig:"GATEWAY_CACHE_SIZE" default:"1073741824"`    // bytes kept in the cache, 0 disables it
+	FetchTimeout time.Duration `envconfig:"GATEWAY_FETCH_TIMEOUT" default:"10m"`        // timeout of fetching an object from the storage
+}
+
+// IPNS публикация изменяемых указателей коллекций
+type IPNS struct {
+	Lifetime          time.Duration `envconfig:"IPNS_LIFETIME" default:"48h"`          // validity of a published record
+	RepublishInterval time.Duration `envconfig:"IPNS_REPUBLISH_INTERVAL" default:"1h"` // how often expiring records are republished, 0 disables it
+	RepublishBefore   time.Duration `envconfig:"IPNS_REPUBLISH_BEFORE" default:"24h"`  // records expiring sooner are republished
+}
+
+// MFS зеркало загрузок в файловом дереве узла Kubo
+type MFS struct {
+	Mirror bool   `envconfig:"MFS_MIRROR" default:"true"`       // copy uploads into MFS
+	Root   string `envconfig:"MFS_ROOT" default:"/nft-service"` // MFS directory holding the mirror
+}
+
+// Monitor наблюдение за узлом Kubo
+type Monitor struct {
+	Interval        time.Duration `envconfig:"MONITOR_INTERVAL" default:"1m"`           // how often node stats are collected, 0 disables polling
+	RepoFillWarning float64       `envconfig:"MONITOR_REPO_FILL_WARNING" default:"0.9"` // repo size to StorageMax ratio above which warnings are logged
+	MetricsToken    string        `envconfig:"MONITOR_METRICS_TOKEN"`                   // bearer token required by /v1/metrics, empty leaves it open
+}
+
+// GC поиск и удаление закреплений, на которые не ссылается ни одна запись базы
+type GC struct {
+	Interval    time.Duration `envconfig:"GC_INTERVAL" default:"24h"`     // how often orphan pins are searched, 0 disables the job
+	GracePeriod time.Duration `envconfig:"GC_GRACE_PERIOD" default:"72h"` // how long a pin stays orphaned before it is removed
+	DryRun      bool          `envconfig:"GC_DRY_RUN" default:"true"`     // only report orphans without unpinning them
+	RepoGC      bool          `envconfig:"GC_REPO_GC" default:"true"`     // run repo/gc after orphans are unpinned
+}
+
+// Integrity проверка целостности содержимого хранилища
+type Integrity struct {
+	AuditInterval time.Duration `envconfig:"INTEGRITY_AUDIT_INTERVAL" default:"24h"`   // how often all nft content is verified, 0 disables the audit
+	Workers       int           `envconfig:"INTEGRITY_WORKERS" default:"2"`            // CIDs verified concurrently by the audit
+	VerifyGateway bool          `envconfig:"INTEGRITY_VERIFY_GATEWAY" default:"false"` // verify content fetched by the gateway before serving it
+}
+
+// Replication копирование закреплений на удаленные сервисы с IPFS Pinning Service API
+type Replication struct {
+	Targets      []RemoteTarget `envconfig:"REPLICATION_TARGETS"`                    // comma separated name=endpoint|token
+	Copies       int            `envconfig:"REPLICATION_COPIES" default:"0"`         // targets each pin is copied to, 0 means all
+	PollInterval time.Duration  `envconfig:"REPLICATION_POLL_INTERVAL" default:"1m"` // how often remote pins in progress are checked
+	Timeout      time.Duration  `envconfig:"REPLICATION_TIMEOUT" default:"30s"`      // timeout of a single remote call
+}
+
+// RemoteTarget удаленный сервис закреплений
+type RemoteTarget struct {
+	Name     string // unique name, stored with the replica
+	Endpoint string // API endpoint without the /pins suffix
+	Token    string // bearer access token
+}
+
+// Decode разбирает сервис в формате name=endpoint|token
+func (t *RemoteTarget) Decode(value string) error {
+	name, rest, ok := strings.Cut(value, "=")
+	if !ok || name == "" || rest == "" {
+		return fmt.Errorf("remote target %q must be name=endpoint|token", value)
+	}
+	t.Name = name
+	t.Endpoint, t.Token, _ = strings.Cut(rest, "|")
+	return nil
+}
 
 type Config struct {
-	App              coreconfig.App
-	Database         coreconfig.Database
-	Logging          coreconfig.Logging
-	Redis            coreconfig.Redis
-	JWT              coreconfig.JWT
-	Secret           string `envconfig:"APP_SECRET"` // Secret of the application
-	IPFS_API_URL     string `envconfig:"IPFS_API_URL" default:"1s"`
-	IPFS_GATEWAY_URL string `envconfig:"IPFS_GATEWAY_URL" default:"1s"`
+	App         coreconfig.App
+	Database    coreconfig.Database
+	Logging     coreconfig.Logging
+	Redis       coreconfig.Redis
+	JWT         coreconfig.JWT
+	IPFS        IPFS
+	Storage     Storage
+	NFT         NFT
+	Pinning     Pinning
+	Replication Replication
+	Gateway     Gateway
+	Integrity   Integrity
+	IPNS        IPNS
+	MFS         MFS
+	Monitor     Monitor
+	GC          GC
+	Secret      string `envconfig:"APP_SECRET"` // Secret of the application
 }
@@ -0,0 +1,56 @@
+package dto
+
+// CreateCollectionRequest represents the request structure for the CreateCollection endpoint.
+type CreateCollectionRequest struct {
+	Slug            string `json:"slug" example:"cool-cats"`
+	Name            string `json:"name" example:"Cool Cats"`
+	Symbol          string `json:"symbol" example:"COOL"`
+	Description     string `json:"description" example:"About this collection"`
+	CoverCid        string `json:"cover_cid" example:"bafy..."`
+	ContractAddress string `json:"contract_address" example:"0x1A92f7381B9F03921564a437210bB9396471050C"`
+	ChainId         int64  `json:"chain_id" example:"1"`
+	// TokenStandard erc721 (default) or erc1155, can't be changed later
+	TokenStandard string `json:"token_standard" example:"erc1155"`
+}
+
+// UpdateCollectionRequest represents the request structure for the UpdateCollection endpoint.
+// Only non-nil fields are changed.
+type UpdateCollectionRequest struct {
+	Name            *string `json:"name" example:"Cool Cats"`
+	Symbol          *string `json:"symbol" example:"COOL"`
+	Description     *string `json:"description" example:"About this collection"`
+	CoverCid        *string `json:"cover_cid" example:"bafy..."`
+	ContractAddress *string `json:"contract_address" example:"0x1A92f7381B9F03921564a437210bB9396471050C"`
+	ChainId         *int64  `json:"chain_id" example:"1"`
+}
+
+// CollectionInfo represents a collection in responses
+type CollectionInfo struct {
+	Slug            string `json:"slug" example:"cool-cats"`
+	Name            string `json:"name" example:"Cool Cats"`
+	Symbol          string `json:"symbol" example:"COOL"`
+	Description     string `json:"description" example:"About this collection"`
+	CoverCid        string `json:"cover_cid" example:"bafy..."`
+	CoverLink       string `json:"cover_link,omitempty" example:"https://dsdsds"`
+	ContractAddress string `json:"contract_address" example:"0x1A92f7381B9F03921564a437210bB9396471050C"`
+	ChainId         int64  `json:"chain_id" example:"1"`
+	OwnerId         int64  `json:"owner_id" example:"1"`
+	TokenStandard   string `json:"token_standard" example:"erc1155"`
+	// MetadataURI is the ERC-1155 uri() template with the {id} placeholder
+	MetadataURI string `json:"metadata_uri,omitempty" example:"https://dsdsds/v1/api/collections/cool-cats/nft/{id}/metadata"`
+}
+
+// ReadCollectionResponse represents the response structure for the ReadCollection endpoint.
+type ReadCollectionResponse struct {
+	Info *CollectionInfo `json:"info"`
+}
+
+// ReadAllCollectionsResponse represents the response structure for the ReadAllCollections endpoint.
+type ReadAllCollectionsResponse struct {
+	Infos []CollectionInfo `json:"infos"`
+}
+
+// CollectionResponse represents the response of collection modification endpoints.
+type CollectionResponse struct {
+	Message string `json:"message"`
+}
@@ -0,0 +1,27 @@
+package dto
+
+import "time"
+
+// DirectoryUploadResponse represents a set of files added under one root directory
+type DirectoryUploadResponse struct {
+	RootCid string `json:"root_cid" example:"bafybeihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku"`
+	// BaseURI addresses the files of the directory as <base_uri><path>
+	BaseURI     string              `json:"base_uri" example:"ipfs://bafybeihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku/"`
+	GatewayLink string              `json:"gateway_link"`
+	Size        int64               `json:"size" example:"1024"`
+	Files       []DirectoryFileInfo `json:"files"`
+	Created     time.Time           `json:"created"`
+	// Pin is the request tracking the root directory, returned on upload
+	Pin *PinRequestStatus `json:"pin,omitempty"`
+}
+
+// DirectoryFileInfo represents a file or a subdirectory of a directory upload
+type DirectoryFileInfo struct {
+	Path      string `json:"path" example:"1.json"`
+	Cid       string `json:"cid" example:"bafkreigh2akiscaildcqabsyg3dfr6chu3fgpregiymsck7e7aqa4s52zy"`
+	Size      int64  `json:"size" example:"512"`
+	Directory bool   `json:"directory,omitempty"`
+	// TokenId is the token named by the file: 1.json and images/1.png belong to the token 1
+	TokenId *int64 `json:"token_id,omitempty" example:"1"`
+	URI     string `json:"uri" example:"ipfs://bafybeihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku/1.json"`
+}
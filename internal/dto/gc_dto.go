@@ -0,0 +1,39 @@
+package dto
+
+import "time"
+
+// GcReportResponse represents the tracked orphan pins and the latest garbage collection
+type GcReportResponse struct {
+	// DryRun tells whether the scheduled collections only report orphans
+	DryRun      bool   `json:"dry_run" example:"true"`
+	GracePeriod string `json:"grace_period" example:"72h0m0s"`
+	// Orphans are the pins no record references, the longest orphaned first
+	Orphans []OrphanPinInfo `json:"orphans"`
+	// LastRun summarizes the latest collection since the service started, absent before the first one
+	LastRun *GcRunInfo `json:"last_run,omitempty"`
+}
+
+// OrphanPinInfo represents a pin no record references
+type OrphanPinInfo struct {
+	Cid       string    `json:"cid" example:"QmWATWQ7fVPP2EFGu71UkfnqhYXDYH566qy47CnJDgvs8u"`
+	FirstSeen time.Time `json:"first_seen"`
+	// Expired is set once the pin stayed orphaned longer than the grace period
+	Expired bool `json:"expired"`
+}
+
+// GcRunInfo summarizes a pass of the orphan pin collection
+type GcRunInfo struct {
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	DryRun   bool      `json:"dry_run" example:"false"`
+	Pinned   int       `json:"pinned" example:"120"`
+	Orphans  int       `json:"orphans" example:"3"`
+	// Expired are the orphans older than the grace period, they are unpinned unless DryRun is set
+	Expired       []string `json:"expired"`
+	Unpinned      []string `json:"unpinned"`
+	Errors        int      `json:"errors" example:"0"`
+	RemovedFiles  int      `json:"removed_files" example:"2"`
+	RemovedBlocks int      `json:"removed_blocks" example:"12"`
+	// Error tells why the collection stopped early
+	Error string `json:"error,omitempty"`
+}
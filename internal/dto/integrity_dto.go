@@ -0,0 +1,34 @@
+package dto
+
+import "time"
+
+// IntegrityReportResponse lists the CIDs failing their latest integrity check
+type IntegrityReportResponse struct {
+	Failures []ContentCheckInfo `json:"failures"`
+	// LastAudit summarizes the latest audit since the service started, absent before the first one
+	LastAudit *AuditRunInfo `json:"last_audit,omitempty"`
+}
+
+// ContentCheckInfo represents the latest failed check of a CID
+type ContentCheckInfo struct {
+	Cid string `json:"cid" example:"QmWATWQ7fVPP2EFGu71UkfnqhYXDYH566qy47CnJDgvs8u"`
+	// Status is mismatch when the content does not hash to the CID and missing when it is absent
+	Status  string    `json:"status" example:"missing"`
+	Error   string    `json:"error"`
+	Source  string    `json:"source" example:"audit"`
+	Checked time.Time `json:"checked"`
+	// Failing is when the CID started failing the checks
+	Failing time.Time `json:"failing"`
+	// Tokens reference the CID as <collection>/<token_id> or <token_id>
+	Tokens []string `json:"tokens"`
+}
+
+// AuditRunInfo summarizes a pass of the integrity audit
+type AuditRunInfo struct {
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Checked  int       `json:"checked" example:"120"`
+	Failed   int       `json:"failed" example:"1"`
+	// Errors counts CIDs which could not be checked
+	Errors int `json:"errors" example:"0"`
+}
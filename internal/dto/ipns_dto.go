@@ -0,0 +1,22 @@
+package dto
+
+import "time"
+
+// PublishIpnsRequest represents the request structure for the PublishCollectionIpns endpoint.
+type PublishIpnsRequest struct {
+	// Cid the record points to, usually the root of the collection metadata directory
+	Cid string `json:"cid" example:"bafybeihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku"`
+}
+
+// IpnsRecordInfo represents the IPNS record of a collection
+type IpnsRecordInfo struct {
+	Name string `json:"name" example:"k51qzi5uqu5dlvj2baxnqndepeb86cbk3ng7n3i46uzyxzyqj2xjonzllnv0v8"`
+	Link string `json:"link" example:"ipns://k51qzi5uqu5dlvj2baxnqndepeb86cbk3ng7n3i46uzyxzyqj2xjonzllnv0v8"`
+	// Value is the CID the record points to
+	Value       string `json:"value" example:"bafybeihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku"`
+	GatewayLink string `json:"gateway_link"`
+	// Sequence counts the publications of the record, republishing increments it as well
+	Sequence  int64     `json:"sequence" example:"3"`
+	Published time.Time `json:"published"`
+	Expires   time.Time `json:"expires"`
+}
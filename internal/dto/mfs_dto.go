@@ -0,0 +1,17 @@
+package dto
+
+// MfsEntryInfo represents a file or a directory of the MFS mirror
+type MfsEntryInfo struct {
+	Name string `json:"name" example:"metadata.json"`
+	// Path is the full MFS path of the entry
+	Path      string `json:"path" example:"/nft-service/cool-cats/1/metadata.json"`
+	Cid       string `json:"cid" example:"bafkreigh2akiscaildcqabsyg3dfr6chu3fgpregiymsck7e7aqa4s52zy"`
+	Size      int64  `json:"size" example:"512"`
+	Directory bool   `json:"directory,omitempty"`
+}
+
+// MfsListResponse represents the entries of a directory of the MFS mirror
+type MfsListResponse struct {
+	Path    string         `json:"path" example:"/nft-service/cool-cats"`
+	Entries []MfsEntryInfo `json:"entries"`
+}
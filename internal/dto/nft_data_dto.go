@@ -1,32 +1,127 @@
 package dto
 
-import "mime/multipart"
+import (
+	"encoding/json"
+	"mime/multipart"
+	"time"
 
+	"main/internal/models"
+)
+
+// CreateNftDataRequest multipart form of a new token. The file is streamed into the storage,
+// so the text fields must be sent before it: a field after the file or a missing id is rejected with 400.
 type CreateNftDataRequest struct {
+	Name        string                `json:"name" form:"name" example:"Token #1"`
 	Description string                `json:"description" example:"About this token"`
 	ImageFile   *multipart.FileHeader `json:"file" form:"file" example:"pic12.png"`
 	Id          int64                 `json:"id" example:"1"`
+	// Attributes JSON array of traits, e.g. [{"trait_type":"level","value":5,"display_type":"number"}]
+	Attributes string `json:"attributes" form:"attributes"`
+	// Collection slug of the collection the token belongs to, empty for standalone tokens
+	Collection string `json:"collection" form:"collection" example:"cool-cats"`
+	// MaxSupply edition size for ERC-1155 collections, zero means unlimited
+	MaxSupply int64 `json:"max_supply" form:"max_supply" example:"100"`
+	// OnDuplicate what to do when a token with the same content exists: "reject" (default) or "existing"
+	OnDuplicate string `json:"on_duplicate" form:"on_duplicate" example:"reject"`
 }
 
 type NftData struct {
-	TokenId     int64  `json:"token_id" example:"1"`
-	Description string `json:"description" example:"About this token"`
-	CidV0       string `json:"cid_v0" example:"dss"`
-	CidV1       string `json:"cid_v1" example:"dss"`
-	FileName    string `json:"file_name" example:"pic12.png"`
-	FileSize    string `json:"file_size" example:"12kb"`
+	TokenId      int64                 `json:"token_id" example:"1"`
+	Name         string                `json:"name" example:"Token #1"`
+	Description  string                `json:"description" example:"About this token"`
+	CidV0        string                `json:"cid_v0" example:"dss"`
+	CidV1        string                `json:"cid_v1" example:"dss"`
+	FileName     string                `json:"file_name" example:"pic12.png"`
+	FileSize     string                `json:"file_size" example:"12kb"`
+	MetadataCid  string                `json:"metadata_cid" example:"dss"`
+	CollectionId int64                 `json:"collection_id" example:"1"`
+	MaxSupply    int64                 `json:"max_supply" example:"1"`
+	AuthorId     int64                 `json:"author_id" example:"1"`
+	Attributes   []models.NftAttribute `json:"attributes"`
 }
 
 type CreateNftDataResponse struct {
-	Message string `json:"message"`
+	Message     string `json:"message"`
+	MetadataCid string `json:"metadata_cid,omitempty"`
+	TokenURI    string `json:"token_uri,omitempty"`
+	// Existing the token already keeping the same content, set instead of creating a new one
+	Existing *NftInfo `json:"existing,omitempty"`
+}
+
+// CidPreviewResponse the CIDs a file would get when uploaded, nothing is stored
+type CidPreviewResponse struct {
+	Name  string `json:"name" example:"pic12.png"`
+	Size  int64  `json:"size" example:"1024"`
+	CidV0 string `json:"cid_v0" example:"QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG"`
+	CidV1 string `json:"cid_v1" example:"bafybeiezfsxd3jlaivqftqlqbmw6kzfgpfnmdyvyexkfrwvlh4pxp2gh5q"`
+	// Existing the live token already keeping the same content
+	Existing *NftInfo `json:"existing,omitempty"`
 }
 
 type NftInfo struct {
 	TokenId     int64  `json:"token_id" example:"1"`
+	Name        string `json:"name" example:"Token #1"`
 	Description string `json:"description" example:"About this token"`
 	CidV0       string `json:"cid_v0" example:"dss"`
 	CidV1       string `json:"cid_v1" example:"dss"`
 	Link        string `json:"link" example:"https://dsdsds"`
+	MetadataCid string `json:"metadata_cid" example:"dss"`
+	TokenURI    string `json:"token_uri" example:"https://dsdsds/v1/api/nft/1/metadata"`
+	Collection  string `json:"collection,omitempty" example:"cool-cats"`
+	// CollectionIpnsLink is the mutable address of the collection content, present once the collection publishes it
+	CollectionIpnsLink string `json:"collection_ipns_link,omitempty" example:"ipns://k51qzi5uqu5dlvj2baxnqndepeb86cbk3ng7n3i46uzyxzyqj2xjonzllnv0v8"`
+	// TokenStandard erc721 or erc1155
+	TokenStandard string `json:"token_standard" example:"erc1155"`
+	// EditionId token id as 64-char lowercase hex, substituted for {id} in ERC-1155 URIs
+	EditionId    string         `json:"edition_id,omitempty" example:"000000000000000000000000000000000000000000000000000000000000004d"`
+	MaxSupply    int64          `json:"max_supply" example:"100"`
+	MintedSupply int64          `json:"minted_supply" example:"3"`
+	CreatorId    int64          `json:"creator_id,omitempty" example:"1"`
+	CreatedAt    time.Time      `json:"created_at"`
+	Attributes   []NftAttribute `json:"attributes"`
+}
+
+// UpdateNftDataRequest represents the request structure for the UpdateNftData endpoint.
+// Only fields present in the request are changed, attributes are replaced as a whole.
+type UpdateNftDataRequest struct {
+	Name        *string `json:"name" example:"Token #1"`
+	Description *string `json:"description" example:"About this token"`
+	// Attributes array of traits, e.g. [{"trait_type":"level","value":5,"display_type":"number"}]
+	Attributes json.RawMessage `json:"attributes"`
+}
+
+// DeleteNftDataResponse represents the response structure for the DeleteNftData endpoint.
+type DeleteNftDataResponse struct {
+	Message  string   `json:"message"`
+	Unpinned []string `json:"unpinned,omitempty"`
+}
+
+// NftRevision represents a revision of the nft in responses
+type NftRevision struct {
+	Revision    int                       `json:"revision" example:"2"`
+	AuthorId    int64                     `json:"author_id,omitempty" example:"1"`
+	CreatedAt   time.Time                 `json:"created_at"`
+	Name        string                    `json:"name" example:"Token #1"`
+	Description string                    `json:"description" example:"About this token"`
+	CidV0       string                    `json:"cid_v0" example:"dss"`
+	CidV1       string                    `json:"cid_v1" example:"dss"`
+	FileName    string                    `json:"file_name,omitempty" example:"1.png"`
+	MetadataCid string                    `json:"metadata_cid" example:"dss"`
+	Attributes  []models.NftMetadataTrait `json:"attributes"`
+	// RollbackOf the revision restored by this one
+	RollbackOf int `json:"rollback_of,omitempty" example:"1"`
+	// Changes fields changed since the previous revision: name, description, attributes, content, metadata
+	Changes []string `json:"changes"`
+}
+
+// ReadNftRevisionsResponse represents the response structure for the ReadNftRevisions endpoint.
+type ReadNftRevisionsResponse struct {
+	Revisions []NftRevision `json:"revisions"`
+}
+
+// MintEditionRequest represents the request structure for the MintEdition endpoint.
+type MintEditionRequest struct {
+	Amount int64 `json:"amount" example:"1"`
 }
 
 type ReadNftResponse struct {
@@ -35,4 +130,28 @@ type ReadNftResponse struct {
 
 type ReadAllNftResponse struct {
 	Infos *[]NftInfo `json:"infos"`
+	// NextCursor is passed as cursor to get the next page, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// NftSearchResult represents a found nft in the SearchNft response
+type NftSearchResult struct {
+	Info *NftInfo `json:"info"`
+	Rank float32  `json:"rank" example:"0.1"`
+	// Snippet HTML-escaped fragment of the name and description with matches wrapped in <b>
+	Snippet string `json:"snippet" example:"a <b>red</b> cat"`
+}
+
+// SearchNftResponse represents the response structure for the SearchNft endpoint.
+type SearchNftResponse struct {
+	Results []NftSearchResult `json:"results"`
+	// NextCursor is passed as cursor to get the next page, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// NftAttribute represents a trait of an nft in requests and responses
+type NftAttribute struct {
+	TraitType   string      `json:"trait_type" example:"Background"`
+	DisplayType string      `json:"display_type,omitempty" example:"number"`
+	Value       interface{} `json:"value"`
 }
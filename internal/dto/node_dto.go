@@ -0,0 +1,58 @@
+package dto
+
+import "time"
+
+// NodeStatsResponse represents the state of the storage node
+type NodeStatsResponse struct {
+	PeerId       string            `json:"peer_id" example:"12D3KooWHz3aPjSvG2QRbyEDmcqpUN1ox4wsGB6Ciu2xY8dpMwnh"`
+	AgentVersion string            `json:"agent_version" example:"kubo/0.34.1/"`
+	Addresses    []string          `json:"addresses"`
+	Version      string            `json:"version" example:"0.34.1"`
+	Commit       string            `json:"commit,omitempty"`
+	RepoVersion  string            `json:"repo_version" example:"16"`
+	System       string            `json:"system" example:"amd64/linux"`
+	Golang       string            `json:"golang" example:"go1.24.2"`
+	Repo         NodeRepoInfo      `json:"repo"`
+	Bandwidth    NodeBandwidthInfo `json:"bandwidth"`
+	Peers        int               `json:"peers" example:"120"`
+	CollectedAt  time.Time         `json:"collected_at"`
+}
+
+// NodeRepoInfo represents the repo usage of the storage node
+type NodeRepoInfo struct {
+	Path       string `json:"path" example:"/data/ipfs"`
+	Size       uint64 `json:"size" example:"1073741824"`
+	StorageMax uint64 `json:"storage_max" example:"10000000000"`
+	// Fill is the Size to StorageMax ratio, zero when the limit is unknown
+	Fill float64 `json:"fill" example:"0.107"`
+	// Warning is set when Fill is above the configured threshold
+	Warning bool `json:"warning,omitempty"`
+}
+
+// NodeBandwidthInfo represents the traffic of the storage node
+type NodeBandwidthInfo struct {
+	TotalIn  int64   `json:"total_in" example:"52428800"`
+	TotalOut int64   `json:"total_out" example:"104857600"`
+	RateIn   float64 `json:"rate_in" example:"1024.5"`
+	RateOut  float64 `json:"rate_out" example:"2048.25"`
+}
+
+// KuboNodeInfo represents the health state of a Kubo node
+type KuboNodeInfo struct {
+	// Name is the RPC API URL of the node
+	Name string `json:"name" example:"http://127.0.0.1:5001/api/v0"`
+	// Primary is set for the first configured node, which serves reads while it is healthy
+	Primary   bool      `json:"primary,omitempty"`
+	Healthy   bool      `json:"healthy"`
+	Error     string    `json:"error,omitempty"`
+	PeerId    string    `json:"peer_id,omitempty" example:"12D3KooWHz3aPjSvG2QRbyEDmcqpUN1ox4wsGB6Ciu2xY8dpMwnh"`
+	Addresses []string  `json:"addresses,omitempty"`
+	Checked   time.Time `json:"checked"`
+}
+
+// KuboNodesResponse lists the Kubo nodes of the service
+type KuboNodesResponse struct {
+	// Replicas is the number of nodes pinning every CID
+	Replicas int            `json:"replicas" example:"2"`
+	Nodes    []KuboNodeInfo `json:"nodes"`
+}
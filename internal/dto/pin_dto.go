@@ -0,0 +1,36 @@
+package dto
+
+import "time"
+
+// PinRequestStatus represents the state of an asynchronous pin request
+type PinRequestStatus struct {
+	RequestId string `json:"requestid" example:"5c3a1f8e-3b7d-4c39-9f4e-2d7f0f2c4a11"`
+	Cid       string `json:"cid" example:"QmTest"`
+	// Status queued, pinning, pinned or failed
+	Status   string     `json:"status" example:"queued"`
+	Attempts int        `json:"attempts" example:"1"`
+	Error    string     `json:"error,omitempty"`
+	Created  time.Time  `json:"created"`
+	PinnedAt *time.Time `json:"pinned_at,omitempty"`
+	// Replicas copies of the pin on remote pinning services
+	Replicas []PinReplicaStatus `json:"replicas,omitempty"`
+}
+
+// PinReplicaStatus represents the state of a pin copy on a remote pinning service
+type PinReplicaStatus struct {
+	Target          string `json:"target" example:"pinata"`
+	RemoteRequestId string `json:"remote_requestid,omitempty"`
+	// Status queued, pinning, pinned or failed
+	Status   string     `json:"status" example:"pinning"`
+	Attempts int        `json:"attempts" example:"1"`
+	Error    string     `json:"error,omitempty"`
+	PinnedAt *time.Time `json:"pinned_at,omitempty"`
+}
+
+// ImportCarResponse represents the result of a CAR file import
+type ImportCarResponse struct {
+	// Roots CIDs of the DAGs imported from the file
+	Roots []string `json:"roots" example:"bafybeihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku"`
+	// Pins requests tracking the roots
+	Pins []*PinRequestStatus `json:"pins"`
+}
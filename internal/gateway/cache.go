@@ -0,0 +1,166 @@
+package gateway
+
+import (
+	"container/list"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tempSuffix marks files which are still being fetched
+const tempSuffix = ".tmp"
+
+// diskCache keeps fetched objects as files named by their key and evicts
+// the least recently used ones once the total size exceeds the capacity.
+// Objects being fetched take their space up front, so that the files
+// in progress are bound by the capacity as well.
+type diskCache struct {
+	dir      string
+	capacity int64
+
+	mu       sync.Mutex
+	size     int64      // cached objects and reserved space
+	reserved int64      // space of the objects being fetched, not evicted
+	lru      *list.List // *cacheEntry, the most recently used at the front
+	entries  map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key  string
+	size int64
+}
+
+// newDiskCache opens the cache directory and indexes the objects left by the previous run.
+// Unfinished fetches are removed.
+func newDiskCache(dir string, capacity int64) (*diskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	dc := &diskCache{dir: dir, capacity: capacity, lru: list.New(), entries: map[string]*list.Element{}}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	type stored struct {
+		key     string
+		size    int64
+		modTime time.Time
+	}
+	var objects []stored
+	for _, file := range files {
+		info, err := file.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if strings.HasSuffix(file.Name(), tempSuffix) {
+			_ = os.Remove(filepath.Join(dir, file.Name()))
+			continue
+		}
+		objects = append(objects, stored{key: file.Name(), size: info.Size(), modTime: info.ModTime()})
+	}
+
+	// the oldest objects go to the back of the list and are evicted first
+	slices.SortFunc(objects, func(a, b stored) int { return b.modTime.Compare(a.modTime) })
+	for _, object := range objects {
+		dc.entries[object.key] = dc.lru.PushBack(&cacheEntry{key: object.key, size: object.size})
+		dc.size += object.size
+	}
+	dc.evict()
+	return dc, nil
+}
+
+// open opens the cached object and marks it as recently used
+func (dc *diskCache) open(key string) (*os.File, int64, bool) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	element, ok := dc.entries[key]
+	if !ok {
+		return nil, 0, false
+	}
+	// an open file stays readable after its eviction
+	file, err := os.Open(dc.path(key))
+	if err != nil {
+		dc.remove(element)
+		return nil, 0, false
+	}
+	dc.lru.MoveToFront(element)
+	return file, element.Value.(*cacheEntry).size, true
+}
+
+// tempFile creates a file for an object being fetched
+func (dc *diskCache) tempFile() (*os.File, error) {
+	return os.CreateTemp(dc.dir, "*"+tempSuffix)
+}
+
+// reserve takes the space for an object being fetched, the least recently used objects are evicted
+// to make room. It fails when the object does not fit the cache next to the other objects in progress.
+func (dc *diskCache) reserve(size int64) bool {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	if size > dc.capacity-dc.reserved {
+		return false
+	}
+	dc.reserved += size
+	dc.size += size
+	dc.evict()
+	return true
+}
+
+// cancel frees the space reserved for an object which was not fetched
+func (dc *diskCache) cancel(size int64) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	dc.reserved -= size
+	dc.size -= size
+}
+
+// put moves the fetched object into the space reserved for it. The temporary file is removed
+// when it can't be moved.
+func (dc *diskCache) put(key, tempPath string, size int64) error {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	dc.reserved -= size
+	if element, ok := dc.entries[key]; ok {
+		dc.size -= size
+		dc.lru.MoveToFront(element)
+		return os.Remove(tempPath)
+	}
+	if err := os.Rename(tempPath, dc.path(key)); err != nil {
+		dc.size -= size
+		_ = os.Remove(tempPath)
+		return err
+	}
+	dc.entries[key] = dc.lru.PushFront(&cacheEntry{key: key, size: size})
+	return nil
+}
+
+// evict removes the least recently used objects until the cache fits its capacity.
+// The reserved space is never larger than the capacity, so that it always fits eventually.
+func (dc *diskCache) evict() {
+	for dc.size > dc.capacity {
+		oldest := dc.lru.Back()
+		if oldest == nil {
+			return
+		}
+		dc.remove(oldest)
+	}
+}
+
+func (dc *diskCache) remove(element *list.Element) {
+	entry := dc.lru.Remove(element).(*cacheEntry)
+	delete(dc.entries, entry.key)
+	dc.size -= entry.size
+	_ = os.Remove(dc.path(entry.key))
+}
+
+func (dc *diskCache) path(key string) string {
+	return filepath.Join(dc.dir, key)
+}
@@ -0,0 +1,411 @@
+// Package gateway serves the content of the storage to HTTP clients. Every object is fetched once
+// no matter how many clients ask for it at the same time, kept in a bounded on-disk cache
+// and read back with random access, so that Range requests can be answered. Objects larger
+// than the cache are streamed from the storage, which reads the requested range only.
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"golang.org/x/sync/singleflight"
+
+	"main/internal/config"
+	"main/internal/storage"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// defaultFetchTimeout is used when the config has no fetch timeout
+const defaultFetchTimeout = 10 * time.Minute
+
+// maxVerified bounds the number of streamed objects remembered as verified
+const maxVerified = 4096
+
+// ErrRangeNotSatisfiable is returned for ranges outside of the content
+var ErrRangeNotSatisfiable = errors.New("range not satisfiable")
+
+// Content is an object opened for reading. Fetched objects are read from their file, objects which
+// do not fit the cache are streamed from the storage. Close releases the content.
+type Content struct {
+	Cid  cid.Cid
+	Size int64
+
+	file    io.ReaderAt
+	release func()
+	stream  func(ctx context.Context, offset, length int64) (io.ReadCloser, error)
+	verify  func() <-chan error
+}
+
+// Reader returns length bytes of the content from the offset. Readers are independent of each other
+// and must be closed before the content.
+func (c *Content) Reader(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+	if length <= 0 {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	if c.file != nil {
+		return io.NopCloser(io.NewSectionReader(c.file, offset, length)), nil
+	}
+	body, err := c.stream(ctx, offset, length)
+	if err != nil || c.verify == nil {
+		return body, err
+	}
+	return &verifiedReader{ReadCloser: body, result: c.verify()}, nil
+}
+
+// Head returns up to n first bytes of the content to detect its type. The bytes are not
+// verified and must not be served.
+func (c *Content) Head(ctx context.Context, n int64) ([]byte, error) {
+	head := make([]byte, min(n, c.Size))
+	if len(head) == 0 {
+		return head, nil
+	}
+	if c.file != nil {
+		read, err := c.file.ReadAt(head, 0)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		return head[:read], nil
+	}
+	body, err := c.stream(ctx, 0, int64(len(head)))
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	read, err := io.ReadFull(body, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return head[:read], nil
+}
+
+// Close releases the file of the content
+func (c *Content) Close() error {
+	if c.release != nil {
+		c.release()
+	}
+	return nil
+}
+
+// Verifier checks that the storage returns the content addressed by the CID.
+type Verifier interface {
+	Verify(ctx context.Context, c cid.Cid) error
+}
+
+// fetchCall is a fetch shared by the requests for the same object. The requests read the fetched
+// file through the handle of the call, so that it stays readable after it is moved into the cache
+// or evicted from it.
+type fetchCall struct {
+	done chan struct{}
+	file *os.File
+	size int64
+	err  error
+	refs int // requests holding the call, guarded by Gateway.mu
+}
+
+// Gateway fetches objects from the storage through the disk cache.
+type Gateway struct {
+	storage       storage.Storage
+	cache         *diskCache
+	fetchTimeout  time.Duration
+	verifier      Verifier
+	verifications singleflight.Group
+
+	mu       sync.Mutex
+	calls    map[string]*fetchCall
+	verified map[string]struct{}
+}
+
+// New creates a gateway on top of the storage. A non-nil verifier checks the content of every
+// object before it enters the cache, objects streamed past the cache are checked while they are read.
+func New(s storage.Storage, cfg *config.Gateway, verifier Verifier) (*Gateway, error) {
+	cache, err := newDiskCache(cfg.CacheDir, cfg.CacheSize)
+	if err != nil {
+		return nil, tvoerrors.Wrap("gateway.New", err)
+	}
+	fetchTimeout := cfg.FetchTimeout
+	if fetchTimeout <= 0 {
+		fetchTimeout = defaultFetchTimeout
+	}
+	return &Gateway{
+		storage:      s,
+		cache:        cache,
+		fetchTimeout: fetchTimeout,
+		verifier:     verifier,
+		calls:        map[string]*fetchCall{},
+		verified:     map[string]struct{}{},
+	}, nil
+}
+
+// Resolve returns the CID of the object at the path inside the root directory.
+// Storages which can't resolve paths only serve the root itself.
+func (g *Gateway) Resolve(ctx context.Context, root cid.Cid, path string) (cid.Cid, error) {
+	const op = "gateway.Gateway.Resolve"
+
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return root, nil
+	}
+	resolver, ok := g.storage.(storage.Resolver)
+	if !ok {
+		return cid.Undef, tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+	}
+	c, err := resolver.Resolve(ctx, root, path)
+	if err != nil {
+		return cid.Undef, tvoerrors.Wrap(op, err)
+	}
+	return c, nil
+}
+
+// Open returns the content of the CID. Concurrent requests for an object missing from the cache
+// share a single fetch and read the file it fills. Storages reading ranges report the size first:
+// objects which do not fit the cache next to the fetches in progress are streamed range by range
+// without being fetched whole.
+func (g *Gateway) Open(ctx context.Context, c cid.Cid) (*Content, error) {
+	const op = "gateway.Gateway.Open"
+
+	// CIDv0 and CIDv1 of the same content share the cache entry
+	key := cid.NewCidV1(c.Type(), c.Hash()).String()
+	if content, ok := g.openCached(key, c); ok {
+		return content, nil
+	}
+
+	size := int64(-1)
+	ranges, ok := g.storage.(storage.RangeStorage)
+	if ok {
+		var err error
+		if size, err = ranges.Size(ctx, c); err != nil {
+			return nil, tvoerrors.Wrap(op, err)
+		}
+	}
+
+	g.mu.Lock()
+	// the object could be cached by a fetch finished in the meantime
+	if content, ok := g.openCached(key, c); ok {
+		g.mu.Unlock()
+		return content, nil
+	}
+	call, ok := g.calls[key]
+	switch {
+	case ok:
+		call.refs++
+	case size < 0 || g.cache.reserve(size):
+		call = &fetchCall{done: make(chan struct{}), refs: 1}
+		g.calls[key] = call
+		go g.fetch(key, c, size, call)
+	default:
+		g.mu.Unlock()
+		return g.stream(key, c, ranges, size), nil
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		g.leave(call)
+		return nil, tvoerrors.Wrap(op, ctx.Err())
+	}
+	if call.err != nil {
+		g.leave(call)
+		return nil, tvoerrors.Wrap(op, call.err)
+	}
+	return &Content{Cid: c, Size: call.size, file: call.file, release: func() { g.leave(call) }}, nil
+}
+
+// openCached opens the object kept by the cache
+func (g *Gateway) openCached(key string, c cid.Cid) (*Content, bool) {
+	file, size, ok := g.cache.open(key)
+	if !ok {
+		return nil, false
+	}
+	return &Content{Cid: c, Size: size, file: file, release: func() { _ = file.Close() }}, true
+}
+
+// fetch downloads the object for the call and moves it into the cache. A known size is reserved
+// in the cache beforehand, otherwise the object is kept when it fits once fetched. The fetch
+// is not bound to the requests, so that the other waiters get the object when the first client
+// goes away.
+func (g *Gateway) fetch(key string, c cid.Cid, size int64, call *fetchCall) {
+	ctx, cancel := context.WithTimeout(context.Background(), g.fetchTimeout)
+	defer cancel()
+
+	file, n, err := g.download(ctx, c, size)
+	switch {
+	case err != nil:
+		if size >= 0 {
+			g.cache.cancel(size)
+		}
+	case size >= 0 || g.cache.reserve(n):
+		// a failed move only leaves the object out of the cache, the requests read the open file
+		_ = g.cache.put(key, file.Name(), n)
+	default:
+		// the object is too large for the cache and is removed once the last request closes it
+		_ = os.Remove(file.Name())
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.calls, key)
+	call.file, call.size, call.err = file, n, err
+	close(call.done)
+	if call.refs == 0 && file != nil {
+		_ = file.Close()
+	}
+}
+
+// leave releases the call for a request, the file is closed after the last one
+func (g *Gateway) leave(call *fetchCall) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	call.refs--
+	if call.refs == 0 && call.file != nil {
+		_ = call.file.Close()
+	}
+}
+
+// download fetches the object into a temporary file of the cache directory. A known size
+// is checked, so that the file never outgrows the space reserved for it.
+func (g *Gateway) download(ctx context.Context, c cid.Cid, size int64) (*os.File, int64, error) {
+	if g.verifier != nil {
+		if err := g.verifier.Verify(ctx, c); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	body, err := g.storage.Cat(ctx, c)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer body.Close()
+
+	file, err := g.cache.tempFile()
+	if err != nil {
+		return nil, 0, err
+	}
+	var r io.Reader = body
+	if size >= 0 {
+		r = io.LimitReader(body, size+1)
+	}
+	n, err := io.Copy(file, r)
+	if err == nil && size >= 0 && n != size {
+		err = fmt.Errorf("content of %s does not match its size %d", c, size)
+	}
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return nil, 0, err
+	}
+	return file, n, nil
+}
+
+// stream serves the object straight from the storage, every range is read with its own request.
+// With a verifier the end of each range is held back until the object is verified.
+func (g *Gateway) stream(key string, c cid.Cid, ranges storage.RangeStorage, size int64) *Content {
+	content := &Content{Cid: c, Size: size, stream: func(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+		return ranges.CatRange(ctx, c, offset, length)
+	}}
+	if g.verifier != nil {
+		content.verify = func() <-chan error { return g.verify(key, c) }
+	}
+	return content
+}
+
+// verify checks the streamed object in the background. Concurrent checks of the object are shared
+// and the objects which passed are remembered, so that the ranges of one object are not checked again.
+func (g *Gateway) verify(key string, c cid.Cid) <-chan error {
+	result := make(chan error, 1)
+	g.mu.Lock()
+	_, passed := g.verified[key]
+	g.mu.Unlock()
+	if passed {
+		result <- nil
+		return result
+	}
+
+	go func() {
+		_, err, _ := g.verifications.Do(key, func() (interface{}, error) {
+			ctx, cancel := context.WithTimeout(context.Background(), g.fetchTimeout)
+			defer cancel()
+
+			if err := g.verifier.Verify(ctx, c); err != nil {
+				return nil, err
+			}
+			g.mu.Lock()
+			if len(g.verified) >= maxVerified {
+				clear(g.verified)
+			}
+			g.verified[key] = struct{}{}
+			g.mu.Unlock()
+			return nil, nil
+		})
+		result <- err
+	}()
+	return result
+}
+
+// verifiedReader returns the end of the range only after the object is verified, so that
+// corrupted content is cut short instead of being served whole.
+type verifiedReader struct {
+	io.ReadCloser
+	result <-chan error
+	err    error
+	waited bool
+}
+
+func (r *verifiedReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if err == io.EOF {
+		if !r.waited {
+			r.err, r.waited = <-r.result, true
+		}
+		if r.err != nil {
+			return n, r.err
+		}
+	}
+	return n, err
+}
+
+// ParseRange parses a single range of the Range header, bytes=0-99, bytes=100- or bytes=-100,
+// and returns its inclusive bounds. ok is false for headers the gateway ignores and answers
+// with the whole content: other units and multiple ranges.
+func ParseRange(header string, size int64) (start, end int64, ok bool, err error) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return 0, 0, false, nil
+	}
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, 0, false, ErrRangeNotSatisfiable
+	}
+
+	if first == "" {
+		// suffix range: the last bytes of the content
+		suffix, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || suffix <= 0 || size == 0 {
+			return 0, 0, false, ErrRangeNotSatisfiable
+		}
+		return max(size-suffix, 0), size - 1, true, nil
+	}
+
+	start, err = strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, false, ErrRangeNotSatisfiable
+	}
+	end = size - 1
+	if last != "" {
+		if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+			return 0, 0, false, ErrRangeNotSatisfiable
+		}
+		end = min(end, size-1)
+	}
+	return start, end, true, nil
+}
@@ -0,0 +1,356 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+
+	"main/internal/config"
+	"main/internal/storage"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// fakeStorage serves fixed objects and counts the fetches, release holds them until closed.
+type fakeStorage struct {
+	storage.Storage
+	objects map[string][]byte
+	fetches atomic.Int32
+	release chan struct{}
+}
+
+func (s *fakeStorage) Cat(_ context.Context, c cid.Cid) (io.ReadCloser, error) {
+	s.fetches.Add(1)
+	if s.release != nil {
+		<-s.release
+	}
+	data, ok := s.objects[c.Hash().String()]
+	if !ok {
+		return nil, tvoerrors.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// fakeRangeStorage reads ranges of the objects without waiting for release and counts the reads.
+type fakeRangeStorage struct {
+	*fakeStorage
+	ranges atomic.Int32
+}
+
+func (s *fakeRangeStorage) Size(_ context.Context, c cid.Cid) (int64, error) {
+	data, ok := s.objects[c.Hash().String()]
+	if !ok {
+		return 0, tvoerrors.ErrNotFound
+	}
+	return int64(len(data)), nil
+}
+
+func (s *fakeRangeStorage) CatRange(_ context.Context, c cid.Cid, offset, length int64) (io.ReadCloser, error) {
+	s.ranges.Add(1)
+	data, ok := s.objects[c.Hash().String()]
+	if !ok {
+		return nil, tvoerrors.ErrNotFound
+	}
+	return io.NopCloser(io.NewSectionReader(bytes.NewReader(data), offset, length)), nil
+}
+
+// fakeVerifier rejects the listed CIDs and counts the checks.
+type fakeVerifier struct {
+	corrupted map[string]bool
+	checks    atomic.Int32
+}
+
+func (v *fakeVerifier) Verify(_ context.Context, c cid.Cid) error {
+	v.checks.Add(1)
+	if v.corrupted[c.String()] {
+		return errors.New("content does not match its cid")
+	}
+	return nil
+}
+
+func testCid(t *testing.T, data string) cid.Cid {
+	t.Helper()
+
+	hash, err := mh.Sum([]byte(data), mh.SHA2_256, -1)
+	if err != nil {
+		t.Fatalf("mh.Sum() error = %v", err)
+	}
+	return cid.NewCidV1(cid.DagProtobuf, hash)
+}
+
+func newTestGateway(t *testing.T, capacity int64, data ...string) (*Gateway, *fakeStorage) {
+	s := &fakeStorage{objects: map[string][]byte{}}
+	for _, object := range data {
+		s.objects[testCid(t, object).Hash().String()] = []byte(object)
+	}
+	g, err := New(s, &config.Gateway{CacheDir: t.TempDir(), CacheSize: capacity}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return g, s
+}
+
+func readContent(t *testing.T, g *Gateway, c cid.Cid) string {
+	t.Helper()
+
+	content, err := g.Open(context.Background(), c)
+	if err != nil {
+		t.Fatalf("Open(%s) error = %v", c, err)
+	}
+	defer content.Close()
+
+	body, err := content.Reader(context.Background(), 0, content.Size)
+	if err != nil {
+		t.Fatalf("Reader(%s) error = %v", c, err)
+	}
+	defer body.Close()
+
+	data, _ := io.ReadAll(body)
+	if int64(len(data)) != content.Size {
+		t.Errorf("Open(%s) size = %d, read %d bytes", c, content.Size, len(data))
+	}
+	return string(data)
+}
+
+func TestGatewayCoalescesFetches(t *testing.T) {
+	g, s := newTestGateway(t, 1024, "hot object")
+	s.release = make(chan struct{})
+	c := testCid(t, "hot object")
+
+	var wg sync.WaitGroup
+	results := make([]string, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = readContent(t, g, c)
+		}(i)
+	}
+	// the waiters join the first fetch while it is held
+	time.Sleep(50 * time.Millisecond)
+	close(s.release)
+	wg.Wait()
+
+	for i, result := range results {
+		if result != "hot object" {
+			t.Errorf("request %d read %q", i, result)
+		}
+	}
+	if fetches := s.fetches.Load(); fetches != 1 {
+		t.Errorf("fetches = %d, expected 1", fetches)
+	}
+
+	// CIDv0 of the same content is served from the cache
+	if result := readContent(t, g, cid.NewCidV0(c.Hash())); result != "hot object" {
+		t.Errorf("read by another CID version = %q", result)
+	}
+	if fetches := s.fetches.Load(); fetches != 1 {
+		t.Errorf("fetches after a cached read = %d, expected 1", fetches)
+	}
+}
+
+func TestGatewayEvictsLeastRecentlyUsed(t *testing.T) {
+	g, s := newTestGateway(t, 20, "first 10 b", "second 10b", "third 10 b", "too large for the cache")
+	first, second, third := testCid(t, "first 10 b"), testCid(t, "second 10b"), testCid(t, "third 10 b")
+
+	readContent(t, g, first)
+	readContent(t, g, second)
+	// first becomes the most recently used, so that second is evicted by third
+	readContent(t, g, first)
+	readContent(t, g, third)
+
+	tests := []struct {
+		c       cid.Cid
+		fetched bool
+	}{
+		{first, false},
+		{third, false},
+		{second, true},
+	}
+	for _, test := range tests {
+		before := s.fetches.Load()
+		readContent(t, g, test.c)
+		if fetched := s.fetches.Load() != before; fetched != test.fetched {
+			t.Errorf("read of %s fetched = %v, expected %v", test.c, fetched, test.fetched)
+		}
+	}
+
+	// objects larger than the cache are served from the fetched file without being kept
+	large := testCid(t, "too large for the cache")
+	before := s.fetches.Load()
+	if result := readContent(t, g, large); result != "too large for the cache" {
+		t.Errorf("large object = %q", result)
+	}
+	if fetches := s.fetches.Load() - before; fetches != 1 {
+		t.Errorf("large object fetches = %d, expected 1", fetches)
+	}
+	if _, _, ok := g.cache.open(cid.NewCidV1(large.Type(), large.Hash()).String()); ok {
+		t.Errorf("large object is kept by the cache")
+	}
+}
+
+func TestGatewayStreamsLargeObjects(t *testing.T) {
+	g, s := newTestGateway(t, 20, "too large for the cache")
+	ranges := &fakeRangeStorage{fakeStorage: s}
+	g.storage = ranges
+	c := testCid(t, "too large for the cache")
+
+	content, err := g.Open(context.Background(), c)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer content.Close()
+
+	if content.Size != 23 {
+		t.Errorf("Open() size = %d, expected 23", content.Size)
+	}
+	body, err := content.Reader(context.Background(), 4, 5)
+	if err != nil {
+		t.Fatalf("Reader() error = %v", err)
+	}
+	data, _ := io.ReadAll(body)
+	_ = body.Close()
+	if string(data) != "large" {
+		t.Errorf("Reader(4, 5) = %q, expected %q", data, "large")
+	}
+	if fetches, reads := s.fetches.Load(), ranges.ranges.Load(); fetches != 0 || reads != 1 {
+		t.Errorf("fetches = %d, range reads = %d, expected only one range read", fetches, reads)
+	}
+}
+
+func TestGatewayReservesFetchesInProgress(t *testing.T) {
+	g, s := newTestGateway(t, 20, "held object", "next object")
+	ranges := &fakeRangeStorage{fakeStorage: s}
+	g.storage = ranges
+	s.release = make(chan struct{})
+	first, second := testCid(t, "held object"), testCid(t, "next object")
+
+	done := make(chan string)
+	go func() {
+		done <- readContent(t, g, first)
+	}()
+	// the held fetch takes its space, so that the next object does not fit next to it
+	time.Sleep(50 * time.Millisecond)
+	if result := readContent(t, g, second); result != "next object" {
+		t.Errorf("second object = %q", result)
+	}
+	if reads := ranges.ranges.Load(); reads != 1 {
+		t.Errorf("range reads = %d, expected the second object to be streamed", reads)
+	}
+
+	close(s.release)
+	if result := <-done; result != "held object" {
+		t.Errorf("first object = %q", result)
+	}
+	if _, _, ok := g.cache.open(cid.NewCidV1(first.Type(), first.Hash()).String()); !ok {
+		t.Errorf("first object is not kept by the cache")
+	}
+	if g.cache.reserved != 0 || g.cache.size != 11 {
+		t.Errorf("cache size = %d, reserved = %d, expected 11 and 0", g.cache.size, g.cache.reserved)
+	}
+}
+
+func TestGatewayVerifiesContent(t *testing.T) {
+	g, s := newTestGateway(t, 20, "verified streamed object", "corrupted streamed object", "corrupted")
+	g.storage = &fakeRangeStorage{fakeStorage: s}
+	verifier := &fakeVerifier{corrupted: map[string]bool{
+		testCid(t, "corrupted streamed object").String(): true,
+		testCid(t, "corrupted").String():                 true,
+	}}
+	g.verifier = verifier
+	ctx := context.Background()
+
+	// objects entering the cache are checked before they are served
+	if _, err := g.Open(ctx, testCid(t, "corrupted")); err == nil {
+		t.Errorf("Open() of corrupted content expected an error")
+	}
+
+	// streamed objects are checked once, the end of a corrupted one is not served
+	for range 2 {
+		if result := readContent(t, g, testCid(t, "verified streamed object")); result != "verified streamed object" {
+			t.Errorf("verified object = %q", result)
+		}
+	}
+	if checks := verifier.checks.Load(); checks != 2 {
+		t.Errorf("checks = %d, expected 2", checks)
+	}
+
+	content, err := g.Open(ctx, testCid(t, "corrupted streamed object"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer content.Close()
+	body, err := content.Reader(ctx, 0, content.Size)
+	if err != nil {
+		t.Fatalf("Reader() error = %v", err)
+	}
+	defer body.Close()
+	if _, err = io.ReadAll(body); err == nil {
+		t.Errorf("read of corrupted streamed content expected an error")
+	}
+}
+
+func TestGatewayCacheSurvivesRestart(t *testing.T) {
+	g, s := newTestGateway(t, 1024, "kept object")
+	c := testCid(t, "kept object")
+	readContent(t, g, c)
+
+	restarted, err := New(s, &config.Gateway{CacheDir: g.cache.dir, CacheSize: 1024}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	readContent(t, restarted, c)
+	if fetches := s.fetches.Load(); fetches != 1 {
+		t.Errorf("fetches = %d, expected 1", fetches)
+	}
+}
+
+func TestGatewayOpenMissing(t *testing.T) {
+	g, _ := newTestGateway(t, 1024)
+	if _, err := g.Open(context.Background(), testCid(t, "missing")); !errors.Is(err, tvoerrors.ErrNotFound) {
+		t.Errorf("Open() error = %v, expected not found", err)
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		header     string
+		start, end int64
+		ok         bool
+		fail       bool
+	}{
+		{header: "", ok: false},
+		{header: "bytes=0-4", start: 0, end: 4, ok: true},
+		{header: "bytes=5-", start: 5, end: 9, ok: true},
+		{header: "bytes=-3", start: 7, end: 9, ok: true},
+		{header: "bytes=-30", start: 0, end: 9, ok: true},
+		{header: "bytes=8-100", start: 8, end: 9, ok: true},
+		{header: "bytes=0-1,4-5", ok: false},
+		{header: "items=0-1", ok: false},
+		{header: "bytes=10-", fail: true},
+		{header: "bytes=5-2", fail: true},
+		{header: "bytes=-0", fail: true},
+		{header: "bytes=a-b", fail: true},
+	}
+
+	for _, test := range tests {
+		start, end, ok, err := ParseRange(test.header, 10)
+		if test.fail {
+			if !errors.Is(err, ErrRangeNotSatisfiable) {
+				t.Errorf("ParseRange(%q) error = %v, expected not satisfiable", test.header, err)
+			}
+			continue
+		}
+		if err != nil || ok != test.ok || start != test.start || end != test.end {
+			t.Errorf("ParseRange(%q) = %d, %d, %v, %v, expected %d, %d, %v", test.header, start, end, ok, err,
+				test.start, test.end, test.ok)
+		}
+	}
+}
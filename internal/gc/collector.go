@@ -0,0 +1,238 @@
+// Package gc ищет закрепления хранилища, на которые не ссылается ни одна запись базы: загрузки,
+// так и не ставшие токенами, и содержимое токенов, создание которых завершилось ошибкой.
+package gc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ipfs/go-cid"
+
+	"main/internal/config"
+	"main/internal/mfs"
+	"main/internal/models"
+	"main/internal/repository"
+	"main/internal/storage"
+	"main/tools/pkg/logger"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// ErrRunning возвращается, если сборка уже выполняется
+var ErrRunning = fmt.Errorf("%w: garbage collection is already running", tvoerrors.ErrConflict)
+
+// Collector сравнивает рекурсивные закрепления хранилища со всеми CID, на которые ссылается база.
+// Закрепления без ссылок запоминаются, и только пробывшие сиротами дольше cfg.GracePeriod
+// снимаются и убираются из зеркала MFS, после чего хранилище собирает мусор. В режиме dry run
+// сироты лишь попадают в отчет.
+type Collector struct {
+	storage storage.Storage
+	mirror  *mfs.Mirror
+	orphans repository.OrphanPinRepository
+	cfg     *config.GC
+	logger  *logger.Logger
+
+	running atomic.Bool
+	mu      sync.Mutex
+	last    *models.GcRun
+}
+
+// NewCollector создает сборку закреплений без ссылок
+func NewCollector(storage storage.Storage, mirror *mfs.Mirror, orphans repository.OrphanPinRepository,
+	cfg *config.GC, logger *logger.Logger) *Collector {
+	return &Collector{
+		storage: storage,
+		mirror:  mirror,
+		orphans: orphans,
+		cfg:     cfg,
+		logger:  logger,
+	}
+}
+
+// Run ищет закрепления без ссылок раз в cfg.Interval, пока не будет отменен ctx.
+// Нулевой интервал отключает сборку.
+func (c *Collector) Run(ctx context.Context) {
+	if c.cfg.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := c.Collect(ctx, c.cfg.DryRun); err != nil && !errors.Is(err, ErrRunning) {
+				c.logger.Error("Error collecting orphan pins", "error", err)
+			}
+		}
+	}
+}
+
+// Collect находит закрепления без ссылок и снимает те, что пробыли сиротами дольше cfg.GracePeriod.
+// При dryRun закрепления не снимаются. Одновременно выполняется только одна сборка.
+func (c *Collector) Collect(ctx context.Context, dryRun bool) (*models.GcRun, error) {
+	if !c.running.CompareAndSwap(false, true) {
+		return nil, ErrRunning
+	}
+	defer c.running.Store(false)
+
+	run := &models.GcRun{StartedAt: time.Now(), DryRun: dryRun, Expired: []string{}, Unpinned: []string{}}
+	err := c.collect(ctx, run)
+	run.FinishedAt = time.Now()
+	if err != nil {
+		run.Error = err.Error()
+	}
+
+	c.mu.Lock()
+	c.last = run
+	c.mu.Unlock()
+
+	c.logger.Info("Orphan pins collected", "dry_run", dryRun, "pinned", run.Pinned, "orphans", run.Orphans,
+		"expired", len(run.Expired), "unpinned", len(run.Unpinned), "errors", run.Errors,
+		"removed_files", run.RemovedFiles, "removed_blocks", run.RemovedBlocks, "duration", run.FinishedAt.Sub(run.StartedAt))
+	return run, err
+}
+
+// LastRun возвращает итоги последней сборки, nil если сборка еще не выполнялась
+func (c *Collector) LastRun() *models.GcRun {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.last == nil {
+		return nil
+	}
+	run := *c.last
+	return &run
+}
+
+// Orphans возвращает запомненные закрепления без ссылок, отмечая пробывших сиротами дольше cfg.GracePeriod
+func (c *Collector) Orphans(ctx context.Context, limit int) ([]models.OrphanPin, error) {
+	return c.orphans.Orphans(ctx, c.cfg.GracePeriod, limit)
+}
+
+// collect выполняет сборку и заполняет run
+func (c *Collector) collect(ctx context.Context, run *models.GcRun) error {
+	pinned, err := c.storage.List(ctx)
+	if err != nil {
+		return err
+	}
+	run.Pinned = len(pinned)
+
+	orphans, err := c.unreferenced(ctx, pinned)
+	if err != nil {
+		return err
+	}
+	run.Orphans = len(orphans)
+
+	tracked, err := c.orphans.TrackOrphans(ctx, orphans, c.cfg.GracePeriod)
+	if err != nil {
+		return err
+	}
+	var expired []cid.Cid
+	for _, orphan := range tracked {
+		if !orphan.Expired {
+			continue
+		}
+		orphanCid, err := cid.Decode(orphan.Cid)
+		if err != nil {
+			continue
+		}
+		expired = append(expired, orphanCid)
+	}
+	if run.DryRun || len(expired) == 0 {
+		for _, orphanCid := range expired {
+			run.Expired = append(run.Expired, orphanCid.String())
+		}
+		return nil
+	}
+
+	// ссылки могли появиться, пока шла сборка, поэтому перед снятием закреплений они читаются заново
+	expired, err = c.stillUnreferenced(ctx, expired)
+	if err != nil {
+		return err
+	}
+	unpinned := make([]cid.Cid, 0, len(expired))
+	for _, orphanCid := range expired {
+		run.Expired = append(run.Expired, orphanCid.String())
+		if err = c.storage.Unpin(ctx, orphanCid); err != nil && !errors.Is(err, tvoerrors.ErrNotFound) {
+			c.logger.Warn("Error unpinning orphan pin", "cid", orphanCid, "error", err)
+			run.Errors++
+			continue
+		}
+		if err = c.orphans.DeleteOrphan(ctx, orphanCid.String()); err != nil {
+			c.logger.Warn("Error forgetting orphan pin", "cid", orphanCid, "error", err)
+		}
+		unpinned = append(unpinned, orphanCid)
+		run.Unpinned = append(run.Unpinned, orphanCid.String())
+	}
+
+	// зеркало MFS ссылается на блоки сирот, и без удаления из него сборка мусора их не освободит
+	if run.RemovedFiles, err = c.mirror.Remove(ctx, unpinned); err != nil {
+		c.logger.Warn("Error removing orphan pins from mfs mirror", "error", err)
+		run.Errors++
+	}
+
+	collector, ok := c.storage.(storage.GarbageCollector)
+	if !c.cfg.RepoGC || !ok || len(run.Unpinned) == 0 {
+		return nil
+	}
+	run.RemovedBlocks, err = collector.CollectGarbage(ctx)
+	return err
+}
+
+// unreferenced возвращает закрепления, на которые не ссылается база.
+// CID сравниваются по multihash, чтобы CIDv0 и CIDv1 одного содержимого совпадали.
+func (c *Collector) unreferenced(ctx context.Context, pinned []cid.Cid) ([]string, error) {
+	referenced, err := c.referenced(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	orphans := []string{}
+	for _, pinnedCid := range pinned {
+		if _, ok := referenced[string(pinnedCid.Hash())]; !ok {
+			orphans = append(orphans, pinnedCid.String())
+		}
+	}
+	return orphans, nil
+}
+
+// stillUnreferenced оставляет из cids те, на которые база по-прежнему не ссылается
+func (c *Collector) stillUnreferenced(ctx context.Context, cids []cid.Cid) ([]cid.Cid, error) {
+	referenced, err := c.referenced(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	orphans := cids[:0]
+	for _, orphanCid := range cids {
+		if _, ok := referenced[string(orphanCid.Hash())]; !ok {
+			orphans = append(orphans, orphanCid)
+		}
+	}
+	return orphans, nil
+}
+
+// referenced возвращает multihash всех CID, на которые ссылается база
+func (c *Collector) referenced(ctx context.Context) (map[string]struct{}, error) {
+	cids, err := c.orphans.ReferencedCids(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := make(map[string]struct{}, len(cids))
+	for _, value := range cids {
+		referencedCid, err := cid.Decode(value)
+		if err != nil {
+			// посторонние значения, например пустые ссылки старых записей, пропускаем
+			continue
+		}
+		referenced[string(referencedCid.Hash())] = struct{}{}
+	}
+	return referenced, nil
+}
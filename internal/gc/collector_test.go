@@ -0,0 +1,304 @@
+package gc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+
+	"main/internal/config"
+	"main/internal/mfs"
+	"main/internal/models"
+	"main/internal/repository"
+	"main/internal/storage"
+	"main/tools/pkg/logger"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// pinStorage хранит закрепления в памяти и считает сборки мусора
+type pinStorage struct {
+	storage.Storage
+	pins     []cid.Cid
+	unpinErr error
+	gcRuns   int
+}
+
+func (s *pinStorage) List(context.Context) ([]cid.Cid, error) {
+	return slices.Clone(s.pins), nil
+}
+
+func (s *pinStorage) Unpin(_ context.Context, c cid.Cid) error {
+	if s.unpinErr != nil {
+		return s.unpinErr
+	}
+	s.pins = slices.DeleteFunc(s.pins, func(pinned cid.Cid) bool { return pinned.Equals(c) })
+	return nil
+}
+
+func (s *pinStorage) CollectGarbage(context.Context) (int, error) {
+	s.gcRuns++
+	return 7, nil
+}
+
+// orphanRepository хранит ссылки и сирот в памяти, время сиротства задается сдвигом now
+type orphanRepository struct {
+	repository.OrphanPinRepository
+	referenced []string
+	firstSeen  map[string]time.Time
+	now        time.Time
+}
+
+func (r *orphanRepository) ReferencedCids(context.Context) ([]string, error) {
+	return r.referenced, nil
+}
+
+func (r *orphanRepository) TrackOrphans(_ context.Context, cids []string,
+	gracePeriod time.Duration) ([]models.OrphanPin, error) {
+	tracked := make(map[string]time.Time, len(cids))
+	for _, value := range cids {
+		firstSeen, ok := r.firstSeen[value]
+		if !ok {
+			firstSeen = r.now
+		}
+		tracked[value] = firstSeen
+	}
+	r.firstSeen = tracked
+	return r.Orphans(context.Background(), gracePeriod, len(tracked))
+}
+
+func (r *orphanRepository) DeleteOrphan(_ context.Context, value string) error {
+	delete(r.firstSeen, value)
+	return nil
+}
+
+func (r *orphanRepository) Orphans(_ context.Context, gracePeriod time.Duration,
+	_ int) ([]models.OrphanPin, error) {
+	var orphans []models.OrphanPin
+	for value, firstSeen := range r.firstSeen {
+		orphans = append(orphans, models.OrphanPin{Cid: value, FirstSeenAt: firstSeen,
+			Expired: !firstSeen.After(r.now.Add(-gracePeriod))})
+	}
+	return orphans, nil
+}
+
+func testCid(t *testing.T, data string) cid.Cid {
+	t.Helper()
+
+	hash, err := mh.Sum([]byte(data), mh.SHA2_256, -1)
+	if err != nil {
+		t.Fatalf("mh.Sum() error = %v", err)
+	}
+	return cid.NewCidV0(hash)
+}
+
+// mirrorStorage держит блоки, пока на них ссылается закрепление или файл зеркала MFS, как узел Kubo
+type mirrorStorage struct {
+	*pinStorage
+	blocks map[string]bool
+	files  map[string]cid.Cid
+}
+
+func (s *mirrorStorage) CopyFile(_ context.Context, c cid.Cid, p string) error {
+	s.files[p] = c
+	return nil
+}
+
+func (s *mirrorStorage) ListFiles(_ context.Context, dir string) ([]storage.FileInfo, error) {
+	seen := map[string]bool{}
+	var files []storage.FileInfo
+	for p, c := range s.files {
+		name, _, isDir := strings.Cut(strings.TrimPrefix(p, dir+"/"), "/")
+		if !strings.HasPrefix(p, dir+"/") || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if isDir {
+			// CID директории зависит только от ее пути
+			hash, _ := mh.Sum([]byte(dir+"/"+name), mh.SHA2_256, -1)
+			c = cid.NewCidV0(hash)
+		}
+		files = append(files, storage.FileInfo{Name: name, Cid: c, Directory: isDir})
+	}
+	if len(files) == 0 {
+		return nil, tvoerrors.ErrNotFound
+	}
+	return files, nil
+}
+
+func (s *mirrorStorage) StatFile(context.Context, string) (*storage.FileInfo, error) {
+	return nil, tvoerrors.ErrNotFound
+}
+
+func (s *mirrorStorage) RemoveFile(_ context.Context, p string) error {
+	for file := range s.files {
+		if file == p || strings.HasPrefix(file, p+"/") {
+			delete(s.files, file)
+		}
+	}
+	return nil
+}
+
+// CollectGarbage удаляет блоки, которые не закреплены и не лежат в зеркале
+func (s *mirrorStorage) CollectGarbage(context.Context) (int, error) {
+	kept := map[string]bool{}
+	for _, c := range s.pins {
+		kept[string(c.Hash())] = true
+	}
+	for _, c := range s.files {
+		kept[string(c.Hash())] = true
+	}
+	removed := 0
+	for hash := range s.blocks {
+		if !kept[hash] {
+			delete(s.blocks, hash)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func newTestCollector(s storage.Storage, orphans *orphanRepository, cfg *config.GC) *Collector {
+	l := &logger.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	mirror := mfs.NewMirror(s, &config.MFS{Mirror: true, Root: "/nft-service"}, l)
+	return NewCollector(s, mirror, orphans, cfg, l)
+}
+
+func TestCollectorCollect(t *testing.T) {
+	token, upload := testCid(t, "token"), testCid(t, "upload")
+	s := &pinStorage{pins: []cid.Cid{token, upload}}
+	// токен записан в базе как CIDv1, закрепление в хранилище как CIDv0
+	orphans := &orphanRepository{referenced: []string{cid.NewCidV1(cid.DagProtobuf, token.Hash()).String(), ""},
+		now: time.Now()}
+	c := newTestCollector(s, orphans, &config.GC{GracePeriod: time.Hour, RepoGC: true})
+	ctx := context.Background()
+
+	// сирота только что найден, срок еще не вышел
+	run, err := c.Collect(ctx, false)
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if run.Pinned != 2 || run.Orphans != 1 || len(run.Expired) != 0 || len(run.Unpinned) != 0 || s.gcRuns != 0 {
+		t.Errorf("Collect() within the grace period = %+v, gc runs %d", run, s.gcRuns)
+	}
+
+	// в режиме dry run истекший сирота только попадает в отчет
+	orphans.now = orphans.now.Add(2 * time.Hour)
+	run, err = c.Collect(ctx, true)
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if !slices.Equal(run.Expired, []string{upload.String()}) || len(run.Unpinned) != 0 || len(s.pins) != 2 {
+		t.Errorf("Collect() dry run = %+v, pins %v", run, s.pins)
+	}
+
+	run, err = c.Collect(ctx, false)
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if !slices.Equal(run.Unpinned, []string{upload.String()}) || run.RemovedBlocks != 7 || s.gcRuns != 1 {
+		t.Errorf("Collect() = %+v, gc runs %d", run, s.gcRuns)
+	}
+	if len(s.pins) != 1 || !s.pins[0].Equals(token) || len(orphans.firstSeen) != 0 {
+		t.Errorf("unexpected pins %v and orphans %v after the collection", s.pins, orphans.firstSeen)
+	}
+	if last := c.LastRun(); last == nil || len(last.Unpinned) != 1 {
+		t.Errorf("LastRun() = %+v", last)
+	}
+}
+
+func TestCollectorReferencedMeanwhile(t *testing.T) {
+	upload := testCid(t, "upload")
+	s := &pinStorage{pins: []cid.Cid{upload}}
+	orphans := &orphanRepository{now: time.Now()}
+	c := newTestCollector(s, orphans, &config.GC{RepoGC: true})
+
+	// токен на загрузку появился, пока сборка сверяла закрепления
+	orphans.firstSeen = map[string]time.Time{upload.String(): orphans.now.Add(-time.Hour)}
+	c.orphans = &referencingRepository{orphanRepository: orphans, value: upload.String()}
+
+	run, err := c.Collect(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if len(run.Unpinned) != 0 || len(s.pins) != 1 || s.gcRuns != 0 {
+		t.Errorf("Collect() unpinned a referenced pin: %+v", run)
+	}
+}
+
+// referencingRepository добавляет ссылку на value после первого чтения ссылок
+type referencingRepository struct {
+	*orphanRepository
+	value string
+	reads int
+}
+
+func (r *referencingRepository) ReferencedCids(ctx context.Context) ([]string, error) {
+	r.reads++
+	if r.reads > 1 {
+		return []string{r.value}, nil
+	}
+	return r.orphanRepository.ReferencedCids(ctx)
+}
+
+func TestCollectorUnpinError(t *testing.T) {
+	upload := testCid(t, "upload")
+	s := &pinStorage{pins: []cid.Cid{upload}, unpinErr: errors.New("connection refused")}
+	orphans := &orphanRepository{now: time.Now()}
+	c := newTestCollector(s, orphans, &config.GC{RepoGC: true})
+
+	run, err := c.Collect(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if run.Errors != 1 || len(run.Unpinned) != 0 || s.gcRuns != 0 || len(orphans.firstSeen) != 1 {
+		t.Errorf("Collect() = %+v, gc runs %d", run, s.gcRuns)
+	}
+}
+
+func TestCollectorRunning(t *testing.T) {
+	c := newTestCollector(&pinStorage{}, &orphanRepository{}, &config.GC{})
+	c.running.Store(true)
+
+	if _, err := c.Collect(context.Background(), true); !errors.Is(err, ErrRunning) {
+		t.Errorf("Collect() error = %v, expected ErrRunning", err)
+	}
+}
+
+func TestCollectorReleasesMirroredBlocks(t *testing.T) {
+	token, upload := testCid(t, "token"), testCid(t, "upload")
+	s := &mirrorStorage{
+		pinStorage: &pinStorage{pins: []cid.Cid{token, upload}},
+		blocks:     map[string]bool{string(token.Hash()): true, string(upload.Hash()): true},
+		files:      map[string]cid.Cid{},
+	}
+	ctx := context.Background()
+	// загрузка лежит в зеркале под CIDv1, закреплена под CIDv0
+	_ = s.CopyFile(ctx, cid.NewCidV1(cid.DagProtobuf, upload.Hash()), "/nft-service/_users/3/cat.png")
+	_ = s.CopyFile(ctx, token, "/nft-service/cool-cats/1/cat.png")
+
+	orphans := &orphanRepository{referenced: []string{token.String()}, now: time.Now()}
+	orphans.firstSeen = map[string]time.Time{upload.String(): orphans.now.Add(-2 * time.Hour)}
+	c := newTestCollector(s, orphans, &config.GC{GracePeriod: time.Hour, RepoGC: true})
+
+	run, err := c.Collect(ctx, false)
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if !slices.Equal(run.Unpinned, []string{upload.String()}) || run.RemovedFiles != 1 || run.RemovedBlocks != 1 ||
+		run.Errors != 0 {
+		t.Errorf("Collect() = %+v", run)
+	}
+	if s.blocks[string(upload.Hash())] || !s.blocks[string(token.Hash())] {
+		t.Errorf("blocks after the collection = %v", s.blocks)
+	}
+	if _, ok := s.files["/nft-service/cool-cats/1/cat.png"]; !ok || len(s.files) != 1 {
+		t.Errorf("mirror after the collection = %v", s.files)
+	}
+}
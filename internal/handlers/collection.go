@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"main/internal/dto"
+	"main/internal/ipns"
+	"main/internal/lib/cids"
+	"main/internal/models"
+	"main/internal/repository"
+	"main/internal/service"
+	httputils "main/tools/pkg/http_utils"
+	"main/tools/pkg/logger"
+	tvoerrors "main/tools/pkg/tvo_errors"
+	tvomodels "main/tools/pkg/tvo_models"
+)
+
+// CollectionHandlers методы управления коллекциями NFT
+type CollectionHandlers struct {
+	logger               *logger.Logger
+	collectionRepository repository.CollectionRepository
+	ipnsRepository       repository.IpnsRepository
+	publisher            *ipns.Publisher
+	gatewayURL           string
+	collectionBaseURI    string
+}
+
+func NewCollectionHandlers(logger *logger.Logger, collectionRepository repository.CollectionRepository,
+	ipnsRepository repository.IpnsRepository, publisher *ipns.Publisher,
+	gatewayURL, collectionBaseURI string) *CollectionHandlers {
+	return &CollectionHandlers{
+		logger:               logger,
+		collectionRepository: collectionRepository,
+		ipnsRepository:       ipnsRepository,
+		publisher:            publisher,
+		gatewayURL:           gatewayURL,
+		collectionBaseURI:    collectionBaseURI,
+	}
+}
+
+// CreateCollection создает коллекцию, владельцем становится текущий пользователь.
+// Создавать коллекции могут авторы и администраторы.
+func (h *CollectionHandlers) CreateCollection(c *fiber.Ctx) (interface{}, error) {
+	var request dto.CreateCollectionRequest
+
+	if err := httputils.ParseRequestBody(c, &request, "CreateCollection", h.logger); err != nil {
+		return nil, tvoerrors.ErrInvalidRequestData
+	}
+
+	userId, err := httputils.UserIDFromToken(c, "CreateCollection", h.logger)
+	if err != nil {
+		return nil, tvoerrors.ErrCastClaims
+	}
+	roleId, err := httputils.RoleIDFromToken(c, "CreateCollection", h.logger)
+	if err != nil {
+		return nil, tvoerrors.ErrCastClaims
+	}
+
+	if tvomodels.RoleId(roleId) != tvomodels.ADMIN && tvomodels.RoleId(roleId) != tvomodels.CREATOR {
+		log.Error("Wrong user role")
+		return nil, tvoerrors.ErrForbidden
+	}
+
+	collection := &models.Collection{
+		Slug:            request.Slug,
+		Name:            request.Name,
+		Symbol:          request.Symbol,
+		Description:     request.Description,
+		CoverCid:        request.CoverCid,
+		ContractAddress: request.ContractAddress,
+		ChainId:         request.ChainId,
+		OwnerId:         userId,
+		TokenStandard:   request.TokenStandard,
+	}
+	if collection.TokenStandard == "" {
+		collection.TokenStandard = models.TokenStandardERC721
+	}
+	if err = service.ValidateCollection(collection); err != nil {
+		log.Error("Invalid collection", "error", err)
+		return nil, err
+	}
+
+	if err = h.collectionRepository.CreateCollection(c.Context(), collection); err != nil {
+		if errors.Is(err, tvoerrors.ErrConflict) {
+			return nil, tvoerrors.ErrConflict
+		}
+		log.Error("Error creating collection", "error", err)
+		return nil, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+
+	return &dto.ReadCollectionResponse{
+		Info: h.collectionInfo(collection, cidBase(c)),
+	}, nil
+}
+
+// ReadCollection отдает коллекцию по ее slug
+func (h *CollectionHandlers) ReadCollection(c *fiber.Ctx) (interface{}, error) {
+	collection, err := h.collectionRepository.CollectionBySlug(c.Context(), c.Params("slug"))
+	if err != nil {
+		if errors.Is(err, tvoerrors.ErrNotFound) {
+			return nil, tvoerrors.ErrNotFound
+		}
+		log.Error("Error accessing to DB", "error", err)
+		return nil, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+
+	return &dto.ReadCollectionResponse{
+		Info: h.collectionInfo(collection, cidBase(c)),
+	}, nil
+}
+
+// ReadAllCollections отдает список коллекций, размер задается параметром limit
+func (h *CollectionHandlers) ReadAllCollections(c *fiber.Ctx) (interface{}, error) {
+	limit, err := service.ParseLimit(c.Query("limit"))
+	if err != nil {
+		log.Error("Error parsing limit", "error", err)
+		return nil, err
+	}
+
+	collections, err := h.collectionRepository.ReadAllCollections(c.Context(), limit)
+	if err != nil {
+		log.Error("Error accessing to DB", "error", err)
+		return nil, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+
+	base := cidBase(c)
+	infos := make([]dto.CollectionInfo, 0, len(collections))
+	for i := range collections {
+		infos = append(infos, *h.collectionInfo(&collections[i], base))
+	}
+
+	return &dto.ReadAllCollectionsResponse{
+		Infos: infos,
+	}, nil
+}
+
+// UpdateCollection изменяет коллекцию, доступно владельцу и администратору
+func (h *CollectionHandlers) UpdateCollection(c *fiber.Ctx) (interface{}, error) {
+	var request dto.UpdateCollectionRequest
+
+	if err := httputils.ParseRequestBody(c, &request, "UpdateCollection", h.logger); err != nil {
+		return nil, tvoerrors.ErrInvalidRequestData
+	}
+
+	collection, err := h.ownedCollection(c, "UpdateCollection")
+	if err != nil {
+		return nil, err
+	}
+
+	service.ApplyCollectionUpdate(collection, &request)
+	if err = service.ValidateCollection(collection); err != nil {
+		log.Error("Invalid collection", "error", err)
+		return nil, err
+	}
+
+	if err = h.collectionRepository.UpdateCollection(c.Context(), collection); err != nil {
+		if errors.Is(err, tvoerrors.ErrConflict) {
+			return nil, tvoerrors.ErrConflict
+		}
+		if errors.Is(err, tvoerrors.ErrNotFound) {
+			return nil, tvoerrors.ErrNotFound
+		}
+		log.Error("Error updating collection", "error", err)
+		return nil, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+
+	return &dto.ReadCollectionResponse{
+		Info: h.collectionInfo(collection, cidBase(c)),
+	}, nil
+}
+
+// DeleteCollection удаляет пустую коллекцию, доступно владельцу и администратору
+func (h *CollectionHandlers) DeleteCollection(c *fiber.Ctx) (interface{}, error) {
+	collection, err := h.ownedCollection(c, "DeleteCollection")
+	if err != nil {
+		return nil, err
+	}
+
+	if err = h.collectionRepository.DeleteCollection(c.Context(), collection.ID); err != nil {
+		if errors.Is(err, tvoerrors.ErrObjectWasNotDelete) {
+			log.Error("Collection still has tokens", "error", err)
+			return nil, tvoerrors.ErrConflict
+		}
+		log.Error("Error deleting collection", "error", err)
+		return nil, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+
+	return &dto.CollectionResponse{
+		Message: "Collection deleted",
+	}, nil
+}
+
+// PublishCollectionIpns направляет IPNS имя коллекции на CID из запроса, например на новую версию
+// директории метаданных. Ключ коллекции создается при первой публикации. Доступно владельцу и администратору.
+func (h *CollectionHandlers) PublishCollectionIpns(c *fiber.Ctx) error {
+	var request dto.PublishIpnsRequest
+	if err := httputils.ParseRequestBody(c, &request, "PublishCollectionIpns", h.logger); err != nil {
+		return httputils.HandleError(c, fiber.StatusBadRequest, tvoerrors.ErrInvalidRequestData)
+	}
+	value, err := cids.Parse(request.Cid)
+	if err != nil {
+		return httputils.HandleError(c, fiber.StatusBadRequest, tvoerrors.ErrInvalidRequestData)
+	}
+
+	collection, err := h.ownedCollection(c, "PublishCollectionIpns")
+	if err != nil {
+		return httputils.HandleError(c, httputils.FiberStatusByErr(err), err)
+	}
+
+	record, err := h.publisher.Publish(c.Context(), collection.ID, value)
+	if err != nil {
+		if errors.Is(err, ipns.ErrUnsupported) {
+			return httputils.HandleError(c, fiber.StatusNotImplemented, err)
+		}
+		log.Error("Error publishing ipns record", "collection", collection.Slug, "error", err)
+		return httputils.HandleError(c, fiber.StatusBadGateway, errSomethingWrong)
+	}
+	return c.JSON(h.ipnsRecordInfo(record, cidBase(c)))
+}
+
+// ReadCollectionIpns отдает текущую запись IPNS коллекции
+func (h *CollectionHandlers) ReadCollectionIpns(c *fiber.Ctx) (interface{}, error) {
+	collection, err := h.collectionRepository.CollectionBySlug(c.Context(), c.Params("slug"))
+	if err != nil {
+		if errors.Is(err, tvoerrors.ErrNotFound) {
+			return nil, tvoerrors.ErrNotFound
+		}
+		log.Error("Error accessing to DB", "error", err)
+		return nil, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+
+	record, err := h.ipnsRepository.IpnsRecordByCollection(c.Context(), collection.ID)
+	if err != nil {
+		if errors.Is(err, tvoerrors.ErrNotFound) {
+			return nil, tvoerrors.ErrNotFound
+		}
+		log.Error("Error accessing to DB", "error", err)
+		return nil, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+	return h.ipnsRecordInfo(record, cidBase(c)), nil
+}
+
+// ipnsRecordInfo преобразует запись IPNS в ответ API, CID отдается в основании base
+func (h *CollectionHandlers) ipnsRecordInfo(record *models.IpnsRecord, base cids.Base) *dto.IpnsRecordInfo {
+	return &dto.IpnsRecordInfo{
+		Name:        record.Name,
+		Link:        ipns.Link(record.Name),
+		Value:       base.FormatString(record.Value),
+		GatewayLink: gatewayLink(h.gatewayURL, record.Value),
+		Sequence:    record.Sequence,
+		Published:   record.PublishedAt,
+		Expires:     record.ExpiresAt,
+	}
+}
+
+// ownedCollection находит коллекцию из параметра slug и проверяет, что пользователь может ее менять
+func (h *CollectionHandlers) ownedCollection(c *fiber.Ctx, method string) (*models.Collection, error) {
+	userId, err := httputils.UserIDFromToken(c, method, h.logger)
+	if err != nil {
+		return nil, tvoerrors.ErrCastClaims
+	}
+	roleId, err := httputils.RoleIDFromToken(c, method, h.logger)
+	if err != nil {
+		return nil, tvoerrors.ErrCastClaims
+	}
+
+	collection, err := h.collectionRepository.CollectionBySlug(c.Context(), c.Params("slug"))
+	if err != nil {
+		if errors.Is(err, tvoerrors.ErrNotFound) {
+			return nil, tvoerrors.ErrNotFound
+		}
+		log.Error("Error accessing to DB", "error", err)
+		return nil, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+
+	if tvomodels.RoleId(roleId) != tvomodels.ADMIN && collection.OwnerId != userId {
+		log.Error("User is not the collection owner")
+		return nil, tvoerrors.ErrForbidden
+	}
+	return collection, nil
+}
+
+// collectionInfo преобразует коллекцию в ответ API, CID отдаются в основании base
+func (h *CollectionHandlers) collectionInfo(collection *models.Collection, base cids.Base) *dto.CollectionInfo {
+	info := &dto.CollectionInfo{
+		Slug:            collection.Slug,
+		Name:            collection.Name,
+		Symbol:          collection.Symbol,
+		Description:     collection.Description,
+		CoverCid:        base.FormatString(collection.CoverCid),
+		ContractAddress: collection.ContractAddress,
+		ChainId:         collection.ChainId,
+		OwnerId:         collection.OwnerId,
+		TokenStandard:   collection.TokenStandard,
+	}
+	if collection.CoverCid != "" {
+		info.CoverLink = gatewayLink(h.gatewayURL, collection.CoverCid)
+	}
+	if collection.TokenStandard == models.TokenStandardERC1155 {
+		info.MetadataURI = service.EditionURI(h.collectionBaseURI, collection.Slug)
+	}
+	return info
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/ipfs/go-cid"
+
+	"main/internal/gateway"
+	"main/internal/lib/cids"
+	httputils "main/tools/pkg/http_utils"
+	"main/tools/pkg/logger"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// immutableCacheControl содержимое по CID не меняется, поэтому кешируется без перепроверки
+const immutableCacheControl = "public, max-age=29030400, immutable"
+
+// GatewayHandlers встроенный шлюз: отдает содержимое хранилища по CID, в том числе по путям внутри директорий
+type GatewayHandlers struct {
+	logger  *logger.Logger
+	gateway *gateway.Gateway
+}
+
+// NewGatewayHandlers конструктор для обработчиков шлюза
+func NewGatewayHandlers(logger *logger.Logger, contentGateway *gateway.Gateway) *GatewayHandlers {
+	return &GatewayHandlers{
+		logger:  logger,
+		gateway: contentGateway,
+	}
+}
+
+// ServeIpfs отдает содержимое по адресу /ipfs/:cid/*path, путь разрешается внутри директории
+func (h *GatewayHandlers) ServeIpfs(c *fiber.Ctx) error {
+	root, err := cids.Parse(c.Params("cid"))
+	if err != nil {
+		return httputils.HandleError(c, fiber.StatusBadRequest, tvoerrors.ErrInvalidRequestData)
+	}
+	contentPath, err := url.PathUnescape(c.Params("*"))
+	if err != nil {
+		return httputils.HandleError(c, fiber.StatusBadRequest, tvoerrors.ErrInvalidRequestData)
+	}
+
+	target, err := h.gateway.Resolve(c.UserContext(), root, contentPath)
+	if err != nil {
+		if errors.Is(err, tvoerrors.ErrNotFound) {
+			return httputils.HandleError(c, fiber.StatusNotFound, tvoerrors.ErrNotFound)
+		}
+		log.Error("Error resolving content path", "cid", root, "path", contentPath, "error", err)
+		return httputils.HandleError(c, fiber.StatusBadGateway, errSomethingWrong)
+	}
+
+	return serveContent(c, h.gateway, target, path.Base("/"+contentPath))
+}
+
+// serveContent отдает объект через шлюз. ETag равен CID, поэтому повторный запрос с If-None-Match
+// получает 304 без обращения к хранилищу. Поддерживается один диапазон в заголовке Range.
+// Тип содержимого определяется по расширению name, иначе по первым байтам.
+func serveContent(c *fiber.Ctx, contentGateway *gateway.Gateway, target cid.Cid, name string) error {
+	etag := `"` + target.String() + `"`
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderCacheControl, immutableCacheControl)
+	c.Set(fiber.HeaderAcceptRanges, "bytes")
+	if etagMatches(c.Get(fiber.HeaderIfNoneMatch), etag) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	ctx := c.UserContext()
+	content, err := contentGateway.Open(ctx, target)
+	if err != nil {
+		if errors.Is(err, tvoerrors.ErrNotFound) {
+			return httputils.HandleError(c, fiber.StatusNotFound, tvoerrors.ErrNotFound)
+		}
+		log.Error("Error fetching content", "cid", target, "error", err)
+		return httputils.HandleError(c, fiber.StatusBadGateway, errSomethingWrong)
+	}
+	c.Set(fiber.HeaderContentType, contentType(ctx, content, name))
+
+	// If-Range с другим ETag означает, что у клиента другая версия, и диапазон не применяется
+	rangeHeader := c.Get(fiber.HeaderRange)
+	if ifRange := c.Get(fiber.HeaderIfRange); ifRange != "" && ifRange != etag {
+		rangeHeader = ""
+	}
+	start, end, ok, err := gateway.ParseRange(rangeHeader, content.Size)
+	if err != nil {
+		_ = content.Close()
+		c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes */%d", content.Size))
+		return c.SendStatus(fiber.StatusRequestedRangeNotSatisfiable)
+	}
+	if ok {
+		c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d", start, end, content.Size))
+		c.Status(fiber.StatusPartialContent)
+	} else {
+		start, end = 0, content.Size-1
+	}
+
+	body, err := content.Reader(ctx, start, end-start+1)
+	if err != nil {
+		_ = content.Close()
+		log.Error("Error reading content", "cid", target, "error", err)
+		return httputils.HandleError(c, fiber.StatusBadGateway, errSomethingWrong)
+	}
+	return c.SendStream(&contentBody{ReadCloser: body, content: content}, int(end-start+1))
+}
+
+// contentBody закрывает содержимое после отправки тела ответа
+type contentBody struct {
+	io.ReadCloser
+	content *gateway.Content
+}
+
+func (b *contentBody) Close() error {
+	err := b.ReadCloser.Close()
+	_ = b.content.Close()
+	return err
+}
+
+// etagMatches проверяет заголовок If-None-Match, который может содержать несколько ETag
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// contentType определяет тип содержимого по расширению имени либо по первым байтам объекта
+func contentType(ctx context.Context, content *gateway.Content, name string) string {
+	if byExt := mime.TypeByExtension(path.Ext(name)); byExt != "" {
+		return byExt
+	}
+	head, err := content.Head(ctx, 512)
+	if err != nil {
+		return fiber.MIMEOctetStream
+	}
+	return http.DetectContentType(head)
+}
+
+// contentDisposition передает исходное имя файла, содержимое показывается в браузере
+func contentDisposition(name string) string {
+	return mime.FormatMediaType("inline", map[string]string{"filename": name})
+}
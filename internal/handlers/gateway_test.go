@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main/internal/config"
+	"main/internal/gateway"
+	"main/internal/storage"
+	"main/tools/pkg/logger"
+)
+
+// newTestGateway поднимает шлюз над локальным хранилищем с одним файлом и возвращает его CID.
+// Объекты больше cacheSize отдаются из хранилища потоком.
+func newTestGateway(t *testing.T, content string, cacheSize int64) (*fiber.App, string) {
+	s, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStorage() error = %v", err)
+	}
+	object, err := s.Add(context.Background(), "file.txt", strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	g, err := gateway.New(s, &config.Gateway{CacheDir: t.TempDir(), CacheSize: cacheSize}, nil)
+	if err != nil {
+		t.Fatalf("gateway.New() error = %v", err)
+	}
+
+	h := NewGatewayHandlers(&logger.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}, g)
+	app := fiber.New()
+	app.Get("/ipfs/:cid", h.ServeIpfs)
+	app.Get("/ipfs/:cid/*", h.ServeIpfs)
+	return app, object.Cid.String()
+}
+
+func TestGatewayServeIpfs(t *testing.T) {
+	for _, cacheSize := range []int64{1 << 20, 4} {
+		testGatewayServeIpfs(t, cacheSize)
+	}
+}
+
+func testGatewayServeIpfs(t *testing.T, cacheSize int64) {
+	app, contentCid := newTestGateway(t, "0123456789", cacheSize)
+	etag := `"` + contentCid + `"`
+
+	tests := []struct {
+		name    string
+		target  string
+		headers map[string]string
+		status  int
+		body    string
+		// contentRange пустой, если заголовка быть не должно
+		contentRange string
+	}{
+		{"whole", "/ipfs/" + contentCid, nil, fiber.StatusOK, "0123456789", ""},
+		{"range", "/ipfs/" + contentCid, map[string]string{"Range": "bytes=2-5"},
+			fiber.StatusPartialContent, "2345", "bytes 2-5/10"},
+		{"suffix range", "/ipfs/" + contentCid, map[string]string{"Range": "bytes=-3"},
+			fiber.StatusPartialContent, "789", "bytes 7-9/10"},
+		{"unsatisfiable", "/ipfs/" + contentCid, map[string]string{"Range": "bytes=20-"},
+			fiber.StatusRequestedRangeNotSatisfiable, "", "bytes */10"},
+		{"stale if-range", "/ipfs/" + contentCid, map[string]string{"Range": "bytes=2-5", "If-Range": `"other"`},
+			fiber.StatusOK, "0123456789", ""},
+		{"not modified", "/ipfs/" + contentCid, map[string]string{"If-None-Match": `"other", ` + etag},
+			fiber.StatusNotModified, "", ""},
+		{"path", "/ipfs/" + contentCid + "/1.json", nil, fiber.StatusNotFound, "", ""},
+		{"bad cid", "/ipfs/not-a-cid", nil, fiber.StatusBadRequest, "", ""},
+		{"missing", "/ipfs/" + testPinCid, nil, fiber.StatusNotFound, "", ""},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(fiber.MethodGet, test.target, nil)
+		for key, value := range test.headers {
+			req.Header.Set(key, value)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("%s, cache %d: request error = %v", test.name, cacheSize, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+
+		if resp.StatusCode != test.status {
+			t.Errorf("%s, cache %d: status = %d, expected %d", test.name, cacheSize, resp.StatusCode, test.status)
+			continue
+		}
+		if result := resp.Header.Get(fiber.HeaderContentRange); result != test.contentRange {
+			t.Errorf("%s, cache %d: Content-Range = %q, expected %q", test.name, cacheSize, result, test.contentRange)
+		}
+		if resp.StatusCode >= 400 {
+			continue
+		}
+		if test.status != fiber.StatusNotModified && string(body) != test.body {
+			t.Errorf("%s, cache %d: body = %q, expected %q", test.name, cacheSize, body, test.body)
+		}
+		if resp.Header.Get(fiber.HeaderETag) != etag ||
+			!strings.Contains(resp.Header.Get(fiber.HeaderCacheControl), "immutable") {
+			t.Errorf("%s, cache %d: ETag = %q, Cache-Control = %q", test.name, cacheSize, resp.Header.Get(fiber.HeaderETag),
+				resp.Header.Get(fiber.HeaderCacheControl))
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"main/internal/config"
+	"main/internal/dto"
+	"main/internal/gc"
+	"main/internal/models"
+	"main/internal/service"
+	httputils "main/tools/pkg/http_utils"
+	"main/tools/pkg/logger"
+	tvoerrors "main/tools/pkg/tvo_errors"
+	tvomodels "main/tools/pkg/tvo_models"
+)
+
+// GcHandlers отчет и запуск сборки закреплений без ссылок
+type GcHandlers struct {
+	logger    *logger.Logger
+	collector *gc.Collector
+	cfg       *config.GC
+}
+
+func NewGcHandlers(logger *logger.Logger, collector *gc.Collector, cfg *config.GC) *GcHandlers {
+	return &GcHandlers{
+		logger:    logger,
+		collector: collector,
+		cfg:       cfg,
+	}
+}
+
+// ReadGc отдает запомненные закрепления без ссылок и итоги последней сборки.
+// Доступно только администраторам, размер списка задается параметром limit.
+func (h *GcHandlers) ReadGc(c *fiber.Ctx) (interface{}, error) {
+	if err := h.checkAdmin(c, "ReadGc"); err != nil {
+		return nil, err
+	}
+
+	limit, err := service.ParseLimit(c.Query("limit"))
+	if err != nil {
+		log.Error("Error parsing limit", "error", err)
+		return nil, err
+	}
+
+	orphans, err := h.collector.Orphans(c.Context(), limit)
+	if err != nil {
+		log.Error("Error accessing to DB", "error", err)
+		return nil, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+
+	response := &dto.GcReportResponse{
+		DryRun:      h.cfg.DryRun,
+		GracePeriod: h.cfg.GracePeriod.String(),
+		Orphans:     make([]dto.OrphanPinInfo, 0, len(orphans)),
+	}
+	base := cidBase(c)
+	for _, orphan := range orphans {
+		response.Orphans = append(response.Orphans, dto.OrphanPinInfo{
+			Cid:       base.FormatString(orphan.Cid),
+			FirstSeen: orphan.FirstSeenAt,
+			Expired:   orphan.Expired,
+		})
+	}
+	if run := h.collector.LastRun(); run != nil {
+		response.LastRun = gcRunInfo(run)
+	}
+	return response, nil
+}
+
+// RunGc запускает сборку закреплений без ссылок и отдает ее итоги. Параметр dry_run
+// переопределяет режим из конфигурации. Доступно только администраторам.
+func (h *GcHandlers) RunGc(c *fiber.Ctx) (interface{}, error) {
+	if err := h.checkAdmin(c, "RunGc"); err != nil {
+		return nil, err
+	}
+
+	dryRun := h.cfg.DryRun
+	if raw := c.Query("dry_run"); raw != "" {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			log.Error("Error parsing dry_run", "error", err)
+			return nil, tvoerrors.ErrInvalidRequestData
+		}
+		dryRun = value
+	}
+
+	run, err := h.collector.Collect(c.Context(), dryRun)
+	if errors.Is(err, gc.ErrRunning) {
+		return nil, err
+	}
+	if err != nil {
+		log.Error("Error collecting orphan pins", "error", err)
+		return nil, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+	return gcRunInfo(run), nil
+}
+
+// checkAdmin проверяет, что запрос выполняет администратор
+func (h *GcHandlers) checkAdmin(c *fiber.Ctx, method string) error {
+	roleId, err := httputils.RoleIDFromToken(c, method, h.logger)
+	if err != nil {
+		return tvoerrors.ErrCastClaims
+	}
+	if tvomodels.RoleId(roleId) != tvomodels.ADMIN {
+		log.Error("Wrong user role")
+		return tvoerrors.ErrForbidden
+	}
+	return nil
+}
+
+// gcRunInfo преобразует итоги сборки в ответ API
+func gcRunInfo(run *models.GcRun) *dto.GcRunInfo {
+	return &dto.GcRunInfo{
+		Started:       run.StartedAt,
+		Finished:      run.FinishedAt,
+		DryRun:        run.DryRun,
+		Pinned:        run.Pinned,
+		Orphans:       run.Orphans,
+		Expired:       run.Expired,
+		Unpinned:      run.Unpinned,
+		Errors:        run.Errors,
+		RemovedFiles:  run.RemovedFiles,
+		RemovedBlocks: run.RemovedBlocks,
+		Error:         run.Error,
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"main/internal/dto"
+	"main/internal/integrity"
+	"main/internal/repository"
+	"main/internal/service"
+	httputils "main/tools/pkg/http_utils"
+	"main/tools/pkg/logger"
+	tvoerrors "main/tools/pkg/tvo_errors"
+	tvomodels "main/tools/pkg/tvo_models"
+)
+
+// IntegrityHandlers отчет о проверках целостности содержимого
+type IntegrityHandlers struct {
+	logger                 *logger.Logger
+	contentCheckRepository repository.ContentCheckRepository
+	auditor                *integrity.Auditor
+}
+
+func NewIntegrityHandlers(logger *logger.Logger, contentCheckRepository repository.ContentCheckRepository,
+	auditor *integrity.Auditor) *IntegrityHandlers {
+	return &IntegrityHandlers{
+		logger:                 logger,
+		contentCheckRepository: contentCheckRepository,
+		auditor:                auditor,
+	}
+}
+
+// ReadReport отдает CID, не прошедшие последнюю проверку, и итоги последнего аудита.
+// Доступно только администраторам, размер списка задается параметром limit.
+func (h *IntegrityHandlers) ReadReport(c *fiber.Ctx) (interface{}, error) {
+	roleId, err := httputils.RoleIDFromToken(c, "ReadReport", h.logger)
+	if err != nil {
+		return nil, tvoerrors.ErrCastClaims
+	}
+	if tvomodels.RoleId(roleId) != tvomodels.ADMIN {
+		log.Error("Wrong user role")
+		return nil, tvoerrors.ErrForbidden
+	}
+
+	limit, err := service.ParseLimit(c.Query("limit"))
+	if err != nil {
+		log.Error("Error parsing limit", "error", err)
+		return nil, err
+	}
+
+	checks, err := h.contentCheckRepository.FailedChecks(c.Context(), limit)
+	if err != nil {
+		log.Error("Error accessing to DB", "error", err)
+		return nil, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+
+	base := cidBase(c)
+	failures := make([]dto.ContentCheckInfo, 0, len(checks))
+	for _, check := range checks {
+		failures = append(failures, dto.ContentCheckInfo{
+			Cid:     base.FormatString(check.Cid),
+			Status:  check.Status,
+			Error:   check.Error,
+			Source:  check.Source,
+			Checked: check.CheckedAt,
+			Failing: check.FailedAt,
+			Tokens:  check.Tokens,
+		})
+	}
+
+	response := &dto.IntegrityReportResponse{Failures: failures}
+	if run := h.auditor.LastRun(); run != nil {
+		response.LastAudit = &dto.AuditRunInfo{
+			Started:  run.StartedAt,
+			Finished: run.FinishedAt,
+			Checked:  run.Checked,
+			Failed:   run.Failed,
+			Errors:   run.Errors,
+		}
+	}
+	return response, nil
+}
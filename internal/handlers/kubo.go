@@ -1,36 +1,85 @@
 package handlers
 
 import (
+	"errors"
+	"io"
+	"strconv"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/ipfs/go-cid"
+
+	"main/internal/dto"
+	"main/internal/lib/cids"
+	"main/internal/mfs"
+	"main/internal/models"
+	"main/internal/pinning"
+	"main/internal/repository"
 	"main/internal/service"
+	"main/internal/storage"
+	httputils "main/tools/pkg/http_utils"
 	"main/tools/pkg/logger"
+	tvoerrors "main/tools/pkg/tvo_errors"
+	tvomodels "main/tools/pkg/tvo_models"
 )
 
 // KuboHandlers
 type KuboHandlers struct {
-	logger *logger.Logger
+	logger               *logger.Logger
+	storage              storage.Storage
+	pinRepository        repository.PinRepository
+	nftDataRepository    repository.NftDataRepository
+	pinReplicaRepository repository.PinReplicaRepository
+	collectionRepository repository.CollectionRepository
+	directoryRepository  repository.DirectoryRepository
+	mirror               *mfs.Mirror
+	pinWorker            *pinning.Worker
+	gatewayURL           string
 }
 
-// NewAuthHandlers конструктор для обработчиков IDM методов
-func NewKuboHandlers(logger *logger.Logger) *KuboHandlers {
+// NewKuboHandlers конструктор для обработчиков методов хранилища
+func NewKuboHandlers(logger *logger.Logger, storage storage.Storage, pinRepository repository.PinRepository,
+	nftDataRepository repository.NftDataRepository, pinReplicaRepository repository.PinReplicaRepository, collectionRepository repository.CollectionRepository,
+	directoryRepository repository.DirectoryRepository, mirror *mfs.Mirror, pinWorker *pinning.Worker,
+	gatewayURL string) *KuboHandlers {
 	return &KuboHandlers{
-		logger: logger,
+		logger:               logger,
+		storage:              storage,
+		pinRepository:        pinRepository,
+		nftDataRepository:    nftDataRepository,
+		pinReplicaRepository: pinReplicaRepository,
+		collectionRepository: collectionRepository,
+		directoryRepository:  directoryRepository,
+		mirror:               mirror,
+		pinWorker:            pinWorker,
+		gatewayURL:           gatewayURL,
 	}
 }
 
 // UploadFileHandler обрабатывает загрузку файла.
-func UploadFileHandler(c *fiber.Ctx) error {
-	file, err := c.FormFile("file")
+func (h *KuboHandlers) UploadFileHandler(c *fiber.Ctx) error {
+	userId, err := httputils.UserIDFromToken(c, "UploadFileHandler", h.logger)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": tvoerrors.ErrCastClaims.Error()})
+	}
+	mirrorPath, err := h.uploadMirrorPath(c, userId)
 	if err != nil {
+		return httputils.HandleError(c, httputils.FiberStatusByErr(err), err)
+	}
+
+	// файл передается в хранилище прямо из тела запроса
+	var object *storage.Object
+	err = streamFormFile(c, "file", func(name string, r io.Reader) (err error) {
+		object, err = h.storage.Add(c.Context(), name, r)
+		return err
+	})
+	if errors.Is(err, ErrFormFileNotFound) {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"status":  "error",
 			"message": "Не удалось получить файл из формы",
 			"data":    err.Error(),
 		})
 	}
-
-	// Вызываем обновленный сервис, который возвращает больше данных
-	addResponse, cidV1, gatewayURL, err := service.AddFileToIPFS(file)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"status":  "error",
@@ -39,57 +88,411 @@ func UploadFileHandler(c *fiber.Ctx) error {
 		})
 	}
 
+	h.mirror.Copy(c.Context(), object.Cid, mirrorPath(object.Name))
+	base := cidBase(c)
+
 	// Формируем расширенный JSON-ответ.
 	// Источник: https://dev.to/hackmamba/robust-media-upload-with-golang-and-cloudinary-fiber-version-2cmf
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"status":  "success",
 		"message": "Файл успешно загружен в IPFS",
 		"data": fiber.Map{
-			"name":       addResponse.Name,
-			"size":       addResponse.Size,
-			"cidV0":      addResponse.Hash,
-			"cidV1":      cidV1,
-			"gatewayUrl": gatewayURL,
+			"name":       object.Name,
+			"size":       strconv.FormatInt(object.Size, 10),
+			"cidV0":      object.CidV0().String(),
+			"cidV1":      base.Format(object.CidV1()),
+			"gatewayUrl": gatewayLink(h.gatewayURL, object.CidV1().String()),
 		},
 	})
 }
 
-// PinCidHandler обрабатывает закрепление CID.
-func PinCidHandler(c *fiber.Ctx) error {
-	cid := c.Params("cid")
-	if cid == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "CID не указан"})
+// ImportCarHandler импортирует CAR файл (CARv1 или CARv2) в хранилище через dag/import и закрепляет его корни.
+// Корни ставятся на учет в таблице pins, чтобы сверка с хранилищем и копирование на удаленные сервисы их видели.
+func (h *KuboHandlers) ImportCarHandler(c *fiber.Ctx) error {
+	dagStorage, ok := h.storage.(storage.DagStorage)
+	if !ok {
+		return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"error": "Хранилище не поддерживает CAR файлы"})
+	}
+
+	userId, err := httputils.UserIDFromToken(c, "ImportCarHandler", h.logger)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": tvoerrors.ErrCastClaims.Error()})
+	}
+
+	var roots []cid.Cid
+	err = streamFormFile(c, "file", func(_ string, r io.Reader) (err error) {
+		roots, err = dagStorage.Import(c.Context(), r)
+		return err
+	})
+	if errors.Is(err, ErrFormFileNotFound) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Не удалось получить файл из формы"})
+	}
+	if err != nil {
+		log.Error("Error importing car", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	base := cidBase(c)
+	response := dto.ImportCarResponse{Roots: make([]string, 0, len(roots)), Pins: []*dto.PinRequestStatus{}}
+	for _, root := range roots {
+		response.Roots = append(response.Roots, base.Format(root))
+		h.mirror.Copy(c.Context(), root, h.mirror.UserPath(userId, root.String()))
+
+		pin := &models.Pin{Cid: root.String(), RequesterId: userId}
+		if err = h.pinRepository.CreatePin(c.Context(), pin); err != nil {
+			log.Error("Error creating pin request", "cid", pin.Cid, "error", err)
+			continue
+		}
+		response.Pins = append(response.Pins, pinRequestStatus(pin, base))
+	}
+	h.pinWorker.Notify()
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+// UploadDirectoryHandler загружает файлы формы одной директорией (wrap-with-directory), имена файлов
+// задают их относительные пути. Файлы, названные по номеру токена (1.json, images/1.png), связываются
+// с токеном, так что вся коллекция адресуется одним корнем: ipfs://<root>/1.json.
+// Параметр collection привязывает загрузку к коллекции, доступно владельцу коллекции и администратору.
+func (h *KuboHandlers) UploadDirectoryHandler(c *fiber.Ctx) error {
+	dirStorage, ok := h.storage.(storage.DirectoryStorage)
+	if !ok {
+		return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"error": "Хранилище не поддерживает директории"})
+	}
+
+	userId, err := httputils.UserIDFromToken(c, "UploadDirectoryHandler", h.logger)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": tvoerrors.ErrCastClaims.Error()})
+	}
+
+	upload := &models.DirectoryUpload{UploaderId: userId}
+	if slug := c.Query("collection"); slug != "" {
+		if upload.CollectionId, err = h.uploadCollection(c, slug, userId); err != nil {
+			return httputils.HandleError(c, httputils.FiberStatusByErr(err), err)
+		}
 	}
 
-	pinResponse, err := service.PinCID(cid)
+	files, err := spoolFormFiles(c, "file", service.MaxDirectoryFiles)
+	if files != nil {
+		defer files.remove()
+	}
+	if err == nil {
+		err = service.ValidateDirectoryPaths(files.paths)
+	}
+	if errors.Is(err, ErrFormFileNotFound) || errors.Is(err, tvoerrors.ErrInvalidRequestData) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Не удалось получить файлы из формы"})
+	}
+	if err != nil {
+		log.Error("Error receiving uploaded files", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "something went wrong"})
+	}
+
+	root, children, err := dirStorage.AddDirectory(c.Context(), files.entries())
 	if err != nil {
+		log.Error("Error adding directory", "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	return c.JSON(pinResponse)
+	uploaded := make(map[string]struct{}, len(files.paths))
+	for _, p := range files.paths {
+		uploaded[p] = struct{}{}
+	}
+	upload.RootCid, upload.Size = root.CidV1().String(), root.Size
+	for i := range children {
+		file := models.DirectoryFile{Path: children[i].Name, Cid: children[i].CidV1().String(), Size: children[i].Size}
+		if _, ok = uploaded[file.Path]; !ok {
+			file.Directory = true
+		} else if tokenId, ok := service.TokenIdFromPath(file.Path); ok {
+			file.TokenId = &tokenId
+		}
+		upload.Files = append(upload.Files, file)
+	}
+	if err = h.directoryRepository.CreateDirectoryUpload(c.Context(), upload); err != nil {
+		log.Error("Error saving directory upload", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "something went wrong"})
+	}
+
+	// корень кладется в зеркало MFS к загрузкам пользователя, файлы токенов коллекции - к токенам
+	h.mirror.Copy(c.Context(), root.Cid, h.mirror.UserPath(userId, upload.RootCid))
+	if slug := c.Query("collection"); slug != "" {
+		for i := range children {
+			if tokenId, ok := service.TokenIdFromPath(children[i].Name); ok && !upload.Files[i].Directory {
+				h.mirror.Copy(c.Context(), children[i].Cid, h.mirror.TokenPath(slug, tokenId, children[i].Name))
+			}
+		}
+	}
+
+	response := h.directoryUploadResponse(upload, cidBase(c))
+	// корень ставится на учет, чтобы сверка с хранилищем и копирование на удаленные сервисы его видели
+	pin := &models.Pin{Cid: root.Cid.String(), RequesterId: userId}
+	if err = h.pinRepository.CreatePin(c.Context(), pin); err != nil {
+		log.Error("Error creating pin request", "cid", pin.Cid, "error", err)
+	} else {
+		response.Pin = pinRequestStatus(pin, cidBase(c))
+		h.pinWorker.Notify()
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+// DirectoryHandler отдает последнюю загрузку директории по ее корневому CID
+func (h *KuboHandlers) DirectoryHandler(c *fiber.Ctx) error {
+	rootCid, err := cids.Parse(c.Params("cid"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "CID не указан или некорректен"})
+	}
+
+	upload, err := h.directoryRepository.DirectoryUploadByRootCid(c.Context(), cids.V1(rootCid).String())
+	if err != nil {
+		if errors.Is(err, tvoerrors.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Директория не найдена"})
+		}
+		log.Error("Error accessing to DB", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "something went wrong"})
+	}
+
+	return c.JSON(h.directoryUploadResponse(upload, cidBase(c)))
+}
+
+// uploadMirrorPath возвращает путь загруженного файла в зеркале MFS. С параметром token_id файл
+// кладется к токену коллекции collection, это доступно владельцу коллекции и администратору,
+// а к токенам без коллекции - только администратору. Иначе файл кладется к загрузкам пользователя.
+func (h *KuboHandlers) uploadMirrorPath(c *fiber.Ctx, userId int64) (func(name string) string, error) {
+	tokenParam := c.Query("token_id")
+	if tokenParam == "" {
+		return func(name string) string { return h.mirror.UserPath(userId, name) }, nil
+	}
+	tokenId, err := strconv.ParseInt(tokenParam, 10, 64)
+	if err != nil || tokenId < 0 {
+		return nil, tvoerrors.ErrInvalidRequestData
+	}
+
+	slug := c.Query("collection")
+	if slug != "" {
+		if _, err = h.uploadCollection(c, slug, userId); err != nil {
+			return nil, err
+		}
+	} else {
+		roleId, err := httputils.RoleIDFromToken(c, "UploadFileHandler", h.logger)
+		if err != nil {
+			return nil, tvoerrors.ErrCastClaims
+		}
+		if tvomodels.RoleId(roleId) != tvomodels.ADMIN {
+			log.Error("Wrong user role")
+			return nil, tvoerrors.ErrForbidden
+		}
+	}
+	return func(name string) string { return h.mirror.TokenPath(slug, tokenId, name) }, nil
+}
+
+// uploadCollection находит коллекцию загрузки и проверяет, что пользователь может ее менять
+func (h *KuboHandlers) uploadCollection(c *fiber.Ctx, slug string, userId int64) (int64, error) {
+	roleId, err := httputils.RoleIDFromToken(c, "UploadDirectoryHandler", h.logger)
+	if err != nil {
+		return 0, tvoerrors.ErrCastClaims
+	}
+
+	collection, err := h.collectionRepository.CollectionBySlug(c.Context(), slug)
+	if err != nil {
+		if errors.Is(err, tvoerrors.ErrNotFound) {
+			return 0, tvoerrors.ErrNotFound
+		}
+		log.Error("Error accessing to DB", "error", err)
+		return 0, errSomethingWrong
+	}
+
+	if tvomodels.RoleId(roleId) != tvomodels.ADMIN && collection.OwnerId != userId {
+		log.Error("User is not the collection owner")
+		return 0, tvoerrors.ErrForbidden
+	}
+	return collection.ID, nil
+}
+
+// directoryUploadResponse преобразует загрузку директории в ответ API. CID отдаются в основании base,
+// адреса для контрактов и шлюза остаются в base32.
+func (h *KuboHandlers) directoryUploadResponse(upload *models.DirectoryUpload,
+	base cids.Base) *dto.DirectoryUploadResponse {
+	baseURI := "ipfs://" + upload.RootCid + "/"
+	response := &dto.DirectoryUploadResponse{
+		RootCid:     base.FormatString(upload.RootCid),
+		BaseURI:     baseURI,
+		GatewayLink: gatewayLink(h.gatewayURL, upload.RootCid),
+		Size:        upload.Size,
+		Files:       make([]dto.DirectoryFileInfo, 0, len(upload.Files)),
+		Created:     upload.CreatedAt,
+	}
+	for _, file := range upload.Files {
+		response.Files = append(response.Files, dto.DirectoryFileInfo{
+			Path:      file.Path,
+			Cid:       base.FormatString(file.Cid),
+			Size:      file.Size,
+			Directory: file.Directory,
+			TokenId:   file.TokenId,
+			URI:       baseURI + file.Path,
+		})
+	}
+	return response
+}
+
+// PinCidHandler ставит закрепление CID в очередь и сразу возвращает идентификатор запроса.
+// Закрепление выполняет фоновый воркер, состояние доступно по /pins/requests/:requestid.
+func (h *KuboHandlers) PinCidHandler(c *fiber.Ctx) error {
+	pinCid, err := cids.Parse(c.Params("cid"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "CID не указан или некорректен"})
+	}
+
+	userId, err := httputils.UserIDFromToken(c, "PinCidHandler", h.logger)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": tvoerrors.ErrCastClaims.Error()})
+	}
+
+	pin := &models.Pin{Cid: pinCid.String(), RequesterId: userId}
+	if err = h.pinRepository.CreatePin(c.Context(), pin); err != nil {
+		log.Error("Error creating pin request", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "something went wrong"})
+	}
+	h.pinWorker.Notify()
+
+	return c.Status(fiber.StatusAccepted).JSON(pinRequestStatus(pin, cidBase(c)))
+}
+
+// PinRequestHandler отдает состояние запроса на закрепление, доступно автору запроса и администратору
+func (h *KuboHandlers) PinRequestHandler(c *fiber.Ctx) error {
+	pin, err := h.pinRepository.PinByRequestId(c.Context(), c.Params("requestid"))
+	if err != nil {
+		if errors.Is(err, tvoerrors.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Запрос на закрепление не найден"})
+		}
+		log.Error("Error accessing to DB", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "something went wrong"})
+	}
+
+	userId, err := httputils.UserIDFromToken(c, "PinRequestHandler", h.logger)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": tvoerrors.ErrCastClaims.Error()})
+	}
+	roleId, err := httputils.RoleIDFromToken(c, "PinRequestHandler", h.logger)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": tvoerrors.ErrCastClaims.Error()})
+	}
+	if tvomodels.RoleId(roleId) != tvomodels.ADMIN && pin.RequesterId != userId {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Запрос на закрепление не найден"})
+	}
+
+	replicas, err := h.pinReplicaRepository.ReplicasByPinId(c.Context(), pin.ID)
+	if err != nil {
+		log.Error("Error accessing to DB", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "something went wrong"})
+	}
+
+	status := pinRequestStatus(pin, cidBase(c))
+	for i := range replicas {
+		status.Replicas = append(status.Replicas, pinReplicaStatus(&replicas[i]))
+	}
+	return c.JSON(status)
 }
 
-// UnpinCidHandler обрабатывает открепление CID.
-func UnpinCidHandler(c *fiber.Ctx) error {
-	cid := c.Params("cid")
-	if cid == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "CID не указан"})
+// UnpinCidHandler снимает запросы пользователя на закрепление CID. Из хранилища CID открепляется,
+// только если его больше не держат запросы других пользователей и токены. Администратор может
+// открепить CID и без своих запросов, например загрузку, так и не ставшую токеном.
+func (h *KuboHandlers) UnpinCidHandler(c *fiber.Ctx) error {
+	unpinCid, err := cids.Parse(c.Params("cid"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "CID не указан или некорректен"})
+	}
+
+	userId, err := httputils.UserIDFromToken(c, "UnpinCidHandler", h.logger)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": tvoerrors.ErrCastClaims.Error()})
+	}
+	roleId, err := httputils.RoleIDFromToken(c, "UnpinCidHandler", h.logger)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": tvoerrors.ErrCastClaims.Error()})
+	}
+
+	ctx := c.Context()
+	// запрос мог быть заведен на другую версию CID
+	deleted, err := h.pinRepository.DeleteUserPins(ctx, userId, cids.Forms(unpinCid))
+	if err != nil {
+		log.Error("Error deleting pin requests", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "something went wrong"})
+	}
+	if deleted == 0 && tvomodels.RoleId(roleId) != tvomodels.ADMIN {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Запрос на закрепление CID не найден"})
 	}
 
-	unpinResponse, err := service.UnpinCID(cid)
+	response := models.PinResponse{Pins: []string{}}
+	inUse, err := cidInUse(ctx, h.pinRepository, h.nftDataRepository, unpinCid)
 	if err != nil {
+		log.Error("Error accessing to DB", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "something went wrong"})
+	}
+	if inUse {
+		return c.JSON(response)
+	}
+
+	err = h.storage.Unpin(ctx, unpinCid)
+	switch {
+	case errors.Is(err, tvoerrors.ErrNotFound):
+		// запрос мог так и не дойти до хранилища
+		if deleted > 0 {
+			return c.JSON(response)
+		}
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "CID не закреплен"})
+	case err != nil:
+		log.Error("Error unpinning cid", "cid", unpinCid, "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	return c.JSON(unpinResponse)
+	response.Pins = append(response.Pins, cidBase(c).Format(unpinCid))
+	return c.JSON(response)
 }
 
 // ListPinsHandler обрабатывает запрос на получение списка закрепленных CID.
-func ListPinsHandler(c *fiber.Ctx) error {
-	lsResponse, err := service.ListPinnedCIDs()
+func (h *KuboHandlers) ListPinsHandler(c *fiber.Ctx) error {
+	pinned, err := h.storage.List(c.Context())
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	// сохраняем формат ответа Kubo /api/v0/pin/ls
+	base := cidBase(c)
+	lsResponse := models.PinLsResponse{Keys: make(map[string]models.PinLsKey, len(pinned))}
+	for _, pinnedCid := range pinned {
+		lsResponse.Keys[base.Format(pinnedCid)] = models.PinLsKey{Type: "recursive"}
+	}
+
 	return c.JSON(lsResponse)
 }
+
+// pinRequestStatus преобразует запрос на закрепление в ответ API, CID отдается в основании base
+func pinRequestStatus(pin *models.Pin, base cids.Base) *dto.PinRequestStatus {
+	status := &dto.PinRequestStatus{
+		RequestId: pin.RequestId,
+		Cid:       base.FormatString(pin.Cid),
+		Status:    pin.Status,
+		Attempts:  pin.Attempts,
+		Error:     pin.Error,
+		Created:   pin.CreatedAt,
+	}
+	if !pin.PinnedAt.IsZero() {
+		status.PinnedAt = &pin.PinnedAt
+	}
+	return status
+}
+
+// pinReplicaStatus преобразует копию закрепления на удаленном сервисе в ответ API
+func pinReplicaStatus(replica *models.PinReplica) dto.PinReplicaStatus {
+	status := dto.PinReplicaStatus{
+		Target:          replica.Target,
+		RemoteRequestId: replica.RemoteRequestId,
+		Status:          replica.Status,
+		Attempts:        replica.Attempts,
+		Error:           replica.Error,
+	}
+	if !replica.PinnedAt.IsZero() {
+		status.PinnedAt = &replica.PinnedAt
+	}
+	return status
+}
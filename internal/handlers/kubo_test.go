@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main/internal/lib/cids"
+	"main/internal/models"
+	"main/internal/storage"
+	"main/tools/pkg/constants"
+	"main/tools/pkg/logger"
+	tvomodels "main/tools/pkg/tvo_models"
+)
+
+func TestUnpinCid(t *testing.T) {
+	ctx := context.Background()
+	s, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStorage() error = %v", err)
+	}
+	obj, err := s.Add(ctx, "1.png", strings.NewReader("image"))
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	v0 := obj.Cid.String()
+	v1 := cids.V1(obj.Cid).String()
+
+	// запросы пользователей 1 и 2 на одно содержимое, заведенные на разные версии CID
+	pins := &fakePinRepository{}
+	_ = pins.CreatePin(ctx, &models.Pin{Cid: v0, RequesterId: 1})
+	_ = pins.CreatePin(ctx, &models.Pin{Cid: v1, RequesterId: 2})
+
+	l := &logger.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	h := NewKuboHandlers(l, s, pins, &fakeNftDataRepository{}, nil, nil, nil, nil, nil, "https://ipfs.io/ipfs/%s")
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		tokenData := tvomodels.TokenData{UserID: 3}
+		switch c.Get(fiber.HeaderAuthorization) {
+		case "user1":
+			tokenData.UserID = 1
+		case "user2":
+			tokenData.UserID = 2
+		case "admin":
+			tokenData.UserRoleID = tvomodels.ADMIN
+		}
+		c.Locals(constants.TOKEN_DATA_KEY, tokenData)
+		return c.Next()
+	})
+	app.Delete("/pins/:cid", h.UnpinCidHandler)
+
+	unpin := func(token, target string) int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodDelete, "/pins/"+target, nil)
+		req.Header.Set(fiber.HeaderAuthorization, token)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("DELETE /pins/%s error = %v", target, err)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+	pinned := func() bool {
+		t.Helper()
+		list, err := s.List(ctx)
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		return len(list) == 1
+	}
+
+	// без своих запросов обычный пользователь ничего не снимает
+	if code := unpin("user3", v1); code != http.StatusNotFound {
+		t.Errorf("DELETE by a stranger = %d, expected 404", code)
+	}
+	if count, _ := pins.PinCountByCid(ctx, v1); count != 1 || !pinned() {
+		t.Fatalf("DELETE by a stranger removed pin requests or content")
+	}
+
+	// запрос пользователя 1 снимается, содержимое держит запрос пользователя 2
+	if code := unpin("user1", v1); code != http.StatusOK {
+		t.Errorf("DELETE by user1 = %d, expected 200", code)
+	}
+	if count, _ := pins.PinCountByCid(ctx, v0); count != 0 {
+		t.Errorf("DELETE by user1 kept %d requests for %s", count, v0)
+	}
+	if count, _ := pins.PinCountByCid(ctx, v1); count != 1 || !pinned() {
+		t.Fatalf("DELETE by user1 removed the request or content of user2")
+	}
+
+	// последний запрос открепляет содержимое из хранилища
+	if code := unpin("user2", v0); code != http.StatusOK {
+		t.Errorf("DELETE by user2 = %d, expected 200", code)
+	}
+	if pinned() {
+		t.Errorf("content is still pinned after the last request was removed")
+	}
+
+	if code := unpin("admin", v1); code != http.StatusNotFound {
+		t.Errorf("DELETE of an unpinned CID by admin = %d, expected 404", code)
+	}
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"path"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"main/internal/dto"
+	"main/internal/lib/cids"
+	"main/internal/mfs"
+	"main/internal/storage"
+	httputils "main/tools/pkg/http_utils"
+	"main/tools/pkg/logger"
+	tvoerrors "main/tools/pkg/tvo_errors"
+	tvomodels "main/tools/pkg/tvo_models"
+)
+
+// MfsHandlers просмотр зеркала загрузок в файловом дереве узла
+type MfsHandlers struct {
+	logger *logger.Logger
+	mirror *mfs.Mirror
+}
+
+func NewMfsHandlers(logger *logger.Logger, mirror *mfs.Mirror) *MfsHandlers {
+	return &MfsHandlers{
+		logger: logger,
+		mirror: mirror,
+	}
+}
+
+// ListMfs отдает элементы директории зеркала из параметра path, путь отсчитывается от корня зеркала.
+// Доступно только администраторам.
+func (h *MfsHandlers) ListMfs(c *fiber.Ctx) (interface{}, error) {
+	if err := h.checkAdmin(c, "ListMfs"); err != nil {
+		return nil, err
+	}
+
+	dir, files, err := h.mirror.List(c.Context(), c.Query("path"))
+	if err != nil {
+		return nil, h.mirrorError(err)
+	}
+
+	base := cidBase(c)
+	entries := make([]dto.MfsEntryInfo, 0, len(files))
+	for i := range files {
+		entries = append(entries, mfsEntryInfo(path.Join(dir, files[i].Name), &files[i], base))
+	}
+	return &dto.MfsListResponse{
+		Path:    dir,
+		Entries: entries,
+	}, nil
+}
+
+// StatMfs описывает файл или директорию зеркала из параметра path. Доступно только администраторам.
+func (h *MfsHandlers) StatMfs(c *fiber.Ctx) (interface{}, error) {
+	if err := h.checkAdmin(c, "StatMfs"); err != nil {
+		return nil, err
+	}
+
+	full, info, err := h.mirror.Stat(c.Context(), c.Query("path"))
+	if err != nil {
+		return nil, h.mirrorError(err)
+	}
+	return mfsEntryInfo(full, info, cidBase(c)), nil
+}
+
+// checkAdmin проверяет, что запрос выполняет администратор
+func (h *MfsHandlers) checkAdmin(c *fiber.Ctx, method string) error {
+	roleId, err := httputils.RoleIDFromToken(c, method, h.logger)
+	if err != nil {
+		return tvoerrors.ErrCastClaims
+	}
+	if tvomodels.RoleId(roleId) != tvomodels.ADMIN {
+		log.Error("Wrong user role")
+		return tvoerrors.ErrForbidden
+	}
+	return nil
+}
+
+// mirrorError переводит ошибку просмотра зеркала в ответ API
+func (h *MfsHandlers) mirrorError(err error) error {
+	switch {
+	case errors.Is(err, tvoerrors.ErrNotFound):
+		return tvoerrors.ErrNotFound
+	case errors.Is(err, mfs.ErrUnsupported):
+		return mfs.ErrUnsupported
+	default:
+		log.Error("Error reading mfs", "error", err)
+		return status.Error(codes.Internal, "something went wrong") //nolint
+	}
+}
+
+// mfsEntryInfo преобразует элемент зеркала в ответ API, CID отдается в основании base
+func mfsEntryInfo(fullPath string, info *storage.FileInfo, base cids.Base) dto.MfsEntryInfo {
+	return dto.MfsEntryInfo{
+		Name:      info.Name,
+		Path:      fullPath,
+		Cid:       base.Format(info.Cid),
+		Size:      info.Size,
+		Directory: info.Directory,
+	}
+}
@@ -1,157 +1,1155 @@
 package handlers
 
 import (
-	"fmt"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
+	"github.com/ipfs/go-cid"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"io"
+	"main/internal/config"
 	"main/internal/dto"
+	"main/internal/gateway"
+	"main/internal/ipns"
+	"main/internal/lib/cids"
+	"main/internal/mfs"
+	"main/internal/models"
+	"main/internal/pinning"
 	"main/internal/repository"
 	"main/internal/service"
+	"main/internal/storage"
+	"main/tools/pkg/helpers"
 	httputils "main/tools/pkg/http_utils"
 	"main/tools/pkg/logger"
 	tvoerrors "main/tools/pkg/tvo_errors"
+	tvomodels "main/tools/pkg/tvo_models"
+	"net/url"
+	"os"
 	"strconv"
+	"strings"
+)
+
+// metadataFileName имя документа метаданных токена в хранилище и в зеркале MFS
+const metadataFileName = "metadata.json"
+
+// действия при создании токена с содержимым, которое уже есть у другого токена
+const (
+	onDuplicateReject   = "reject"
+	onDuplicateExisting = "existing"
 )
 
 // NftHandlers
 type NftHandlers struct {
-	logger            *logger.Logger
-	nftDataRepository repository.NftDataRepository
+	logger               *logger.Logger
+	nftDataRepository    repository.NftDataRepository
+	collectionRepository repository.CollectionRepository
+	pinRepository        repository.PinRepository
+	storage              storage.Storage
+	gateway              *gateway.Gateway
+	mirror               *mfs.Mirror
+	pinWorker            *pinning.Worker
+	gatewayURL           string
+	metadataBaseURI      string
+	collectionBaseURI    string
 }
 
-func NewNftHandlers(logger *logger.Logger, nftRepository repository.NftDataRepository) *NftHandlers {
+func NewNftHandlers(logger *logger.Logger, nftRepository repository.NftDataRepository,
+	collectionRepository repository.CollectionRepository, pinRepository repository.PinRepository,
+	storage storage.Storage, contentGateway *gateway.Gateway, mirror *mfs.Mirror, pinWorker *pinning.Worker,
+	gatewayURL string, nftCfg *config.NFT) *NftHandlers {
 	return &NftHandlers{
-		logger:            logger,
-		nftDataRepository: nftRepository,
+		logger:               logger,
+		nftDataRepository:    nftRepository,
+		collectionRepository: collectionRepository,
+		pinRepository:        pinRepository,
+		storage:              storage,
+		gateway:              contentGateway,
+		mirror:               mirror,
+		pinWorker:            pinWorker,
+		gatewayURL:           gatewayURL,
+		metadataBaseURI:      nftCfg.MetadataBaseURI,
+		collectionBaseURI:    nftCfg.CollectionBaseURI,
 	}
 }
 
+// CreateNftData создает токен из multipart формы. Форма читается потоком, поэтому текстовые поля
+// (id обязательно, name, description, attributes, collection, max_supply, on_duplicate) передаются
+// перед файлом file. Поле после файла или отсутствие id дают 400. Файл сохраняется во временный файл:
+// по его CID сначала ищется токен с тем же содержимым, и лишь затем файл добавляется в хранилище.
 func (h *NftHandlers) CreateNftData(c *fiber.Ctx) (interface{}, error) {
-	var request dto.CreateNftDataRequest
+	ctx := httputils.CtxWithAuthToken(c)
+	roleId, err := httputils.RoleIDFromToken(c, "CreateNftData", h.logger)
+	if err != nil {
+		return nil, tvoerrors.ErrCastClaims
+	}
+	userId, err := httputils.UserIDFromToken(c, "CreateNftData", h.logger)
+	if err != nil {
+		return nil, tvoerrors.ErrCastClaims
+	}
 
-	if err := httputils.ParseRequestBody(c, &request, "CreateNftData", h.logger); err != nil {
+	// параметры токена проверяются до чтения файла, а в хранилище ничего не попадает,
+	// пока не прочитана вся форма
+	var draft *nftDraft
+	var name string
+	var file *os.File
+	err = streamForm(c, "file", func(form url.Values, fileName string, r io.Reader) error {
+		request, err := createNftDataRequest(form)
+		if err != nil {
+			log.Error("Wrong nft data form", "error", err)
+			return tvoerrors.ErrInvalidRequestData
+		}
+		if draft, err = h.newNftDraft(ctx, request, roleId, userId); err != nil {
+			return err
+		}
+		name = fileName
+		if file, err = spoolFile(r); err != nil {
+			log.Error("Error saving nft file", "error", err)
+			return status.Error(codes.Internal, "something went wrong") //nolint
+		}
+		return nil
+	})
+	if file != nil {
+		defer removeSpooled(file)
+	}
+	if errors.Is(err, ErrFormFileNotFound) {
+		log.Error("Error reading image file", "error", err)
 		return nil, tvoerrors.ErrInvalidRequestData
 	}
-	file, err := c.FormFile("file")
 	if err != nil {
-		log.Error("Error reading image file", "error", err)
+		return nil, err
+	}
+	request := draft.request
+
+	// CID вычисляется без сохранения, так что отклоненный дубликат не оставляет закрепления
+	hashed, err := storage.Hash(ctx, h.storage, name, file)
+	if err != nil {
+		log.Error("Error hashing nft file", "error", err)
+		return nil, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+	existing, err := h.nftByContent(ctx, hashed)
+	if err != nil {
+		log.Error("Error checking nft content", "error", err)
 		return nil, status.Error(codes.Internal, "something went wrong") //nolint
 	}
+	if existing != nil {
+		return h.duplicateNft(c, request, existing)
+	}
 
-	ctx := httputils.CtxWithAuthToken(c)
-	roleId, err := httputils.RoleIDFromToken(c, "CreateNftData", h.logger)
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		log.Error("Error reading nft file", "error", err)
+		return nil, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+	object, err := h.storage.Add(ctx, name, file)
 	if err != nil {
-		return nil, tvoerrors.ErrCastClaims
+		log.Error("Error creating nft data ", "error", err)
+		return nil, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+
+	// формируем и закрепляем документ метаданных ERC-721
+	metadata := service.BuildNftMetadata(request.Id, request.Name, request.Description, object.CidV1().String(),
+		service.AttributesToTraits(draft.attributes))
+	metadataObject, err := h.storage.Add(ctx, metadataFileName, bytes.NewReader(helpers.JsonEncode(metadata)))
+	if err != nil {
+		log.Error("Error pinning nft metadata", "error", err)
+		return nil, status.Error(codes.Internal, "something went wrong") //nolint
 	}
 
-	if roleId != 100 {
+	nftData := &dto.NftData{
+		TokenId:      request.Id,
+		Name:         request.Name,
+		Description:  request.Description,
+		CidV0:        object.CidV0().String(),
+		CidV1:        object.CidV1().String(),
+		FileName:     object.Name,
+		FileSize:     strconv.FormatInt(object.Size, 10),
+		MetadataCid:  metadataObject.Cid.String(),
+		CollectionId: draft.collectionId,
+		MaxSupply:    draft.maxSupply,
+		AuthorId:     userId,
+		Attributes:   draft.attributes,
+	}
+
+	err = h.nftDataRepository.CreateNftData(ctx, nftData)
+	if err != nil {
+		// то же содержимое могли загрузить одновременно, тогда оно уже принадлежит другому токену.
+		// Закрепленные метаданные этой загрузки ни на что не ссылаются, их снимет сборщик осиротевших закреплений
+		if errors.Is(err, repository.ErrDuplicateContent) {
+			if existing, err = h.nftByContent(ctx, object); err == nil && existing != nil {
+				return h.duplicateNft(c, request, existing)
+			}
+			return nil, tvoerrors.ErrConflict
+		}
+		if errors.Is(err, tvoerrors.ErrConflict) {
+			return nil, tvoerrors.ErrConflict
+		}
+		log.Error("Error creating nft data", "error", err)
+		return nil, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+	h.trackPins(ctx, userId, object, metadataObject)
+	h.mirrorFile(ctx, request.Collection, request.Id, object.Cid.String(), object.Name)
+	h.mirrorFile(ctx, request.Collection, request.Id, nftData.MetadataCid, metadataFileName)
+
+	return &dto.CreateNftDataResponse{
+		Message:     "NFT data created successful",
+		MetadataCid: cidBase(c).FormatString(nftData.MetadataCid),
+		TokenURI:    h.tokenURI(request.Collection, draft.standard, request.Id),
+	}, nil
+}
+
+// duplicateNft отвечает на загрузку содержимого, которое уже принадлежит токену existing:
+// по умолчанию конфликтом, с on_duplicate=existing - найденным токеном
+func (h *NftHandlers) duplicateNft(c *fiber.Ctx, request *dto.CreateNftDataRequest,
+	existing *models.NftDataModel) (interface{}, error) {
+	if request.OnDuplicate != onDuplicateExisting {
+		log.Error("Nft content already exists", "cid", existing.CidV0, "token_id", existing.TokenId,
+			"collection", existing.Collection)
+		return nil, tvoerrors.ErrConflict
+	}
+	info := h.nftInfo(existing, cidBase(c))
+	return &dto.CreateNftDataResponse{
+		Message:     "NFT data already exists",
+		MetadataCid: info.MetadataCid,
+		TokenURI:    info.TokenURI,
+		Existing:    info,
+	}, nil
+}
+
+// nftDraft проверенные параметры создаваемого токена
+type nftDraft struct {
+	request      *dto.CreateNftDataRequest
+	collectionId int64
+	standard     string
+	maxSupply    int64
+	attributes   []models.NftAttribute
+}
+
+// createNftDataRequest собирает запрос из текстовых полей формы. Поле id обязательно:
+// без него токен молча получил бы номер 0.
+func createNftDataRequest(form url.Values) (*dto.CreateNftDataRequest, error) {
+	request := &dto.CreateNftDataRequest{
+		Name:        form.Get("name"),
+		Description: form.Get("description"),
+		Attributes:  form.Get("attributes"),
+		Collection:  form.Get("collection"),
+		OnDuplicate: form.Get("on_duplicate"),
+	}
+	if !form.Has("id") {
+		return nil, errors.New("id field is missing, form fields must precede the file")
+	}
+	var err error
+	if request.Id, err = strconv.ParseInt(form.Get("id"), 10, 64); err != nil {
+		return nil, err
+	}
+	if value := form.Get("max_supply"); value != "" {
+		if request.MaxSupply, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return nil, err
+		}
+	}
+	return request, nil
+}
+
+// newNftDraft проверяет права на выпуск токена и его параметры
+func (h *NftHandlers) newNftDraft(ctx context.Context, request *dto.CreateNftDataRequest, roleId,
+	userId int64) (*nftDraft, error) {
+	switch request.OnDuplicate {
+	case "", onDuplicateReject, onDuplicateExisting:
+	default:
+		log.Error("Wrong on_duplicate value", "on_duplicate", request.OnDuplicate)
+		return nil, tvoerrors.ErrInvalidRequestData
+	}
+
+	var collection *models.Collection
+	if request.Collection != "" {
+		var err error
+		collection, err = h.collectionRepository.CollectionBySlug(ctx, request.Collection)
+		if err != nil {
+			if errors.Is(err, tvoerrors.ErrNotFound) {
+				return nil, tvoerrors.ErrNotFound
+			}
+			log.Error("Error accessing to DB", "error", err)
+			return nil, status.Error(codes.Internal, "something went wrong") //nolint
+		}
+	}
+
+	// токены выпускает администратор, в свою коллекцию - также ее владелец
+	if tvomodels.RoleId(roleId) != tvomodels.ADMIN && (collection == nil || collection.OwnerId != userId) {
 		log.Error("Wrong user role")
 		return nil, tvoerrors.ErrForbidden
 	}
 
-	isExist, err := h.nftDataRepository.TokenIdExists(ctx, request.Id)
+	draft := &nftDraft{request: request, standard: models.TokenStandardERC721}
+	if collection != nil {
+		draft.collectionId = collection.ID
+		draft.standard = collection.TokenStandard
+	}
+
+	var err error
+	draft.maxSupply, err = service.EditionMaxSupply(draft.standard, request.MaxSupply)
+	if err != nil {
+		log.Error("Wrong max supply", "error", err)
+		return nil, err
+	}
+
+	draft.attributes, err = service.ParseNftAttributes(request.Attributes)
+	if err != nil {
+		log.Error("Error parsing nft attributes", "error", err)
+		return nil, err
+	}
+
+	isExist, err := h.nftDataRepository.TokenIdExists(ctx, draft.collectionId, request.Id)
 	if err != nil {
 		log.Error("Error accessing to DB", "error", err)
 		return nil, status.Error(codes.Internal, "something went wrong") //nolint
 	}
 	if isExist {
-		log.Error("Wrong token id", "error", err)
-		return nil, status.Error(codes.Internal, "wrong token id (is exist)") //nolint
+		log.Error("Token id already exists", "token_id", request.Id, "collection", request.Collection)
+		return nil, tvoerrors.ErrConflict
+	}
+	return draft, nil
+}
+
+// PreviewCid вычисляет CIDv0 и CIDv1, которые получит файл при загрузке, ничего не сохраняя.
+// Если файл уже загружен как содержимое живого токена, в ответе будет этот токен.
+func (h *NftHandlers) PreviewCid(c *fiber.Ctx) (interface{}, error) {
+	ctx := c.Context()
+
+	// файл хешируется прямо из тела запроса
+	var object *storage.Object
+	err := streamFormFile(c, "file", func(name string, r io.Reader) (err error) {
+		object, err = storage.Hash(ctx, h.storage, name, r)
+		return err
+	})
+	if errors.Is(err, ErrFormFileNotFound) {
+		return nil, tvoerrors.ErrInvalidRequestData
 	}
-	addResponse, cidV1, _, err := service.AddFileToIPFS(file)
 	if err != nil {
-		log.Error("Error creating nft data ", "error", err)
+		log.Error("Error hashing file", "error", err)
 		return nil, status.Error(codes.Internal, "something went wrong") //nolint
 	}
 
-	nftData := &dto.NftData{
-		TokenId:     request.Id,
-		Description: request.Description,
-		CidV0:       addResponse.Hash,
-		CidV1:       cidV1,
-		FileName:    addResponse.Name,
-		FileSize:    addResponse.Size,
+	response := &dto.CidPreviewResponse{
+		Name:  object.Name,
+		Size:  object.Size,
+		CidV0: object.CidV0().String(),
+		CidV1: cidBase(c).Format(object.CidV1()),
 	}
-
-	err = h.nftDataRepository.CreateNftData(ctx, nftData)
+	existing, err := h.nftByContent(ctx, object)
 	if err != nil {
-		log.Error("Error creating nft data", "error", err)
+		log.Error("Error accessing to DB", "error", err)
 		return nil, status.Error(codes.Internal, "something went wrong") //nolint
 	}
+	if existing != nil {
+		response.Existing = h.nftInfo(existing, cidBase(c))
+	}
+	return response, nil
+}
 
-	return &dto.CreateNftDataResponse{
-		Message: "NFT data created successful",
+func (h *NftHandlers) ReadNft(c *fiber.Ctx) (interface{}, error) {
+	nft, err := h.readNft(c)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.ReadNftResponse{
+		Info: h.nftInfo(&nft, cidBase(c)),
 	}, nil
 }
 
-func (h *NftHandlers) ReadNft(c *fiber.Ctx) (interface{}, error) {
-	strId := c.Params("id")
-	if strId == "" {
-		log.Error("Error reading nft id", "error")
+// ReadNftMetadata отдает документ метаданных токена, адрес метода используется как tokenURI контракта ERC-721
+// либо, с номером в виде hex, как uri() контракта ERC-1155
+func (h *NftHandlers) ReadNftMetadata(c *fiber.Ctx) (interface{}, error) {
+	nft, err := h.readNft(c)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := h.nftMetadata(c.Context(), &nft)
+	if err != nil {
+		log.Error("Error reading nft metadata", "error", err)
 		return nil, status.Error(codes.Internal, "something went wrong") //nolint
 	}
-	tokenId, err := strconv.ParseInt(strId, 10, 64)
 
+	return metadata, nil
+}
+
+// UpdateNftData изменяет имя, описание и атрибуты токена и закрепляет новый документ метаданных.
+// Доступно администратору и владельцу коллекции токена.
+func (h *NftHandlers) UpdateNftData(c *fiber.Ctx) (interface{}, error) {
+	var request dto.UpdateNftDataRequest
+
+	if err := httputils.ParseRequestBody(c, &request, "UpdateNftData", h.logger); err != nil {
+		return nil, tvoerrors.ErrInvalidRequestData
+	}
+
+	nft, err := h.readNft(c)
 	if err != nil {
-		log.Error("Error parsing nft id", "error", err)
+		return nil, err
+	}
+	if err = h.checkManager(c, &nft, "UpdateNftData"); err != nil {
+		return nil, err
+	}
+	userId, err := httputils.UserIDFromToken(c, "UpdateNftData", h.logger)
+	if err != nil {
+		return nil, tvoerrors.ErrCastClaims
+	}
+
+	ctx := c.Context()
+
+	metadata, err := h.nftMetadata(ctx, &nft)
+	if err != nil {
+		log.Error("Error reading nft metadata", "error", err)
 		return nil, status.Error(codes.Internal, "something went wrong") //nolint
 	}
 
+	if request.Name != nil {
+		metadata.Name = *request.Name
+	}
+	nft.Name = metadata.Name
+	if request.Description != nil {
+		nft.Description = *request.Description
+	}
+	if request.Attributes != nil {
+		if nft.Attributes, err = service.ParseNftAttributes(string(request.Attributes)); err != nil {
+			log.Error("Error parsing nft attributes", "error", err)
+			return nil, err
+		}
+	}
+
+	updated := service.BuildNftMetadata(nft.TokenId, metadata.Name, nft.Description, nft.CidV1,
+		service.AttributesToTraits(nft.Attributes))
+	metadataObject, err := h.storage.Add(ctx, metadataFileName, bytes.NewReader(helpers.JsonEncode(updated)))
+	if err != nil {
+		log.Error("Error pinning nft metadata", "error", err)
+		return nil, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+	nft.MetadataCid = metadataObject.Cid.String()
+
+	if err = h.nftDataRepository.UpdateNftData(ctx, &nft, models.NftChange{AuthorId: userId}); err != nil {
+		if errors.Is(err, tvoerrors.ErrNotFound) {
+			return nil, tvoerrors.ErrNotFound
+		}
+		log.Error("Error updating nft data", "error", err)
+		return nil, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+	h.trackPins(ctx, userId, metadataObject)
+	h.mirrorFile(ctx, nft.Collection, nft.TokenId, nft.MetadataCid, metadataFileName)
+
+	return &dto.ReadNftResponse{
+		Info: h.nftInfo(&nft, cidBase(c)),
+	}, nil
+}
+
+// DeleteNftData помечает токен удаленным. С параметром unpin=true снимает запросы пользователя
+// на закрепление файла и метаданных и открепляет их, если на них не ссылаются другие токены
+// и не осталось запросов других пользователей.
+// Доступно администратору и владельцу коллекции токена.
+func (h *NftHandlers) DeleteNftData(c *fiber.Ctx) (interface{}, error) {
+	nft, err := h.readNft(c)
+	if err != nil {
+		return nil, err
+	}
+	if err = h.checkManager(c, &nft, "DeleteNftData"); err != nil {
+		return nil, err
+	}
+
 	ctx := c.Context()
 
-	nft, err := h.nftDataRepository.ReadNftData(ctx, tokenId)
+	nft, err = h.nftDataRepository.DeleteNftData(ctx, nft.CollectionId, nft.TokenId)
+	if err != nil {
+		if errors.Is(err, tvoerrors.ErrNotFound) {
+			return nil, tvoerrors.ErrNotFound
+		}
+		log.Error("Error deleting nft data", "error", err)
+		return nil, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+
+	response := &dto.DeleteNftDataResponse{
+		Message: "NFT data deleted",
+	}
+	if c.Query("unpin") != "true" {
+		return response, nil
+	}
+
+	userId, err := httputils.UserIDFromToken(c, "DeleteNftData", h.logger)
+	if err != nil {
+		return nil, tvoerrors.ErrCastClaims
+	}
+
+	for _, raw := range []string{nft.CidV0, nft.MetadataCid} {
+		if raw == "" {
+			continue
+		}
+		contentCid, err := cid.Decode(raw)
+		if err != nil {
+			log.Error("Error decoding cid", "cid", raw, "error", err)
+			continue
+		}
+		// содержимое, общее с другими токенами, остается закрепленным вместе с запросами на него
+		referenced, err := h.cidReferenced(ctx, contentCid)
+		if err != nil {
+			log.Error("Error accessing to DB", "error", err)
+			return nil, status.Error(codes.Internal, "something went wrong") //nolint
+		}
+		if referenced {
+			continue
+		}
+
+		// иначе сверка с хранилищем закрепит CID снова. Запросы других пользователей, в том числе
+		// через Pinning Service API, не трогаем: пока они есть, CID остается закрепленным
+		if _, err = h.pinRepository.DeleteUserPins(ctx, userId, cids.Forms(contentCid)); err != nil {
+			log.Error("Error deleting pin requests", "cid", raw, "error", err)
+			return nil, status.Error(codes.Internal, "something went wrong") //nolint
+		}
+		inUse, err := cidInUse(ctx, h.pinRepository, h.nftDataRepository, contentCid)
+		if err != nil {
+			log.Error("Error accessing to DB", "error", err)
+			return nil, status.Error(codes.Internal, "something went wrong") //nolint
+		}
+		if inUse {
+			continue
+		}
+		// токен уже удален, поэтому ошибку снятия закрепления только логируем
+		if err = h.storage.Unpin(ctx, contentCid); err != nil && !errors.Is(err, tvoerrors.ErrNotFound) {
+			log.Error("Error unpinning content", "cid", raw, "error", err)
+			continue
+		}
+		response.Unpinned = append(response.Unpinned, raw)
+	}
+
+	return response, nil
+}
+
+// cidReferenced проверяет, ссылаются ли на CID токены в любой из его форм
+func (h *NftHandlers) cidReferenced(ctx context.Context, c cid.Cid) (bool, error) {
+	for _, form := range cids.Forms(c) {
+		referenced, err := h.nftDataRepository.CidReferenced(ctx, form)
+		if err != nil || referenced {
+			return referenced, err
+		}
+	}
+	return false, nil
+}
+
+// RestoreNftData восстанавливает удаленный токен, аналог DigupUser. Только для администратора.
+// Содержимое токена закрепляется снова до восстановления: закрепление могли снять при удалении.
+func (h *NftHandlers) RestoreNftData(c *fiber.Ctx) (interface{}, error) {
+	tokenId, err := service.ParseTokenId(c.Params("id"))
+	if err != nil {
+		log.Error("Error parsing nft id", "error", err)
+		return nil, tvoerrors.ErrInvalidRequestData
+	}
+
+	roleId, err := httputils.RoleIDFromToken(c, "RestoreNftData", h.logger)
 	if err != nil {
+		return nil, tvoerrors.ErrCastClaims
+	}
+	if tvomodels.RoleId(roleId) != tvomodels.ADMIN {
+		log.Error("Only admin can restore nft")
+		return nil, tvoerrors.ErrForbidden
+	}
+
+	userId, err := httputils.UserIDFromToken(c, "RestoreNftData", h.logger)
+	if err != nil {
+		return nil, tvoerrors.ErrCastClaims
+	}
+
+	collectionId, err := h.collectionId(c)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := c.Context()
+	deleted, err := h.nftDataRepository.ReadDeletedNftData(ctx, collectionId, tokenId)
+	if err != nil {
+		if errors.Is(err, tvoerrors.ErrNotFound) {
+			return nil, tvoerrors.ErrNotFound
+		}
 		log.Error("Error accessing to DB", "error", err)
 		return nil, status.Error(codes.Internal, "something went wrong") //nolint
 	}
+	if err = h.pinNftContent(ctx, userId, &deleted); err != nil {
+		log.Error("Error pinning restored nft content", "token_id", tokenId, "error", err)
+		return nil, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+
+	nft, err := h.nftDataRepository.RestoreNftData(ctx, collectionId, tokenId)
+	if err != nil {
+		if errors.Is(err, tvoerrors.ErrNotFound) {
+			return nil, tvoerrors.ErrNotFound
+		}
+		if errors.Is(err, tvoerrors.ErrConflict) {
+			log.Error("Token id already issued", "error", err)
+			return nil, tvoerrors.ErrConflict
+		}
+		log.Error("Error restoring nft data", "error", err)
+		return nil, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+
 	return &dto.ReadNftResponse{
-		Info: &dto.NftInfo{
-			TokenId:     nft.TokenId,
-			Description: nft.Description,
-			CidV0:       nft.CidV0,
-			CidV1:       nft.CidV1,
-			Link:        fmt.Sprintf(service.KuboGatewayUrlTemplate, nft.CidV1),
-		},
+		Info: h.nftInfo(&nft, cidBase(c)),
 	}, nil
 }
 
+// ReadAllNft отдает страницу списка токенов: GET /nft?limit=&cursor=&sort=created_at:desc.
+// Страницы выбираются по ключу (created_at, id), курсор следующей страницы возвращается в next_cursor.
+// Фильтры: collection, creator, created_from, created_to, available и trait.<name>[.<op>].
 func (h *NftHandlers) ReadAllNft(c *fiber.Ctx) (interface{}, error) {
-	strLimit := c.Params("limit")
-	if strLimit == "" {
-		log.Error("Error reading limit", "error")
+	query, err := url.ParseQuery(string(c.Context().QueryArgs().QueryString()))
+	if err != nil {
+		log.Error("Error parsing query", "error", err)
+		return nil, tvoerrors.ErrInvalidRequestData
+	}
+	filter, limit, err := service.ParseNftListQuery(query)
+	if err != nil {
+		log.Error("Error parsing list query", "error", err)
+		return nil, err
+	}
+
+	if filter.CollectionId, err = h.listCollectionId(c, query); err != nil {
+		return nil, err
+	}
+
+	// запрашиваем на одну запись больше, чтобы узнать, есть ли следующая страница
+	nfts, err := h.nftDataRepository.ReadAllNftData(c.Context(), limit+1, filter)
+	if err != nil {
+		log.Error("Error accessing to DB", "error", err)
 		return nil, status.Error(codes.Internal, "something went wrong") //nolint
 	}
-	limit, err := strconv.ParseInt(strLimit, 10, 64)
+	nfts, nextCursor := service.NextNftPage(nfts, limit)
 
+	base := cidBase(c)
+	infos := make([]dto.NftInfo, 0, len(nfts))
+	for i := range nfts {
+		infos = append(infos, *h.nftInfo(&nfts[i], base))
+	}
+
+	return &dto.ReadAllNftResponse{
+		Infos:      &infos,
+		NextCursor: nextCursor,
+	}, nil
+}
+
+// SearchNft ищет токены по названию, описанию, имени файла и атрибутам.
+// Выдача отсортирована по релевантности, найденные слова в snippet выделены тегом <b>.
+// Принимает те же фильтры и пагинацию, что и ReadAllNft.
+func (h *NftHandlers) SearchNft(c *fiber.Ctx) (interface{}, error) {
+	query, err := url.ParseQuery(string(c.Context().QueryArgs().QueryString()))
+	if err != nil {
+		log.Error("Error parsing query", "error", err)
+		return nil, tvoerrors.ErrInvalidRequestData
+	}
+	search, limit, err := service.ParseNftSearchQuery(query)
 	if err != nil {
-		log.Error("Error parsing limit", "error", err)
+		log.Error("Error parsing search query", "error", err)
+		return nil, err
+	}
+
+	if search.Filter.CollectionId, err = h.listCollectionId(c, query); err != nil {
+		return nil, err
+	}
+
+	// запрашиваем на одну запись больше, чтобы узнать, есть ли следующая страница
+	hits, err := h.nftDataRepository.SearchNftData(c.Context(), search, limit+1)
+	if err != nil {
+		log.Error("Error accessing to DB", "error", err)
 		return nil, status.Error(codes.Internal, "something went wrong") //nolint
 	}
+	hits, nextCursor := service.NextNftSearchPage(hits, limit)
+
+	base := cidBase(c)
+	results := make([]dto.NftSearchResult, 0, len(hits))
+	for i := range hits {
+		results = append(results, dto.NftSearchResult{
+			Info:    h.nftInfo(&hits[i].Nft, base),
+			Rank:    hits[i].Rank(),
+			Snippet: service.HighlightSnippet(hits[i].Snippet),
+		})
+	}
+
+	return &dto.SearchNftResponse{
+		Results:    results,
+		NextCursor: nextCursor,
+	}, nil
+}
+
+// MintEdition увеличивает число выпущенных экземпляров токена, доступно владельцу коллекции и администратору
+func (h *NftHandlers) MintEdition(c *fiber.Ctx) (interface{}, error) {
+	var request dto.MintEditionRequest
+
+	if err := httputils.ParseRequestBody(c, &request, "MintEdition", h.logger); err != nil {
+		return nil, tvoerrors.ErrInvalidRequestData
+	}
+	if request.Amount <= 0 {
+		log.Error("Wrong mint amount", "amount", request.Amount)
+		return nil, tvoerrors.ErrInvalidRequestData
+	}
+
+	tokenId, err := service.ParseTokenId(c.Params("id"))
+	if err != nil {
+		log.Error("Error parsing nft id", "error", err)
+		return nil, tvoerrors.ErrInvalidRequestData
+	}
+
+	roleId, err := httputils.RoleIDFromToken(c, "MintEdition", h.logger)
+	if err != nil {
+		return nil, tvoerrors.ErrCastClaims
+	}
+	userId, err := httputils.UserIDFromToken(c, "MintEdition", h.logger)
+	if err != nil {
+		return nil, tvoerrors.ErrCastClaims
+	}
 
 	ctx := c.Context()
 
-	nfts, err := h.nftDataRepository.ReadAllNftData(ctx, int(limit))
+	collection, err := h.collectionRepository.CollectionBySlug(ctx, c.Params("slug"))
 	if err != nil {
+		if errors.Is(err, tvoerrors.ErrNotFound) {
+			return nil, tvoerrors.ErrNotFound
+		}
 		log.Error("Error accessing to DB", "error", err)
 		return nil, status.Error(codes.Internal, "something went wrong") //nolint
 	}
 
-	infos := []dto.NftInfo{}
-	if len(nfts) > 0 {
-		for _, nft := range nfts {
-			infos = append(infos, dto.NftInfo{
-				TokenId:     nft.TokenId,
-				Description: nft.Description,
-				CidV0:       nft.CidV0,
-				CidV1:       nft.CidV1,
-				Link:        fmt.Sprintf(service.KuboGatewayUrlTemplate, nft.CidV1),
-			})
+	if tvomodels.RoleId(roleId) != tvomodels.ADMIN && collection.OwnerId != userId {
+		log.Error("User is not the collection owner")
+		return nil, tvoerrors.ErrForbidden
+	}
+
+	nft, err := h.nftDataRepository.MintEdition(ctx, collection.ID, tokenId, request.Amount)
+	if err != nil {
+		if errors.Is(err, tvoerrors.ErrNotFound) {
+			return nil, tvoerrors.ErrNotFound
+		}
+		if errors.Is(err, tvoerrors.ErrConflict) {
+			log.Error("Max supply exceeded", "error", err)
+			return nil, tvoerrors.ErrConflict
 		}
+		log.Error("Error minting edition", "error", err)
+		return nil, status.Error(codes.Internal, "something went wrong") //nolint
 	}
 
-	return &dto.ReadAllNftResponse{
-		Infos: &infos,
+	return &dto.ReadNftResponse{
+		Info: h.nftInfo(&nft, cidBase(c)),
+	}, nil
+}
+
+// ReadNftRevisions отдает историю изменений токена, начиная с последней ревизии
+func (h *NftHandlers) ReadNftRevisions(c *fiber.Ctx) (interface{}, error) {
+	nft, err := h.readNft(c)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions, err := h.nftDataRepository.ReadNftRevisions(c.Context(), nft.ID)
+	if err != nil {
+		log.Error("Error accessing to DB", "error", err)
+		return nil, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+
+	result := service.RevisionsToDto(revisions)
+	base := cidBase(c)
+	for i := range result {
+		result[i].CidV1, result[i].MetadataCid = base.FormatString(result[i].CidV1),
+			base.FormatString(result[i].MetadataCid)
+	}
+	return &dto.ReadNftRevisionsResponse{
+		Revisions: result,
+	}, nil
+}
+
+// RollbackNftData возвращает токен к состоянию ревизии :revision, откат записывается новой ревизией.
+// Только для администратора.
+func (h *NftHandlers) RollbackNftData(c *fiber.Ctx) (interface{}, error) {
+	revisionNumber, err := strconv.Atoi(c.Params("revision"))
+	if err != nil || revisionNumber <= 0 {
+		log.Error("Error parsing revision", "error", err)
+		return nil, tvoerrors.ErrInvalidRequestData
+	}
+
+	roleId, err := httputils.RoleIDFromToken(c, "RollbackNftData", h.logger)
+	if err != nil {
+		return nil, tvoerrors.ErrCastClaims
+	}
+	if tvomodels.RoleId(roleId) != tvomodels.ADMIN {
+		log.Error("Only admin can roll back nft")
+		return nil, tvoerrors.ErrForbidden
+	}
+	userId, err := httputils.UserIDFromToken(c, "RollbackNftData", h.logger)
+	if err != nil {
+		return nil, tvoerrors.ErrCastClaims
+	}
+
+	nft, err := h.readNft(c)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := c.Context()
+
+	revision, err := h.nftDataRepository.ReadNftRevision(ctx, nft.ID, revisionNumber)
+	if err != nil {
+		if errors.Is(err, tvoerrors.ErrNotFound) {
+			return nil, tvoerrors.ErrNotFound
+		}
+		log.Error("Error accessing to DB", "error", err)
+		return nil, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+
+	attributes, err := service.TraitsToAttributes(revision.Attributes)
+	if err != nil {
+		log.Error("Error parsing revision attributes", "error", err)
+		return nil, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+
+	nft.Name = revision.Name
+	nft.Description = revision.Description
+	nft.CidV0 = revision.CidV0
+	nft.CidV1 = revision.CidV1
+	// ревизии, записанные до появления имени файла, его не хранят
+	if revision.FileName != "" {
+		nft.FileName = revision.FileName
+	}
+	nft.MetadataCid = revision.MetadataCid
+	nft.Attributes = attributes
+
+	// закрепление содержимого ревизии могли снять удалением токена или сборкой мусора
+	if err = h.pinNftContent(ctx, userId, &nft); err != nil {
+		log.Error("Error pinning revision content", "revision", revisionNumber, "error", err)
+		return nil, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+
+	err = h.nftDataRepository.UpdateNftData(ctx, &nft, models.NftChange{AuthorId: userId, RollbackOf: revisionNumber})
+	if err != nil {
+		if errors.Is(err, tvoerrors.ErrNotFound) {
+			return nil, tvoerrors.ErrNotFound
+		}
+		log.Error("Error rolling back nft data", "error", err)
+		return nil, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+	h.mirrorFile(ctx, nft.Collection, nft.TokenId, nft.CidV0, nft.FileName)
+	h.mirrorFile(ctx, nft.Collection, nft.TokenId, nft.MetadataCid, metadataFileName)
+
+	return &dto.ReadNftResponse{
+		Info: h.nftInfo(&nft, cidBase(c)),
 	}, nil
 }
+
+// ReadNftContent отдает файл токена через встроенный шлюз с поддержкой Range и кешированием по CID
+func (h *NftHandlers) ReadNftContent(c *fiber.Ctx) error {
+	nft, err := h.readNft(c)
+	if err != nil {
+		return httputils.HandleError(c, httputils.FiberStatusByErr(err), err)
+	}
+
+	content, err := cid.Decode(nft.CidV0)
+	if err != nil {
+		log.Error("Error decoding nft cid", "error", err)
+		return httputils.HandleError(c, fiber.StatusInternalServerError, errSomethingWrong)
+	}
+
+	if nft.FileName != "" {
+		c.Set(fiber.HeaderContentDisposition, contentDisposition(nft.FileName))
+	}
+	return serveContent(c, h.gateway, content, nft.FileName)
+}
+
+// ExportNftCar отдает файл и метаданные токена одним CAR файлом, выгруженным из хранилища через dag/export
+func (h *NftHandlers) ExportNftCar(c *fiber.Ctx) error {
+	dagStorage, ok := h.storage.(storage.DagStorage)
+	if !ok {
+		return httputils.HandleError(c, fiber.StatusNotImplemented, errCarUnsupported)
+	}
+
+	nft, err := h.readNft(c)
+	if err != nil {
+		return httputils.HandleError(c, httputils.FiberStatusByErr(err), err)
+	}
+
+	roots, err := decodeCids(nft.CidV0, nft.MetadataCid)
+	if err != nil || len(roots) == 0 {
+		log.Error("Error decoding nft cids", "error", err)
+		return httputils.HandleError(c, fiber.StatusInternalServerError, errSomethingWrong)
+	}
+
+	if err = sendCar(c, dagStorage, roots, strconv.FormatInt(nft.TokenId, 10)+".car"); err != nil {
+		log.Error("Error exporting nft car", "error", err)
+		return httputils.HandleError(c, fiber.StatusInternalServerError, errSomethingWrong)
+	}
+	return nil
+}
+
+// ExportCollectionCar отдает файлы и метаданные всех токенов коллекции одним CAR файлом.
+// Только для администратора.
+func (h *NftHandlers) ExportCollectionCar(c *fiber.Ctx) error {
+	dagStorage, ok := h.storage.(storage.DagStorage)
+	if !ok {
+		return httputils.HandleError(c, fiber.StatusNotImplemented, errCarUnsupported)
+	}
+
+	roleId, err := httputils.RoleIDFromToken(c, "ExportCollectionCar", h.logger)
+	if err != nil {
+		return httputils.HandleError(c, fiber.StatusUnauthorized, tvoerrors.ErrCastClaims)
+	}
+	if tvomodels.RoleId(roleId) != tvomodels.ADMIN {
+		log.Error("Wrong user role")
+		return httputils.HandleError(c, fiber.StatusForbidden, tvoerrors.ErrForbidden)
+	}
+
+	collection, err := h.collectionRepository.CollectionBySlug(c.Context(), c.Params("slug"))
+	if err != nil {
+		if errors.Is(err, tvoerrors.ErrNotFound) {
+			return httputils.HandleError(c, fiber.StatusNotFound, tvoerrors.ErrNotFound)
+		}
+		log.Error("Error accessing to DB", "error", err)
+		return httputils.HandleError(c, fiber.StatusInternalServerError, errSomethingWrong)
+	}
+
+	raw, err := h.nftDataRepository.CollectionCids(c.Context(), collection.ID)
+	if err != nil {
+		log.Error("Error accessing to DB", "error", err)
+		return httputils.HandleError(c, fiber.StatusInternalServerError, errSomethingWrong)
+	}
+	if len(raw) == 0 {
+		return httputils.HandleError(c, fiber.StatusNotFound, tvoerrors.ErrNotFound)
+	}
+	roots, err := decodeCids(raw...)
+	if err != nil {
+		log.Error("Error decoding nft cids", "error", err)
+		return httputils.HandleError(c, fiber.StatusInternalServerError, errSomethingWrong)
+	}
+
+	if err = sendCar(c, dagStorage, roots, collection.Slug+".car"); err != nil {
+		log.Error("Error exporting collection car", "error", err)
+		return httputils.HandleError(c, fiber.StatusInternalServerError, errSomethingWrong)
+	}
+	return nil
+}
+
+// readNft находит токен по параметрам пути id и slug
+func (h *NftHandlers) readNft(c *fiber.Ctx) (models.NftDataModel, error) {
+	tokenId, err := service.ParseTokenId(c.Params("id"))
+	if err != nil {
+		log.Error("Error parsing nft id", "error", err)
+		return models.NftDataModel{}, tvoerrors.ErrInvalidRequestData
+	}
+
+	collectionId, err := h.collectionId(c)
+	if err != nil {
+		return models.NftDataModel{}, err
+	}
+
+	nft, err := h.nftDataRepository.ReadNftData(c.Context(), collectionId, tokenId)
+	if err != nil {
+		if errors.Is(err, tvoerrors.ErrNotFound) {
+			return nft, tvoerrors.ErrNotFound
+		}
+		log.Error("Error accessing to DB", "error", err)
+		return nft, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+	return nft, nil
+}
+
+// checkManager проверяет, что пользователь может менять токен: администратор либо владелец его коллекции
+func (h *NftHandlers) checkManager(c *fiber.Ctx, nft *models.NftDataModel, method string) error {
+	roleId, err := httputils.RoleIDFromToken(c, method, h.logger)
+	if err != nil {
+		return tvoerrors.ErrCastClaims
+	}
+	if tvomodels.RoleId(roleId) == tvomodels.ADMIN {
+		return nil
+	}
+
+	userId, err := httputils.UserIDFromToken(c, method, h.logger)
+	if err != nil {
+		return tvoerrors.ErrCastClaims
+	}
+
+	if nft.Collection != "" {
+		collection, err := h.collectionRepository.CollectionBySlug(c.Context(), nft.Collection)
+		if err != nil && !errors.Is(err, tvoerrors.ErrNotFound) {
+			log.Error("Error accessing to DB", "error", err)
+			return status.Error(codes.Internal, "something went wrong") //nolint
+		}
+		if err == nil && collection.OwnerId == userId {
+			return nil
+		}
+	}
+
+	log.Error("User can't manage the nft")
+	return tvoerrors.ErrForbidden
+}
+
+// nftMetadata читает закрепленный документ метаданных токена
+func (h *NftHandlers) nftMetadata(ctx context.Context, nft *models.NftDataModel) (*models.NftMetadata, error) {
+	// для токенов, созданных до появления метаданных, собираем документ из БД
+	if nft.MetadataCid == "" {
+		return service.BuildNftMetadata(nft.TokenId, "", nft.Description, nft.CidV1,
+			service.AttributesToTraits(nft.Attributes)), nil
+	}
+
+	metadataCid, err := cid.Decode(nft.MetadataCid)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := h.storage.Cat(ctx, metadataCid)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var metadata models.NftMetadata
+	if err = json.NewDecoder(body).Decode(&metadata); err != nil {
+		return nil, err
+	}
+	return &metadata, nil
+}
+
+// listCollectionId определяет коллекцию для списка токенов: из пути либо по параметру collection.
+// Ноль означает токены всех коллекций.
+func (h *NftHandlers) listCollectionId(c *fiber.Ctx, query url.Values) (int64, error) {
+	collectionId, err := h.collectionId(c)
+	if err != nil || collectionId != 0 {
+		return collectionId, err
+	}
+
+	slug := query.Get("collection")
+	if slug == "" {
+		return 0, nil
+	}
+	collection, err := h.collectionRepository.CollectionBySlug(c.Context(), slug)
+	if err != nil {
+		if errors.Is(err, tvoerrors.ErrNotFound) {
+			return 0, tvoerrors.ErrNotFound
+		}
+		log.Error("Error accessing to DB", "error", err)
+		return 0, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+	return collection.ID, nil
+}
+
+// nftByContent возвращает живой токен с содержимым object либо nil, если такого нет
+func (h *NftHandlers) nftByContent(ctx context.Context, object *storage.Object) (*models.NftDataModel, error) {
+	nft, err := h.nftDataRepository.ReadNftDataByCid(ctx, object.CidV0().String(), object.CidV1().String())
+	if err != nil {
+		if errors.Is(err, tvoerrors.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &nft, nil
+}
+
+// nftInfo преобразует запись БД в ответ API, CID отдаются в основании base
+func (h *NftHandlers) nftInfo(nft *models.NftDataModel, base cids.Base) *dto.NftInfo {
+	info := &dto.NftInfo{
+		TokenId:       nft.TokenId,
+		Name:          nft.Name,
+		Description:   nft.Description,
+		CidV0:         nft.CidV0,
+		CidV1:         base.FormatString(nft.CidV1),
+		Link:          gatewayLink(h.gatewayURL, nft.CidV1),
+		MetadataCid:   base.FormatString(nft.MetadataCid),
+		TokenURI:      h.tokenURI(nft.Collection, nft.TokenStandard, nft.TokenId),
+		Collection:    nft.Collection,
+		TokenStandard: nft.TokenStandard,
+		MaxSupply:     nft.MaxSupply,
+		MintedSupply:  nft.MintedSupply,
+		CreatorId:     nft.CreatorId,
+		CreatedAt:     nft.CreatedAt,
+		Attributes:    service.AttributesToDto(nft.Attributes),
+	}
+	if nft.TokenStandard == models.TokenStandardERC1155 {
+		info.EditionId = service.EditionHexId(nft.TokenId)
+	}
+	if nft.CollectionIpnsName != "" {
+		info.CollectionIpnsLink = ipns.Link(nft.CollectionIpnsName)
+	}
+	return info
+}
+
+// tokenURI возвращает адрес метаданных токена с учетом коллекции;
+// для ERC-1155 номер подставляется в {id} в виде hex, как это делают кошельки
+func (h *NftHandlers) tokenURI(collection, standard string, tokenId int64) string {
+	if collection == "" {
+		return service.TokenURI(h.metadataBaseURI, tokenId)
+	}
+	if standard == models.TokenStandardERC1155 {
+		return strings.ReplaceAll(service.EditionURI(h.collectionBaseURI, collection),
+			service.EditionIdPlaceholder, service.EditionHexId(tokenId))
+	}
+	return service.CollectionTokenURI(h.collectionBaseURI, collection, tokenId)
+}
+
+// collectionId находит коллекцию из параметра пути slug; для маршрутов без коллекции возвращает 0
+func (h *NftHandlers) collectionId(c *fiber.Ctx) (int64, error) {
+	slug := c.Params("slug")
+	if slug == "" {
+		return 0, nil
+	}
+
+	collection, err := h.collectionRepository.CollectionBySlug(c.Context(), slug)
+	if err != nil {
+		if errors.Is(err, tvoerrors.ErrNotFound) {
+			return 0, tvoerrors.ErrNotFound
+		}
+		log.Error("Error accessing to DB", "error", err)
+		return 0, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+	return collection.ID, nil
+}
+
+// mirrorFile кладет файл токена в зеркало MFS: <root>/<коллекция>/<номер токена>/<имя файла>
+func (h *NftHandlers) mirrorFile(ctx context.Context, collection string, tokenId int64, value, name string) {
+	c, err := cid.Decode(value)
+	if err != nil {
+		return
+	}
+	if name == "" {
+		name = value
+	}
+	h.mirror.Copy(ctx, c, h.mirror.TokenPath(collection, tokenId, name))
+}
+
+// pinNftContent снова закрепляет файл и метаданные токена, закрепление которых могли снять при удалении
+// токена или сборке мусора, и заводит на них запросы на закрепление, если запросов еще нет
+func (h *NftHandlers) pinNftContent(ctx context.Context, userId int64, nft *models.NftDataModel) error {
+	files := []struct{ value, name string }{{nft.CidV0, nft.FileName}, {nft.MetadataCid, metadataFileName}}
+	objects := make([]*storage.Object, 0, len(files))
+	for _, file := range files {
+		if file.value == "" {
+			continue
+		}
+		c, err := cid.Decode(file.value)
+		if err != nil {
+			return err
+		}
+		if err = h.storage.Pin(ctx, c); err != nil {
+			return err
+		}
+		count, err := h.pinRepository.PinCountByCid(ctx, file.value)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			objects = append(objects, &storage.Object{Name: file.name, Cid: c})
+		}
+	}
+	h.trackPins(ctx, userId, objects...)
+	return nil
+}
+
+// trackPins заводит запросы на закрепление загруженного содержимого токена. Содержимое уже закреплено
+// в хранилище, запросы нужны для сверки с ним и копирования на удаленные сервисы закреплений.
+func (h *NftHandlers) trackPins(ctx context.Context, userId int64, objects ...*storage.Object) {
+	for _, object := range objects {
+		pin := &models.Pin{Cid: object.Cid.String(), Name: object.Name, RequesterId: userId}
+		if err := h.pinRepository.CreatePin(ctx, pin); err != nil {
+			log.Error("Error creating pin request", "cid", pin.Cid, "error", err)
+		}
+	}
+	h.pinWorker.Notify()
+}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main/internal/config"
+	"main/internal/dto"
+	"main/internal/mfs"
+	"main/internal/models"
+	"main/internal/pinning"
+	"main/internal/repository"
+	"main/internal/storage"
+	"main/tools/pkg/constants"
+	httputils "main/tools/pkg/http_utils"
+	"main/tools/pkg/logger"
+	tvoerrors "main/tools/pkg/tvo_errors"
+	tvomodels "main/tools/pkg/tvo_models"
+)
+
+func TestCreateNftDataRequest(t *testing.T) {
+	request, err := createNftDataRequest(url.Values{"id": {"7"}, "name": {"Token #7"}, "max_supply": {"10"}})
+	if err != nil {
+		t.Fatalf("createNftDataRequest() error = %v", err)
+	}
+	if request.Id != 7 || request.Name != "Token #7" || request.MaxSupply != 10 {
+		t.Errorf("createNftDataRequest() = %+v", request)
+	}
+
+	// без id токен получил бы номер 0, поэтому форма отклоняется
+	invalid := []url.Values{
+		{"name": {"Token #7"}},
+		{"id": {""}},
+		{"id": {"seven"}},
+		{"id": {"7"}, "max_supply": {"many"}},
+	}
+	for _, form := range invalid {
+		if _, err = createNftDataRequest(form); err == nil {
+			t.Errorf("createNftDataRequest(%v) expected an error", form)
+		}
+	}
+}
+
+// fakeNftStore хранит токены в памяти и, как и БД, отклоняет второй живой токен с тем же содержимым
+type fakeNftStore struct {
+	repository.NftDataRepository
+
+	mu        sync.Mutex
+	nfts      []models.NftDataModel
+	revisions []models.NftRevision
+	stale     bool
+}
+
+func (r *fakeNftStore) ReadNftData(_ context.Context, collectionId, tokenId int64) (models.NftDataModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, nft := range r.nfts {
+		if nft.CollectionId == collectionId && nft.TokenId == tokenId {
+			return nft, nil
+		}
+	}
+	return models.NftDataModel{}, tvoerrors.ErrNotFound
+}
+
+func (r *fakeNftStore) ReadNftRevision(_ context.Context, nftId int64, revision int) (models.NftRevision, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rev := range r.revisions {
+		if rev.NftID == nftId && rev.Revision == revision {
+			return rev, nil
+		}
+	}
+	return models.NftRevision{}, tvoerrors.ErrNotFound
+}
+
+func (r *fakeNftStore) UpdateNftData(_ context.Context, nft *models.NftDataModel, change models.NftChange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.nfts {
+		if r.nfts[i].ID == nft.ID {
+			r.nfts[i] = *nft
+			r.revisions = append(r.revisions, models.NftRevision{NftID: nft.ID, Revision: len(r.revisions) + 1,
+				Name: nft.Name, CidV0: nft.CidV0, CidV1: nft.CidV1, FileName: nft.FileName,
+				MetadataCid: nft.MetadataCid, RollbackOf: change.RollbackOf})
+			return nil
+		}
+	}
+	return tvoerrors.ErrNotFound
+}
+
+func (r *fakeNftStore) TokenIdExists(_ context.Context, collectionId, tokenId int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, nft := range r.nfts {
+		if nft.CollectionId == collectionId && nft.TokenId == tokenId {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeNftStore) ReadNftDataByCid(_ context.Context, cidv0, cidv1 string) (models.NftDataModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// stale имитирует одновременную загрузку: токен появляется уже после проверки
+	if r.stale {
+		r.stale = false
+		return models.NftDataModel{}, tvoerrors.ErrNotFound
+	}
+	for _, nft := range r.nfts {
+		if nft.CidV1 == cidv1 || (cidv0 != "" && nft.CidV0 == cidv0) {
+			return nft, nil
+		}
+	}
+	return models.NftDataModel{}, tvoerrors.ErrNotFound
+}
+
+func (r *fakeNftStore) CreateNftData(_ context.Context, data *dto.NftData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, nft := range r.nfts {
+		if nft.CidV1 == data.CidV1 {
+			return repository.ErrDuplicateContent
+		}
+	}
+	r.nfts = append(r.nfts, models.NftDataModel{TokenId: data.TokenId, Name: data.Name, CidV0: data.CidV0,
+		CidV1: data.CidV1, MetadataCid: data.MetadataCid, FileName: data.FileName})
+	return nil
+}
+
+func TestCreateNftDataDuplicate(t *testing.T) {
+	ctx := context.Background()
+	s, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStorage() error = %v", err)
+	}
+	l := &logger.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	pins := &fakePinRepository{}
+	nfts := &fakeNftStore{}
+	h := NewNftHandlers(l, nfts, nil, pins, s, nil, mfs.NewMirror(s, &config.MFS{}, l),
+		pinning.NewWorker(pins, s, nil, &config.Pinning{}, l), "https://ipfs.io/ipfs/%s", &config.NFT{})
+
+	app := fiber.New(fiber.Config{StreamRequestBody: true, DisablePreParseMultipartForm: true})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(constants.TOKEN_DATA_KEY, tvomodels.TokenData{UserID: 1, UserRoleID: tvomodels.ADMIN})
+		return c.Next()
+	})
+	app.Post("/nft_data", httputils.FiberJSONWrapper(h.CreateNftData))
+
+	create := func(id, onDuplicate string, fieldAfterFile bool) int {
+		t.Helper()
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		_ = writer.WriteField("name", "Token #"+id)
+		if onDuplicate != "" {
+			_ = writer.WriteField("on_duplicate", onDuplicate)
+		}
+		if !fieldAfterFile {
+			_ = writer.WriteField("id", id)
+		}
+		part, _ := writer.CreateFormFile("file", "1.png")
+		_, _ = part.Write([]byte("image"))
+		if fieldAfterFile {
+			_ = writer.WriteField("id", id)
+		}
+		_ = writer.Close()
+		req := httptest.NewRequest(fiber.MethodPost, "/nft_data", &body)
+		req.Header.Set(fiber.HeaderContentType, writer.FormDataContentType())
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("POST /nft_data error = %v", err)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+	stored := func() int {
+		t.Helper()
+		list, err := s.List(ctx)
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		return len(list)
+	}
+
+	// поле после файла отклоняется до того, как что-либо попало в хранилище
+	if code := create("1", "", true); code != http.StatusBadRequest || stored() != 0 {
+		t.Fatalf("POST with a field after the file = %d, %d objects stored", code, stored())
+	}
+
+	if code := create("1", "", false); code != http.StatusOK || stored() != 2 {
+		t.Fatalf("POST of a new token = %d, %d objects stored, expected the file and the metadata", code, stored())
+	}
+
+	// дубликат отклоняется по вычисленному CID, метаданные для него не закрепляются
+	if code := create("2", "", false); code != http.StatusConflict || stored() != 2 {
+		t.Errorf("POST of duplicate content = %d, %d objects stored", code, stored())
+	}
+	if code := create("3", onDuplicateExisting, false); code != http.StatusOK || stored() != 2 {
+		t.Errorf("POST of duplicate content with on_duplicate=existing = %d, %d objects stored", code, stored())
+	}
+
+	// одновременную загрузку того же содержимого останавливает проверка при записи в БД
+	nfts.stale = true
+	if code := create("4", onDuplicateExisting, false); code != http.StatusOK {
+		t.Errorf("POST of content created concurrently = %d, expected the existing token", code)
+	}
+	nfts.stale = true
+	if code := create("5", "", false); code != http.StatusConflict {
+		t.Errorf("POST of content created concurrently = %d, expected 409", code)
+	}
+	if len(nfts.nfts) != 1 {
+		t.Errorf("%d tokens created, expected 1", len(nfts.nfts))
+	}
+}
+
+func TestRollbackNftDataRestoresFileName(t *testing.T) {
+	ctx := context.Background()
+	s, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStorage() error = %v", err)
+	}
+	first, err := s.Add(ctx, "1.png", bytes.NewReader([]byte("image")))
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	second, err := s.Add(ctx, "1.jpg", bytes.NewReader([]byte("another image")))
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	l := &logger.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	pins := &fakePinRepository{}
+	nfts := &fakeNftStore{
+		nfts: []models.NftDataModel{{ID: 1, TokenId: 1, Name: "Token #1", CidV0: second.Cid.String(),
+			FileName: "1.jpg"}},
+		revisions: []models.NftRevision{
+			{NftID: 1, Revision: 1, Name: "Token #1", CidV0: first.Cid.String(), FileName: "1.png"},
+			{NftID: 1, Revision: 2, Name: "Token #1", CidV0: second.Cid.String(), FileName: "1.jpg"},
+			// ревизия, записанная до появления имени файла в истории
+			{NftID: 1, Revision: 3, Name: "Token #1", CidV0: first.Cid.String()},
+		},
+	}
+	h := NewNftHandlers(l, nfts, nil, pins, s, nil, mfs.NewMirror(s, &config.MFS{}, l),
+		pinning.NewWorker(pins, s, nil, &config.Pinning{}, l), "https://ipfs.io/ipfs/%s", &config.NFT{})
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(constants.TOKEN_DATA_KEY, tvomodels.TokenData{UserID: 1, UserRoleID: tvomodels.ADMIN})
+		return c.Next()
+	})
+	app.Post("/nft/:id/revisions/:revision/rollback", httputils.FiberJSONWrapper(h.RollbackNftData))
+
+	rollback := func(revision string) models.NftDataModel {
+		t.Helper()
+		req := httptest.NewRequest(fiber.MethodPost, "/nft/1/revisions/"+revision+"/rollback", nil)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("POST rollback error = %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("POST rollback to revision %s = %d", revision, resp.StatusCode)
+		}
+		return nfts.nfts[0]
+	}
+
+	if nft := rollback("1"); nft.CidV0 != first.Cid.String() || nft.FileName != "1.png" {
+		t.Errorf("rollback to revision 1 = %s %q, expected %s %q", nft.CidV0, nft.FileName, first.Cid, "1.png")
+	}
+	if revision := nfts.revisions[len(nfts.revisions)-1]; revision.FileName != "1.png" || revision.RollbackOf != 1 {
+		t.Errorf("rollback revision = %+v, expected the restored file name", revision)
+	}
+
+	// без имени файла в ревизии остается текущее имя
+	rollback("2")
+	if nft := rollback("3"); nft.CidV0 != first.Cid.String() || nft.FileName != "1.jpg" {
+		t.Errorf("rollback to revision 3 = %s %q, expected the current file name", nft.CidV0, nft.FileName)
+	}
+}
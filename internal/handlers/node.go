@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"main/internal/cluster"
+	"main/internal/dto"
+	"main/internal/monitor"
+	httputils "main/tools/pkg/http_utils"
+	"main/tools/pkg/logger"
+	tvoerrors "main/tools/pkg/tvo_errors"
+	tvomodels "main/tools/pkg/tvo_models"
+)
+
+// metricsContentType тип ответа с метриками в текстовом формате Prometheus
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// NodeHandlers состояние узла хранилища для администраторов и систем мониторинга
+type NodeHandlers struct {
+	logger       *logger.Logger
+	monitor      *monitor.Monitor
+	nodes        *cluster.Registry
+	metricsToken string
+}
+
+func NewNodeHandlers(logger *logger.Logger, monitor *monitor.Monitor, nodes *cluster.Registry,
+	metricsToken string) *NodeHandlers {
+	return &NodeHandlers{
+		logger:       logger,
+		monitor:      monitor,
+		nodes:        nodes,
+		metricsToken: metricsToken,
+	}
+}
+
+// ReadNode собирает и отдает состояние основного узла Kubo: идентификатор, версию, заполнение
+// репозитория, трафик и число пиров. Доступно только администраторам.
+func (h *NodeHandlers) ReadNode(c *fiber.Ctx) (interface{}, error) {
+	if err := h.checkAdmin(c, "ReadNode"); err != nil {
+		return nil, err
+	}
+
+	stats, err := h.monitor.Collect(c.Context())
+	if err != nil {
+		if errors.Is(err, monitor.ErrUnsupported) {
+			return nil, err
+		}
+		log.Error("Error collecting kubo node stats", "error", err)
+		return nil, status.Error(codes.Internal, "something went wrong") //nolint
+	}
+
+	return &dto.NodeStatsResponse{
+		PeerId:       stats.PeerId,
+		AgentVersion: stats.AgentVersion,
+		Addresses:    stats.Addresses,
+		Version:      stats.Version,
+		Commit:       stats.Commit,
+		RepoVersion:  stats.RepoVersion,
+		System:       stats.System,
+		Golang:       stats.Golang,
+		Repo: dto.NodeRepoInfo{
+			Path:       stats.RepoPath,
+			Size:       stats.RepoSize,
+			StorageMax: stats.StorageMax,
+			Fill:       stats.RepoFill(),
+			Warning:    h.monitor.RepoAlmostFull(stats),
+		},
+		Bandwidth: dto.NodeBandwidthInfo{
+			TotalIn:  stats.TotalIn,
+			TotalOut: stats.TotalOut,
+			RateIn:   stats.RateIn,
+			RateOut:  stats.RateOut,
+		},
+		Peers:       stats.Peers,
+		CollectedAt: stats.CollectedAt,
+	}, nil
+}
+
+// ReadNodes отдает доступность всех узлов Kubo из реестра. Доступно только администраторам.
+func (h *NodeHandlers) ReadNodes(c *fiber.Ctx) (interface{}, error) {
+	if err := h.checkAdmin(c, "ReadNodes"); err != nil {
+		return nil, err
+	}
+
+	states := h.nodes.States()
+	response := &dto.KuboNodesResponse{
+		Replicas: h.nodes.Replicas(),
+		Nodes:    make([]dto.KuboNodeInfo, 0, len(states)),
+	}
+	for _, state := range states {
+		response.Nodes = append(response.Nodes, dto.KuboNodeInfo{
+			Name:      state.Name,
+			Primary:   state.Primary,
+			Healthy:   state.Healthy,
+			Error:     state.Error,
+			PeerId:    state.PeerId,
+			Addresses: state.Addresses,
+			Checked:   state.CheckedAt,
+		})
+	}
+	return response, nil
+}
+
+// Metrics отдает состояние узла в текстовом формате Prometheus.
+// Если задан токен метрик, он должен быть передан в заголовке Authorization: Bearer <token>.
+func (h *NodeHandlers) Metrics(c *fiber.Ctx) error {
+	if h.metricsToken != "" {
+		token, found := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(token), []byte(h.metricsToken)) != 1 {
+			return httputils.HandleError(c, fiber.StatusUnauthorized, tvoerrors.ErrUnauthorized)
+		}
+	}
+
+	var body bytes.Buffer
+	if err := h.monitor.WriteMetrics(c.Context(), &body); err != nil {
+		log.Error("Error writing metrics", "error", err)
+		return httputils.HandleError(c, fiber.StatusInternalServerError, err)
+	}
+	c.Set(fiber.HeaderContentType, metricsContentType)
+	return c.Send(body.Bytes())
+}
+
+// checkAdmin проверяет, что запрос выполняет администратор
+func (h *NodeHandlers) checkAdmin(c *fiber.Ctx, method string) error {
+	roleId, err := httputils.RoleIDFromToken(c, method, h.logger)
+	if err != nil {
+		return tvoerrors.ErrCastClaims
+	}
+	if tvomodels.RoleId(roleId) != tvomodels.ADMIN {
+		log.Error("Wrong user role")
+		return tvoerrors.ErrForbidden
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/ipfs/go-cid"
+
+	"main/internal/models"
+	"main/internal/pinning"
+	"main/internal/repository"
+	"main/internal/service"
+	"main/internal/storage"
+	"main/tools/pkg/constants"
+	httputils "main/tools/pkg/http_utils"
+	"main/tools/pkg/logger"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// remotePinError ошибка, которая отдается клиенту объектом Failure
+type remotePinError struct {
+	status  int
+	reason  string
+	details string
+}
+
+func (e *remotePinError) Error() string {
+	return e.reason + ": " + e.details
+}
+
+var (
+	errPinNotFound = &remotePinError{fiber.StatusNotFound, "NOT_FOUND", "pin request not found"}
+	errPinInternal = &remotePinError{fiber.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "something went wrong"}
+)
+
+// badPinRequest ошибка в параметрах или теле запроса
+func badPinRequest(details string) error {
+	return &remotePinError{fiber.StatusBadRequest, "BAD_REQUEST", details}
+}
+
+// unauthorizedPin ошибка авторизации
+func unauthorizedPin(details string) error {
+	return &remotePinError{fiber.StatusUnauthorized, "UNAUTHORIZED", details}
+}
+
+// PinningServiceHandlers реализация IPFS Pinning Service API поверх очереди закреплений.
+// Каждый пользователь видит только свои запросы на закрепление.
+// Источник: https://ipfs.github.io/pinning-services-api-spec/
+type PinningServiceHandlers struct {
+	logger            *logger.Logger
+	pinRepository     repository.PinRepository
+	nftDataRepository repository.NftDataRepository
+	storage           storage.Storage
+	pinWorker         *pinning.Worker
+	delegates         []string
+}
+
+// NewPinningServiceHandlers конструктор для обработчиков Pinning Service API.
+// delegates multiaddr узла хранилища, к которым клиенту стоит подключиться для передачи содержимого.
+func NewPinningServiceHandlers(logger *logger.Logger, pinRepository repository.PinRepository,
+	nftDataRepository repository.NftDataRepository, storage storage.Storage, pinWorker *pinning.Worker,
+	delegates []string) *PinningServiceHandlers {
+	return &PinningServiceHandlers{
+		logger:            logger,
+		pinRepository:     pinRepository,
+		nftDataRepository: nftDataRepository,
+		storage:           storage,
+		pinWorker:         pinWorker,
+		delegates:         delegates,
+	}
+}
+
+// Authenticate проверяет bearer токен переданной middleware авторизации и отвечает на ошибки
+// в формате Failure спецификации, которого ожидают клиенты вроде ipfs pin remote
+func (h *PinningServiceHandlers) Authenticate(auth fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := auth(c); err != nil {
+			return err
+		}
+		// middleware не передала запрос дальше, токен не принят
+		if c.Locals(constants.TOKEN_DATA_KEY) == nil {
+			return remotePinFailure(c, unauthorizedPin("access token is missing or invalid"))
+		}
+		return nil
+	}
+}
+
+// ListPins отдает запросы на закрепление пользователя с фильтрами cid, name, match, status,
+// before, after, meta и limit от новых к старым
+func (h *PinningServiceHandlers) ListPins(c *fiber.Ctx) error {
+	query, err := url.ParseQuery(string(c.Context().QueryArgs().QueryString()))
+	if err != nil {
+		return remotePinFailure(c, badPinRequest("malformed query"))
+	}
+	filter, err := service.ParsePinQuery(query)
+	if err != nil {
+		return remotePinFailure(c, badPinRequest(err.Error()))
+	}
+	if filter.RequesterId, err = httputils.UserIDFromToken(c, "ListPins", h.logger); err != nil {
+		return remotePinFailure(c, unauthorizedPin(err.Error()))
+	}
+
+	pins, count, err := h.pinRepository.ListPins(c.Context(), filter)
+	if err != nil {
+		return remotePinFailure(c, err)
+	}
+
+	results := make([]models.RemotePinStatus, 0, len(pins))
+	for i := range pins {
+		results = append(results, service.PinToRemoteStatus(&pins[i], h.delegates))
+	}
+	return c.JSON(models.RemotePinResults{
+		Count:   count,
+		Results: results,
+	})
+}
+
+// AddPin ставит CID в очередь на закрепление
+func (h *PinningServiceHandlers) AddPin(c *fiber.Ctx) error {
+	pin, err := h.parsePin(c, "AddPin")
+	if err != nil {
+		return remotePinFailure(c, err)
+	}
+
+	if err = h.pinRepository.CreatePin(c.Context(), pin); err != nil {
+		return remotePinFailure(c, err)
+	}
+	h.pinWorker.Notify()
+
+	return c.Status(fiber.StatusAccepted).JSON(service.PinToRemoteStatus(pin, h.delegates))
+}
+
+// GetPin отдает состояние запроса на закрепление
+func (h *PinningServiceHandlers) GetPin(c *fiber.Ctx) error {
+	pin, err := h.ownedPin(c, "GetPin")
+	if err != nil {
+		return remotePinFailure(c, err)
+	}
+	return c.JSON(service.PinToRemoteStatus(pin, h.delegates))
+}
+
+// ReplacePin заменяет запрос на закрепление новым, прежний запрос удаляется.
+// Прежний CID открепляется, если на него больше ничего не ссылается.
+func (h *PinningServiceHandlers) ReplacePin(c *fiber.Ctx) error {
+	existing, err := h.ownedPin(c, "ReplacePin")
+	if err != nil {
+		return remotePinFailure(c, err)
+	}
+	pin, err := h.parsePin(c, "ReplacePin")
+	if err != nil {
+		return remotePinFailure(c, err)
+	}
+
+	if err = h.pinRepository.ReplacePin(c.Context(), existing.ID, pin); err != nil {
+		return remotePinFailure(c, err)
+	}
+	h.pinWorker.Notify()
+
+	if existing.Cid != pin.Cid {
+		h.releaseCid(c.Context(), existing.Cid)
+	}
+	return c.Status(fiber.StatusAccepted).JSON(service.PinToRemoteStatus(pin, h.delegates))
+}
+
+// RemovePin удаляет запрос на закрепление.
+// CID открепляется, если на него больше ничего не ссылается.
+func (h *PinningServiceHandlers) RemovePin(c *fiber.Ctx) error {
+	pin, err := h.ownedPin(c, "RemovePin")
+	if err != nil {
+		return remotePinFailure(c, err)
+	}
+
+	if err = h.pinRepository.DeletePin(c.Context(), pin.ID); err != nil {
+		return remotePinFailure(c, err)
+	}
+
+	h.releaseCid(c.Context(), pin.Cid)
+	return c.SendStatus(fiber.StatusAccepted)
+}
+
+// parsePin разбирает объект Pin из тела запроса и привязывает его к пользователю
+func (h *PinningServiceHandlers) parsePin(c *fiber.Ctx, method string) (*models.Pin, error) {
+	var remote models.RemotePin
+	if err := json.Unmarshal(c.Body(), &remote); err != nil {
+		return nil, badPinRequest("body must be a Pin object")
+	}
+	pin, err := service.RemotePinToModel(&remote)
+	if err != nil {
+		return nil, badPinRequest(err.Error())
+	}
+
+	if pin.RequesterId, err = httputils.UserIDFromToken(c, method, h.logger); err != nil {
+		return nil, unauthorizedPin(err.Error())
+	}
+	return pin, nil
+}
+
+// ownedPin находит запрос на закрепление из параметра requestid среди запросов пользователя
+func (h *PinningServiceHandlers) ownedPin(c *fiber.Ctx, method string) (*models.Pin, error) {
+	userId, err := httputils.UserIDFromToken(c, method, h.logger)
+	if err != nil {
+		return nil, unauthorizedPin(err.Error())
+	}
+
+	pin, err := h.pinRepository.PinByRequestId(c.Context(), c.Params("requestid"))
+	if err != nil {
+		return nil, err
+	}
+	// чужие запросы не раскрываются
+	if pin.RequesterId != userId {
+		return nil, errPinNotFound
+	}
+	return pin, nil
+}
+
+// releaseCid открепляет CID, если на него не осталось запросов на закрепление и токенов.
+// Ошибки только логируются: запрос уже удален, а лишнее закрепление безопасно.
+func (h *PinningServiceHandlers) releaseCid(ctx context.Context, raw string) {
+	c, err := cid.Decode(raw)
+	if err != nil {
+		return
+	}
+	inUse, err := cidInUse(ctx, h.pinRepository, h.nftDataRepository, c)
+	if err != nil {
+		log.Error("Error accessing to DB", "error", err)
+		return
+	}
+	if inUse {
+		return
+	}
+
+	if err = h.storage.Unpin(ctx, c); err != nil && !errors.Is(err, tvoerrors.ErrNotFound) {
+		log.Error("Error unpinning cid", "cid", raw, "error", err)
+	}
+}
+
+// remotePinFailure отправляет ошибку объектом Failure. Ошибки репозитория приводятся к NOT_FOUND
+// либо INTERNAL_SERVER_ERROR.
+func remotePinFailure(c *fiber.Ctx, err error) error {
+	var failure *remotePinError
+	switch {
+	case errors.As(err, &failure):
+	case errors.Is(err, tvoerrors.ErrNotFound):
+		failure = errPinNotFound
+	default:
+		log.Error("Error processing pin request", "error", err)
+		failure = errPinInternal
+	}
+
+	return c.Status(failure.status).JSON(models.RemotePinFailure{
+		Error: models.RemotePinError{Reason: failure.reason, Details: failure.details},
+	})
+}
@@ -0,0 +1,357 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main/internal/config"
+	"main/internal/models"
+	"main/internal/pinning"
+	"main/internal/repository"
+	"main/internal/storage"
+	httpmiddlewares "main/tools/pkg/http_middlewares"
+	"main/tools/pkg/logger"
+	tvoerrors "main/tools/pkg/tvo_errors"
+	tvomodels "main/tools/pkg/tvo_models"
+)
+
+const (
+	testPinCid      = "bafybeihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku"
+	testPinDelegate = "/ip4/127.0.0.1/tcp/4001/p2p/QmNode"
+)
+
+// fakePinRepository is an in-memory stand-in for the pins table.
+type fakePinRepository struct {
+	mu   sync.Mutex
+	pins []models.Pin
+}
+
+func (r *fakePinRepository) CreatePin(_ context.Context, pin *models.Pin) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pin.ID = int64(len(r.pins) + 1)
+	pin.RequestId = fmt.Sprintf("00000000-0000-4000-8000-%012d", pin.ID)
+	pin.Status = models.PinStatusQueued
+	pin.CreatedAt = time.Now()
+	r.pins = append(r.pins, *pin)
+	return nil
+}
+
+func (r *fakePinRepository) PinByRequestId(_ context.Context, requestId string) (*models.Pin, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, pin := range r.pins {
+		if pin.RequestId == requestId {
+			return &pin, nil
+		}
+	}
+	return nil, tvoerrors.ErrNotFound
+}
+
+func (r *fakePinRepository) ClaimPins(_ context.Context, _ int) ([]models.Pin, error) {
+	return nil, nil
+}
+
+func (r *fakePinRepository) UpdatePin(_ context.Context, _ *models.Pin, _ time.Duration) error {
+	return nil
+}
+
+func (r *fakePinRepository) PinsByStatus(_ context.Context, _ ...string) ([]models.Pin, error) {
+	return nil, nil
+}
+
+func (r *fakePinRepository) RequeueStalePins(_ context.Context, _ time.Duration) (int64, error) {
+	return 0, nil
+}
+
+func (r *fakePinRepository) RequeuePin(_ context.Context, _ *models.Pin) error {
+	return nil
+}
+
+func (r *fakePinRepository) DeleteUserPins(_ context.Context, requesterId int64, cids []string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for i, pin := range r.pins {
+		if pin.ID != 0 && pin.RequesterId == requesterId && slices.Contains(cids, pin.Cid) {
+			r.pins[i] = models.Pin{}
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (r *fakePinRepository) ListPins(_ context.Context, filter *models.PinFilter) ([]models.Pin, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pins := []models.Pin{}
+	for _, pin := range r.pins {
+		if pin.RequesterId != filter.RequesterId || pin.ID == 0 {
+			continue
+		}
+		for _, status := range filter.Statuses {
+			if pin.Status == status {
+				pins = append(pins, pin)
+			}
+		}
+	}
+	count := len(pins)
+	return pins[:min(count, filter.Limit)], count, nil
+}
+
+func (r *fakePinRepository) ReplacePin(ctx context.Context, oldId int64, pin *models.Pin) error {
+	if err := r.DeletePin(ctx, oldId); err != nil {
+		return err
+	}
+	return r.CreatePin(ctx, pin)
+}
+
+func (r *fakePinRepository) DeletePin(_ context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// удаленный запрос помечается нулевым id, чтобы не сдвигать остальные
+	r.pins[id-1] = models.Pin{}
+	return nil
+}
+
+func (r *fakePinRepository) PinCountByCid(_ context.Context, cid string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for _, pin := range r.pins {
+		if pin.Cid == cid {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// fakeNftDataRepository reports every CID as not referenced by tokens.
+type fakeNftDataRepository struct {
+	repository.NftDataRepository
+}
+
+func (r *fakeNftDataRepository) CidReferenced(_ context.Context, _ string) (bool, error) {
+	return false, nil
+}
+
+// newTestPinningService собирает маршруты Pinning Service API так же, как server.addRoutesV1.
+// Токен user1 принадлежит пользователю 1, user2 - пользователю 2.
+func newTestPinningService(t *testing.T) *fiber.App {
+	s, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStorage() error = %v", err)
+	}
+	l := &logger.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	pins := &fakePinRepository{}
+	worker := pinning.NewWorker(pins, s, nil, &config.Pinning{}, l)
+	h := NewPinningServiceHandlers(l, pins, &fakeNftDataRepository{}, s, worker, []string{testPinDelegate})
+
+	check := func(_ context.Context, token string) (*tvomodels.TokenData, error) {
+		switch token {
+		case "user1":
+			return &tvomodels.TokenData{UserID: 1}, nil
+		case "user2":
+			return &tvomodels.TokenData{UserID: 2}, nil
+		}
+		return nil, tvoerrors.ErrInvalidJWT
+	}
+
+	app := fiber.New()
+	psa := app.Group("/psa", h.Authenticate(httpmiddlewares.NewAuthMiddleware(check, false, l)))
+	psa.Get("/pins", h.ListPins)
+	psa.Post("/pins", h.AddPin)
+	psa.Get("/pins/:requestid", h.GetPin)
+	psa.Post("/pins/:requestid", h.ReplacePin)
+	psa.Delete("/pins/:requestid", h.RemovePin)
+	return app
+}
+
+// doPinRequest выполняет запрос и разбирает тело ответа в result, если оно есть
+func doPinRequest(t *testing.T, app *fiber.App, method, target, token, body string, result interface{}) int {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	if token != "" {
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("%s %s error = %v", method, target, err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	if result != nil && len(data) > 0 {
+		if err = json.Unmarshal(data, result); err != nil {
+			t.Fatalf("%s %s body %q is not JSON: %v", method, target, data, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// checkPinStatus проверяет обязательные поля схемы PinStatus
+func checkPinStatus(t *testing.T, status map[string]interface{}) {
+	t.Helper()
+
+	for _, field := range []string{"requestid", "status", "created", "pin", "delegates"} {
+		if _, ok := status[field]; !ok {
+			t.Errorf("PinStatus %v misses required field %q", status, field)
+		}
+	}
+	switch status["status"] {
+	case models.PinStatusQueued, models.PinStatusPinning, models.PinStatusPinned, models.PinStatusFailed:
+	default:
+		t.Errorf("PinStatus status = %v, expected one of the spec values", status["status"])
+	}
+	if created, _ := status["created"].(string); created != "" {
+		if _, err := time.Parse(time.RFC3339, created); err != nil {
+			t.Errorf("PinStatus created = %q, expected RFC 3339", created)
+		}
+	}
+	if pin, _ := status["pin"].(map[string]interface{}); pin == nil || pin["cid"] == "" {
+		t.Errorf("PinStatus pin = %v, expected object with cid", status["pin"])
+	}
+	if delegates, _ := status["delegates"].([]interface{}); len(delegates) == 0 {
+		t.Errorf("PinStatus delegates = %v, expected at least one", status["delegates"])
+	}
+}
+
+// checkPinFailure проверяет схему Failure
+func checkPinFailure(t *testing.T, failure map[string]interface{}, reason string) {
+	t.Helper()
+
+	e, _ := failure["error"].(map[string]interface{})
+	if e == nil || e["reason"] != reason {
+		t.Errorf("Failure = %v, expected error.reason %q", failure, reason)
+	}
+}
+
+func TestPinningServiceAuth(t *testing.T) {
+	app := newTestPinningService(t)
+
+	for _, token := range []string{"", "wrong"} {
+		var failure map[string]interface{}
+		if code := doPinRequest(t, app, http.MethodGet, "/psa/pins", token, "", &failure); code != http.StatusUnauthorized {
+			t.Errorf("GET /pins with token %q = %d, expected 401", token, code)
+		}
+		checkPinFailure(t, failure, "UNAUTHORIZED")
+	}
+}
+
+func TestPinningServiceLifecycle(t *testing.T) {
+	app := newTestPinningService(t)
+
+	var added map[string]interface{}
+	body := `{"cid":"` + testPinCid + `","name":"cat.png","origins":["/ip4/203.0.113.1/tcp/4001"],"meta":{"app_id":"99"}}`
+	if code := doPinRequest(t, app, http.MethodPost, "/psa/pins", "user1", body, &added); code != http.StatusAccepted {
+		t.Fatalf("POST /pins = %d, expected 202", code)
+	}
+	checkPinStatus(t, added)
+	requestId, _ := added["requestid"].(string)
+	if pin := added["pin"].(map[string]interface{}); pin["name"] != "cat.png" || pin["cid"] != testPinCid {
+		t.Errorf("POST /pins pin = %v", pin)
+	}
+
+	var status map[string]interface{}
+	if code := doPinRequest(t, app, http.MethodGet, "/psa/pins/"+requestId, "user1", "", &status); code != http.StatusOK {
+		t.Fatalf("GET /pins/{requestid} = %d, expected 200", code)
+	}
+	checkPinStatus(t, status)
+	if status["requestid"] != requestId || status["status"] != models.PinStatusQueued {
+		t.Errorf("GET /pins/{requestid} = %v", status)
+	}
+
+	// чужой запрос не виден
+	var failure map[string]interface{}
+	if code := doPinRequest(t, app, http.MethodGet, "/psa/pins/"+requestId, "user2", "", &failure); code != http.StatusNotFound {
+		t.Errorf("GET foreign /pins/{requestid} = %d, expected 404", code)
+	}
+	checkPinFailure(t, failure, "NOT_FOUND")
+
+	var results map[string]interface{}
+	if code := doPinRequest(t, app, http.MethodGet, "/psa/pins?status=queued,pinning", "user1", "", &results); code != http.StatusOK {
+		t.Fatalf("GET /pins = %d, expected 200", code)
+	}
+	if list, _ := results["results"].([]interface{}); results["count"] != float64(1) || len(list) != 1 {
+		t.Errorf("GET /pins = %v, expected one result", results)
+	} else {
+		checkPinStatus(t, list[0].(map[string]interface{}))
+	}
+
+	// по умолчанию отдаются только закрепленные
+	results = nil
+	doPinRequest(t, app, http.MethodGet, "/psa/pins", "user1", "", &results)
+	if list, ok := results["results"].([]interface{}); !ok || results["count"] != float64(0) || len(list) != 0 {
+		t.Errorf("GET /pins without status = %v, expected empty results", results)
+	}
+
+	var replaced map[string]interface{}
+	body = `{"cid":"QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn"}`
+	if code := doPinRequest(t, app, http.MethodPost, "/psa/pins/"+requestId, "user1", body, &replaced); code != http.StatusAccepted {
+		t.Fatalf("POST /pins/{requestid} = %d, expected 202", code)
+	}
+	checkPinStatus(t, replaced)
+	if replaced["requestid"] == requestId {
+		t.Errorf("POST /pins/{requestid} kept requestid %s, expected a new one", requestId)
+	}
+	if code := doPinRequest(t, app, http.MethodGet, "/psa/pins/"+requestId, "user1", "", nil); code != http.StatusNotFound {
+		t.Errorf("GET replaced /pins/{requestid} = %d, expected 404", code)
+	}
+
+	newId, _ := replaced["requestid"].(string)
+	if code := doPinRequest(t, app, http.MethodDelete, "/psa/pins/"+newId, "user2", "", nil); code != http.StatusNotFound {
+		t.Errorf("DELETE foreign /pins/{requestid} = %d, expected 404", code)
+	}
+	if code := doPinRequest(t, app, http.MethodDelete, "/psa/pins/"+newId, "user1", "", nil); code != http.StatusAccepted {
+		t.Errorf("DELETE /pins/{requestid} = %d, expected 202", code)
+	}
+	if code := doPinRequest(t, app, http.MethodGet, "/psa/pins/"+newId, "user1", "", nil); code != http.StatusNotFound {
+		t.Errorf("GET removed /pins/{requestid} = %d, expected 404", code)
+	}
+}
+
+func TestPinningServiceBadRequest(t *testing.T) {
+	app := newTestPinningService(t)
+
+	tests := []struct {
+		method string
+		target string
+		body   string
+	}{
+		{http.MethodPost, "/psa/pins", `{"cid":"nope"}`},
+		{http.MethodPost, "/psa/pins", `{"cid":"` + testPinCid + `","origins":["203.0.113.1:4001"]}`},
+		{http.MethodPost, "/psa/pins", `[]`},
+		{http.MethodGet, "/psa/pins?limit=0", ""},
+		{http.MethodGet, "/psa/pins?status=lost", ""},
+		{http.MethodGet, "/psa/pins?cid=nope", ""},
+		{http.MethodGet, "/psa/pins?match=fuzzy", ""},
+	}
+
+	for _, test := range tests {
+		var failure map[string]interface{}
+		if code := doPinRequest(t, app, test.method, test.target, "user1", test.body, &failure); code != http.StatusBadRequest {
+			t.Errorf("%s %s = %d, expected 400", test.method, test.target, code)
+		}
+		checkPinFailure(t, failure, "BAD_REQUEST")
+	}
+}
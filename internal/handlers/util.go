@@ -1 +1,347 @@
 package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/ipfs/go-cid"
+
+	"main/internal/lib/car"
+	"main/internal/lib/cids"
+	"main/internal/repository"
+	"main/internal/service"
+	"main/internal/storage"
+	httputils "main/tools/pkg/http_utils"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// carContentType тип содержимого CARv1 файла
+const carContentType = "application/vnd.ipld.car; version=1"
+
+var ErrFormFileNotFound = errors.New("file not found in form")
+
+var (
+	errCarUnsupported = errors.New("storage does not support car files")
+	errSomethingWrong = errors.New("something went wrong")
+)
+
+// cidBaseKey ключ Locals с основанием multibase, в котором клиент просит отдавать CID
+const cidBaseKey = "cid_base"
+
+// CidBase разбирает параметр cid_base запроса (base32, base36, base58btc и другие основания multibase).
+// CID ответа отдаются в этом основании, неизвестное основание отклоняется с кодом 400.
+func CidBase(c *fiber.Ctx) error {
+	base, err := cids.ParseBase(c.Query("cid_base"))
+	if err != nil {
+		return httputils.HandleError(c, fiber.StatusBadRequest, err)
+	}
+	c.Locals(cidBaseKey, base)
+	return c.Next()
+}
+
+// cidBase возвращает основание multibase запроса, без параметра CID отдаются как есть
+func cidBase(c *fiber.Ctx) cids.Base {
+	base, _ := c.Locals(cidBaseKey).(cids.Base)
+	return base
+}
+
+// gatewayLink формирует ссылку на контент по шаблону шлюза из конфига
+func gatewayLink(template, cid string) string {
+	return fmt.Sprintf(template, cid)
+}
+
+// maxFormValuesSize ограничение на суммарный размер текстовых полей формы, читаемых в память
+const maxFormValuesSize = 1 << 20
+
+// streamFormFile читает multipart тело запроса потоком и передает в callback
+// содержимое файла из поля field, не сохраняя его ни в память, ни на диск
+func streamFormFile(c *fiber.Ctx, field string, callback func(name string, r io.Reader) error) error {
+	return readForm(c, field, false, func(_ url.Values, name string, r io.Reader) error {
+		return callback(name, r)
+	})
+}
+
+// streamForm читает multipart тело запроса потоком и передает в callback текстовые поля формы
+// и содержимое файла из поля field. Файл не буферизуется, поэтому поля должны идти перед ним:
+// текстовое поле после файла дает tvoerrors.ErrInvalidRequestData, даже если callback отработал.
+func streamForm(c *fiber.Ctx, field string, callback func(form url.Values, name string, r io.Reader) error) error {
+	return readForm(c, field, true, callback)
+}
+
+// readForm читает multipart тело запроса до файла из поля field. Если strict, остаток тела
+// после файла проверяется на текстовые поля, которые клиент передал слишком поздно.
+func readForm(c *fiber.Ctx, field string, strict bool,
+	callback func(form url.Values, name string, r io.Reader) error) error {
+	const op = "handlers.readForm"
+
+	_, params, err := mime.ParseMediaType(c.Get(fiber.HeaderContentType))
+	if err != nil || params["boundary"] == "" {
+		return ErrFormFileNotFound
+	}
+
+	body := c.Context().RequestBodyStream()
+	if body == nil {
+		body = bytes.NewReader(c.Body())
+	}
+
+	form := url.Values{}
+	remaining := int64(maxFormValuesSize)
+	reader := multipart.NewReader(body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return ErrFormFileNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case part.FormName() == field && part.FileName() != "":
+			err = callback(form, part.FileName(), part)
+			_ = part.Close()
+			if err != nil || !strict {
+				return err
+			}
+			return lateFormFields(reader, op)
+		case part.FileName() == "" && part.FormName() != "":
+			var value []byte
+			value, err = io.ReadAll(io.LimitReader(part, remaining+1))
+			remaining -= int64(len(value))
+			if err == nil && remaining < 0 {
+				err = tvoerrors.Wrap(op, tvoerrors.ErrInvalidRequestData)
+			}
+			form.Add(part.FormName(), string(value))
+		}
+		_ = part.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// lateFormFields дочитывает тело после файла и отклоняет текстовые поля, которые в нем остались
+func lateFormFields(reader *multipart.Reader, op string) error {
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		late := part.FileName() == "" && part.FormName() != ""
+		_ = part.Close()
+		if late {
+			log.Error("Form field after the file", "field", part.FormName())
+			return tvoerrors.Wrap(op, tvoerrors.ErrInvalidRequestData)
+		}
+	}
+}
+
+// spoolFile сохраняет содержимое r во временный файл и возвращает его открытым с начала.
+// Файл удаляет removeSpooled.
+func spoolFile(r io.Reader) (*os.File, error) {
+	file, err := os.CreateTemp("", "nft-upload-")
+	if err != nil {
+		return nil, err
+	}
+	if _, err = io.Copy(file, r); err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		removeSpooled(file)
+		return nil, err
+	}
+	return file, nil
+}
+
+// removeSpooled закрывает и удаляет временный файл
+func removeSpooled(file *os.File) {
+	_ = file.Close()
+	_ = os.Remove(file.Name())
+}
+
+// formFiles файлы формы, сохраненные во временную директорию
+type formFiles struct {
+	dir   string
+	paths []string
+}
+
+// spoolFormFiles читает multipart тело запроса потоком и сохраняет все файлы из поля field во временную
+// директорию. Имя файла в форме задает его относительный путь (images/1.png), а не только имя.
+// Временные файлы удаляет remove, в том числе при ошибке.
+func spoolFormFiles(c *fiber.Ctx, field string, limit int) (*formFiles, error) {
+	_, params, err := mime.ParseMediaType(c.Get(fiber.HeaderContentType))
+	if err != nil || params["boundary"] == "" {
+		return nil, ErrFormFileNotFound
+	}
+
+	body := c.Context().RequestBodyStream()
+	if body == nil {
+		body = bytes.NewReader(c.Body())
+	}
+
+	dir, err := os.MkdirTemp("", "nft-upload-")
+	if err != nil {
+		return nil, err
+	}
+	files := &formFiles{dir: dir}
+
+	reader := multipart.NewReader(body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return files, err
+		}
+
+		// part.FileName() оставляет только последний элемент пути, поэтому имя берется из заголовка
+		_, disposition, _ := mime.ParseMediaType(part.Header.Get(fiber.HeaderContentDisposition))
+		if part.FormName() == field && disposition["filename"] != "" {
+			err = files.save(part, disposition["filename"], limit)
+		}
+		_ = part.Close()
+		if err != nil {
+			return files, err
+		}
+	}
+
+	if len(files.paths) == 0 {
+		return files, ErrFormFileNotFound
+	}
+	return files, nil
+}
+
+// save сохраняет файл формы под очередным номером
+func (f *formFiles) save(r io.Reader, name string, limit int) error {
+	p, err := service.CleanUploadPath(name)
+	if err != nil {
+		return err
+	}
+	if len(f.paths) >= limit {
+		return tvoerrors.Wrap("handlers.formFiles.save", tvoerrors.ErrInvalidRequestData)
+	}
+
+	file, err := os.Create(filepath.Join(f.dir, strconv.Itoa(len(f.paths))))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	f.paths = append(f.paths, p)
+	return nil
+}
+
+// entries возвращает сохраненные файлы для передачи в хранилище
+func (f *formFiles) entries() []service.DirectoryEntry {
+	entries := make([]service.DirectoryEntry, 0, len(f.paths))
+	for i, p := range f.paths {
+		spooled := filepath.Join(f.dir, strconv.Itoa(i))
+		entries = append(entries, service.DirectoryEntry{Path: p, Open: func() (io.ReadCloser, error) {
+			return os.Open(spooled)
+		}})
+	}
+	return entries
+}
+
+// remove удаляет временную директорию
+func (f *formFiles) remove() {
+	if err := os.RemoveAll(f.dir); err != nil {
+		log.Error("Error removing uploaded files", "dir", f.dir, "error", err)
+	}
+}
+
+// cidInUse проверяет, держат ли CID в хранилище запросы на закрепление или токены, включая ревизии
+// живых токенов. CID сравнивается во всех формах, под которыми он может быть записан.
+func cidInUse(ctx context.Context, pins repository.PinRepository, nfts repository.NftDataRepository,
+	c cid.Cid) (bool, error) {
+	for _, form := range cids.Forms(c) {
+		count, err := pins.PinCountByCid(ctx, form)
+		if err != nil {
+			return false, err
+		}
+		referenced, err := nfts.CidReferenced(ctx, form)
+		if err != nil {
+			return false, err
+		}
+		if count > 0 || referenced {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// decodeCids разбирает CID, пустые значения пропускаются
+func decodeCids(raw ...string) ([]cid.Cid, error) {
+	cids := make([]cid.Cid, 0, len(raw))
+	for _, value := range raw {
+		if value == "" {
+			continue
+		}
+		c, err := cid.Decode(value)
+		if err != nil {
+			return nil, err
+		}
+		cids = append(cids, c)
+	}
+	return cids, nil
+}
+
+// sendCar отдает DAG с корнями roots одним CARv1 файлом filename. Архив собирается потоком
+// по мере выгрузки из хранилища, блоки, общие для нескольких корней, передаются один раз.
+func sendCar(c *fiber.Ctx, dagStorage storage.DagStorage, roots []cid.Cid, filename string) error {
+	ctx := c.UserContext()
+
+	// первую выгрузку открываем до ответа, чтобы недоступность хранилища вернулась ошибкой
+	first, err := dagStorage.Export(ctx, roots[0])
+	if err != nil {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		w, err := car.NewWriter(pw, roots)
+		body := first
+		for i := 0; err == nil && i < len(roots); i++ {
+			if i > 0 {
+				if body, err = dagStorage.Export(ctx, roots[i]); err != nil {
+					break
+				}
+			}
+			err = w.Append(body)
+			_ = body.Close()
+			body = nil
+		}
+		if body != nil {
+			_ = body.Close()
+		}
+		if err != nil && !errors.Is(err, io.ErrClosedPipe) {
+			log.Error("Error exporting car", "file", filename, "error", err)
+		}
+		pw.CloseWithError(err)
+	}()
+
+	c.Attachment(filename)
+	c.Set(fiber.HeaderContentType, carContentType)
+	return c.SendStream(pr)
+}
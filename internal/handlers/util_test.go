@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+func TestStreamForm(t *testing.T) {
+	var form url.Values
+	var name, content string
+	var streamErr error
+	app := fiber.New(fiber.Config{StreamRequestBody: true, DisablePreParseMultipartForm: true})
+	app.Post("/", func(c *fiber.Ctx) error {
+		form, name, content = nil, "", ""
+		streamErr = streamForm(c, "file", func(values url.Values, fileName string, r io.Reader) error {
+			data, err := io.ReadAll(r)
+			form, name, content = values, fileName, string(data)
+			return err
+		})
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	send := func(build func(w *multipart.Writer)) {
+		t.Helper()
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		build(writer)
+		_ = writer.Close()
+		req := httptest.NewRequest(fiber.MethodPost, "/", &body)
+		req.Header.Set(fiber.HeaderContentType, writer.FormDataContentType())
+		if _, err := app.Test(req); err != nil {
+			t.Fatalf("request error = %v", err)
+		}
+	}
+
+	// поля до файла читаются, другие файлы после него допустимы
+	send(func(w *multipart.Writer) {
+		_ = w.WriteField("name", "Token #1")
+		_ = w.WriteField("id", "7")
+		part, _ := w.CreateFormFile("file", "1.png")
+		_, _ = part.Write([]byte("image"))
+		part, _ = w.CreateFormFile("extra", "2.png")
+		_, _ = part.Write([]byte("other"))
+	})
+	if streamErr != nil {
+		t.Fatalf("streamForm() error = %v", streamErr)
+	}
+	if form.Get("name") != "Token #1" || form.Get("id") != "7" {
+		t.Errorf("streamForm() form = %v", form)
+	}
+	if name != "1.png" || content != "image" {
+		t.Errorf("streamForm() file = %q, %q", name, content)
+	}
+
+	// поле после файла не теряется молча, а отклоняет запрос
+	send(func(w *multipart.Writer) {
+		_ = w.WriteField("name", "Token #1")
+		part, _ := w.CreateFormFile("file", "1.png")
+		_, _ = part.Write([]byte("image"))
+		_ = w.WriteField("id", "7")
+	})
+	if !errors.Is(streamErr, tvoerrors.ErrInvalidRequestData) {
+		t.Errorf("streamForm() with a field after the file error = %v, expected ErrInvalidRequestData", streamErr)
+	}
+
+	send(func(w *multipart.Writer) {
+		_ = w.WriteField("name", "Token #1")
+	})
+	if !errors.Is(streamErr, ErrFormFileNotFound) {
+		t.Errorf("streamForm() without a file error = %v, expected ErrFormFileNotFound", streamErr)
+	}
+}
+
+func TestStreamFormFileIgnoresFields(t *testing.T) {
+	var content string
+	var streamErr error
+	app := fiber.New(fiber.Config{StreamRequestBody: true, DisablePreParseMultipartForm: true})
+	app.Post("/", func(c *fiber.Ctx) error {
+		streamErr = streamFormFile(c, "file", func(_ string, r io.Reader) error {
+			data, err := io.ReadAll(r)
+			content = string(data)
+			return err
+		})
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	// загрузке без полей формы поле после файла не мешает
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("file", "1.png")
+	_, _ = part.Write([]byte("image"))
+	_ = writer.WriteField("note", "late")
+	_ = writer.Close()
+	req := httptest.NewRequest(fiber.MethodPost, "/", &body)
+	req.Header.Set(fiber.HeaderContentType, writer.FormDataContentType())
+	if _, err := app.Test(req); err != nil {
+		t.Fatalf("request error = %v", err)
+	}
+	if streamErr != nil || content != "image" {
+		t.Errorf("streamFormFile() = %q, %v", content, streamErr)
+	}
+}
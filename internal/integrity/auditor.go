@@ -0,0 +1,176 @@
+package integrity
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+
+	"main/internal/config"
+	"main/internal/models"
+	"main/internal/repository"
+	"main/tools/pkg/logger"
+)
+
+// Auditor проверяет содержимое всех токенов по расписанию и записывает результаты проверок
+// в отчет о целостности. Записываются только расхождения и пропажи содержимого, ошибки доступа
+// к хранилищу лишь попадают в лог.
+type Auditor struct {
+	verifier *Verifier
+	checks   repository.ContentCheckRepository
+	nfts     repository.NftDataRepository
+	cfg      *config.Integrity
+	logger   *logger.Logger
+
+	mu   sync.Mutex
+	last *models.AuditRun
+}
+
+// NewAuditor создает аудит содержимого токенов
+func NewAuditor(verifier *Verifier, checks repository.ContentCheckRepository, nfts repository.NftDataRepository,
+	cfg *config.Integrity, logger *logger.Logger) *Auditor {
+	return &Auditor{
+		verifier: verifier,
+		checks:   checks,
+		nfts:     nfts,
+		cfg:      cfg,
+		logger:   logger,
+	}
+}
+
+// Run проверяет все содержимое раз в cfg.AuditInterval, пока не будет отменен ctx.
+// Нулевой интервал отключает аудит.
+func (a *Auditor) Run(ctx context.Context) {
+	if a.cfg.AuditInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(a.cfg.AuditInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := a.Audit(ctx); err != nil {
+				a.logger.Error("Error auditing content integrity", "error", err)
+			}
+		}
+	}
+}
+
+// Audit проверяет CID содержимого и метаданных всех токенов в cfg.Workers потоков
+func (a *Auditor) Audit(ctx context.Context) (*models.AuditRun, error) {
+	cids, err := a.nfts.ContentCids(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &models.AuditRun{StartedAt: time.Now()}
+	var mu sync.Mutex
+	queue := make(chan string)
+	var wg sync.WaitGroup
+	for range max(a.cfg.Workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for value := range queue {
+				failed, err := a.checkString(ctx, value)
+				mu.Lock()
+				run.Checked++
+				if err != nil {
+					run.Errors++
+				} else if failed {
+					run.Failed++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, value := range cids {
+		if ctx.Err() != nil {
+			break
+		}
+		queue <- value
+	}
+	close(queue)
+	wg.Wait()
+	run.FinishedAt = time.Now()
+
+	a.mu.Lock()
+	a.last = run
+	a.mu.Unlock()
+
+	a.logger.Info("Content integrity audit finished", "checked", run.Checked, "failed", run.Failed,
+		"errors", run.Errors, "duration", run.FinishedAt.Sub(run.StartedAt))
+	return run, ctx.Err()
+}
+
+// LastRun возвращает итоги последнего аудита, nil если аудит еще не выполнялся
+func (a *Auditor) LastRun() *models.AuditRun {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.last == nil {
+		return nil
+	}
+	run := *a.last
+	return &run
+}
+
+// checkString проверяет CID из таблицы nft_data. failed означает, что содержимое не прошло проверку.
+func (a *Auditor) checkString(ctx context.Context, value string) (bool, error) {
+	c, err := cid.Decode(value)
+	if err != nil {
+		a.logger.Warn("Skipping invalid content cid", "cid", value, "error", err)
+		return false, err
+	}
+	err = a.Check(ctx, c, models.ContentCheckSourceAudit)
+	if _, recorded := checkStatus(err); recorded {
+		return err != nil, nil
+	}
+	return false, err
+}
+
+// Check проверяет CID и записывает результат в отчет. Ошибки, не связанные с содержимым,
+// не записываются и возвращаются как есть.
+func (a *Auditor) Check(ctx context.Context, c cid.Cid, source string) error {
+	err := a.verifier.Verify(ctx, c)
+	// содержимое, которое нельзя проверить без доступа к блокам, пропускаем
+	if errors.Is(err, ErrUnverifiable) {
+		return nil
+	}
+	status, recorded := checkStatus(err)
+	if !recorded {
+		a.logger.Warn("Error verifying content", "cid", c, "error", err)
+		return err
+	}
+
+	check := &models.ContentCheck{Cid: c.String(), Status: status, Source: source}
+	if err != nil {
+		check.Error = err.Error()
+		a.logger.Error("Content integrity check failed", "cid", c, "source", source, "error", err)
+	}
+	if saveErr := a.checks.SaveCheck(ctx, check); saveErr != nil {
+		a.logger.Error("Error saving content check", "cid", c, "error", saveErr)
+	}
+	return err
+}
+
+// ReadVerifier возвращает проверку для шлюза: содержимое проверяется перед отдачей клиентам,
+// а результат записывается с источником gateway
+func (a *Auditor) ReadVerifier() *ReadVerifier {
+	return &ReadVerifier{auditor: a}
+}
+
+// ReadVerifier проверка содержимого, читаемого через шлюз
+type ReadVerifier struct {
+	auditor *Auditor
+}
+
+// Verify проверяет CID и записывает результат в отчет
+func (v *ReadVerifier) Verify(ctx context.Context, c cid.Cid) error {
+	return v.auditor.Check(ctx, c, models.ContentCheckSourceGateway)
+}
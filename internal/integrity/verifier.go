@@ -0,0 +1,170 @@
+// Package integrity проверяет, что хранилище отдает именно то содержимое, на которое указывает CID.
+package integrity
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ipfs/go-cid"
+
+	"main/internal/lib/dagcbor"
+	"main/internal/lib/unixfs"
+	"main/internal/models"
+	"main/internal/storage"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// ошибки содержимого, которые записываются в отчет
+var (
+	ErrMismatch = errors.New("content does not match its cid")
+	ErrMissing  = errors.New("content is missing from the storage")
+)
+
+// maxVerifyBlocks ограничивает обход одного DAG
+const maxVerifyBlocks = 1 << 20
+
+// maxRawSize ограничивает содержимое raw CID, которое проверяется целиком в памяти
+const maxRawSize = 4 << 20
+
+// Verifier пересчитывает мультихеш содержимого локально и сравнивает его с мультихешем CID
+type Verifier struct {
+	storage storage.Storage
+}
+
+// NewVerifier создает проверку содержимого хранилища
+func NewVerifier(s storage.Storage) *Verifier {
+	return &Verifier{storage: s}
+}
+
+// ErrUnverifiable возвращается для содержимого, которое нельзя проверить без доступа к блокам.
+// Это не ошибка содержимого, в отчет она не записывается.
+var ErrUnverifiable = errors.New("content cannot be verified without block access")
+
+// Verify проверяет DAG с корнем c. Хранилища с доступом к блокам проверяются поблочно с обходом
+// ссылок dag-pb и dag-cbor, для остальных файл собирается заново импортером UnixFS с параметрами Kubo add.
+func (v *Verifier) Verify(ctx context.Context, c cid.Cid) error {
+	if blocks, ok := v.storage.(storage.BlockStorage); ok {
+		return verifyBlocks(ctx, blocks, c)
+	}
+	return v.verifyContent(ctx, c)
+}
+
+// verifyBlocks проверяет все блоки DAG, каждый блок запрашивается один раз
+func verifyBlocks(ctx context.Context, blocks storage.BlockStorage, root cid.Cid) error {
+	seen := map[string]struct{}{}
+	queue := []cid.Cid{root}
+	for len(queue) > 0 {
+		c := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		if _, ok := seen[c.KeyString()]; ok {
+			continue
+		}
+		if len(seen) >= maxVerifyBlocks {
+			return fmt.Errorf("dag %s has more than %d blocks", root, maxVerifyBlocks)
+		}
+		seen[c.KeyString()] = struct{}{}
+
+		block, err := blocks.Block(ctx, c)
+		if errors.Is(err, tvoerrors.ErrNotFound) {
+			return fmt.Errorf("%w: block %s", ErrMissing, c)
+		}
+		if err != nil {
+			return err
+		}
+		if err = verifyBlock(c, block); err != nil {
+			return err
+		}
+
+		// ссылки есть у узлов dag-pb и dag-cbor, raw блоки являются листьями
+		var links []cid.Cid
+		switch c.Type() {
+		case cid.DagProtobuf:
+			links, err = unixfs.Links(block)
+		case cid.DagCBOR:
+			links, err = dagcbor.Links(block)
+		}
+		if err != nil {
+			return fmt.Errorf("block %s: %w", c, err)
+		}
+		queue = append(queue, links...)
+	}
+	return nil
+}
+
+// verifyContent проверяет содержимое хранилищ без доступа к блокам. Файл dag-pb собирается
+// с листьями dag-pb, как в Kubo по умолчанию, а при несовпадении - с raw листьями, как при CIDv1.
+func (v *Verifier) verifyContent(ctx context.Context, c cid.Cid) error {
+	if c.Type() != cid.DagProtobuf && c.Type() != cid.Raw {
+		return fmt.Errorf("%w: codec %#x of %s", ErrUnverifiable, c.Type(), c)
+	}
+
+	err := v.verifyFile(ctx, c, unixfs.Options{})
+	if errors.Is(err, ErrMismatch) && c.Type() == cid.DagProtobuf {
+		if rawErr := v.verifyFile(ctx, c, unixfs.Options{RawLeaves: true}); rawErr == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// verifyFile собирает файл c импортером UnixFS с параметрами opts и сравнивает мультихеши
+func (v *Verifier) verifyFile(ctx context.Context, c cid.Cid, opts unixfs.Options) error {
+	body, err := v.storage.Cat(ctx, c)
+	if errors.Is(err, tvoerrors.ErrNotFound) {
+		return fmt.Errorf("%w: %s", ErrMissing, c)
+	}
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if c.Type() == cid.Raw {
+		data, err := io.ReadAll(io.LimitReader(body, maxRawSize+1))
+		if err != nil {
+			return err
+		}
+		if len(data) > maxRawSize {
+			return fmt.Errorf("%w: raw content %s is larger than a block", ErrMismatch, c)
+		}
+		return verifyBlock(c, data)
+	}
+
+	res, err := unixfs.SumWith(body, opts)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(res.Cid.Hash(), c.Hash()) {
+		return fmt.Errorf("%w: %s, content hashes to %s", ErrMismatch, c, res.Cid)
+	}
+	return nil
+}
+
+// verifyBlock пересчитывает мультихеш блока той же хеш-функцией, что указана в CID
+func verifyBlock(c cid.Cid, block []byte) error {
+	sum, err := c.Prefix().Sum(block)
+	if err != nil {
+		return fmt.Errorf("block %s: %w", c, err)
+	}
+	if !bytes.Equal(sum.Hash(), c.Hash()) {
+		return fmt.Errorf("%w: block %s hashes to %s", ErrMismatch, c, sum)
+	}
+	return nil
+}
+
+// checkStatus возвращает статус проверки для отчета. ok false означает ошибку, не связанную
+// с содержимым, например недоступность хранилища; такие ошибки в отчет не записываются.
+func checkStatus(err error) (string, bool) {
+	switch {
+	case err == nil:
+		return models.ContentCheckOK, true
+	case errors.Is(err, ErrMismatch):
+		return models.ContentCheckMismatch, true
+	case errors.Is(err, ErrMissing):
+		return models.ContentCheckMissing, true
+	default:
+		return "", false
+	}
+}
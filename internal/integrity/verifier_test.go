@@ -0,0 +1,195 @@
+package integrity
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+
+	"main/internal/config"
+	"main/internal/lib/unixfs"
+	"main/internal/models"
+	"main/internal/repository"
+	"main/internal/storage"
+	"main/tools/pkg/logger"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// blockStorage отдает блоки DAG из памяти, failure возвращается вместо любого блока
+type blockStorage struct {
+	storage.Storage
+	blocks  map[string][]byte
+	failure error
+}
+
+func (s *blockStorage) Block(_ context.Context, c cid.Cid) ([]byte, error) {
+	if s.failure != nil {
+		return nil, s.failure
+	}
+	block, ok := s.blocks[c.KeyString()]
+	if !ok {
+		return nil, tvoerrors.ErrNotFound
+	}
+	return block, nil
+}
+
+// contentStorage отдает одно и то же содержимое по любому CID
+type contentStorage struct {
+	storage.Storage
+	content []byte
+}
+
+func (s *contentStorage) Cat(_ context.Context, _ cid.Cid) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(s.content)), nil
+}
+
+// checkRepository запоминает записанные проверки
+type checkRepository struct {
+	repository.ContentCheckRepository
+	saved []models.ContentCheck
+}
+
+func (r *checkRepository) SaveCheck(_ context.Context, check *models.ContentCheck) error {
+	r.saved = append(r.saved, *check)
+	return nil
+}
+
+// importDag разбивает содержимое на блоки UnixFS и возвращает корень и CID одного из листьев
+func importDag(t *testing.T) (*blockStorage, cid.Cid, cid.Cid) {
+	t.Helper()
+
+	s := &blockStorage{blocks: map[string][]byte{}}
+	var leaf cid.Cid
+	content := bytes.Repeat([]byte("0123456789"), unixfs.ChunkSize/4)
+	res, err := unixfs.Import(bytes.NewReader(content), func(c cid.Cid, data []byte) error {
+		if !leaf.Defined() {
+			leaf = c
+		}
+		s.blocks[c.KeyString()] = data
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if len(s.blocks) < 3 {
+		t.Fatalf("Import() blocks = %d, expected a root with leaves", len(s.blocks))
+	}
+	return s, res.Cid, leaf
+}
+
+func TestVerifyBlocks(t *testing.T) {
+	s, root, leaf := importDag(t)
+	verifier := NewVerifier(s)
+
+	if err := verifier.Verify(context.Background(), root); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	// испорченный лист обнаруживается при обходе ссылок корня
+	original := s.blocks[leaf.KeyString()]
+	s.blocks[leaf.KeyString()] = append([]byte("x"), original[1:]...)
+	if err := verifier.Verify(context.Background(), root); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Verify() of a corrupted leaf error = %v, expected mismatch", err)
+	}
+
+	delete(s.blocks, leaf.KeyString())
+	if err := verifier.Verify(context.Background(), root); !errors.Is(err, ErrMissing) {
+		t.Errorf("Verify() of a missing leaf error = %v, expected missing", err)
+	}
+}
+
+func TestVerifyDagCbor(t *testing.T) {
+	leafData := []byte("token image")
+	hash, _ := mh.Sum(leafData, mh.SHA2_256, -1)
+	leaf := cid.NewCidV1(cid.Raw, hash)
+
+	// {"image": leaf}, ссылка - тег 42 над байтами CID с префиксом 0x00
+	link := append([]byte{0x00}, leaf.Bytes()...)
+	block := append([]byte{0xa1, 0x65}, "image"...)
+	block = append(block, 0xd8, 0x2a, 0x58, byte(len(link)))
+	block = append(block, link...)
+	hash, _ = mh.Sum(block, mh.SHA2_256, -1)
+	root := cid.NewCidV1(cid.DagCBOR, hash)
+
+	s := &blockStorage{blocks: map[string][]byte{root.KeyString(): block, leaf.KeyString(): leafData}}
+	verifier := NewVerifier(s)
+	if err := verifier.Verify(context.Background(), root); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	s.blocks[leaf.KeyString()] = []byte("other image")
+	if err := verifier.Verify(context.Background(), root); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Verify() of a corrupted linked leaf error = %v, expected mismatch", err)
+	}
+}
+
+func TestVerifyContent(t *testing.T) {
+	s, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStorage() error = %v", err)
+	}
+	object, err := s.Add(context.Background(), "file.txt", bytes.NewReader([]byte("hello world")))
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	verifier := NewVerifier(s)
+
+	if err = verifier.Verify(context.Background(), object.Cid); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	// файл с raw листьями проверяется повторной сборкой с теми же параметрами
+	content := bytes.Repeat([]byte("0123456789"), unixfs.ChunkSize/4)
+	rawLeaves, _ := unixfs.SumWith(bytes.NewReader(content), unixfs.Options{RawLeaves: true})
+	if err = verifier.Verify(context.Background(), rawLeaves.Cid); !errors.Is(err, ErrMissing) {
+		t.Errorf("Verify() of missing raw-leaf content error = %v, expected missing", err)
+	}
+	contentVerifier := NewVerifier(&contentStorage{Storage: s, content: content})
+	if err = contentVerifier.Verify(context.Background(), rawLeaves.Cid); err != nil {
+		t.Errorf("Verify() of raw-leaf content error = %v", err)
+	}
+
+	// содержимое dag-cbor без доступа к блокам не проверяется
+	hash, _ := mh.Sum([]byte{0xa0}, mh.SHA2_256, -1)
+	if err = verifier.Verify(context.Background(), cid.NewCidV1(cid.DagCBOR, hash)); !errors.Is(err, ErrUnverifiable) {
+		t.Errorf("Verify() of dag-cbor content error = %v, expected unverifiable", err)
+	}
+
+	missing, _ := unixfs.Sum(bytes.NewReader([]byte("missing")))
+	if err = verifier.Verify(context.Background(), missing.Cid); !errors.Is(err, ErrMissing) {
+		t.Errorf("Verify() of missing content error = %v, expected missing", err)
+	}
+}
+
+func TestAuditorCheck(t *testing.T) {
+	s, root, leaf := importDag(t)
+	checks := &checkRepository{}
+	auditor := NewAuditor(NewVerifier(s), checks, nil, &config.Integrity{},
+		&logger.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+
+	_ = auditor.Check(context.Background(), root, models.ContentCheckSourceAudit)
+	delete(s.blocks, leaf.KeyString())
+	_ = auditor.ReadVerifier().Verify(context.Background(), root)
+
+	// недоступность хранилища не означает порчу содержимого и в отчет не попадает
+	s.failure = errors.New("connection refused")
+	if err := auditor.Check(context.Background(), root, models.ContentCheckSourceAudit); err == nil {
+		t.Errorf("Check() with an unavailable storage returned no error")
+	}
+
+	if len(checks.saved) != 2 {
+		t.Fatalf("saved checks = %+v, expected 2", checks.saved)
+	}
+	if result := checks.saved[0]; result.Status != models.ContentCheckOK || result.Error != "" {
+		t.Errorf("first check = %+v, expected ok", result)
+	}
+	if result := checks.saved[1]; result.Status != models.ContentCheckMissing ||
+		result.Source != models.ContentCheckSourceGateway || result.Cid != root.String() {
+		t.Errorf("second check = %+v, expected missing from gateway", result)
+	}
+}
@@ -0,0 +1,173 @@
+// Package ipns публикует изменяемые указатели коллекций: запись IPNS ключа коллекции указывает
+// на текущий CID и обновляется без смены адреса ipns://<name>.
+package ipns
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+
+	"main/internal/config"
+	"main/internal/models"
+	"main/internal/repository"
+	"main/internal/storage"
+	"main/tools/pkg/logger"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// ErrUnsupported возвращается, если хранилище не публикует записи IPNS
+var ErrUnsupported = errors.New("storage does not support ipns")
+
+// keyPrefix префикс имен ключей коллекций на узле
+const keyPrefix = "nft-service-collection-"
+
+// republishBatch число записей, которые переопубликовываются за один проход
+const republishBatch = 100
+
+// Publisher публикует записи IPNS коллекций и переопубликовывает их до истечения срока действия.
+// Публикации одной коллекции выполняются по очереди, чтобы переопубликация не вернула старое значение.
+type Publisher struct {
+	storage storage.NameStorage
+	records repository.IpnsRepository
+	cfg     *config.IPNS
+	logger  *logger.Logger
+
+	mu    sync.Mutex
+	locks map[int64]*sync.Mutex
+}
+
+// NewPublisher создает публикацию записей. Если хранилище не поддерживает IPNS,
+// Publish возвращает ErrUnsupported, а переопубликация не выполняется.
+func NewPublisher(s storage.Storage, records repository.IpnsRepository, cfg *config.IPNS,
+	logger *logger.Logger) *Publisher {
+	nameStorage, _ := s.(storage.NameStorage)
+	return &Publisher{
+		storage: nameStorage,
+		records: records,
+		cfg:     cfg,
+		logger:  logger,
+		locks:   map[int64]*sync.Mutex{},
+	}
+}
+
+// Link возвращает адрес ipns:// имени
+func Link(name string) string {
+	return "ipns://" + name
+}
+
+// Publish направляет запись IPNS ключа коллекции на CID, ключ создается при первой публикации
+func (p *Publisher) Publish(ctx context.Context, collectionId int64, c cid.Cid) (*models.IpnsRecord, error) {
+	if p.storage == nil {
+		return nil, ErrUnsupported
+	}
+	unlock := p.lock(collectionId)
+	defer unlock()
+
+	return p.publish(ctx, collectionId, c)
+}
+
+// Run переопубликовывает истекающие записи раз в cfg.RepublishInterval, пока не будет отменен ctx
+func (p *Publisher) Run(ctx context.Context) {
+	if p.storage == nil || p.cfg.RepublishInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(p.cfg.RepublishInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := p.Republish(ctx); err != nil {
+			p.logger.Error("Error republishing ipns records", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Republish переопубликовывает записи, срок действия которых истекает раньше, чем через cfg.RepublishBefore.
+// Возвращает число переопубликованных записей.
+func (p *Publisher) Republish(ctx context.Context) (int, error) {
+	records, err := p.records.ExpiringIpnsRecords(ctx, time.Now().Add(p.cfg.RepublishBefore), republishBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	republished := 0
+	for _, record := range records {
+		if ctx.Err() != nil {
+			return republished, ctx.Err()
+		}
+		if err = p.republish(ctx, record.CollectionId); err != nil {
+			p.logger.Warn("Error republishing ipns record", "name", record.Name, "error", err)
+			continue
+		}
+		republished++
+	}
+	return republished, nil
+}
+
+// republish публикует текущее значение записи коллекции заново.
+// Значение перечитывается под блокировкой, так как его могли изменить после выборки.
+func (p *Publisher) republish(ctx context.Context, collectionId int64) error {
+	unlock := p.lock(collectionId)
+	defer unlock()
+
+	record, err := p.records.IpnsRecordByCollection(ctx, collectionId)
+	if err != nil {
+		return err
+	}
+	c, err := cid.Decode(record.Value)
+	if err != nil {
+		return err
+	}
+	_, err = p.publish(ctx, collectionId, c)
+	return err
+}
+
+func (p *Publisher) publish(ctx context.Context, collectionId int64, c cid.Cid) (*models.IpnsRecord, error) {
+	const op = "ipns.Publisher.publish"
+
+	keyName := keyPrefix + strconv.FormatInt(collectionId, 10)
+	if _, err := p.storage.Key(ctx, keyName); err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	publishedAt := time.Now()
+	name, err := p.storage.Publish(ctx, keyName, c, p.cfg.Lifetime)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+
+	record := &models.IpnsRecord{
+		CollectionId: collectionId,
+		KeyName:      keyName,
+		Name:         name,
+		Value:        c.String(),
+		PublishedAt:  publishedAt,
+		ExpiresAt:    publishedAt.Add(p.cfg.Lifetime),
+	}
+	if err = p.records.SaveIpnsRecord(ctx, record); err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	return record, nil
+}
+
+// lock блокирует публикации коллекции и возвращает функцию снятия блокировки
+func (p *Publisher) lock(collectionId int64) func() {
+	p.mu.Lock()
+	lock, ok := p.locks[collectionId]
+	if !ok {
+		lock = &sync.Mutex{}
+		p.locks[collectionId] = lock
+	}
+	p.mu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
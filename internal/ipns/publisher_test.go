@@ -0,0 +1,150 @@
+package ipns
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+
+	"main/internal/config"
+	"main/internal/models"
+	"main/internal/repository"
+	"main/internal/storage"
+	"main/tools/pkg/logger"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// nameStorage запоминает опубликованные значения ключей
+type nameStorage struct {
+	storage.Storage
+	published map[string][]cid.Cid
+}
+
+func (s *nameStorage) Key(_ context.Context, keyName string) (string, error) {
+	return "k51" + keyName, nil
+}
+
+func (s *nameStorage) Publish(_ context.Context, keyName string, c cid.Cid, _ time.Duration) (string, error) {
+	s.published[keyName] = append(s.published[keyName], c)
+	return "k51" + keyName, nil
+}
+
+// ipnsRepository хранит записи в памяти и увеличивает номер при каждой публикации
+type ipnsRepository struct {
+	repository.IpnsRepository
+	records map[int64]models.IpnsRecord
+}
+
+func (r *ipnsRepository) IpnsRecordByCollection(_ context.Context, collectionId int64) (*models.IpnsRecord, error) {
+	record, ok := r.records[collectionId]
+	if !ok {
+		return nil, tvoerrors.ErrNotFound
+	}
+	return &record, nil
+}
+
+func (r *ipnsRepository) SaveIpnsRecord(_ context.Context, record *models.IpnsRecord) error {
+	if previous, ok := r.records[record.CollectionId]; ok {
+		record.Sequence = previous.Sequence + 1
+	}
+	r.records[record.CollectionId] = *record
+	return nil
+}
+
+func (r *ipnsRepository) ExpiringIpnsRecords(_ context.Context, before time.Time,
+	_ int) ([]models.IpnsRecord, error) {
+	var records []models.IpnsRecord
+	for _, record := range r.records {
+		if record.ExpiresAt.Before(before) {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+func testCid(t *testing.T, data string) cid.Cid {
+	t.Helper()
+
+	hash, err := mh.Sum([]byte(data), mh.SHA2_256, -1)
+	if err != nil {
+		t.Fatalf("mh.Sum() error = %v", err)
+	}
+	return cid.NewCidV1(cid.DagProtobuf, hash)
+}
+
+func newTestPublisher(s storage.Storage, records *ipnsRepository, cfg *config.IPNS) *Publisher {
+	return NewPublisher(s, records, cfg, &logger.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+}
+
+func TestPublisherPublish(t *testing.T) {
+	s := &nameStorage{published: map[string][]cid.Cid{}}
+	records := &ipnsRepository{records: map[int64]models.IpnsRecord{}}
+	p := newTestPublisher(s, records, &config.IPNS{Lifetime: 48 * time.Hour})
+
+	first, second := testCid(t, "first"), testCid(t, "second")
+	if _, err := p.Publish(context.Background(), 7, first); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	record, err := p.Publish(context.Background(), 7, second)
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	if record.Name != "k51"+keyPrefix+"7" || record.Value != second.String() || record.Sequence != 1 {
+		t.Errorf("record = %+v, expected the second value with sequence 1", record)
+	}
+	if lifetime := record.ExpiresAt.Sub(record.PublishedAt); lifetime != 48*time.Hour {
+		t.Errorf("record lifetime = %s, expected 48h", lifetime)
+	}
+	if Link(record.Name) != "ipns://k51"+keyPrefix+"7" {
+		t.Errorf("Link() = %s", Link(record.Name))
+	}
+}
+
+func TestPublisherRepublish(t *testing.T) {
+	s := &nameStorage{published: map[string][]cid.Cid{}}
+	records := &ipnsRepository{records: map[int64]models.IpnsRecord{}}
+	p := newTestPublisher(s, records, &config.IPNS{Lifetime: 48 * time.Hour, RepublishBefore: 24 * time.Hour})
+
+	if _, err := p.Publish(context.Background(), 1, testCid(t, "fresh")); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	expiring := testCid(t, "expiring")
+	if _, err := p.Publish(context.Background(), 2, expiring); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	record := records.records[2]
+	record.ExpiresAt = time.Now().Add(time.Hour)
+	records.records[2] = record
+
+	republished, err := p.Republish(context.Background())
+	if err != nil || republished != 1 {
+		t.Fatalf("Republish() = %d, %v, expected 1", republished, err)
+	}
+	values := s.published[keyPrefix+"2"]
+	if len(values) != 2 || values[1] != expiring {
+		t.Errorf("published values = %v, expected the expiring value twice", values)
+	}
+	if record = records.records[2]; record.Sequence != 1 || time.Until(record.ExpiresAt) < 47*time.Hour {
+		t.Errorf("republished record = %+v", record)
+	}
+	if len(s.published[keyPrefix+"1"]) != 1 {
+		t.Errorf("fresh record was republished")
+	}
+}
+
+func TestPublisherUnsupported(t *testing.T) {
+	s, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStorage() error = %v", err)
+	}
+	p := newTestPublisher(s, &ipnsRepository{}, &config.IPNS{})
+	if _, err = p.Publish(context.Background(), 1, testCid(t, "value")); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Publish() error = %v, expected ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,145 @@
+// Package car writes CARv1 archives and merges the CARv1 streams of several DAGs
+// into one archive, so that DAGs exported one root at a time can be shipped together.
+// Format: https://ipld.io/specs/transport/car/carv1/
+package car
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ipfs/go-cid"
+)
+
+// MaxSectionSize bounds a single block section, Kubo blocks are at most 2 MiB.
+const MaxSectionSize = 8 << 20
+
+// ErrSectionTooLarge is returned for sections above MaxSectionSize.
+var ErrSectionTooLarge = errors.New("car section is too large")
+
+// cbor major types used by the header
+const (
+	cborBytes = 2 << 5
+	cborText  = 3 << 5
+	cborArray = 4 << 5
+	cborMap   = 5 << 5
+	cborTag   = 6 << 5
+)
+
+// cidTag is the dag-cbor tag of a link
+const cidTag = 42
+
+// Writer writes a CARv1 archive, every block is written once.
+type Writer struct {
+	w    io.Writer
+	seen map[string]struct{}
+}
+
+// NewWriter writes the header with the roots and returns a writer for the blocks.
+func NewWriter(w io.Writer, roots []cid.Cid) (*Writer, error) {
+	if err := writeSection(w, encodeHeader(roots)); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w, seen: map[string]struct{}{}}, nil
+}
+
+// Put writes the block unless a block with the same CID is already written.
+func (cw *Writer) Put(c cid.Cid, data []byte) error {
+	key := c.KeyString()
+	if _, ok := cw.seen[key]; ok {
+		return nil
+	}
+	cw.seen[key] = struct{}{}
+
+	section := append(c.Bytes(), data...)
+	return writeSection(cw.w, section)
+}
+
+// Append copies the blocks of the CARv1 stream r, its header is dropped.
+func (cw *Writer) Append(r io.Reader) error {
+	br := bufio.NewReader(r)
+	if _, err := readSection(br); err != nil {
+		return fmt.Errorf("reading car header: %w", err)
+	}
+
+	for {
+		section, err := readSection(br)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		n, c, err := cid.CidFromBytes(section)
+		if err != nil {
+			return fmt.Errorf("reading block cid: %w", err)
+		}
+		if err = cw.Put(c, section[n:]); err != nil {
+			return err
+		}
+	}
+}
+
+// encodeHeader encodes {roots: [...], version: 1} as canonical dag-cbor
+func encodeHeader(roots []cid.Cid) []byte {
+	b := appendHead(nil, cborMap, 2)
+	b = appendHead(b, cborText, 5)
+	b = append(b, "roots"...)
+	b = appendHead(b, cborArray, uint64(len(roots)))
+	for _, root := range roots {
+		// a dag-cbor link is the binary CID prefixed with the identity multibase byte
+		raw := root.Bytes()
+		b = appendHead(b, cborTag, cidTag)
+		b = appendHead(b, cborBytes, uint64(len(raw)+1))
+		b = append(b, 0)
+		b = append(b, raw...)
+	}
+	b = appendHead(b, cborText, 7)
+	b = append(b, "version"...)
+	return appendHead(b, 0, 1)
+}
+
+// appendHead appends a cbor item head with the shortest argument encoding
+func appendHead(b []byte, major byte, v uint64) []byte {
+	switch {
+	case v < 24:
+		return append(b, major|byte(v))
+	case v <= 0xff:
+		return append(b, major|24, byte(v))
+	case v <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(b, major|25), uint16(v))
+	case v <= 0xffffffff:
+		return binary.BigEndian.AppendUint32(append(b, major|26), uint32(v))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, major|27), v)
+	}
+}
+
+// writeSection writes the varint length and the data
+func writeSection(w io.Writer, data []byte) error {
+	if _, err := w.Write(binary.AppendUvarint(nil, uint64(len(data)))); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// readSection reads a varint length prefixed section, io.EOF means the end of the stream
+func readSection(r *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if size > MaxSectionSize {
+		return nil, ErrSectionTooLarge
+	}
+
+	data := make([]byte, size)
+	if _, err = io.ReadFull(r, data); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return data, nil
+}
@@ -0,0 +1,112 @@
+package car
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+)
+
+func testBlock(t *testing.T, data string) cid.Cid {
+	t.Helper()
+
+	hash, err := mh.Sum([]byte(data), mh.SHA2_256, -1)
+	if err != nil {
+		t.Fatalf("mh.Sum() error = %v", err)
+	}
+	return cid.NewCidV1(cid.Raw, hash)
+}
+
+func TestNewWriterHeader(t *testing.T) {
+	root := testBlock(t, "root")
+
+	var buf bytes.Buffer
+	if _, err := NewWriter(&buf, []cid.Cid{root}); err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+
+	// {"roots": [42(h'00' + cid)], "version": 1}
+	raw := root.Bytes()
+	header := "a2" + "65" + hex.EncodeToString([]byte("roots")) + "81" + "d82a" + "58" +
+		hex.EncodeToString([]byte{byte(len(raw) + 1), 0}) + hex.EncodeToString(raw) +
+		"67" + hex.EncodeToString([]byte("version")) + "01"
+	expected := hex.EncodeToString([]byte{byte(len(header) / 2)}) + header
+
+	if result := hex.EncodeToString(buf.Bytes()); result != expected {
+		t.Errorf("header = %s, expected %s", result, expected)
+	}
+}
+
+func TestWriterAppend(t *testing.T) {
+	shared, first, second := testBlock(t, "shared"), testBlock(t, "first"), testBlock(t, "second")
+
+	// exports of two DAGs sharing a block
+	export := func(root cid.Cid, data string) []byte {
+		var buf bytes.Buffer
+		w, err := NewWriter(&buf, []cid.Cid{root})
+		if err != nil {
+			t.Fatalf("NewWriter() error = %v", err)
+		}
+		_ = w.Put(root, []byte(data))
+		_ = w.Put(shared, []byte("shared"))
+		return buf.Bytes()
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, []cid.Cid{first, second})
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	for _, part := range [][]byte{export(first, "first"), export(second, "second")} {
+		if err = w.Append(bytes.NewReader(part)); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	r := bufio.NewReader(&buf)
+	if _, err = readSection(r); err != nil {
+		t.Fatalf("reading header error = %v", err)
+	}
+	var blocks []string
+	for {
+		section, err := readSection(r)
+		if err != nil {
+			break
+		}
+		n, c, err := cid.CidFromBytes(section)
+		if err != nil {
+			t.Fatalf("CidFromBytes() error = %v", err)
+		}
+		if string(section[n:]) == "" || !c.Equals(testBlock(t, string(section[n:]))) {
+			t.Errorf("block %s has data %q", c, section[n:])
+		}
+		blocks = append(blocks, string(section[n:]))
+	}
+
+	if len(blocks) != 3 || blocks[0] != "first" || blocks[1] != "shared" || blocks[2] != "second" {
+		t.Errorf("blocks = %v, expected first, shared and second once", blocks)
+	}
+}
+
+func TestWriterAppendInvalid(t *testing.T) {
+	w, _ := NewWriter(&bytes.Buffer{}, nil)
+
+	tests := []struct {
+		input    []byte
+		expected error
+	}{
+		{[]byte{0x05, 'a'}, nil},
+		{[]byte{0x01, 0xa0, 0xff, 0xff, 0xff, 0x7f}, ErrSectionTooLarge},
+	}
+
+	for _, test := range tests {
+		err := w.Append(bytes.NewReader(test.input))
+		if err == nil || (test.expected != nil && !errors.Is(err, test.expected)) {
+			t.Errorf("Append(%x) error = %v, expected %v", test.input, err, test.expected)
+		}
+	}
+}
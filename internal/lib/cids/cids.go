@@ -0,0 +1,119 @@
+// Package cids parses CIDs given by clients in any of their common forms and
+// formats CIDs in the multibase requested by a client.
+package cids
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multibase"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// ErrInvalid is returned for values which are not a CID.
+var ErrInvalid = errors.New("invalid cid")
+
+// ErrUnsupportedBase is returned for unknown multibase names.
+var ErrUnsupportedBase = errors.New("unsupported multibase")
+
+// Parse decodes a CID in any multibase, bare or written as ipfs://<cid> or /ipfs/<cid>.
+// A path after the CID is rejected, the value must name the CID only.
+func Parse(value string) (cid.Cid, error) {
+	raw := strings.TrimSpace(value)
+	for _, prefix := range []string{"ipfs://", "/ipfs/"} {
+		if rest, ok := strings.CutPrefix(raw, prefix); ok {
+			raw = strings.TrimSuffix(rest, "/")
+			break
+		}
+	}
+	if raw == "" || strings.ContainsAny(raw, "/?#") {
+		return cid.Undef, fmt.Errorf("%w: %q", ErrInvalid, value)
+	}
+
+	c, err := cid.Decode(raw)
+	if err != nil {
+		return cid.Undef, fmt.Errorf("%w: %q: %v", ErrInvalid, value, err)
+	}
+	return c, nil
+}
+
+// Base is the multibase CIDs are formatted in. The zero value keeps the form
+// of every CID: base58btc for CIDv0 and base32 for CIDv1.
+type Base struct {
+	encoder *multibase.Encoder
+}
+
+// ParseBase returns the multibase with the name, e.g. base32, base36 or base58btc.
+// An empty name returns the zero Base.
+func ParseBase(name string) (Base, error) {
+	if name == "" {
+		return Base{}, nil
+	}
+	encoder, err := multibase.EncoderByName(name)
+	if err != nil || encoder.Encoding() == multibase.Identity {
+		return Base{}, fmt.Errorf("%w: %q", ErrUnsupportedBase, name)
+	}
+	return Base{encoder: &encoder}, nil
+}
+
+// Format returns the CID in the base. CIDv0 is only written in base58btc, so for
+// any other base it is converted to the CIDv1 of the same content.
+func (b Base) Format(c cid.Cid) string {
+	if b.encoder == nil || !c.Defined() {
+		return c.String()
+	}
+	if c.Version() == 0 {
+		if b.encoder.Encoding() == multibase.Base58BTC {
+			return c.String()
+		}
+		c = V1(c)
+	}
+	return c.Encode(*b.encoder)
+}
+
+// FormatString formats the CID stored as text, values which are not a CID are returned as is.
+func (b Base) FormatString(value string) string {
+	if b.encoder == nil {
+		return value
+	}
+	c, err := cid.Decode(value)
+	if err != nil {
+		return value
+	}
+	return b.Format(c)
+}
+
+// V1 returns the CIDv1 of the content keeping its codec, so raw leaves and
+// dag-cbor nodes stay what they are.
+func V1(c cid.Cid) cid.Cid {
+	return cid.NewCidV1(c.Type(), c.Hash())
+}
+
+// V0 returns the CIDv0 of the content. Only dag-pb nodes hashed with sha2-256
+// have a CIDv0, for other CIDs ok is false.
+func V0(c cid.Cid) (cid.Cid, bool) {
+	prefix := c.Prefix()
+	if prefix.Codec != cid.DagProtobuf || prefix.MhType != mh.SHA2_256 || prefix.MhLength != 32 {
+		return cid.Undef, false
+	}
+	return cid.NewCidV0(c.Hash()), true
+}
+
+// Forms returns the text forms the content may be stored under: the CID itself,
+// its CIDv1 in base32 and its CIDv0 when there is one. Every form is returned once.
+func Forms(c cid.Cid) []string {
+	forms := []string{c.String()}
+	add := func(form cid.Cid) {
+		if value := form.String(); !slices.Contains(forms, value) {
+			forms = append(forms, value)
+		}
+	}
+	add(V1(c))
+	if v0, ok := V0(c); ok {
+		add(v0)
+	}
+	return forms
+}
@@ -0,0 +1,121 @@
+package cids
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+)
+
+const (
+	testV0       = "QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o"
+	testV1       = "bafybeicg2rebjoofv4kbyovkw7af3rpiitvnl6i7ckcywaq6xjcxnc2mby"
+	testV1Base36 = "k2jmtxt4nv2kx0qz1ncwpjwzdixb27xsgqxk7kgho4nnmjceustvi80e"
+	testV1Base58 = "zdj7WaCPMsy3FP2whSwpFXHjDEaTVmjnddYSn5W2Gdn4aEZFj"
+)
+
+func TestParse(t *testing.T) {
+	valid := []struct {
+		input    string
+		expected string
+	}{
+		{testV0, testV0},
+		{" " + testV1 + "\n", testV1},
+		{testV1Base36, testV1},
+		{testV1Base58, testV1},
+		{"ipfs://" + testV1, testV1},
+		{"ipfs://" + testV0 + "/", testV0},
+		{"/ipfs/" + testV1Base36, testV1},
+	}
+	for _, test := range valid {
+		c, err := Parse(test.input)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", test.input, err)
+			continue
+		}
+		if c.String() != test.expected {
+			t.Errorf("Parse(%q) = %s, expected %s", test.input, c, test.expected)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"not-a-cid",
+		"ipfs://",
+		"/ipfs/" + testV1 + "/image.png",
+		testV1 + "?filename=1.png",
+		"../../pin/rm?arg=" + testV1,
+	}
+	for _, input := range invalid {
+		if _, err := Parse(input); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Parse(%q) error = %v, expected ErrInvalid", input, err)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	v0, _ := cid.Decode(testV0)
+	v1, _ := cid.Decode(testV1)
+
+	tests := []struct {
+		base     string
+		input    cid.Cid
+		expected string
+	}{
+		{"", v0, testV0},
+		{"", v1, testV1},
+		{"base32", v0, testV1},
+		{"base36", v0, testV1Base36},
+		{"base36", v1, testV1Base36},
+		{"base58btc", v0, testV0},
+		{"base58btc", v1, testV1Base58},
+	}
+	for _, test := range tests {
+		base, err := ParseBase(test.base)
+		if err != nil {
+			t.Fatalf("ParseBase(%q) error = %v", test.base, err)
+		}
+		if res := base.Format(test.input); res != test.expected {
+			t.Errorf("Format(%s) in %q = %s, expected %s", test.input, test.base, res, test.expected)
+		}
+	}
+
+	base, _ := ParseBase("base36")
+	if res := base.FormatString("not-a-cid"); res != "not-a-cid" {
+		t.Errorf("FormatString() = %q, expected the value as is", res)
+	}
+
+	for _, name := range []string{"identity", "base1000"} {
+		if _, err := ParseBase(name); !errors.Is(err, ErrUnsupportedBase) {
+			t.Errorf("ParseBase(%q) error = %v, expected ErrUnsupportedBase", name, err)
+		}
+	}
+}
+
+func TestForms(t *testing.T) {
+	v0, _ := cid.Decode(testV0)
+	if forms := Forms(v0); !slices.Equal(forms, []string{testV0, testV1}) {
+		t.Errorf("Forms(v0) = %v", forms)
+	}
+	v1, _ := cid.Decode(testV1)
+	if forms := Forms(v1); !slices.Equal(forms, []string{testV1, testV0}) {
+		t.Errorf("Forms(v1) = %v", forms)
+	}
+
+	// raw leaves and dag-cbor nodes have no CIDv0 and keep their codec
+	hash, _ := mh.Sum([]byte("leaf"), mh.SHA2_256, -1)
+	for _, codec := range []uint64{cid.Raw, cid.DagCBOR} {
+		c := cid.NewCidV1(codec, hash)
+		if _, ok := V0(c); ok {
+			t.Errorf("V0() of codec %#x expected no CIDv0", codec)
+		}
+		if V1(c) != c {
+			t.Errorf("V1(%s) = %s, expected the CID as is", c, V1(c))
+		}
+		if forms := Forms(c); !slices.Equal(forms, []string{c.String()}) {
+			t.Errorf("Forms(%s) = %v", c, forms)
+		}
+	}
+}
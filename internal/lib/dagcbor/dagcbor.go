@@ -0,0 +1,127 @@
+// Package dagcbor reads the links of dag-cbor blocks without decoding them into values.
+// Format: https://ipld.io/specs/codecs/dag-cbor/spec/
+package dagcbor
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+)
+
+// cbor major types
+const (
+	majorUint   = 0
+	majorNegint = 1
+	majorBytes  = 2
+	majorText   = 3
+	majorArray  = 4
+	majorMap    = 5
+	majorTag    = 6
+	majorSimple = 7
+)
+
+// cidTag is the dag-cbor tag of a link
+const cidTag = 42
+
+// maxDepth bounds the nesting of arrays, maps and tags
+const maxDepth = 512
+
+// ErrMalformed is returned for blocks which are not valid dag-cbor.
+var ErrMalformed = errors.New("malformed dag-cbor block")
+
+// Links returns the CIDs linked from the block in the order they appear.
+func Links(block []byte) ([]cid.Cid, error) {
+	var links []cid.Cid
+	rest, err := walk(block, &links, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: trailing bytes", ErrMalformed)
+	}
+	return links, nil
+}
+
+// walk skips one item, collecting the links inside it, and returns the bytes after it
+func walk(b []byte, links *[]cid.Cid, depth int) ([]byte, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("%w: nested too deep", ErrMalformed)
+	}
+	major, arg, b, err := readHead(b)
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case majorUint, majorNegint, majorSimple:
+		return b, nil
+	case majorBytes, majorText:
+		if uint64(len(b)) < arg {
+			return nil, fmt.Errorf("%w: truncated string", ErrMalformed)
+		}
+		return b[arg:], nil
+	case majorArray, majorMap:
+		// every item takes at least one byte, which also bounds the loop
+		if arg > uint64(len(b)) {
+			return nil, fmt.Errorf("%w: truncated collection", ErrMalformed)
+		}
+		items := arg
+		if major == majorMap {
+			items *= 2
+		}
+		for range items {
+			if b, err = walk(b, links, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	default: // majorTag
+		if arg != cidTag {
+			return walk(b, links, depth+1)
+		}
+		return readLink(b, links)
+	}
+}
+
+// readLink reads the byte string of a link: the binary CID prefixed with the identity multibase byte
+func readLink(b []byte, links *[]cid.Cid) ([]byte, error) {
+	major, size, b, err := readHead(b)
+	if err != nil {
+		return nil, err
+	}
+	if major != majorBytes || size < 2 || uint64(len(b)) < size || b[0] != 0 {
+		return nil, fmt.Errorf("%w: invalid link", ErrMalformed)
+	}
+	c, err := cid.Cast(b[1:size])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid link: %v", ErrMalformed, err)
+	}
+	*links = append(*links, c)
+	return b[size:], nil
+}
+
+// readHead reads an item head, dag-cbor does not allow indefinite lengths
+func readHead(b []byte) (byte, uint64, []byte, error) {
+	if len(b) == 0 {
+		return 0, 0, nil, fmt.Errorf("%w: unexpected end", ErrMalformed)
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	b = b[1:]
+	if info < 24 {
+		return major, uint64(info), b, nil
+	}
+	if info > 27 {
+		return 0, 0, nil, fmt.Errorf("%w: unsupported item head %#x", ErrMalformed, info)
+	}
+
+	size := 1 << (info - 24)
+	if len(b) < size {
+		return 0, 0, nil, fmt.Errorf("%w: unexpected end", ErrMalformed)
+	}
+	var arg uint64
+	for _, x := range b[:size] {
+		arg = arg<<8 | uint64(x)
+	}
+	return major, arg, b[size:], nil
+}
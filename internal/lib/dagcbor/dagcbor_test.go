@@ -0,0 +1,66 @@
+package dagcbor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+)
+
+func testCid(t *testing.T, codec uint64, data string) cid.Cid {
+	t.Helper()
+
+	hash, err := mh.Sum([]byte(data), mh.SHA2_256, -1)
+	if err != nil {
+		t.Fatalf("mh.Sum() error = %v", err)
+	}
+	return cid.NewCidV1(codec, hash)
+}
+
+// appendLink appends tag 42 with the CID bytes prefixed with the identity multibase byte
+func appendLink(b []byte, c cid.Cid) []byte {
+	raw := c.Bytes()
+	b = append(b, 0xd8, cidTag, 0x58, byte(len(raw)+1), 0)
+	return append(b, raw...)
+}
+
+func TestLinks(t *testing.T) {
+	leaf, child := testCid(t, cid.Raw, "leaf"), testCid(t, cid.DagCBOR, "child")
+
+	// {"a": leaf, "b": [1, -2, child], "c": "text", "d": 1.5}
+	block := []byte{0xa4, 0x61, 'a'}
+	block = appendLink(block, leaf)
+	block = append(block, 0x61, 'b', 0x83, 0x01, 0x21)
+	block = appendLink(block, child)
+	block = append(block, 0x61, 'c', 0x64, 't', 'e', 'x', 't')
+	block = append(block, 0x61, 'd', 0xfb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0)
+
+	links, err := Links(block)
+	if err != nil {
+		t.Fatalf("Links() error = %v", err)
+	}
+	if len(links) != 2 || !links[0].Equals(leaf) || !links[1].Equals(child) {
+		t.Errorf("Links() = %v, expected [%s %s]", links, leaf, child)
+	}
+}
+
+func TestLinksMalformed(t *testing.T) {
+	link := appendLink(nil, testCid(t, cid.Raw, "leaf"))
+
+	tests := map[string][]byte{
+		"empty":           {},
+		"truncated":       link[:len(link)-1],
+		"trailing bytes":  append(append([]byte{}, link...), 0x01),
+		"indefinite":      {0x9f, 0x01, 0xff},
+		"huge array":      {0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		"link not bytes":  {0xd8, cidTag, 0x61, 'a'},
+		"link no prefix":  {0xd8, cidTag, 0x42, 0x01, 0x55},
+		"truncated bytes": {0x45, 0x01},
+	}
+	for name, block := range tests {
+		if _, err := Links(block); !errors.Is(err, ErrMalformed) {
+			t.Errorf("Links(%s) error = %v, expected ErrMalformed", name, err)
+		}
+	}
+}
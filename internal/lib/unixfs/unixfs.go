@@ -0,0 +1,264 @@
+// Package unixfs implements the subset of the UnixFS importer used by Kubo's
+// default `ipfs add` (256 KiB chunks, balanced layout, dag-pb leaves, CIDv0),
+// so that content stored outside of Kubo gets the same CIDs. The raw leaves
+// layout of `ipfs add --cid-version=1` is supported as well.
+package unixfs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// ChunkSize is the default Kubo chunker size (size-262144).
+const ChunkSize = 256 * 1024
+
+// MaxLinks is the maximum number of children of a balanced layout node.
+const MaxLinks = 174
+
+// unixfs data type of a file node
+const typeFile = 2
+
+// V0Prefix describes CIDv0 blocks produced by the importer.
+var V0Prefix = cid.Prefix{
+	Version:  0,
+	Codec:    cid.DagProtobuf,
+	MhType:   mh.SHA2_256,
+	MhLength: -1,
+}
+
+// V1Prefix describes dag-pb blocks produced with raw leaves, they link CIDv1 children.
+var V1Prefix = cid.Prefix{
+	Version:  1,
+	Codec:    cid.DagProtobuf,
+	MhType:   mh.SHA2_256,
+	MhLength: -1,
+}
+
+// RawPrefix describes raw leaves.
+var RawPrefix = cid.Prefix{
+	Version:  1,
+	Codec:    cid.Raw,
+	MhType:   mh.SHA2_256,
+	MhLength: -1,
+}
+
+// Options changes the importer settings from the Kubo defaults.
+type Options struct {
+	// RawLeaves stores chunks as raw blocks, as `ipfs add --raw-leaves` does.
+	// Content of a single chunk is then the raw leaf itself.
+	RawLeaves bool
+}
+
+// BlockFunc receives every block produced by the importer.
+type BlockFunc func(c cid.Cid, data []byte) error
+
+// Result describes an imported file.
+type Result struct {
+	Cid      cid.Cid
+	FileSize uint64 // size of the original content
+	DagSize  uint64 // cumulative size of all blocks, as reported by Kubo add
+}
+
+// node is a link to an already built subtree.
+type node struct {
+	cid      cid.Cid
+	fileSize uint64
+	dagSize  uint64
+}
+
+// Sum computes the CID of the content without keeping any blocks.
+func Sum(r io.Reader) (*Result, error) {
+	return Import(r, nil)
+}
+
+// SumWith computes the CID of the content imported with the options.
+func SumWith(r io.Reader, opts Options) (*Result, error) {
+	return ImportWith(r, nil, opts)
+}
+
+// Import chunks the content and builds a balanced dag-pb DAG, passing every
+// block to put when it is not nil. Only one chunk is held in memory at a time.
+func Import(r io.Reader, put BlockFunc) (*Result, error) {
+	return ImportWith(r, put, Options{})
+}
+
+// ImportWith is Import with the options.
+func ImportWith(r io.Reader, put BlockFunc, opts Options) (*Result, error) {
+	parentPrefix := V0Prefix
+	if opts.RawLeaves {
+		parentPrefix = V1Prefix
+	}
+
+	buf := make([]byte, ChunkSize)
+	var leaves []node
+
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 || (len(leaves) == 0 && errors.Is(err, io.EOF)) {
+			var leaf node
+			var leafErr error
+			if opts.RawLeaves {
+				// the buffer is reused for the next chunk, put may keep the block
+				leaf, leafErr = emit(put, RawPrefix, bytes.Clone(buf[:n]), uint64(n), 0)
+			} else {
+				leaf, leafErr = emit(put, V0Prefix, encodeNode(nil, encodeData(buf[:n], uint64(n), nil)), uint64(n), 0)
+			}
+			if leafErr != nil {
+				return nil, leafErr
+			}
+			leaves = append(leaves, leaf)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// строим слои дерева, пока не останется один корень
+	level := leaves
+	for len(level) > 1 {
+		var parents []node
+		for start := 0; start < len(level); start += MaxLinks {
+			end := min(start+MaxLinks, len(level))
+			parent, err := buildParent(put, parentPrefix, level[start:end])
+			if err != nil {
+				return nil, err
+			}
+			parents = append(parents, parent)
+		}
+		level = parents
+	}
+
+	root := level[0]
+	return &Result{Cid: root.cid, FileSize: root.fileSize, DagSize: root.dagSize}, nil
+}
+
+// buildParent creates an intermediate file node over the children.
+func buildParent(put BlockFunc, prefix cid.Prefix, children []node) (node, error) {
+	var fileSize, childrenSize uint64
+	blockSizes := make([]uint64, 0, len(children))
+	for _, child := range children {
+		fileSize += child.fileSize
+		childrenSize += child.dagSize
+		blockSizes = append(blockSizes, child.fileSize)
+	}
+	return emit(put, prefix, encodeNode(children, encodeData(nil, fileSize, blockSizes)), fileSize, childrenSize)
+}
+
+// emit hashes an encoded block with the prefix and hands it to put.
+func emit(put BlockFunc, prefix cid.Prefix, block []byte, fileSize, childrenSize uint64) (node, error) {
+	c, err := prefix.Sum(block)
+	if err != nil {
+		return node{}, err
+	}
+	if put != nil {
+		if err = put(c, block); err != nil {
+			return node{}, err
+		}
+	}
+	return node{cid: c, fileSize: fileSize, dagSize: uint64(len(block)) + childrenSize}, nil
+}
+
+// encodeData serializes the UnixFS Data protobuf of a file node.
+func encodeData(data []byte, fileSize uint64, blockSizes []uint64) []byte {
+	out := appendVarintField(nil, 1, typeFile)
+	if len(data) > 0 {
+		out = appendBytesField(out, 2, data)
+	}
+	out = appendVarintField(out, 3, fileSize)
+	for _, size := range blockSizes {
+		out = appendVarintField(out, 4, size)
+	}
+	return out
+}
+
+// encodeNode serializes a dag-pb PBNode in canonical form (links before data).
+func encodeNode(links []node, data []byte) []byte {
+	var out []byte
+	for _, link := range links {
+		var pbLink []byte
+		pbLink = appendBytesField(pbLink, 1, link.cid.Bytes())
+		pbLink = appendBytesField(pbLink, 2, nil)
+		pbLink = appendVarintField(pbLink, 3, link.dagSize)
+		out = appendBytesField(out, 2, pbLink)
+	}
+	return appendBytesField(out, 1, data)
+}
+
+// ErrMalformedNode is returned for blocks which are not valid dag-pb nodes.
+var ErrMalformedNode = errors.New("malformed dag-pb node")
+
+// Links decodes the CIDs of the links of a dag-pb node in their order.
+func Links(block []byte) ([]cid.Cid, error) {
+	var links []cid.Cid
+	err := walkFields(block, func(field int, value []byte) error {
+		if field != 2 {
+			return nil
+		}
+		// PBLink: the Hash field holds the binary CID
+		return walkFields(value, func(field int, value []byte) error {
+			if field != 1 {
+				return nil
+			}
+			c, err := cid.Cast(value)
+			if err != nil {
+				return ErrMalformedNode
+			}
+			links = append(links, c)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// walkFields passes the length-delimited fields of a protobuf message to fn and skips varints.
+func walkFields(b []byte, fn func(field int, value []byte) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return ErrMalformedNode
+		}
+		b = b[n:]
+
+		switch key & 7 {
+		case 0:
+			if _, n = binary.Uvarint(b); n <= 0 {
+				return ErrMalformedNode
+			}
+			b = b[n:]
+		case 2:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || size > uint64(len(b)-n) {
+				return ErrMalformedNode
+			}
+			if err := fn(int(key>>3), b[n:n+int(size)]); err != nil {
+				return err
+			}
+			b = b[n+int(size):]
+		default:
+			return ErrMalformedNode
+		}
+	}
+	return nil
+}
+
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	b = binary.AppendUvarint(b, uint64(field<<3))
+	return binary.AppendUvarint(b, v)
+}
+
+func appendBytesField(b []byte, field int, v []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(field<<3|2))
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
@@ -0,0 +1,170 @@
+package unixfs
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+)
+
+func TestSum(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", "QmbFMke1KXqnYyBBWxB74N4c5SBnJMVAiMNRcGu6x1AwQH"},
+		{"hello world", "Qmf412jQZiuVUtdgnB36FXFX7xg5V6KEbSJ4dpQuhkLyfD"},
+		{"hello world\n", "QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o"},
+	}
+
+	for _, test := range tests {
+		res, err := Sum(strings.NewReader(test.input))
+		if err != nil {
+			t.Fatalf("Sum(%q) error = %v", test.input, err)
+		}
+		if res.Cid.String() != test.expected {
+			t.Errorf("Sum(%q) = %s, expected %s", test.input, res.Cid, test.expected)
+		}
+		if res.FileSize != uint64(len(test.input)) {
+			t.Errorf("Sum(%q) file size = %d, expected %d", test.input, res.FileSize, len(test.input))
+		}
+	}
+}
+
+func TestImportBalancedLayout(t *testing.T) {
+	content := bytes.Repeat([]byte{'x'}, ChunkSize*(MaxLinks+1)+10)
+
+	var blocks int
+	var blocksSize uint64
+	res, err := Import(bytes.NewReader(content), func(c cid.Cid, data []byte) error {
+		blocks++
+		blocksSize += uint64(len(data))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	// 176 leaves, two intermediate nodes and the root
+	if blocks != MaxLinks+2+3 {
+		t.Errorf("Import() blocks = %d, expected %d", blocks, MaxLinks+5)
+	}
+	if res.FileSize != uint64(len(content)) {
+		t.Errorf("Import() file size = %d, expected %d", res.FileSize, len(content))
+	}
+	if res.DagSize != blocksSize {
+		t.Errorf("Import() dag size = %d, expected %d", res.DagSize, blocksSize)
+	}
+}
+
+func TestLinks(t *testing.T) {
+	content := bytes.Repeat([]byte{'y'}, ChunkSize*3)
+
+	blocks := map[cid.Cid][]byte{}
+	res, err := Import(bytes.NewReader(content), func(c cid.Cid, data []byte) error {
+		blocks[c] = data
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	links, err := Links(blocks[res.Cid])
+	if err != nil {
+		t.Fatalf("Links() error = %v", err)
+	}
+	// the three chunks are equal and share one leaf block
+	if len(links) != 3 || !links[0].Equals(links[2]) {
+		t.Fatalf("Links() = %v, expected three links to the same leaf", links)
+	}
+	if _, ok := blocks[links[0]]; !ok {
+		t.Errorf("link %s is not a block of the DAG", links[0])
+	}
+	if leafLinks, err := Links(blocks[links[0]]); err != nil || len(leafLinks) != 0 {
+		t.Errorf("Links(leaf) = %v, %v, expected none", leafLinks, err)
+	}
+
+	if _, err = Links([]byte{0x12, 0x05, 0x0a}); !errors.Is(err, ErrMalformedNode) {
+		t.Errorf("Links(truncated) error = %v, expected ErrMalformedNode", err)
+	}
+}
+
+func TestSumRawLeaves(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", "bafkreihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku"},
+		{"hello world", "bafkreifzjut3te2nhyekklss27nh3k72ysco7y32koao5eei66wof36n5e"},
+	}
+
+	for _, test := range tests {
+		res, err := SumWith(strings.NewReader(test.input), Options{RawLeaves: true})
+		if err != nil {
+			t.Fatalf("SumWith(%q) error = %v", test.input, err)
+		}
+		if res.Cid.String() != test.expected || res.DagSize != uint64(len(test.input)) {
+			t.Errorf("SumWith(%q) = %s, %d, expected %s", test.input, res.Cid, res.DagSize, test.expected)
+		}
+	}
+
+	// several chunks are linked from a CIDv1 dag-pb root
+	content := bytes.Repeat([]byte{'z'}, ChunkSize*2+1)
+	blocks := map[cid.Cid][]byte{}
+	var blocksSize uint64
+	res, err := ImportWith(bytes.NewReader(content), func(c cid.Cid, data []byte) error {
+		blocks[c] = data
+		blocksSize += uint64(len(data))
+		return nil
+	}, Options{RawLeaves: true})
+	if err != nil {
+		t.Fatalf("ImportWith() error = %v", err)
+	}
+	if res.Cid.Version() != 1 || res.Cid.Type() != cid.DagProtobuf || res.DagSize != blocksSize {
+		t.Errorf("ImportWith() = %s, dag size %d, expected a CIDv1 dag-pb root of %d bytes", res.Cid, res.DagSize,
+			blocksSize)
+	}
+	links, err := Links(blocks[res.Cid])
+	if err != nil || len(links) != 3 {
+		t.Fatalf("Links() = %v, %v", links, err)
+	}
+	for _, link := range links {
+		if link.Type() != cid.Raw || !bytes.Equal(blocks[link], content[:len(blocks[link])]) {
+			t.Errorf("leaf %s is not a raw chunk of the content", link)
+		}
+	}
+}
+
+func TestSumMatchesKubo(t *testing.T) {
+	multiChunk := bytes.Repeat([]byte("0123456789"), ChunkSize*3/10+7)
+	// more leaves than fit into one node, the balanced layout adds a level
+	tree := make([]byte, ChunkSize*(MaxLinks+1)+100)
+	for i := range tree {
+		tree[i] = byte(i % 251)
+	}
+
+	// CIDs returned by `ipfs add` and `ipfs add --cid-version=1` for the same content
+	tests := []struct {
+		name      string
+		input     []byte
+		rawLeaves bool
+		expected  string
+	}{
+		{"multi-chunk", multiChunk, false, "QmSKgNCaiV8RnLnEiWYfWG9YzWNTrgjTKCJNFR5dR8hBft"},
+		{"multi-chunk raw leaves", multiChunk, true, "bafybeihsmdyd6wex3pnan4dnmkndmahggz6oweiods4mfzoeyyduymxlke"},
+		{"tree", tree, false, "QmPQYkcZqfyNEtS1KdzfKHxxyEX1dCNH1eeYLj4YA4ETFb"},
+		{"tree raw leaves", tree, true, "bafybeidwpjzq4iygyts7wi6ncqnk5gpnbi4ua2hapafbbcbhniu6a5sqze"},
+	}
+
+	for _, test := range tests {
+		res, err := SumWith(bytes.NewReader(test.input), Options{RawLeaves: test.rawLeaves})
+		if err != nil {
+			t.Fatalf("SumWith(%s) error = %v", test.name, err)
+		}
+		if res.Cid.String() != test.expected {
+			t.Errorf("SumWith(%s) = %s, expected %s", test.name, res.Cid, test.expected)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+// Package mfs раскладывает загруженное содержимое по файловому дереву узла (MFS):
+// <root>/<коллекция>/<номер токена>/<имя файла>. По дереву можно ориентироваться в WebUI узла
+// и при необходимости восстановить по нему данные токенов.
+package mfs
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+
+	"main/internal/config"
+	"main/internal/storage"
+	"main/tools/pkg/logger"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// ErrUnsupported возвращается, если хранилище не ведет файловое дерево
+var ErrUnsupported = errors.New("storage does not support mfs")
+
+// директории для содержимого вне коллекций, имена не совпадают со slug коллекций
+const (
+	noCollectionDir = "_tokens"
+	usersDir        = "_users"
+)
+
+// Mirror копирует загрузки в файловое дерево хранилища. Ошибки копирования не прерывают загрузку
+// и только пишутся в лог: содержимое уже сохранено и закреплено, дерево лишь отражает его.
+type Mirror struct {
+	storage storage.FilesStorage
+	root    string
+	logger  *logger.Logger
+}
+
+// NewMirror создает зеркало. Если хранилище не поддерживает MFS или зеркало выключено,
+// копирование пропускается, а просмотр дерева возвращает ErrUnsupported.
+func NewMirror(s storage.Storage, cfg *config.MFS, logger *logger.Logger) *Mirror {
+	m := &Mirror{root: path.Clean("/" + cfg.Root), logger: logger}
+	if filesStorage, ok := s.(storage.FilesStorage); ok && cfg.Mirror {
+		m.storage = filesStorage
+	}
+	return m
+}
+
+// Root возвращает корень зеркала
+func (m *Mirror) Root() string {
+	return m.root
+}
+
+// TokenPath возвращает путь файла токена, токены без коллекции лежат в директории _tokens
+func (m *Mirror) TokenPath(collection string, tokenId int64, name string) string {
+	if collection == "" {
+		collection = noCollectionDir
+	}
+	return path.Join(m.root, collection, strconv.FormatInt(tokenId, 10), fileName(name))
+}
+
+// UserPath возвращает путь загрузки пользователя, не привязанной к токену
+func (m *Mirror) UserPath(userId int64, name string) string {
+	return path.Join(m.root, usersDir, strconv.FormatInt(userId, 10), fileName(name))
+}
+
+// Copy помещает содержимое CID по пути зеркала, прежнее содержимое по этому пути заменяется
+func (m *Mirror) Copy(ctx context.Context, c cid.Cid, p string) {
+	if m.storage == nil {
+		return
+	}
+	if err := m.storage.CopyFile(ctx, c, p); err != nil {
+		m.logger.Warn("Error mirroring upload into mfs", "cid", c, "path", p, "error", err)
+	}
+}
+
+// List возвращает элементы директории зеркала. Путь отсчитывается от корня зеркала.
+func (m *Mirror) List(ctx context.Context, p string) (string, []storage.FileInfo, error) {
+	if m.storage == nil {
+		return "", nil, ErrUnsupported
+	}
+	full := m.Resolve(p)
+	files, err := m.storage.ListFiles(ctx, full)
+	return full, files, err
+}
+
+// Stat описывает файл или директорию зеркала. Путь отсчитывается от корня зеркала.
+func (m *Mirror) Stat(ctx context.Context, p string) (string, *storage.FileInfo, error) {
+	if m.storage == nil {
+		return "", nil, ErrUnsupported
+	}
+	full := m.Resolve(p)
+	info, err := m.storage.StatFile(ctx, full)
+	return full, info, err
+}
+
+// Remove убирает из зеркала файлы и директории с содержимым cids: пока содержимое лежит в дереве,
+// узел не соберет его блоки, даже если закрепление снято. Обходится все зеркало, CID сравниваются
+// по multihash. Возвращает число убранных путей.
+func (m *Mirror) Remove(ctx context.Context, cids []cid.Cid) (int, error) {
+	if m.storage == nil || len(cids) == 0 {
+		return 0, nil
+	}
+	hashes := make(map[string]struct{}, len(cids))
+	for _, c := range cids {
+		hashes[string(c.Hash())] = struct{}{}
+	}
+
+	return m.remove(ctx, m.root, hashes)
+}
+
+// remove обходит директорию dir и убирает элементы с содержимым из hashes, в них не заходя
+func (m *Mirror) remove(ctx context.Context, dir string, hashes map[string]struct{}) (int, error) {
+	files, err := m.storage.ListFiles(ctx, dir)
+	// корень мог еще не создаваться, а директорию могли убрать во время обхода
+	if errors.Is(err, tvoerrors.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, file := range files {
+		p := path.Join(dir, file.Name)
+		if _, ok := hashes[string(file.Cid.Hash())]; ok {
+			if err = m.storage.RemoveFile(ctx, p); err != nil && !errors.Is(err, tvoerrors.ErrNotFound) {
+				return removed, err
+			}
+			removed++
+			continue
+		}
+		if file.Directory {
+			n, err := m.remove(ctx, p, hashes)
+			removed += n
+			if err != nil {
+				return removed, err
+			}
+		}
+	}
+	return removed, nil
+}
+
+// Resolve переводит путь относительно корня зеркала в путь MFS, путь, уже начинающийся с корня,
+// принимается как есть. Элементы .. не выводят за пределы зеркала: /../other становится <root>/other.
+func (m *Mirror) Resolve(p string) string {
+	cleaned := path.Clean("/" + p)
+	if cleaned == m.root || strings.HasPrefix(cleaned, m.root+"/") {
+		return cleaned
+	}
+	return path.Join(m.root, cleaned)
+}
+
+// fileName приводит имя загруженного файла к одному элементу пути
+func fileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == ".." {
+		return "file"
+	}
+	return name
+}
@@ -0,0 +1,91 @@
+package mfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+
+	"main/internal/config"
+	"main/internal/storage"
+	"main/tools/pkg/logger"
+)
+
+// filesStorage запоминает скопированные пути
+type filesStorage struct {
+	storage.Storage
+	copied map[string]cid.Cid
+}
+
+func (s *filesStorage) CopyFile(_ context.Context, c cid.Cid, p string) error {
+	s.copied[p] = c
+	return nil
+}
+
+func (s *filesStorage) ListFiles(_ context.Context, p string) ([]storage.FileInfo, error) {
+	return []storage.FileInfo{{Name: p}}, nil
+}
+
+func (s *filesStorage) StatFile(_ context.Context, p string) (*storage.FileInfo, error) {
+	return &storage.FileInfo{Name: p}, nil
+}
+
+func (s *filesStorage) RemoveFile(_ context.Context, p string) error {
+	delete(s.copied, p)
+	return nil
+}
+
+func newTestMirror(s storage.Storage, enabled bool) *Mirror {
+	return NewMirror(s, &config.MFS{Mirror: enabled, Root: "/nft-service"},
+		&logger.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+}
+
+func TestMirrorPaths(t *testing.T) {
+	m := newTestMirror(&filesStorage{}, true)
+
+	tests := []struct {
+		result   string
+		expected string
+	}{
+		{m.TokenPath("cool-cats", 7, "cat.png"), "/nft-service/cool-cats/7/cat.png"},
+		{m.TokenPath("", 7, "images/cat.png"), "/nft-service/_tokens/7/cat.png"},
+		{m.TokenPath("cool-cats", 7, "../.."), "/nft-service/cool-cats/7/file"},
+		{m.UserPath(3, `C:\uploads\cat.png`), "/nft-service/_users/3/cat.png"},
+		{m.Resolve(""), "/nft-service"},
+		{m.Resolve("cool-cats/7"), "/nft-service/cool-cats/7"},
+		{m.Resolve("/nft-service/cool-cats"), "/nft-service/cool-cats"},
+		{m.Resolve("/../../etc"), "/nft-service/etc"},
+		{m.Resolve("/nft-service-other"), "/nft-service/nft-service-other"},
+	}
+	for _, test := range tests {
+		if test.result != test.expected {
+			t.Errorf("path = %s, expected %s", test.result, test.expected)
+		}
+	}
+}
+
+func TestMirrorCopy(t *testing.T) {
+	hash, _ := mh.Sum([]byte("cat"), mh.SHA2_256, -1)
+	c := cid.NewCidV1(cid.Raw, hash)
+
+	s := &filesStorage{copied: map[string]cid.Cid{}}
+	newTestMirror(s, true).Copy(context.Background(), c, "/nft-service/cool-cats/7/cat.png")
+	if s.copied["/nft-service/cool-cats/7/cat.png"] != c {
+		t.Errorf("copied = %v", s.copied)
+	}
+
+	// выключенное зеркало ничего не копирует, а просмотр сообщает, что зеркала нет
+	disabled := &filesStorage{copied: map[string]cid.Cid{}}
+	m := newTestMirror(disabled, false)
+	m.Copy(context.Background(), c, "/nft-service/cool-cats/7/cat.png")
+	if len(disabled.copied) != 0 {
+		t.Errorf("disabled mirror copied %v", disabled.copied)
+	}
+	if _, _, err := m.List(context.Background(), ""); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("List() error = %v, expected ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// token standards of a collection
+const (
+	TokenStandardERC721  = "erc721"
+	TokenStandardERC1155 = "erc1155"
+)
+
+// Collection represents a group of tokens issued by one contract
+type Collection struct {
+	ID              int64     `json:"id"`
+	Slug            string    `json:"slug"`
+	Name            string    `json:"name"`
+	Symbol          string    `json:"symbol"`
+	Description     string    `json:"description"`
+	CoverCid        string    `json:"cover_cid"`
+	ContractAddress string    `json:"contract_address"`
+	ChainId         int64     `json:"chain_id"`
+	TokenStandard   string    `json:"token_standard"`
+	OwnerId         int64     `json:"owner_id"`
+	CreatedAt       time.Time `json:"-"`
+	UpdatedAt       time.Time `json:"-"`
+	DeletedAt       time.Time `json:"-"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// статусы проверки целостности содержимого
+const (
+	ContentCheckOK       = "ok"
+	ContentCheckMismatch = "mismatch"
+	ContentCheckMissing  = "missing"
+)
+
+// источники проверки целостности
+const (
+	ContentCheckSourceAudit   = "audit"
+	ContentCheckSourceGateway = "gateway"
+)
+
+// ContentCheck is the latest integrity check of a CID
+type ContentCheck struct {
+	ID     int64
+	Cid    string
+	Status string
+	// Error describes the mismatch or the missing block
+	Error     string
+	Source    string
+	CheckedAt time.Time
+	// FailedAt is when the CID started failing the checks, zero while it passes
+	FailedAt time.Time
+	// Tokens are the nft tokens referencing the CID as <collection>/<token_id> or <token_id>
+	Tokens []string
+}
+
+// AuditRun summarizes a pass of the integrity audit over all nft content
+type AuditRun struct {
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Checked    int
+	Failed     int
+	// Errors counts CIDs which could not be checked, e.g. while the storage was unavailable
+	Errors int
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// DirectoryUpload is a set of files added to the storage under one root directory
+type DirectoryUpload struct {
+	ID      int64
+	RootCid string
+	// Size is the cumulative DAG size of the directory
+	Size int64
+	// CollectionId is zero for uploads outside of collections
+	CollectionId int64
+	UploaderId   int64
+	Files        []DirectoryFile
+	CreatedAt    time.Time
+}
+
+// DirectoryFile is a file or a subdirectory of a directory upload
+type DirectoryFile struct {
+	// Path is relative to the root directory
+	Path      string
+	Cid       string
+	Size      int64
+	Directory bool
+	// TokenId is the token named by the file, nil when the name is not a token id
+	TokenId *int64
+}
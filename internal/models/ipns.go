@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// IpnsRecord is the IPNS record of a collection key, a stable address of content that can be updated
+type IpnsRecord struct {
+	ID           int64
+	CollectionId int64
+	// KeyName is the name of the key in the storage node
+	KeyName string
+	// Name is the IPNS name of the key, addressed as ipns://<name>
+	Name  string
+	Value string
+	// Sequence counts the publications of the record, it starts at zero
+	Sequence    int64
+	PublishedAt time.Time
+	ExpiresAt   time.Time
+	CreatedAt   time.Time
+}
@@ -21,3 +21,113 @@ type PinLsKey struct {
 type PinLsResponse struct {
 	Keys map[string]PinLsKey `json:"Keys"`
 }
+
+// IdResponse представляет ответ от /api/v0/id
+type IdResponse struct {
+	ID              string   `json:"ID"`
+	PublicKey       string   `json:"PublicKey"`
+	Addresses       []string `json:"Addresses"`
+	AgentVersion    string   `json:"AgentVersion"`
+	ProtocolVersion string   `json:"ProtocolVersion"`
+}
+
+// ResolveResponse представляет ответ от /api/v0/resolve
+type ResolveResponse struct {
+	Path string `json:"Path"`
+}
+
+// DagImportResponse представляет объект ответа от /api/v0/dag/import
+type DagImportResponse struct {
+	Root *DagImportRoot
+}
+
+// DagImportRoot представляет корень импортированного CAR файла
+type DagImportRoot struct {
+	Cid struct {
+		Link string `json:"/"`
+	}
+	PinErrorMsg string
+}
+
+// KeyResponse представляет ключ из ответа /api/v0/key/gen и /api/v0/key/list
+type KeyResponse struct {
+	Name string `json:"Name"`
+	Id   string `json:"Id"`
+}
+
+// KeyListResponse представляет ответ от /api/v0/key/list
+type KeyListResponse struct {
+	Keys []KeyResponse `json:"Keys"`
+}
+
+// NamePublishResponse представляет ответ от /api/v0/name/publish
+type NamePublishResponse struct {
+	Name  string `json:"Name"`
+	Value string `json:"Value"`
+}
+
+// FilesEntry представляет элемент директории из ответа /api/v0/files/ls
+type FilesEntry struct {
+	Name string `json:"Name"`
+	Type int    `json:"Type"` // 0 файл, 1 директория
+	Size int64  `json:"Size"`
+	Hash string `json:"Hash"`
+}
+
+// FilesLsResponse представляет ответ от /api/v0/files/ls
+type FilesLsResponse struct {
+	Entries []FilesEntry `json:"Entries"`
+}
+
+// FilesStatResponse представляет ответ от /api/v0/files/stat
+type FilesStatResponse struct {
+	Hash           string `json:"Hash"`
+	Size           int64  `json:"Size"`
+	CumulativeSize int64  `json:"CumulativeSize"`
+	Type           string `json:"Type"` // file или directory
+}
+
+// RepoStatResponse представляет ответ от /api/v0/repo/stat
+type RepoStatResponse struct {
+	RepoSize   uint64 `json:"RepoSize"`
+	StorageMax uint64 `json:"StorageMax"`
+	NumObjects uint64 `json:"NumObjects"`
+	RepoPath   string `json:"RepoPath"`
+	Version    string `json:"Version"`
+}
+
+// StatsBwResponse представляет ответ от /api/v0/stats/bw
+type StatsBwResponse struct {
+	TotalIn  int64   `json:"TotalIn"`
+	TotalOut int64   `json:"TotalOut"`
+	RateIn   float64 `json:"RateIn"`
+	RateOut  float64 `json:"RateOut"`
+}
+
+// SwarmPeer представляет пира из ответа /api/v0/swarm/peers
+type SwarmPeer struct {
+	Addr string `json:"Addr"`
+	Peer string `json:"Peer"`
+}
+
+// SwarmPeersResponse представляет ответ от /api/v0/swarm/peers
+type SwarmPeersResponse struct {
+	Peers []SwarmPeer `json:"Peers"`
+}
+
+// VersionResponse представляет ответ от /api/v0/version
+type VersionResponse struct {
+	Version string `json:"Version"`
+	Commit  string `json:"Commit"`
+	Repo    string `json:"Repo"`
+	System  string `json:"System"`
+	Golang  string `json:"Golang"`
+}
+
+// RepoGcResponse представляет объект ответа от /api/v0/repo/gc
+type RepoGcResponse struct {
+	Key *struct {
+		Link string `json:"/"`
+	} `json:"Key"`
+	Error string `json:"Error"`
+}
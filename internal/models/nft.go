@@ -3,13 +3,25 @@ package models
 import "time"
 
 type NftDataModel struct {
-	ID            int64     `json:"id"`
-	TokenId       int64     `json:"token_id" example:"1"`
-	Description   string    `json:"description" example:"About this token"`
-	CidV0         string    `json:"cid_v0" example:"dss"`
-	CidV1         string    `json:"cid_v1" example:"dss"`
-	FileName      string    `json:"file_name" example:"pic12.png"`
-	FileSize      string    `json:"file_size" example:"12kb"`
+	ID            int64  `json:"id"`
+	TokenId       int64  `json:"token_id" example:"1"`
+	Name          string `json:"name" example:"Token #1"`
+	Description   string `json:"description" example:"About this token"`
+	CidV0         string `json:"cid_v0" example:"dss"`
+	CidV1         string `json:"cid_v1" example:"dss"`
+	FileName      string `json:"file_name" example:"pic12.png"`
+	FileSize      string `json:"file_size" example:"12kb"`
+	MetadataCid   string `json:"metadata_cid" example:"dss"`
+	CollectionId  int64  `json:"collection_id" example:"1"`
+	Collection    string `json:"collection" example:"cool-cats"`
+	TokenStandard string `json:"token_standard" example:"erc721"`
+	// CollectionIpnsName is the IPNS name of the collection, empty until the collection publishes one
+	CollectionIpnsName string `json:"collection_ipns_name"`
+	// MaxSupply is the edition size, zero means unlimited
+	MaxSupply     int64 `json:"max_supply" example:"1"`
+	MintedSupply  int64 `json:"minted_supply" example:"0"`
+	CreatorId     int64 `json:"creator_id" example:"1"`
+	Attributes    []NftAttribute
 	CreatedAt     time.Time `json:"-"`
 	UpdatedAt     time.Time `json:"-"`
 	DeletedAt     time.Time `json:"-"`
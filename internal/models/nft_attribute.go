@@ -0,0 +1,118 @@
+package models
+
+import "time"
+
+// attribute value types
+const (
+	AttributeString = "string"
+	AttributeNumber = "number"
+)
+
+// trait filter operators
+const (
+	TraitEq  = "eq"
+	TraitGt  = "gt"
+	TraitGte = "gte"
+	TraitLt  = "lt"
+	TraitLte = "lte"
+)
+
+// NftAttribute represents a trait of an nft stored in nft_attributes
+type NftAttribute struct {
+	ID           int64   `json:"-"`
+	NftID        int64   `json:"-"`
+	TraitType    string  `json:"trait_type"`
+	DisplayType  string  `json:"display_type,omitempty"`
+	ValueType    string  `json:"-"`
+	StringValue  string  `json:"-"`
+	NumericValue float64 `json:"-"`
+}
+
+// Value returns the typed value of the attribute
+func (a *NftAttribute) Value() interface{} {
+	if a.ValueType == AttributeNumber {
+		return a.NumericValue
+	}
+	return a.StringValue
+}
+
+// Trait converts the attribute to the metadata format
+func (a *NftAttribute) Trait() NftMetadataTrait {
+	return NftMetadataTrait{
+		TraitType:   a.TraitType,
+		DisplayType: a.DisplayType,
+		Value:       a.Value(),
+	}
+}
+
+// TraitFilter is a condition on an nft attribute used by list queries.
+// Values of an equality filter are combined with OR, range filters have a single value.
+type TraitFilter struct {
+	TraitType string
+	Op        string
+	Values    []string
+}
+
+// NftCursor is the position of the last row of a page in the (created_at, id) order
+type NftCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        int64     `json:"id"`
+}
+
+// NftFilter holds the conditions of nft list queries
+type NftFilter struct {
+	// CollectionId limits the list to one collection, zero means any collection
+	CollectionId int64
+	// CreatorId limits the list to tokens created by the user, zero means any creator
+	CreatorId int64
+	// CreatedFrom and CreatedTo bound the creation time, zero values are not applied.
+	// CreatedFrom is inclusive, CreatedTo is exclusive.
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	// Available keeps only tokens whose edition is not sold out
+	Available bool
+	Traits    []TraitFilter
+	// Ascending sorts by (created_at, id) from old to new, newest first otherwise
+	Ascending bool
+	// After continues the list after the cursor position
+	After *NftCursor
+}
+
+// SnippetStartSel and SnippetStopSel mark matched words in search snippets produced by the database.
+// Control characters can not appear in user text, so the markers survive HTML escaping of the snippet.
+const (
+	SnippetStartSel = "\x02"
+	SnippetStopSel  = "\x03"
+)
+
+// SearchRankScale scales the search rank to the integer key search pages are ordered by. The float rank
+// is recomputed by every query, the rounded key compares exactly between pages, equal keys go in the id order.
+const SearchRankScale = 1_000_000
+
+// NftSearchCursor is the position of the last row of a search page in the (rank key, id) order
+type NftSearchCursor struct {
+	RankKey int64 `json:"r"`
+	ID      int64 `json:"id"`
+}
+
+// NftSearch is a full-text search over nft data
+type NftSearch struct {
+	// Text is the query in the web search syntax: words, "quoted phrases", -excluded, or
+	Text   string
+	Filter NftFilter
+	// After continues the search after the cursor position
+	After *NftSearchCursor
+}
+
+// NftSearchHit is a found nft with its rank and a highlighted fragment of the name and description
+type NftSearchHit struct {
+	Nft NftDataModel
+	// RankKey is the rank multiplied by SearchRankScale and rounded
+	RankKey int64
+	Snippet string
+}
+
+// Rank returns the rank of the hit rounded to 1/SearchRankScale
+func (h *NftSearchHit) Rank() float32 {
+	return float32(h.RankKey) / SearchRankScale
+}
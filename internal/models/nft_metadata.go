@@ -0,0 +1,17 @@
+package models
+
+// NftMetadata represents the ERC-721 metadata JSON document
+// Источник: https://eips.ethereum.org/EIPS/eip-721
+type NftMetadata struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Image       string             `json:"image"`
+	Attributes  []NftMetadataTrait `json:"attributes"`
+}
+
+// NftMetadataTrait represents a single entry of the metadata attributes list
+type NftMetadataTrait struct {
+	TraitType   string      `json:"trait_type,omitempty"`
+	DisplayType string      `json:"display_type,omitempty"`
+	Value       interface{} `json:"value"`
+}
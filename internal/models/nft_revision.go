@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// NftRevision is a snapshot of the nft state stored in nft_data_revisions after each change
+type NftRevision struct {
+	ID          int64
+	NftID       int64
+	Revision    int
+	AuthorId    int64
+	Name        string
+	Description string
+	CidV0       string
+	CidV1       string
+	FileName    string
+	MetadataCid string
+	Attributes  []NftMetadataTrait
+	// RollbackOf is the revision restored by this change, zero for regular changes
+	RollbackOf int
+	CreatedAt  time.Time
+}
+
+// NftChange describes who made a change of the nft and why
+type NftChange struct {
+	AuthorId   int64
+	RollbackOf int
+}
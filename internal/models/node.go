@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// NodeStats is a snapshot of the storage node state
+type NodeStats struct {
+	PeerId       string
+	AgentVersion string
+	Addresses    []string
+	Version      string
+	Commit       string
+	RepoVersion  string
+	System       string
+	Golang       string
+	RepoPath     string
+	RepoSize     uint64
+	// StorageMax is the repo size limit of the node config, zero when the node reports none
+	StorageMax  uint64
+	TotalIn     int64
+	TotalOut    int64
+	RateIn      float64
+	RateOut     float64
+	Peers       int
+	CollectedAt time.Time
+}
+
+// RepoFill returns the repo size to StorageMax ratio, zero when the limit is unknown
+func (s *NodeStats) RepoFill() float64 {
+	if s.StorageMax == 0 {
+		return 0
+	}
+	return float64(s.RepoSize) / float64(s.StorageMax)
+}
+
+// KuboNode is the health state of a Kubo node
+type KuboNode struct {
+	// Name is the RPC API URL of the node
+	Name    string
+	Primary bool
+	Healthy bool
+	// Error is the last failure of the node, empty while it is healthy
+	Error     string
+	PeerId    string
+	Addresses []string
+	CheckedAt time.Time
+}
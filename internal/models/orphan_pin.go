@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// OrphanPin is a pin of the storage which no database record references
+type OrphanPin struct {
+	ID  int64
+	Cid string
+	// FirstSeenAt is when the pin was first found orphaned, the grace period counts from it
+	FirstSeenAt time.Time
+	// Expired is set once the pin stayed orphaned longer than the grace period
+	Expired bool
+}
+
+// GcRun summarizes a pass of the orphan pin collection
+type GcRun struct {
+	StartedAt  time.Time
+	FinishedAt time.Time
+	DryRun     bool
+	// Pinned counts the recursive pins of the storage
+	Pinned  int
+	Orphans int
+	// Expired are the orphans older than the grace period, they are unpinned unless DryRun is set
+	Expired  []string
+	Unpinned []string
+	// Errors counts the expired orphans which could not be unpinned and failed removals from the MFS mirror
+	Errors int
+	// RemovedFiles counts the MFS mirror entries of the unpinned orphans
+	RemovedFiles int
+	// RemovedBlocks counts the blocks removed by the storage garbage collection
+	RemovedBlocks int
+	// Error tells why the run stopped early, empty when it completed
+	Error string
+}
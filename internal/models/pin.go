@@ -0,0 +1,78 @@
+package models
+
+import "time"
+
+// статусы закрепления, совпадают со статусами IPFS Pinning Service API
+const (
+	PinStatusQueued  = "queued"
+	PinStatusPinning = "pinning"
+	PinStatusPinned  = "pinned"
+	PinStatusFailed  = "failed"
+)
+
+// способы сравнения имени в фильтре закреплений
+const (
+	PinMatchExact    = "exact"
+	PinMatchIExact   = "iexact"
+	PinMatchPartial  = "partial"
+	PinMatchIPartial = "ipartial"
+)
+
+// Pin is a request to keep a CID pinned in the storage, driven to completion by the pinning worker
+type Pin struct {
+	ID        int64
+	RequestId string
+	Cid       string
+	Name      string
+	// Origins are multiaddrs of peers known to provide the content
+	Origins     []string
+	Meta        map[string]string
+	RequesterId int64
+	Status      string
+	Attempts    int
+	// Error is the reason of the last failed attempt
+	Error    string
+	PinnedAt time.Time
+	// NextAttemptAt is when a queued pin is picked up again
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// PinFilter holds the conditions of pin list queries
+type PinFilter struct {
+	RequesterId int64
+	// Cids limits the list to the CIDs, empty means any CID
+	Cids []string
+	// Name is compared according to Match, empty means any name
+	Name  string
+	Match string
+	// Statuses limits the list to pins in any of the statuses
+	Statuses []string
+	// Before and After bound the creation time exclusively, zero values are not applied
+	Before time.Time
+	After  time.Time
+	// Meta keeps pins whose meta contains all the pairs
+	Meta  map[string]string
+	Limit int
+}
+
+// PinReplica is a copy of a pin on a remote pinning service, driven to completion by the replicator
+type PinReplica struct {
+	ID    int64
+	PinID int64
+	// Cid and Name are taken from the pin
+	Cid    string
+	Name   string
+	Target string
+	// RemoteRequestId is the request id of the pin on the remote service, empty until it is added
+	RemoteRequestId string
+	Status          string
+	Attempts        int
+	// Error is the reason of the last failed attempt
+	Error         string
+	PinnedAt      time.Time
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Объекты IPFS Pinning Service API.
+// Источник: https://ipfs.github.io/pinning-services-api-spec/
+
+// RemotePin представляет объект Pin
+type RemotePin struct {
+	Cid     string            `json:"cid"`
+	Name    string            `json:"name,omitempty"`
+	Origins []string          `json:"origins,omitempty"`
+	Meta    map[string]string `json:"meta,omitempty"`
+}
+
+// RemotePinStatus представляет объект PinStatus
+type RemotePinStatus struct {
+	RequestId string            `json:"requestid"`
+	Status    string            `json:"status"`
+	Created   time.Time         `json:"created"`
+	Pin       RemotePin         `json:"pin"`
+	Delegates []string          `json:"delegates"`
+	Info      map[string]string `json:"info,omitempty"`
+}
+
+// RemotePinResults представляет объект PinResults
+type RemotePinResults struct {
+	Count   int               `json:"count"`
+	Results []RemotePinStatus `json:"results"`
+}
+
+// RemotePinFailure представляет объект Failure
+type RemotePinFailure struct {
+	Error RemotePinError `json:"error"`
+}
+
+// RemotePinError описание ошибки в объекте Failure
+type RemotePinError struct {
+	Reason  string `json:"reason"`
+	Details string `json:"details,omitempty"`
+}
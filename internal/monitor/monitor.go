@@ -0,0 +1,210 @@
+// Package monitor собирает состояние узла Kubo: идентификатор, версию, заполнение репозитория,
+// трафик и число пиров, и отдает его в формате метрик Prometheus.
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"main/internal/config"
+	"main/internal/models"
+	"main/tools/pkg/logger"
+)
+
+// ErrUnsupported возвращается, если контент хранится не на узле Kubo
+var ErrUnsupported = errors.New("storage node does not report stats")
+
+// NodeClient методы узла, из которых собирается состояние
+type NodeClient interface {
+	ID(ctx context.Context) (*models.IdResponse, error)
+	Version(ctx context.Context) (*models.VersionResponse, error)
+	RepoStat(ctx context.Context) (*models.RepoStatResponse, error)
+	StatsBw(ctx context.Context) (*models.StatsBwResponse, error)
+	SwarmPeers(ctx context.Context) (*models.SwarmPeersResponse, error)
+}
+
+// Monitor периодически собирает состояние узла, хранит последний снимок и предупреждает в логе,
+// когда репозиторий заполнен выше порога cfg.RepoFillWarning.
+type Monitor struct {
+	node   NodeClient
+	cfg    *config.Monitor
+	logger *logger.Logger
+
+	mu     sync.Mutex
+	last   *models.NodeStats
+	failed bool
+}
+
+// NewMonitor создает наблюдение за узлом. Если node равен nil, сбор состояния
+// возвращает ErrUnsupported, а метрики не пишутся.
+func NewMonitor(node NodeClient, cfg *config.Monitor, logger *logger.Logger) *Monitor {
+	return &Monitor{
+		node:   node,
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+// Run собирает состояние узла раз в cfg.Interval, пока не будет отменен ctx.
+// Нулевой интервал отключает опрос, тогда состояние собирается при запросе метрик.
+func (m *Monitor) Run(ctx context.Context) {
+	if m.node == nil || m.cfg.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := m.Collect(ctx); err != nil {
+			m.logger.Warn("Error collecting kubo node stats", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Collect запрашивает состояние узла, сохраняет его как последний снимок и проверяет заполнение репозитория
+func (m *Monitor) Collect(ctx context.Context) (*models.NodeStats, error) {
+	if m.node == nil {
+		return nil, ErrUnsupported
+	}
+
+	var (
+		id      *models.IdResponse
+		version *models.VersionResponse
+		repo    *models.RepoStatResponse
+		bw      *models.StatsBwResponse
+		peers   *models.SwarmPeersResponse
+	)
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.Go(func() (err error) { id, err = m.node.ID(groupCtx); return err })
+	group.Go(func() (err error) { version, err = m.node.Version(groupCtx); return err })
+	group.Go(func() (err error) { repo, err = m.node.RepoStat(groupCtx); return err })
+	group.Go(func() (err error) { bw, err = m.node.StatsBw(groupCtx); return err })
+	group.Go(func() (err error) { peers, err = m.node.SwarmPeers(groupCtx); return err })
+	if err := group.Wait(); err != nil {
+		m.mu.Lock()
+		m.failed = true
+		m.mu.Unlock()
+		return nil, err
+	}
+
+	stats := &models.NodeStats{
+		PeerId:       id.ID,
+		AgentVersion: id.AgentVersion,
+		Addresses:    id.Addresses,
+		Version:      version.Version,
+		Commit:       version.Commit,
+		RepoVersion:  version.Repo,
+		System:       version.System,
+		Golang:       version.Golang,
+		RepoPath:     repo.RepoPath,
+		RepoSize:     repo.RepoSize,
+		StorageMax:   repo.StorageMax,
+		TotalIn:      bw.TotalIn,
+		TotalOut:     bw.TotalOut,
+		RateIn:       bw.RateIn,
+		RateOut:      bw.RateOut,
+		Peers:        len(peers.Peers),
+		CollectedAt:  time.Now(),
+	}
+	if m.RepoAlmostFull(stats) {
+		m.logger.Warn("Kubo repo is almost full", "repo_size", stats.RepoSize, "storage_max", stats.StorageMax,
+			"fill", strconv.FormatFloat(stats.RepoFill(), 'f', 3, 64), "threshold", m.cfg.RepoFillWarning)
+	}
+
+	m.mu.Lock()
+	m.last, m.failed = stats, false
+	m.mu.Unlock()
+	return stats, nil
+}
+
+// RepoAlmostFull сообщает, что заполнение репозитория достигло порога cfg.RepoFillWarning
+func (m *Monitor) RepoAlmostFull(stats *models.NodeStats) bool {
+	return m.cfg.RepoFillWarning > 0 && stats.RepoFill() >= m.cfg.RepoFillWarning
+}
+
+// Latest возвращает последний снимок и признак того, что последний сбор завершился ошибкой
+func (m *Monitor) Latest() (*models.NodeStats, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.last == nil {
+		return nil, m.failed
+	}
+	stats := *m.last
+	return &stats, m.failed
+}
+
+// WriteMetrics пишет последний снимок в текстовом формате Prometheus. Если опрос выключен,
+// состояние собирается заново. kubo_up равна 0, когда узел не ответил при последнем сборе.
+func (m *Monitor) WriteMetrics(ctx context.Context, w io.Writer) error {
+	if m.node == nil {
+		return nil
+	}
+	if m.cfg.Interval <= 0 {
+		_, _ = m.Collect(ctx)
+	}
+	stats, failed := m.Latest()
+
+	metrics := &metricsWriter{w: w}
+	metrics.gauge("kubo_up", "Whether the last collection of the node stats succeeded.", nil, boolValue(!failed))
+	if stats != nil {
+		metrics.gauge("kubo_info", "Version of the node.",
+			[]string{"peer_id", stats.PeerId, "version", stats.Version, "repo_version", stats.RepoVersion}, 1)
+		metrics.gauge("kubo_repo_size_bytes", "Size of the node repo.", nil, float64(stats.RepoSize))
+		metrics.gauge("kubo_repo_storage_max_bytes", "StorageMax of the node config.", nil,
+			float64(stats.StorageMax))
+		metrics.gauge("kubo_repo_fill_ratio", "Repo size to StorageMax ratio.", nil, stats.RepoFill())
+		metrics.gauge("kubo_swarm_peers", "Number of connected peers.", nil, float64(stats.Peers))
+		metrics.gauge("kubo_bandwidth_total_in_bytes", "Bytes received by the node.", nil, float64(stats.TotalIn))
+		metrics.gauge("kubo_bandwidth_total_out_bytes", "Bytes sent by the node.", nil, float64(stats.TotalOut))
+		metrics.gauge("kubo_bandwidth_rate_in_bytes", "Receive rate in bytes per second.", nil, stats.RateIn)
+		metrics.gauge("kubo_bandwidth_rate_out_bytes", "Send rate in bytes per second.", nil, stats.RateOut)
+		metrics.gauge("kubo_stats_collected_timestamp_seconds", "Time of the last successful collection.", nil,
+			float64(stats.CollectedAt.Unix()))
+	}
+	return metrics.err
+}
+
+// metricsWriter пишет метрики в текстовом формате Prometheus и запоминает первую ошибку записи
+type metricsWriter struct {
+	w   io.Writer
+	err error
+}
+
+// gauge пишет метрику типа gauge, labels задаются парами имя, значение
+func (mw *metricsWriter) gauge(name, help string, labels []string, value float64) {
+	if mw.err != nil {
+		return
+	}
+	var pairs []string
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", labels[i], labels[i+1]))
+	}
+	series := name
+	if len(pairs) > 0 {
+		series += "{" + strings.Join(pairs, ",") + "}"
+	}
+	_, mw.err = fmt.Fprintf(mw.w, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", name, help, name, series,
+		strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+func boolValue(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,124 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"main/internal/config"
+	"main/internal/models"
+	"main/tools/pkg/logger"
+)
+
+// fakeNode узел с заданным состоянием, err возвращается из StatsBw
+type fakeNode struct {
+	repo models.RepoStatResponse
+	err  error
+}
+
+func (n *fakeNode) ID(context.Context) (*models.IdResponse, error) {
+	return &models.IdResponse{ID: "12D3KooWPeer", AgentVersion: "kubo/0.34.1/"}, nil
+}
+
+func (n *fakeNode) Version(context.Context) (*models.VersionResponse, error) {
+	return &models.VersionResponse{Version: "0.34.1", Repo: "16"}, nil
+}
+
+func (n *fakeNode) RepoStat(context.Context) (*models.RepoStatResponse, error) {
+	repo := n.repo
+	return &repo, nil
+}
+
+func (n *fakeNode) StatsBw(context.Context) (*models.StatsBwResponse, error) {
+	if n.err != nil {
+		return nil, n.err
+	}
+	return &models.StatsBwResponse{TotalIn: 10, TotalOut: 20, RateIn: 1.5, RateOut: 2.5}, nil
+}
+
+func (n *fakeNode) SwarmPeers(context.Context) (*models.SwarmPeersResponse, error) {
+	return &models.SwarmPeersResponse{Peers: []models.SwarmPeer{{Peer: "a"}, {Peer: "b"}, {Peer: "c"}}}, nil
+}
+
+func newTestMonitor(node NodeClient, cfg *config.Monitor, logs io.Writer) *Monitor {
+	return NewMonitor(node, cfg, &logger.Logger{Logger: slog.New(slog.NewTextHandler(logs, nil))})
+}
+
+func TestMonitorCollect(t *testing.T) {
+	var logs bytes.Buffer
+	node := &fakeNode{repo: models.RepoStatResponse{RepoSize: 950, StorageMax: 1000, RepoPath: "/data/ipfs"}}
+	m := newTestMonitor(node, &config.Monitor{RepoFillWarning: 0.9}, &logs)
+
+	stats, err := m.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if stats.PeerId != "12D3KooWPeer" || stats.Version != "0.34.1" || stats.Peers != 3 || stats.TotalOut != 20 {
+		t.Errorf("Collect() = %+v", stats)
+	}
+	if stats.RepoFill() != 0.95 || !m.RepoAlmostFull(stats) {
+		t.Errorf("RepoFill() = %v, expected 0.95 above the threshold", stats.RepoFill())
+	}
+	if !strings.Contains(logs.String(), "Kubo repo is almost full") {
+		t.Errorf("expected a repo fill warning, logs: %s", logs.String())
+	}
+
+	logs.Reset()
+	node.repo.RepoSize = 100
+	if _, err = m.Collect(context.Background()); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("unexpected logs below the threshold: %s", logs.String())
+	}
+}
+
+func TestMonitorWriteMetrics(t *testing.T) {
+	node := &fakeNode{repo: models.RepoStatResponse{RepoSize: 250, StorageMax: 1000}}
+	m := newTestMonitor(node, &config.Monitor{}, io.Discard)
+
+	var body bytes.Buffer
+	if err := m.WriteMetrics(context.Background(), &body); err != nil {
+		t.Fatalf("WriteMetrics() error = %v", err)
+	}
+	for _, line := range []string{
+		"# TYPE kubo_repo_size_bytes gauge",
+		"kubo_up 1",
+		`kubo_info{peer_id="12D3KooWPeer",version="0.34.1",repo_version="16"} 1`,
+		"kubo_repo_size_bytes 250",
+		"kubo_repo_storage_max_bytes 1000",
+		"kubo_repo_fill_ratio 0.25",
+		"kubo_swarm_peers 3",
+		"kubo_bandwidth_rate_out_bytes 2.5",
+	} {
+		if !strings.Contains(body.String(), line+"\n") {
+			t.Errorf("metrics do not contain %q:\n%s", line, body.String())
+		}
+	}
+
+	// после ошибки узла остается последний снимок, а kubo_up становится 0
+	node.err = errors.New("connection refused")
+	body.Reset()
+	if err := m.WriteMetrics(context.Background(), &body); err != nil {
+		t.Fatalf("WriteMetrics() error = %v", err)
+	}
+	if !strings.Contains(body.String(), "kubo_up 0\n") || !strings.Contains(body.String(), "kubo_swarm_peers 3\n") {
+		t.Errorf("unexpected metrics after a failed collection:\n%s", body.String())
+	}
+}
+
+func TestMonitorUnsupported(t *testing.T) {
+	m := newTestMonitor(nil, &config.Monitor{}, io.Discard)
+
+	if _, err := m.Collect(context.Background()); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Collect() error = %v, expected ErrUnsupported", err)
+	}
+	var body bytes.Buffer
+	if err := m.WriteMetrics(context.Background(), &body); err != nil || body.Len() != 0 {
+		t.Errorf("WriteMetrics() = %q, %v, expected no metrics", body.String(), err)
+	}
+}
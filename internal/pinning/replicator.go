@@ -0,0 +1,223 @@
+package pinning
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"main/internal/config"
+	"main/internal/models"
+	"main/internal/repository"
+	"main/tools/pkg/logger"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// сообщения об ошибках копий, которые не приходят от удаленного сервиса
+const (
+	targetMissingMessage = "remote target is not configured"
+	remoteLostMessage    = "pin request is lost by the remote service"
+	remoteFailedMessage  = "remote service failed to pin"
+)
+
+// RemotePinner удаленный сервис закреплений, реализуется service.RemotePinClient
+type RemotePinner interface {
+	Name() string
+	AddPin(ctx context.Context, pin *models.RemotePin) (*models.RemotePinStatus, error)
+	GetPin(ctx context.Context, requestId string) (*models.RemotePinStatus, error)
+}
+
+// Replicator копирует выполненные закрепления на удаленные сервисы закреплений: создает на них
+// запросы на закрепление, дожидается их выполнения и повторяет неудачные попытки с растущей задержкой.
+type Replicator struct {
+	replicas    repository.PinReplicaRepository
+	targets     []RemotePinner
+	cfg         *config.Pinning
+	replication *config.Replication
+	logger      *logger.Logger
+	wake        chan struct{}
+}
+
+// NewReplicator создает репликатор закреплений. Без targets репликатор ничего не делает.
+func NewReplicator(replicas repository.PinReplicaRepository, targets []RemotePinner, cfg *config.Pinning,
+	replication *config.Replication, logger *logger.Logger) *Replicator {
+	return &Replicator{
+		replicas:    replicas,
+		targets:     targets,
+		cfg:         cfg,
+		replication: replication,
+		logger:      logger,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Enqueue ставит в очередь копии выполненного закрепления
+func (r *Replicator) Enqueue(ctx context.Context, pin *models.Pin) error {
+	targets := r.Targets(pin.ID)
+	if len(targets) == 0 {
+		return nil
+	}
+	if err := r.replicas.CreateReplicas(ctx, pin.ID, targets); err != nil {
+		return err
+	}
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Targets выбирает сервисы для копий закрепления: replication.Copies сервисов подряд,
+// начиная со сдвига по pinId, чтобы закрепления распределялись между сервисами равномерно.
+// Без ограничения числа копий закрепление копируется на все сервисы.
+func (r *Replicator) Targets(pinId int64) []string {
+	count := len(r.targets)
+	if count == 0 {
+		return nil
+	}
+	copies := r.replication.Copies
+	if copies <= 0 || copies > count {
+		copies = count
+	}
+
+	names := make([]string, 0, copies)
+	for i := 0; i < copies; i++ {
+		names = append(names, r.targets[(int(pinId%int64(count))+i)%count].Name())
+	}
+	return names
+}
+
+// Run обрабатывает очередь копий, пока не будет отменен ctx
+func (r *Replicator) Run(ctx context.Context) {
+	if len(r.targets) == 0 {
+		return
+	}
+
+	poll := time.NewTicker(r.cfg.PollInterval)
+	defer poll.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			processed, err := r.ProcessQueue(ctx)
+			if err != nil {
+				r.logger.Error("Error processing replica queue", "error", err)
+				break
+			}
+			if processed == 0 {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+		case <-r.wake:
+		}
+	}
+}
+
+// ProcessQueue забирает до cfg.Workers копий, готовых к обработке, и обрабатывает их параллельно.
+// Возвращает число обработанных копий.
+func (r *Replicator) ProcessQueue(ctx context.Context) (int, error) {
+	// копия откладывается на время обработки, чтобы ее не забрал другой экземпляр сервиса
+	lease := r.replication.Timeout + r.replication.PollInterval
+	replicas, err := r.replicas.ClaimReplicas(ctx, max(r.cfg.Workers, 1), lease)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for i := range replicas {
+		wg.Add(1)
+		go func(replica *models.PinReplica) {
+			defer wg.Done()
+			r.process(ctx, replica)
+		}(&replicas[i])
+	}
+	wg.Wait()
+
+	return len(replicas), nil
+}
+
+// process создает копию на удаленном сервисе либо проверяет состояние созданной и сохраняет результат
+func (r *Replicator) process(ctx context.Context, replica *models.PinReplica) {
+	var retryAfter time.Duration
+
+	target := r.target(replica.Target)
+	if target == nil {
+		// сервис убран из конфига, копия больше не обрабатывается
+		replica.Status, replica.Error = models.PinStatusFailed, targetMissingMessage
+		r.save(ctx, replica, 0)
+		return
+	}
+
+	var status *models.RemotePinStatus
+	var err error
+	adding := replica.Status == models.PinStatusQueued || replica.RemoteRequestId == ""
+	if adding {
+		replica.Attempts++
+		status, err = target.AddPin(ctx, &models.RemotePin{Cid: replica.Cid, Name: replica.Name})
+	} else {
+		status, err = target.GetPin(ctx, replica.RemoteRequestId)
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		// репликатор остановлен, копия обрабатывается снова без задержки
+		ctx = context.WithoutCancel(ctx)
+	case !adding && errors.Is(err, tvoerrors.ErrNotFound):
+		retryAfter = r.retry(replica, remoteLostMessage)
+	case !adding && err != nil:
+		// сервис временно недоступен, состояние копии проверяется позже
+		replica.Error = err.Error()
+		retryAfter = r.replication.PollInterval
+	case err != nil:
+		retryAfter = r.retry(replica, err.Error())
+	case status.Status == models.PinStatusPinned:
+		replica.RemoteRequestId = status.RequestId
+		replica.Status, replica.Error = models.PinStatusPinned, ""
+	case status.Status == models.PinStatusFailed:
+		reason := remoteFailedMessage
+		if details := status.Info["status_details"]; details != "" {
+			reason = details
+		}
+		retryAfter = r.retry(replica, reason)
+	default:
+		// сервис принял запрос и закрепляет содержимое
+		replica.RemoteRequestId = status.RequestId
+		replica.Status, replica.Error = models.PinStatusPinning, ""
+		retryAfter = r.replication.PollInterval
+	}
+
+	r.save(ctx, replica, retryAfter)
+}
+
+// retry возвращает копию в очередь с растущей задержкой либо, после cfg.MaxAttempts попыток,
+// переводит ее в статус failed. Возвращает задержку перед следующей попыткой.
+func (r *Replicator) retry(replica *models.PinReplica, reason string) time.Duration {
+	replica.RemoteRequestId, replica.Error = "", reason
+	if replica.Attempts >= r.cfg.MaxAttempts {
+		replica.Status = models.PinStatusFailed
+		return 0
+	}
+	replica.Status = models.PinStatusQueued
+	return retryDelay(r.cfg, replica.Attempts)
+}
+
+func (r *Replicator) save(ctx context.Context, replica *models.PinReplica, retryAfter time.Duration) {
+	if err := r.replicas.UpdateReplica(ctx, replica, retryAfter); err != nil {
+		r.logger.Error("Error saving replica status", "pin_id", replica.PinID, "target", replica.Target,
+			"error", err)
+	}
+}
+
+func (r *Replicator) target(name string) RemotePinner {
+	for _, target := range r.targets {
+		if target.Name() == name {
+			return target
+		}
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package pinning
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"main/internal/config"
+	"main/internal/models"
+	"main/internal/service"
+	"main/tools/pkg/logger"
+)
+
+// fakePinReplicaRepository is an in-memory stand-in for the pin_replicas table.
+type fakePinReplicaRepository struct {
+	mu       sync.Mutex
+	replicas []models.PinReplica
+}
+
+func (r *fakePinReplicaRepository) CreateReplicas(_ context.Context, pinId int64, targets []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, target := range targets {
+		r.replicas = append(r.replicas, models.PinReplica{
+			ID:     int64(len(r.replicas) + 1),
+			PinID:  pinId,
+			Cid:    testReplicaCid,
+			Target: target,
+			Status: models.PinStatusQueued,
+		})
+	}
+	return nil
+}
+
+func (r *fakePinReplicaRepository) ClaimReplicas(_ context.Context, limit int,
+	lease time.Duration) ([]models.PinReplica, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	claimed := []models.PinReplica{}
+	for i := range r.replicas {
+		replica := &r.replicas[i]
+		due := !replica.NextAttemptAt.After(time.Now())
+		active := replica.Status == models.PinStatusQueued || replica.Status == models.PinStatusPinning
+		if len(claimed) < limit && due && active {
+			replica.NextAttemptAt = time.Now().Add(lease)
+			claimed = append(claimed, *replica)
+		}
+	}
+	return claimed, nil
+}
+
+func (r *fakePinReplicaRepository) UpdateReplica(_ context.Context, replica *models.PinReplica,
+	retryAfter time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	replica.NextAttemptAt = time.Now().Add(retryAfter)
+	r.replicas[replica.ID-1] = *replica
+	return nil
+}
+
+func (r *fakePinReplicaRepository) ReplicasByPinId(_ context.Context, _ int64) ([]models.PinReplica, error) {
+	return nil, nil
+}
+
+// due makes all replicas ready for the next attempt
+func (r *fakePinReplicaRepository) due() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.replicas {
+		r.replicas[i].NextAttemptAt = time.Time{}
+	}
+}
+
+func (r *fakePinReplicaRepository) replica(target string) models.PinReplica {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, replica := range r.replicas {
+		if replica.Target == target {
+			return replica
+		}
+	}
+	return models.PinReplica{}
+}
+
+const testReplicaCid = "bafybeihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku"
+
+// newRemotePinService поднимает стенд сервиса закреплений: POST /pins принимает запрос в очередь,
+// GET /pins/{requestid} отдает его закрепленным. С broken сервис отвечает ошибкой на любой запрос.
+func newRemotePinService(t *testing.T, broken bool) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"reason":"UNAUTHORIZED"}}`))
+			return
+		}
+		if broken {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"error":{"reason":"INTERNAL_SERVER_ERROR","details":"disk is full"}}`))
+			return
+		}
+
+		status := models.RemotePinStatus{RequestId: "remote-1", Created: time.Now(), Delegates: []string{}}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/pins":
+			if err := json.NewDecoder(r.Body).Decode(&status.Pin); err != nil || status.Pin.Cid != testReplicaCid {
+				t.Errorf("POST /pins body = %+v, %v", status.Pin, err)
+			}
+			status.Status = models.PinStatusQueued
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodGet && r.URL.Path == "/pins/remote-1":
+			status.Status = models.PinStatusPinned
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestReplicator(t *testing.T, copies int) (*Replicator, *fakePinReplicaRepository) {
+	good := newRemotePinService(t, false)
+	broken := newRemotePinService(t, true)
+
+	targets := []RemotePinner{
+		service.NewRemotePinClient(&config.RemoteTarget{Name: "good", Endpoint: good.URL + "/", Token: "secret"}, 0),
+		service.NewRemotePinClient(&config.RemoteTarget{Name: "broken", Endpoint: broken.URL, Token: "secret"}, 0),
+	}
+	repo := &fakePinReplicaRepository{}
+	cfg := &config.Pinning{Workers: 2, MaxAttempts: 2, RetryDelay: time.Minute, MaxRetryDelay: time.Hour}
+	replication := &config.Replication{Copies: copies, PollInterval: time.Minute}
+	l := &logger.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	return NewReplicator(repo, targets, cfg, replication, l), repo
+}
+
+func TestReplicatorProcessQueue(t *testing.T) {
+	replicator, repo := newTestReplicator(t, 0)
+	ctx := context.Background()
+
+	if err := replicator.Enqueue(ctx, &models.Pin{ID: 2, Cid: testReplicaCid}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if processed, err := replicator.ProcessQueue(ctx); err != nil || processed != 2 {
+		t.Fatalf("ProcessQueue() = %d, %v, expected 2", processed, err)
+	}
+
+	good := repo.replica("good")
+	if good.Status != models.PinStatusPinning || good.RemoteRequestId != "remote-1" || good.Attempts != 1 {
+		t.Errorf("good replica after add = %+v, expected pinning", good)
+	}
+	broken := repo.replica("broken")
+	if broken.Status != models.PinStatusQueued || !strings.Contains(broken.Error, "disk is full") {
+		t.Errorf("broken replica after add = %+v, expected queued with error", broken)
+	}
+
+	// копии ждут очередной проверки
+	if processed, _ := replicator.ProcessQueue(ctx); processed != 0 {
+		t.Errorf("ProcessQueue() before delay = %d, expected 0", processed)
+	}
+
+	repo.due()
+	if processed, _ := replicator.ProcessQueue(ctx); processed != 2 {
+		t.Errorf("ProcessQueue() after delay = %d, expected 2", processed)
+	}
+	if good = repo.replica("good"); good.Status != models.PinStatusPinned || good.Attempts != 1 {
+		t.Errorf("good replica after check = %+v, expected pinned", good)
+	}
+	if broken = repo.replica("broken"); broken.Status != models.PinStatusFailed || broken.Attempts != 2 {
+		t.Errorf("broken replica after last attempt = %+v, expected failed", broken)
+	}
+}
+
+func TestReplicatorTargets(t *testing.T) {
+	tests := []struct {
+		copies   int
+		pinId    int64
+		expected []string
+	}{
+		{0, 1, []string{"broken", "good"}},
+		{1, 1, []string{"broken"}},
+		{1, 2, []string{"good"}},
+		{5, 2, []string{"good", "broken"}},
+	}
+
+	for _, test := range tests {
+		replicator, _ := newTestReplicator(t, test.copies)
+		if result := replicator.Targets(test.pinId); !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("Targets(%d) with %d copies = %v, expected %v", test.pinId, test.copies, result, test.expected)
+		}
+	}
+}
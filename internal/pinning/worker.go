@@ -0,0 +1,240 @@
+package pinning
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+
+	"main/internal/config"
+	"main/internal/models"
+	"main/internal/repository"
+	"main/internal/storage"
+	"main/tools/pkg/logger"
+)
+
+// Worker доводит закрепления из таблицы pins до конца: забирает очередь, повторяет неудачные
+// попытки с растущей задержкой и периодически сверяет таблицу со списком закреплений хранилища.
+type Worker struct {
+	pins       repository.PinRepository
+	storage    storage.Storage
+	replicator *Replicator
+	cfg        *config.Pinning
+	logger     *logger.Logger
+	wake       chan struct{}
+}
+
+// NewWorker создает воркер закреплений. Выполненные закрепления передаются replicator
+// для копирования на удаленные сервисы, replicator может быть nil.
+func NewWorker(pins repository.PinRepository, storage storage.Storage, replicator *Replicator, cfg *config.Pinning,
+	logger *logger.Logger) *Worker {
+	return &Worker{
+		pins:       pins,
+		storage:    storage,
+		replicator: replicator,
+		cfg:        cfg,
+		logger:     logger,
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// Notify будит воркер после постановки закрепления в очередь, не дожидаясь очередного опроса
+func (w *Worker) Notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run обрабатывает очередь и сверяет таблицу с хранилищем, пока не будет отменен ctx.
+// Нулевой интервал отключает опрос очереди или сверку: без опроса очередь обрабатывается только по Notify.
+func (w *Worker) Run(ctx context.Context) {
+	// из nil канала ничего не приходит, так что отключенный тикер просто не срабатывает
+	var poll, reconcile <-chan time.Time
+	if w.cfg.PollInterval > 0 {
+		ticker := time.NewTicker(w.cfg.PollInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+	if w.cfg.ReconcileInterval > 0 {
+		ticker := time.NewTicker(w.cfg.ReconcileInterval)
+		defer ticker.Stop()
+		reconcile = ticker.C
+		w.reconcile(ctx)
+	}
+
+	for {
+		w.drain(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll:
+		case <-w.wake:
+		case <-reconcile:
+			w.reconcile(ctx)
+		}
+	}
+}
+
+// ProcessQueue забирает из очереди до cfg.Workers закреплений и выполняет их параллельно.
+// Возвращает число обработанных закреплений.
+func (w *Worker) ProcessQueue(ctx context.Context) (int, error) {
+	pins, err := w.pins.ClaimPins(ctx, max(w.cfg.Workers, 1))
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for i := range pins {
+		wg.Add(1)
+		go func(pin *models.Pin) {
+			defer wg.Done()
+			w.process(ctx, pin)
+		}(&pins[i])
+	}
+	wg.Wait()
+
+	return len(pins), nil
+}
+
+// Reconcile сверяет таблицу pins со списком закреплений хранилища: пропавшие закрепления
+// возвращаются в очередь, а ожидающие и неудачные, которые уже есть в хранилище, считаются выполненными.
+// Закрепления, зависшие в статусе pinning дольше cfg.StaleAfter, также возвращаются в очередь.
+func (w *Worker) Reconcile(ctx context.Context) error {
+	stale, err := w.pins.RequeueStalePins(ctx, w.cfg.StaleAfter)
+	if err != nil {
+		return err
+	}
+
+	cids, err := w.storage.List(ctx)
+	if err != nil {
+		return err
+	}
+	// CID сравниваются по multihash, чтобы CIDv0 и CIDv1 одного содержимого совпадали
+	stored := make(map[string]struct{}, len(cids))
+	for _, c := range cids {
+		stored[string(c.Hash())] = struct{}{}
+	}
+
+	pins, err := w.pins.PinsByStatus(ctx, models.PinStatusPinned, models.PinStatusQueued, models.PinStatusFailed)
+	if err != nil {
+		return err
+	}
+
+	var requeued, confirmed int
+	for i := range pins {
+		pin := &pins[i]
+		c, err := cid.Decode(pin.Cid)
+		if err != nil {
+			continue
+		}
+		_, present := stored[string(c.Hash())]
+
+		switch {
+		case pin.Status == models.PinStatusPinned && !present:
+			// пропавшее закрепление начинает попытки заново, иначе попытки, потраченные
+			// на первое закрепление, оставили бы ему одну
+			w.logger.Warn("Pin is missing in the storage, queued again", "request_id", pin.RequestId, "cid", pin.Cid)
+			if err = w.pins.RequeuePin(ctx, pin); err != nil {
+				return err
+			}
+			requeued++
+		case pin.Status != models.PinStatusPinned && present:
+			pin.Status, pin.Error = models.PinStatusPinned, ""
+			if err = w.pins.UpdatePin(ctx, pin, 0); err != nil {
+				return err
+			}
+			w.replicate(ctx, pin)
+			confirmed++
+		}
+	}
+
+	w.logger.Info("Pins reconciled", "stale", stale, "requeued", requeued, "confirmed", confirmed)
+	return nil
+}
+
+// RetryDelay задержка перед следующей попыткой после attempt неудачных:
+// cfg.RetryDelay удваивается с каждой попыткой, но не превышает cfg.MaxRetryDelay
+func (w *Worker) RetryDelay(attempt int) time.Duration {
+	return retryDelay(w.cfg, attempt)
+}
+
+// drain обрабатывает очередь, пока в ней есть закрепления, готовые к попытке
+func (w *Worker) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		processed, err := w.ProcessQueue(ctx)
+		if err != nil {
+			w.logger.Error("Error processing pin queue", "error", err)
+			return
+		}
+		if processed == 0 {
+			return
+		}
+	}
+}
+
+func (w *Worker) reconcile(ctx context.Context) {
+	if err := w.Reconcile(ctx); err != nil && ctx.Err() == nil {
+		w.logger.Error("Error reconciling pins", "error", err)
+	}
+}
+
+// process выполняет одну попытку закрепления и сохраняет ее результат
+func (w *Worker) process(ctx context.Context, pin *models.Pin) {
+	var retryAfter time.Duration
+
+	// подключение к источникам ускоряет поиск содержимого, но не обязательно для закрепления
+	if connector, ok := w.storage.(storage.Connector); ok && len(pin.Origins) > 0 {
+		if err := connector.Connect(ctx, pin.Origins); err != nil {
+			w.logger.Warn("Error connecting to pin origins", "request_id", pin.RequestId, "error", err)
+		}
+	}
+
+	c, err := cid.Decode(pin.Cid)
+	if err == nil {
+		err = w.storage.Pin(ctx, c)
+	}
+
+	switch {
+	case err == nil:
+		pin.Status, pin.Error = models.PinStatusPinned, ""
+	case ctx.Err() != nil:
+		// воркер остановлен, попытка возвращается в очередь без задержки
+		pin.Status, pin.Error = models.PinStatusQueued, ""
+		ctx = context.WithoutCancel(ctx)
+	case pin.Attempts >= w.cfg.MaxAttempts:
+		pin.Status, pin.Error = models.PinStatusFailed, err.Error()
+	default:
+		pin.Status, pin.Error = models.PinStatusQueued, err.Error()
+		retryAfter = w.RetryDelay(pin.Attempts)
+	}
+
+	if err = w.pins.UpdatePin(ctx, pin, retryAfter); err != nil {
+		w.logger.Error("Error saving pin status", "request_id", pin.RequestId, "error", err)
+		return
+	}
+	if pin.Status == models.PinStatusPinned {
+		w.replicate(ctx, pin)
+	}
+}
+
+// replicate ставит в очередь копии выполненного закрепления на удаленных сервисах
+func (w *Worker) replicate(ctx context.Context, pin *models.Pin) {
+	if w.replicator == nil {
+		return
+	}
+	if err := w.replicator.Enqueue(ctx, pin); err != nil {
+		w.logger.Error("Error queueing pin replicas", "request_id", pin.RequestId, "error", err)
+	}
+}
+
+// retryDelay задержка перед следующей попыткой после attempt неудачных
+func retryDelay(cfg *config.Pinning, attempt int) time.Duration {
+	delay := cfg.RetryDelay
+	for i := 1; i < attempt && delay < cfg.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, cfg.MaxRetryDelay)
+}
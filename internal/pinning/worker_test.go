@@ -0,0 +1,255 @@
+package pinning
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"main/internal/config"
+	"main/internal/models"
+	"main/internal/storage"
+	"main/tools/pkg/logger"
+)
+
+// fakePinRepository is an in-memory stand-in for the pins table.
+type fakePinRepository struct {
+	mu   sync.Mutex
+	pins []models.Pin
+}
+
+func (r *fakePinRepository) CreatePin(_ context.Context, pin *models.Pin) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pin.ID = int64(len(r.pins) + 1)
+	pin.Status = models.PinStatusQueued
+	r.pins = append(r.pins, *pin)
+	return nil
+}
+
+func (r *fakePinRepository) PinByRequestId(_ context.Context, requestId string) (*models.Pin, error) {
+	return nil, nil
+}
+
+func (r *fakePinRepository) ClaimPins(_ context.Context, limit int) ([]models.Pin, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	claimed := []models.Pin{}
+	for i := range r.pins {
+		pin := &r.pins[i]
+		if len(claimed) < limit && pin.Status == models.PinStatusQueued && !pin.NextAttemptAt.After(time.Now()) {
+			pin.Status = models.PinStatusPinning
+			pin.Attempts++
+			claimed = append(claimed, *pin)
+		}
+	}
+	return claimed, nil
+}
+
+func (r *fakePinRepository) UpdatePin(_ context.Context, pin *models.Pin, retryAfter time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pin.NextAttemptAt = time.Now().Add(retryAfter)
+	r.pins[pin.ID-1] = *pin
+	return nil
+}
+
+func (r *fakePinRepository) PinsByStatus(_ context.Context, statuses ...string) ([]models.Pin, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pins := []models.Pin{}
+	for _, pin := range r.pins {
+		for _, status := range statuses {
+			if pin.Status == status {
+				pins = append(pins, pin)
+			}
+		}
+	}
+	return pins, nil
+}
+
+func (r *fakePinRepository) RequeueStalePins(_ context.Context, _ time.Duration) (int64, error) {
+	return 0, nil
+}
+
+func (r *fakePinRepository) RequeuePin(_ context.Context, pin *models.Pin) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pin.Status, pin.Error, pin.Attempts, pin.NextAttemptAt = models.PinStatusQueued, "", 0, time.Time{}
+	r.pins[pin.ID-1] = *pin
+	return nil
+}
+
+func (r *fakePinRepository) DeleteUserPins(_ context.Context, _ int64, _ []string) (int64, error) {
+	return 0, nil
+}
+
+func (r *fakePinRepository) ListPins(_ context.Context, _ *models.PinFilter) ([]models.Pin, int, error) {
+	return nil, 0, nil
+}
+
+func (r *fakePinRepository) ReplacePin(_ context.Context, _ int64, _ *models.Pin) error {
+	return nil
+}
+
+func (r *fakePinRepository) DeletePin(_ context.Context, _ int64) error {
+	return nil
+}
+
+func (r *fakePinRepository) PinCountByCid(_ context.Context, _ string) (int64, error) {
+	return 0, nil
+}
+
+func (r *fakePinRepository) pin(id int64) models.Pin {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pins[id-1]
+}
+
+func newTestWorker(t *testing.T) (*Worker, *fakePinRepository, storage.Storage) {
+	s, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStorage() error = %v", err)
+	}
+
+	repo := &fakePinRepository{}
+	cfg := &config.Pinning{Workers: 2, MaxAttempts: 2, RetryDelay: time.Minute, MaxRetryDelay: time.Hour}
+	return NewWorker(repo, s, nil, cfg, &logger.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}), repo, s
+}
+
+func TestWorkerProcessQueue(t *testing.T) {
+	worker, repo, s := newTestWorker(t)
+	ctx := context.Background()
+
+	object, err := s.Add(ctx, "hello.txt", strings.NewReader("hello world"))
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	stored := &models.Pin{Cid: object.Cid.String()}
+	missing := &models.Pin{Cid: "QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn"}
+	for _, pin := range []*models.Pin{stored, missing} {
+		if err = repo.CreatePin(ctx, pin); err != nil {
+			t.Fatalf("CreatePin() error = %v", err)
+		}
+	}
+
+	if processed, err := worker.ProcessQueue(ctx); err != nil || processed != 2 {
+		t.Fatalf("ProcessQueue() = %d, %v, expected 2", processed, err)
+	}
+	if pin := repo.pin(stored.ID); pin.Status != models.PinStatusPinned {
+		t.Errorf("stored pin status = %s, expected pinned", pin.Status)
+	}
+	pin := repo.pin(missing.ID)
+	if pin.Status != models.PinStatusQueued || pin.Error == "" || pin.Attempts != 1 {
+		t.Errorf("missing pin after first attempt = %+v, expected queued with error", pin)
+	}
+
+	// повторная попытка откладывается до истечения задержки
+	if processed, _ := worker.ProcessQueue(ctx); processed != 0 {
+		t.Errorf("ProcessQueue() before retry delay = %d, expected 0", processed)
+	}
+
+	pin.NextAttemptAt = time.Time{}
+	_ = repo.UpdatePin(ctx, &pin, 0)
+	if processed, _ := worker.ProcessQueue(ctx); processed != 1 {
+		t.Errorf("ProcessQueue() after retry delay = %d, expected 1", processed)
+	}
+	if pin = repo.pin(missing.ID); pin.Status != models.PinStatusFailed || pin.Attempts != 2 {
+		t.Errorf("missing pin after last attempt = %+v, expected failed", pin)
+	}
+}
+
+func TestWorkerReconcile(t *testing.T) {
+	worker, repo, s := newTestWorker(t)
+	ctx := context.Background()
+
+	lost, _ := s.Add(ctx, "lost.txt", strings.NewReader("lost"))
+	found, _ := s.Add(ctx, "found.txt", strings.NewReader("found"))
+
+	lostPin := &models.Pin{Cid: lost.Cid.String()}
+	foundPin := &models.Pin{Cid: found.CidV1().String()}
+	_ = repo.CreatePin(ctx, lostPin)
+	_ = repo.CreatePin(ctx, foundPin)
+	// закрепление потратило все попытки, прежде чем его содержимое нашлось
+	repo.pins[0].Status, repo.pins[0].Attempts, repo.pins[0].Error = models.PinStatusPinned, 2, "timeout"
+	repo.pins[1].Status = models.PinStatusFailed
+
+	if err := s.Unpin(ctx, lost.Cid); err != nil {
+		t.Fatalf("Unpin() error = %v", err)
+	}
+
+	if err := worker.Reconcile(ctx); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if pin := repo.pin(lostPin.ID); pin.Status != models.PinStatusQueued || pin.Error != "" || pin.Attempts != 0 {
+		t.Errorf("lost pin = %+v, expected queued with no attempts", pin)
+	}
+	if pin := repo.pin(foundPin.ID); pin.Status != models.PinStatusPinned || pin.Error != "" {
+		t.Errorf("found pin = %+v, expected pinned", pin)
+	}
+
+	// после сброса у пропавшего закрепления снова есть повторы
+	if processed, _ := worker.ProcessQueue(ctx); processed != 1 {
+		t.Errorf("ProcessQueue() = %d, expected the lost pin", processed)
+	}
+	if pin := repo.pin(lostPin.ID); pin.Status != models.PinStatusQueued || pin.Attempts != 1 {
+		t.Errorf("lost pin after a failed attempt = %+v, expected queued for a retry", pin)
+	}
+}
+
+func TestWorkerRunWithoutIntervals(t *testing.T) {
+	worker, repo, s := newTestWorker(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	object, _ := s.Add(ctx, "hello.txt", strings.NewReader("hello world"))
+
+	// нулевые интервалы отключают опрос и сверку, очередь обрабатывается по Notify
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		worker.Run(ctx)
+	}()
+	pin := &models.Pin{Cid: object.Cid.String()}
+	_ = repo.CreatePin(ctx, pin)
+	worker.Notify()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for repo.pin(pin.ID).Status != models.PinStatusPinned {
+		if time.Now().After(deadline) {
+			t.Fatalf("pin = %+v, expected pinned after Notify", repo.pin(pin.ID))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+}
+
+func TestWorkerRetryDelay(t *testing.T) {
+	worker := &Worker{cfg: &config.Pinning{RetryDelay: 30 * time.Second, MaxRetryDelay: 5 * time.Minute}}
+
+	tests := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{4, 4 * time.Minute},
+		{5, 5 * time.Minute},
+		{100, 5 * time.Minute},
+	}
+
+	for _, test := range tests {
+		if result := worker.RetryDelay(test.attempt); result != test.expected {
+			t.Errorf("RetryDelay(%d) = %s, expected %s", test.attempt, result, test.expected)
+		}
+	}
+}
@@ -2,12 +2,17 @@ package repository
 
 import (
 	"context"
+	"fmt"
 	"main/internal/dto"
 	"time"
 
 	"main/internal/models"
+	tvoerrors "main/tools/pkg/tvo_errors"
 )
 
+// ErrDuplicateContent is returned when a new nft has the content of another not deleted nft.
+var ErrDuplicateContent = fmt.Errorf("%w: content already belongs to an nft", tvoerrors.ErrConflict)
+
 // RoleRepository provides methods for managing roles.
 type RoleRepository interface {
 	RoleByName(ctx context.Context, roleName string) (*models.Role, error)
@@ -41,7 +46,79 @@ type UserRepository interface {
 
 type NftDataRepository interface {
 	CreateNftData(ctx context.Context, nftData *dto.NftData) error
-	ReadNftData(ctx context.Context, tokenId int64) (models.NftDataModel, error)
-	ReadAllNftData(ctx context.Context, limit int) ([]models.NftDataModel, error)
-	TokenIdExists(ctx context.Context, tokenId int64) (bool, error)
+	ReadNftData(ctx context.Context, collectionId, tokenId int64) (models.NftDataModel, error)
+	ReadNftDataByCid(ctx context.Context, cidv0, cidv1 string) (models.NftDataModel, error)
+	ReadAllNftData(ctx context.Context, limit int, filter *models.NftFilter) ([]models.NftDataModel, error)
+	SearchNftData(ctx context.Context, search *models.NftSearch, limit int) ([]models.NftSearchHit, error)
+	TokenIdExists(ctx context.Context, collectionId, tokenId int64) (bool, error)
+	MintEdition(ctx context.Context, collectionId, tokenId, amount int64) (models.NftDataModel, error)
+	UpdateNftData(ctx context.Context, nft *models.NftDataModel, change models.NftChange) error
+	DeleteNftData(ctx context.Context, collectionId, tokenId int64) (models.NftDataModel, error)
+	ReadDeletedNftData(ctx context.Context, collectionId, tokenId int64) (models.NftDataModel, error)
+	RestoreNftData(ctx context.Context, collectionId, tokenId int64) (models.NftDataModel, error)
+	CidReferenced(ctx context.Context, cid string) (bool, error)
+	CollectionCids(ctx context.Context, collectionId int64) ([]string, error)
+	ContentCids(ctx context.Context) ([]string, error)
+	ReadNftRevisions(ctx context.Context, nftId int64) ([]models.NftRevision, error)
+	ReadNftRevision(ctx context.Context, nftId int64, revision int) (models.NftRevision, error)
+}
+
+// CollectionRepository provides methods for managing nft collections.
+type CollectionRepository interface {
+	CreateCollection(ctx context.Context, collection *models.Collection) error
+	CollectionBySlug(ctx context.Context, slug string) (*models.Collection, error)
+	ReadAllCollections(ctx context.Context, limit int) ([]models.Collection, error)
+	UpdateCollection(ctx context.Context, collection *models.Collection) error
+	DeleteCollection(ctx context.Context, id int64) error
+}
+
+// PinRepository provides methods for tracking pin requests.
+type PinRepository interface {
+	CreatePin(ctx context.Context, pin *models.Pin) error
+	PinByRequestId(ctx context.Context, requestId string) (*models.Pin, error)
+	ClaimPins(ctx context.Context, limit int) ([]models.Pin, error)
+	UpdatePin(ctx context.Context, pin *models.Pin, retryAfter time.Duration) error
+	PinsByStatus(ctx context.Context, statuses ...string) ([]models.Pin, error)
+	RequeueStalePins(ctx context.Context, olderThan time.Duration) (int64, error)
+	RequeuePin(ctx context.Context, pin *models.Pin) error
+	DeleteUserPins(ctx context.Context, requesterId int64, cids []string) (int64, error)
+	ListPins(ctx context.Context, filter *models.PinFilter) ([]models.Pin, int, error)
+	ReplacePin(ctx context.Context, oldId int64, pin *models.Pin) error
+	DeletePin(ctx context.Context, id int64) error
+	PinCountByCid(ctx context.Context, cid string) (int64, error)
+}
+
+// PinReplicaRepository provides methods for tracking copies of pins on remote pinning services.
+type PinReplicaRepository interface {
+	CreateReplicas(ctx context.Context, pinId int64, targets []string) error
+	ClaimReplicas(ctx context.Context, limit int, lease time.Duration) ([]models.PinReplica, error)
+	UpdateReplica(ctx context.Context, replica *models.PinReplica, retryAfter time.Duration) error
+	ReplicasByPinId(ctx context.Context, pinId int64) ([]models.PinReplica, error)
+}
+
+// DirectoryRepository provides methods for recording directory uploads.
+type DirectoryRepository interface {
+	CreateDirectoryUpload(ctx context.Context, upload *models.DirectoryUpload) error
+	DirectoryUploadByRootCid(ctx context.Context, rootCid string) (*models.DirectoryUpload, error)
+}
+
+// ContentCheckRepository provides methods for recording content integrity checks.
+type ContentCheckRepository interface {
+	SaveCheck(ctx context.Context, check *models.ContentCheck) error
+	FailedChecks(ctx context.Context, limit int) ([]models.ContentCheck, error)
+}
+
+// IpnsRepository provides methods for tracking IPNS records of collections.
+type IpnsRepository interface {
+	IpnsRecordByCollection(ctx context.Context, collectionId int64) (*models.IpnsRecord, error)
+	SaveIpnsRecord(ctx context.Context, record *models.IpnsRecord) error
+	ExpiringIpnsRecords(ctx context.Context, before time.Time, limit int) ([]models.IpnsRecord, error)
+}
+
+// OrphanPinRepository provides methods for tracking storage pins no record references.
+type OrphanPinRepository interface {
+	ReferencedCids(ctx context.Context) ([]string, error)
+	TrackOrphans(ctx context.Context, cids []string, gracePeriod time.Duration) ([]models.OrphanPin, error)
+	DeleteOrphan(ctx context.Context, cid string) error
+	Orphans(ctx context.Context, gracePeriod time.Duration, limit int) ([]models.OrphanPin, error)
 }
@@ -0,0 +1,147 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"main/internal/models"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// uniqueViolation is the PostgreSQL error code of a unique constraint violation
+const uniqueViolation = "23505"
+
+const collectionColumns = `id, slug, name, symbol, description, cover_cid, contract_address, chain_id,
+	COALESCE(owner_id, 0), token_standard, created_at`
+
+// CollectionRepository handles collection-related operations in PostgreSQL.
+type CollectionRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewCollectionRepository creates a new instance of CollectionRepository with the given PostgreSQL connection pool.
+func NewCollectionRepository(db *pgxpool.Pool) *CollectionRepository {
+	return &CollectionRepository{
+		db: db,
+	}
+}
+
+// CreateCollection saves a new collection. A taken slug or contract address returns ErrConflict.
+func (cr *CollectionRepository) CreateCollection(ctx context.Context, collection *models.Collection) error {
+	const op = "postgresql.CollectionRepository.CreateCollection"
+
+	query := `INSERT INTO collections (slug, name, symbol, description, cover_cid, contract_address, chain_id, owner_id,
+		token_standard) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, created_at`
+	if err := cr.db.QueryRow(ctx, query, collection.Slug, collection.Name, collection.Symbol, collection.Description,
+		collection.CoverCid, collection.ContractAddress, collection.ChainId, collection.OwnerId,
+		collection.TokenStandard).
+		Scan(&collection.ID, &collection.CreatedAt); err != nil {
+		if isUniqueViolation(err) {
+			return tvoerrors.Wrap(op, tvoerrors.ErrConflict)
+		}
+		return tvoerrors.Wrap(op, err)
+	}
+	return nil
+}
+
+// CollectionBySlug retrieves a collection by its slug.
+func (cr *CollectionRepository) CollectionBySlug(ctx context.Context, slug string) (*models.Collection, error) {
+	const op = "postgresql.CollectionRepository.CollectionBySlug"
+
+	query := "SELECT " + collectionColumns + " FROM collections WHERE slug = $1 AND deleted_at IS NULL;"
+	collection, err := scanCollection(cr.db.QueryRow(ctx, query, slug))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+		}
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	return collection, nil
+}
+
+// ReadAllCollections takes collections ordered by creation time.
+func (cr *CollectionRepository) ReadAllCollections(ctx context.Context, limit int) ([]models.Collection, error) {
+	const op = "postgresql.CollectionRepository.ReadAllCollections"
+
+	query := "SELECT " + collectionColumns + ` FROM collections WHERE deleted_at IS NULL
+		ORDER BY created_at DESC, id DESC LIMIT $1;`
+	rows, err := cr.db.Query(ctx, query, limit)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	defer rows.Close()
+
+	collections := []models.Collection{}
+	for rows.Next() {
+		collection, err := scanCollection(rows)
+		if err != nil {
+			return nil, tvoerrors.Wrap(op, err)
+		}
+		collections = append(collections, *collection)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	return collections, nil
+}
+
+// UpdateCollection saves the mutable fields of the collection.
+func (cr *CollectionRepository) UpdateCollection(ctx context.Context, collection *models.Collection) error {
+	const op = "postgresql.CollectionRepository.UpdateCollection"
+
+	query := `UPDATE collections SET name = $1, symbol = $2, description = $3, cover_cid = $4, contract_address = $5,
+		chain_id = $6, updated_at = $7 WHERE id = $8 AND deleted_at IS NULL;`
+	result, err := cr.db.Exec(ctx, query, collection.Name, collection.Symbol, collection.Description,
+		collection.CoverCid, collection.ContractAddress, collection.ChainId, time.Now().UTC(), collection.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return tvoerrors.Wrap(op, tvoerrors.ErrConflict)
+		}
+		return tvoerrors.Wrap(op, err)
+	}
+
+	if result.RowsAffected() != 1 {
+		return tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+	}
+	return nil
+}
+
+// DeleteCollection marks the collection as deleted. Collections that still have tokens can't be deleted.
+func (cr *CollectionRepository) DeleteCollection(ctx context.Context, id int64) error {
+	const op = "postgresql.CollectionRepository.DeleteCollection"
+
+	query := `UPDATE collections SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL
+		AND NOT EXISTS (SELECT 1 FROM nft_data WHERE collection_id = $2 AND deleted_at IS NULL);`
+	result, err := cr.db.Exec(ctx, query, time.Now().UTC(), id)
+	if err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+
+	if result.RowsAffected() != 1 {
+		return tvoerrors.Wrap(op, tvoerrors.ErrObjectWasNotDelete)
+	}
+	return nil
+}
+
+// scanCollection reads a row selected with collectionColumns
+func scanCollection(row pgx.Row) (*models.Collection, error) {
+	var collection models.Collection
+	if err := row.Scan(&collection.ID, &collection.Slug, &collection.Name, &collection.Symbol,
+		&collection.Description, &collection.CoverCid, &collection.ContractAddress, &collection.ChainId,
+		&collection.OwnerId, &collection.TokenStandard, &collection.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &collection, nil
+}
+
+// isUniqueViolation reports whether the error is a unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
@@ -0,0 +1,79 @@
+package postgresql
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"main/internal/models"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// ContentCheckRepository handles content integrity checks in PostgreSQL.
+type ContentCheckRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewContentCheckRepository creates a new instance of ContentCheckRepository with the given PostgreSQL connection pool.
+func NewContentCheckRepository(db *pgxpool.Pool) *ContentCheckRepository {
+	return &ContentCheckRepository{
+		db: db,
+	}
+}
+
+// SaveCheck records the latest check of the CID. The failure time is kept while the CID keeps failing
+// and cleared once it passes.
+func (cr *ContentCheckRepository) SaveCheck(ctx context.Context, check *models.ContentCheck) error {
+	const op = "postgresql.ContentCheckRepository.SaveCheck"
+
+	query := `INSERT INTO content_checks (cid, status, error, source, checked_at, failed_at)
+		VALUES ($1, $2, $3, $4, now(), CASE WHEN $2 <> 'ok' THEN now() END)
+		ON CONFLICT (cid) DO UPDATE SET status = EXCLUDED.status, error = EXCLUDED.error,
+			source = EXCLUDED.source, checked_at = EXCLUDED.checked_at,
+			failed_at = CASE WHEN EXCLUDED.status = 'ok' THEN NULL
+				ELSE COALESCE(content_checks.failed_at, EXCLUDED.failed_at) END
+		RETURNING id, checked_at, failed_at;`
+	var failedAt *time.Time
+	if err := cr.db.QueryRow(ctx, query, check.Cid, check.Status, check.Error, check.Source).Scan(&check.ID,
+		&check.CheckedAt, &failedAt); err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+	check.FailedAt = timeOrZero(failedAt)
+	return nil
+}
+
+// FailedChecks retrieves the CIDs failing their latest check, the most recent failures first,
+// with the tokens referencing them.
+func (cr *ContentCheckRepository) FailedChecks(ctx context.Context, limit int) ([]models.ContentCheck, error) {
+	const op = "postgresql.ContentCheckRepository.FailedChecks"
+
+	query := `SELECT cc.id, cc.cid, cc.status, cc.error, cc.source, cc.checked_at, cc.failed_at,
+			ARRAY(SELECT COALESCE(col.slug || '/', '') || n.token_id FROM nft_data n
+				LEFT JOIN collections col ON col.id = n.collection_id
+				WHERE (n.cidv0 = cc.cid OR n.metadata_cid = cc.cid) AND n.deleted_at IS NULL
+				ORDER BY 1)
+		FROM content_checks cc WHERE cc.status <> 'ok'
+		ORDER BY cc.failed_at DESC, cc.id DESC LIMIT $1;`
+	rows, err := cr.db.Query(ctx, query, limit)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	defer rows.Close()
+
+	checks := []models.ContentCheck{}
+	for rows.Next() {
+		var check models.ContentCheck
+		var failedAt *time.Time
+		if err = rows.Scan(&check.ID, &check.Cid, &check.Status, &check.Error, &check.Source, &check.CheckedAt,
+			&failedAt, &check.Tokens); err != nil {
+			return nil, tvoerrors.Wrap(op, err)
+		}
+		check.FailedAt = timeOrZero(failedAt)
+		checks = append(checks, check)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	return checks, nil
+}
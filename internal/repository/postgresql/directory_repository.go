@@ -0,0 +1,99 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"main/internal/models"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// DirectoryRepository handles directory uploads in PostgreSQL.
+type DirectoryRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewDirectoryRepository creates a new instance of DirectoryRepository with the given PostgreSQL connection pool.
+func NewDirectoryRepository(db *pgxpool.Pool) *DirectoryRepository {
+	return &DirectoryRepository{
+		db: db,
+	}
+}
+
+// CreateDirectoryUpload saves the upload together with its files and subdirectories.
+func (dr *DirectoryRepository) CreateDirectoryUpload(ctx context.Context, upload *models.DirectoryUpload) error {
+	const op = "postgresql.DirectoryRepository.CreateDirectoryUpload"
+
+	tx, err := dr.db.Begin(ctx)
+	if err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	query := `INSERT INTO directory_uploads (root_cid, size, collection_id, uploader_id) VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+	if err = tx.QueryRow(ctx, query, upload.RootCid, upload.Size, nullableId(upload.CollectionId),
+		nullableId(upload.UploaderId)).Scan(&upload.ID, &upload.CreatedAt); err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+
+	count := len(upload.Files)
+	paths, cids, sizes := make([]string, 0, count), make([]string, 0, count), make([]int64, 0, count)
+	directories, tokenIds := make([]bool, 0, count), make([]*int64, 0, count)
+	for _, file := range upload.Files {
+		paths, cids, sizes = append(paths, file.Path), append(cids, file.Cid), append(sizes, file.Size)
+		directories, tokenIds = append(directories, file.Directory), append(tokenIds, file.TokenId)
+	}
+
+	query = `INSERT INTO directory_upload_files (upload_id, path, cid, size, directory, token_id)
+		SELECT $1, * FROM unnest($2::varchar[], $3::varchar[], $4::bigint[], $5::boolean[], $6::bigint[]);`
+	if _, err = tx.Exec(ctx, query, upload.ID, paths, cids, sizes, directories, tokenIds); err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+	return nil
+}
+
+// DirectoryUploadByRootCid retrieves the latest upload of the root directory with its files ordered by path.
+func (dr *DirectoryRepository) DirectoryUploadByRootCid(ctx context.Context,
+	rootCid string) (*models.DirectoryUpload, error) {
+	const op = "postgresql.DirectoryRepository.DirectoryUploadByRootCid"
+
+	var upload models.DirectoryUpload
+	query := `SELECT id, root_cid, size, COALESCE(collection_id, 0), COALESCE(uploader_id, 0), created_at
+		FROM directory_uploads WHERE root_cid = $1 ORDER BY created_at DESC, id DESC LIMIT 1;`
+	if err := dr.db.QueryRow(ctx, query, rootCid).Scan(&upload.ID, &upload.RootCid, &upload.Size,
+		&upload.CollectionId, &upload.UploaderId, &upload.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+		}
+		return nil, tvoerrors.Wrap(op, err)
+	}
+
+	query = `SELECT path, cid, size, directory, token_id FROM directory_upload_files
+		WHERE upload_id = $1 ORDER BY path;`
+	rows, err := dr.db.Query(ctx, query, upload.ID)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	defer rows.Close()
+
+	upload.Files = []models.DirectoryFile{}
+	for rows.Next() {
+		var file models.DirectoryFile
+		if err = rows.Scan(&file.Path, &file.Cid, &file.Size, &file.Directory, &file.TokenId); err != nil {
+			return nil, tvoerrors.Wrap(op, err)
+		}
+		upload.Files = append(upload.Files, file)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	return &upload, nil
+}
@@ -0,0 +1,97 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"main/internal/models"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+const ipnsColumns = `ipns_records.id, ipns_records.collection_id, ipns_records.key_name, ipns_records.name,
+	ipns_records.value, ipns_records.sequence, ipns_records.published_at, ipns_records.expires_at,
+	ipns_records.created_at`
+
+// IpnsRepository handles IPNS records of collections in PostgreSQL.
+type IpnsRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewIpnsRepository creates a new instance of IpnsRepository with the given PostgreSQL connection pool.
+func NewIpnsRepository(db *pgxpool.Pool) *IpnsRepository {
+	return &IpnsRepository{
+		db: db,
+	}
+}
+
+// IpnsRecordByCollection retrieves the IPNS record of the collection.
+func (ir *IpnsRepository) IpnsRecordByCollection(ctx context.Context, collectionId int64) (*models.IpnsRecord, error) {
+	const op = "postgresql.IpnsRepository.IpnsRecordByCollection"
+
+	query := "SELECT " + ipnsColumns + " FROM ipns_records WHERE collection_id = $1;"
+	var record models.IpnsRecord
+	if err := scanIpnsRecord(ir.db.QueryRow(ctx, query, collectionId), &record); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+		}
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	return &record, nil
+}
+
+// SaveIpnsRecord records a publication of the collection record, every publication after the first one
+// increments the sequence.
+func (ir *IpnsRepository) SaveIpnsRecord(ctx context.Context, record *models.IpnsRecord) error {
+	const op = "postgresql.IpnsRepository.SaveIpnsRecord"
+
+	query := `INSERT INTO ipns_records (collection_id, key_name, name, value, published_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (collection_id) DO UPDATE SET key_name = EXCLUDED.key_name, name = EXCLUDED.name,
+			value = EXCLUDED.value, sequence = ipns_records.sequence + 1,
+			published_at = EXCLUDED.published_at, expires_at = EXCLUDED.expires_at
+		RETURNING id, sequence, created_at;`
+	if err := ir.db.QueryRow(ctx, query, record.CollectionId, record.KeyName, record.Name, record.Value,
+		record.PublishedAt, record.ExpiresAt).Scan(&record.ID, &record.Sequence, &record.CreatedAt); err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+	return nil
+}
+
+// ExpiringIpnsRecords takes records of live collections expiring before the time, the earliest first.
+func (ir *IpnsRepository) ExpiringIpnsRecords(ctx context.Context, before time.Time,
+	limit int) ([]models.IpnsRecord, error) {
+	const op = "postgresql.IpnsRepository.ExpiringIpnsRecords"
+
+	query := "SELECT " + ipnsColumns + ` FROM ipns_records
+		JOIN collections ON collections.id = ipns_records.collection_id
+		WHERE ipns_records.expires_at < $1 AND collections.deleted_at IS NULL
+		ORDER BY ipns_records.expires_at LIMIT $2;`
+	rows, err := ir.db.Query(ctx, query, before, limit)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	defer rows.Close()
+
+	records := []models.IpnsRecord{}
+	for rows.Next() {
+		var record models.IpnsRecord
+		if err = scanIpnsRecord(rows, &record); err != nil {
+			return nil, tvoerrors.Wrap(op, err)
+		}
+		records = append(records, record)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	return records, nil
+}
+
+// scanIpnsRecord reads a row selected with ipnsColumns
+func scanIpnsRecord(row pgx.Row, record *models.IpnsRecord) error {
+	return row.Scan(&record.ID, &record.CollectionId, &record.KeyName, &record.Name, &record.Value, &record.Sequence,
+		&record.PublishedAt, &record.ExpiresAt, &record.CreatedAt)
+}
@@ -2,14 +2,35 @@ package postgresql
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"main/internal/dto"
 	"main/internal/models"
+	"main/internal/repository"
 	tvoerrors "main/tools/pkg/tvo_errors"
 )
 
+// revisionColumns are the columns of nft_data_revisions read by scanRevision
+const revisionColumns = `id, nft_id, revision, COALESCE(author_id, 0), name, description, cidv0, cidv1,
+	COALESCE(file_name, ''), metadata_cid, attributes, COALESCE(rollback_of, 0), created_at`
+
+// nftColumns are the columns of nft_data joined with the collection slug and IPNS name
+const nftColumns = `nft_data.id, nft_data.token_id, nft_data.name, nft_data.content, nft_data.cidv0, nft_data.cidv1,
+	nft_data.metadata_cid, COALESCE(nft_data.collection_id, 0), COALESCE(collections.slug, ''),
+	COALESCE(collections.token_standard, 'erc721'), COALESCE(ipns_records.name, ''), nft_data.max_supply,
+	nft_data.minted_supply, COALESCE(nft_data.creator_id, 0), COALESCE(nft_data.file_name, ''), nft_data.created_at`
+
+// nftFrom joins nft_data with its collection and the IPNS record of the collection
+const nftFrom = ` FROM nft_data LEFT JOIN collections ON collections.id = nft_data.collection_id
+	LEFT JOIN ipns_records ON ipns_records.collection_id = nft_data.collection_id`
+
 // NftDataRepository handles nft-related operations in PostgreSQL.
 type NftDataRepository struct {
 	db *pgxpool.Pool
@@ -22,41 +43,112 @@ func NewNftDataRepository(db *pgxpool.Pool) *NftDataRepository {
 	}
 }
 
-// CreateNftData saves a new nft data
+// CreateNftData saves a new nft data together with its attributes.
+// Fails with repository.ErrDuplicateContent if a not deleted nft has the same content.
 func (ur *NftDataRepository) CreateNftData(ctx context.Context, data *dto.NftData) error {
 	const op = "postgresql.NftDataRepository.CreateNftData"
 	var nft models.NftDataModel
 
-	query := "INSERT INTO nft_data (token_id, content, cidv0, cidv1, file_size, file_name) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id"
-	if err := ur.db.QueryRow(ctx, query, data.TokenId, data.Description, data.CidV0, data.CidV1, data.FileSize, data.FileName).
-		Scan(&nft.ID); err != nil {
+	tx, err := ur.db.Begin(ctx)
+	if err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Concurrent uploads of the same content are serialized by a transaction lock on the CID, so the check
+	// sees the nft committed by the other upload. There is no row to lock with FOR UPDATE yet, and older
+	// nfts may already share content, which rules out a unique index.
+	if _, err = tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtextextended('nft_data.cidv1:' || $1, 0));",
+		data.CidV1); err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+	var duplicate bool
+	if err = tx.QueryRow(ctx, `SELECT EXISTS(SELECT id FROM nft_data WHERE ((cidv0 = $1 AND $1 <> '') OR cidv1 = $2)
+		AND deleted_at IS NULL);`, data.CidV0, data.CidV1).Scan(&duplicate); err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+	if duplicate {
+		return tvoerrors.Wrap(op, repository.ErrDuplicateContent)
+	}
+
+	query := `INSERT INTO nft_data (token_id, name, content, cidv0, cidv1, file_size, file_name, metadata_cid,
+		collection_id, max_supply, creator_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`
+	if err = tx.QueryRow(ctx, query, data.TokenId, data.Name, data.Description, data.CidV0, data.CidV1, data.FileSize,
+		data.FileName, data.MetadataCid, nullableId(data.CollectionId), data.MaxSupply,
+		nullableId(data.AuthorId)).Scan(&nft.ID); err != nil {
+		if isUniqueViolation(err) {
+			return tvoerrors.Wrap(op, tvoerrors.ErrConflict)
+		}
+		return tvoerrors.Wrap(op, err)
+	}
+
+	if err = insertAttributes(ctx, tx, nft.ID, data.Attributes); err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+	if err = refreshSearchVector(ctx, tx, nft.ID); err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+
+	nft.Name, nft.Description, nft.CidV0, nft.CidV1, nft.MetadataCid = data.Name, data.Description, data.CidV0, data.CidV1,
+		data.MetadataCid
+	nft.FileName = data.FileName
+	nft.Attributes = data.Attributes
+	if err = insertRevision(ctx, tx, &nft, models.NftChange{AuthorId: data.AuthorId}); err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
 		return tvoerrors.Wrap(op, err)
 	}
 	return nil
 }
 
-// ReadNftData takes one nft data
-func (ur *NftDataRepository) ReadNftData(ctx context.Context, tokenId int64) (models.NftDataModel, error) {
+// ReadNftData takes one nft data of the collection, zero collectionId means a token without collection
+func (ur *NftDataRepository) ReadNftData(ctx context.Context, collectionId, tokenId int64) (models.NftDataModel, error) {
 	const op = "postgresql.NftDataRepository.ReadNftData"
 	var nft models.NftDataModel
-	query := "SELECT token_id, content, cidv0, cidv1  FROM nft_data where token_id = $1 LIMIT 1;"
+	query := "SELECT " + nftColumns + nftFrom +
+		` WHERE nft_data.collection_id IS NOT DISTINCT FROM $1::bigint AND nft_data.token_id = $2
+		AND nft_data.deleted_at IS NULL LIMIT 1;`
 
-	if err := ur.db.QueryRow(ctx, query, tokenId).Scan(
-		&nft.TokenId, &nft.Description, &nft.CidV0, &nft.CidV1); err != nil {
-		if !errors.Is(err, pgx.ErrNoRows) {
-			return nft, tvoerrors.Wrap("postgresql.NftDataRepository.ReadNftData", err)
+	if err := scanNft(ur.db.QueryRow(ctx, query, nullableId(collectionId), tokenId), &nft); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nft, tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
 		}
+		return nft, tvoerrors.Wrap(op, err)
 	}
 
+	attributes, err := ur.attributesByNftIds(ctx, []int64{nft.ID})
+	if err != nil {
+		return nft, tvoerrors.Wrap(op, err)
+	}
+	nft.Attributes = attributes[nft.ID]
+
 	return nft, nil
 }
 
-// ReadAllNftData takes all nft data
-func (ur *NftDataRepository) ReadAllNftData(ctx context.Context, limit int) ([]models.NftDataModel, error) {
-	const op = "postgresql.NftDataRepository.ReadNftData"
-	query := "SELECT token_id, content, cidv0, cidv1  FROM nft_data LIMIT $1;"
+// ReadAllNftData takes a page of nft data matching the filter in the (created_at, id) order.
+// The page continues after filter.After when it is set.
+func (ur *NftDataRepository) ReadAllNftData(ctx context.Context, limit int, filter *models.NftFilter) ([]models.NftDataModel, error) {
+	const op = "postgresql.NftDataRepository.ReadAllNftData"
+
+	conditions, args := listConditions(filter, nil)
+
+	direction, order := "<", "DESC"
+	if filter.Ascending {
+		direction, order = ">", "ASC"
+	}
+	if filter.After != nil {
+		args = append(args, filter.After.CreatedAt, filter.After.ID)
+		conditions = append(conditions, fmt.Sprintf("(nft_data.created_at, nft_data.id) %s ($%d, $%d)",
+			direction, len(args)-1, len(args)))
+	}
+	args = append(args, limit)
+
+	query := "SELECT " + nftColumns + nftFrom + " WHERE " + strings.Join(conditions, " AND ") +
+		fmt.Sprintf(" ORDER BY nft_data.created_at %s, nft_data.id %s LIMIT $%d;", order, order, len(args))
 
-	rows, err := ur.db.Query(ctx, query, limit)
+	rows, err := ur.db.Query(ctx, query, args...)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return []models.NftDataModel{}, nil
@@ -66,30 +158,600 @@ func (ur *NftDataRepository) ReadAllNftData(ctx context.Context, limit int) ([]m
 	defer rows.Close()
 
 	var nfts []models.NftDataModel
+	var ids []int64
 	for rows.Next() {
 		var nft models.NftDataModel
-		if err := rows.Scan(&nft.TokenId, &nft.Description, &nft.CidV0, &nft.CidV1); err != nil {
+		if err := scanNft(rows, &nft); err != nil {
 			return nil, tvoerrors.Wrap(op, err)
 		}
 		nfts = append(nfts, nft)
+		ids = append(ids, nft.ID)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, tvoerrors.Wrap(op, err)
 	}
 
+	attributes, err := ur.attributesByNftIds(ctx, ids)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	for i := range nfts {
+		nfts[i].Attributes = attributes[nfts[i].ID]
+	}
+
 	return nfts, nil
 }
 
-// TokenIdExists checks if a nft data exists by its token iD.
-func (ur *NftDataRepository) TokenIdExists(ctx context.Context, tokenId int64) (bool, error) {
+// SearchNftData takes a page of nft data matching the full-text query in the (rank key, id) order.
+// The query is parsed with both russian and english configs, so that either stemming matches.
+// The rank is rounded to an integer key once per row, so the cursor compares with it exactly.
+func (ur *NftDataRepository) SearchNftData(ctx context.Context, search *models.NftSearch,
+	limit int) ([]models.NftSearchHit, error) {
+	const op = "postgresql.NftDataRepository.SearchNftData"
+
+	conditions, args := listConditions(&search.Filter, []interface{}{search.Text})
+	conditions = append(conditions, "nft_data.search_vector @@ q.query")
+
+	if search.After != nil {
+		args = append(args, search.After.RankKey, search.After.ID)
+		conditions = append(conditions, fmt.Sprintf("(r.rank_key, nft_data.id) < ($%d::bigint, $%d)",
+			len(args)-1, len(args)))
+	}
+	args = append(args, fmt.Sprintf(`StartSel="%s", StopSel="%s", MaxFragments=2, MaxWords=25, MinWords=8,
+		FragmentDelimiter=" ... "`, models.SnippetStartSel, models.SnippetStopSel), limit)
+
+	query := `WITH q AS (SELECT websearch_to_tsquery('russian', $1) || websearch_to_tsquery('english', $1) AS query)
+		SELECT ` + nftColumns + fmt.Sprintf(`, r.rank_key,
+			ts_headline('russian', concat_ws('. ', NULLIF(nft_data.name, ''), nft_data.content), q.query, $%d)`,
+		len(args)-1) + nftFrom + fmt.Sprintf(` CROSS JOIN q CROSS JOIN LATERAL (SELECT
+			round(ts_rank_cd(nft_data.search_vector, q.query)::numeric * %d)::bigint AS rank_key) r`,
+		models.SearchRankScale) + " WHERE " + strings.Join(conditions, " AND ") +
+		fmt.Sprintf(" ORDER BY r.rank_key DESC, nft_data.id DESC LIMIT $%d;", len(args))
+
+	rows, err := ur.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	defer rows.Close()
+
+	var hits []models.NftSearchHit
+	var ids []int64
+	for rows.Next() {
+		var hit models.NftSearchHit
+		if err := scanNft(rows, &hit.Nft, &hit.RankKey, &hit.Snippet); err != nil {
+			return nil, tvoerrors.Wrap(op, err)
+		}
+		hits = append(hits, hit)
+		ids = append(ids, hit.Nft.ID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+
+	attributes, err := ur.attributesByNftIds(ctx, ids)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	for i := range hits {
+		hits[i].Nft.Attributes = attributes[hits[i].Nft.ID]
+	}
+
+	return hits, nil
+}
+
+// UpdateNftData saves the name, description, content CIDs and file name, metadata CID and attributes of the nft
+// and records the new state as a revision. Attributes are replaced as a whole.
+func (ur *NftDataRepository) UpdateNftData(ctx context.Context, nft *models.NftDataModel, change models.NftChange) error {
+	const op = "postgresql.NftDataRepository.UpdateNftData"
+
+	tx, err := ur.db.Begin(ctx)
+	if err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	query := `UPDATE nft_data SET name = $1, content = $2, cidv0 = $3, cidv1 = $4, file_name = $5, metadata_cid = $6,
+		updated_at = $7 WHERE id = $8 AND deleted_at IS NULL;`
+	result, err := tx.Exec(ctx, query, nft.Name, nft.Description, nft.CidV0, nft.CidV1, nft.FileName, nft.MetadataCid,
+		time.Now().UTC(), nft.ID)
+	if err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+	if result.RowsAffected() != 1 {
+		return tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+	}
+
+	if _, err = tx.Exec(ctx, "DELETE FROM nft_attributes WHERE nft_id = $1;", nft.ID); err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+	if err = insertAttributes(ctx, tx, nft.ID, nft.Attributes); err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+	if err = refreshSearchVector(ctx, tx, nft.ID); err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+	if err = insertRevision(ctx, tx, nft, change); err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+	return nil
+}
+
+// ReadNftRevisions takes the change history of the nft, newest first
+func (ur *NftDataRepository) ReadNftRevisions(ctx context.Context, nftId int64) ([]models.NftRevision, error) {
+	const op = "postgresql.NftDataRepository.ReadNftRevisions"
+
+	query := "SELECT " + revisionColumns + " FROM nft_data_revisions WHERE nft_id = $1 ORDER BY revision DESC;"
+	rows, err := ur.db.Query(ctx, query, nftId)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	defer rows.Close()
+
+	revisions := []models.NftRevision{}
+	for rows.Next() {
+		var revision models.NftRevision
+		if err = scanRevision(rows, &revision); err != nil {
+			return nil, tvoerrors.Wrap(op, err)
+		}
+		revisions = append(revisions, revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	return revisions, nil
+}
+
+// ReadNftRevision takes one revision of the nft
+func (ur *NftDataRepository) ReadNftRevision(ctx context.Context, nftId int64, revision int) (models.NftRevision, error) {
+	const op = "postgresql.NftDataRepository.ReadNftRevision"
+	var result models.NftRevision
+
+	query := "SELECT " + revisionColumns + " FROM nft_data_revisions WHERE nft_id = $1 AND revision = $2;"
+	if err := scanRevision(ur.db.QueryRow(ctx, query, nftId, revision), &result); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return result, tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+		}
+		return result, tvoerrors.Wrap(op, err)
+	}
+	return result, nil
+}
+
+// DeleteNftData marks the nft as deleted and returns the deleted row.
+func (ur *NftDataRepository) DeleteNftData(ctx context.Context, collectionId, tokenId int64) (models.NftDataModel, error) {
+	const op = "postgresql.NftDataRepository.DeleteNftData"
+
+	nft, err := ur.ReadNftData(ctx, collectionId, tokenId)
+	if err != nil {
+		return nft, tvoerrors.Wrap(op, err)
+	}
+
+	query := "UPDATE nft_data SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL;"
+	result, err := ur.db.Exec(ctx, query, time.Now().UTC(), nft.ID)
+	if err != nil {
+		return nft, tvoerrors.Wrap(op, err)
+	}
+	// the token was deleted concurrently
+	if result.RowsAffected() != 1 {
+		return nft, tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+	}
+	return nft, nil
+}
+
+// ReadDeletedNftData takes the most recently deleted nft with the token id, the one RestoreNftData restores.
+// Attributes are not loaded.
+func (ur *NftDataRepository) ReadDeletedNftData(ctx context.Context, collectionId, tokenId int64) (models.NftDataModel, error) {
+	const op = "postgresql.NftDataRepository.ReadDeletedNftData"
+	var nft models.NftDataModel
+	query := "SELECT " + nftColumns + nftFrom +
+		` WHERE nft_data.collection_id IS NOT DISTINCT FROM $1::bigint AND nft_data.token_id = $2
+		AND nft_data.deleted_at IS NOT NULL ORDER BY nft_data.deleted_at DESC LIMIT 1;`
+
+	if err := scanNft(ur.db.QueryRow(ctx, query, nullableId(collectionId), tokenId), &nft); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nft, tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+		}
+		return nft, tvoerrors.Wrap(op, err)
+	}
+	return nft, nil
+}
+
+// RestoreNftData restores the most recently deleted nft with the token id.
+// Fails with ErrConflict if the token id was issued again after deletion.
+func (ur *NftDataRepository) RestoreNftData(ctx context.Context, collectionId, tokenId int64) (models.NftDataModel, error) {
+	const op = "postgresql.NftDataRepository.RestoreNftData"
+
+	var id int64
+	var exists bool
+	now := time.Now().UTC()
+
+	tx, err := ur.db.Begin(ctx)
+	if err != nil {
+		return models.NftDataModel{}, tvoerrors.Wrap(op, err)
+	}
+
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	query := `SELECT id FROM nft_data WHERE collection_id IS NOT DISTINCT FROM $1::bigint AND token_id = $2
+		AND deleted_at IS NOT NULL ORDER BY deleted_at DESC LIMIT 1 FOR UPDATE;`
+
+	if err = tx.QueryRow(ctx, query, nullableId(collectionId), tokenId).Scan(&id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.NftDataModel{}, tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+		}
+		return models.NftDataModel{}, tvoerrors.Wrap(op, err)
+	}
+
+	// the token id must not be issued again since the deletion
+	query = `SELECT EXISTS (SELECT id FROM nft_data WHERE collection_id IS NOT DISTINCT FROM $1::bigint
+		AND token_id = $2 AND deleted_at IS NULL);`
+	if err = tx.QueryRow(ctx, query, nullableId(collectionId), tokenId).Scan(&exists); err != nil {
+		return models.NftDataModel{}, tvoerrors.Wrap(op, err)
+	}
+
+	if exists {
+		return models.NftDataModel{}, tvoerrors.Wrap(op, tvoerrors.ErrConflict)
+	}
+
+	query = "UPDATE nft_data SET deleted_at = NULL, updated_at = $1 WHERE id = $2;"
+
+	result, err := tx.Exec(ctx, query, now, id)
+	if err != nil {
+		return models.NftDataModel{}, tvoerrors.Wrap(op, err)
+	}
+
+	if result.RowsAffected() != 1 {
+		return models.NftDataModel{}, tvoerrors.Wrap(op, tvoerrors.ErrUpdateFailed)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return models.NftDataModel{}, tvoerrors.Wrap(op, err)
+	}
+
+	return ur.ReadNftData(ctx, collectionId, tokenId)
+}
+
+// ReadNftDataByCid takes the earliest not deleted nft whose content has one of the CIDs.
+func (ur *NftDataRepository) ReadNftDataByCid(ctx context.Context, cidv0, cidv1 string) (models.NftDataModel, error) {
+	const op = "postgresql.NftDataRepository.ReadNftDataByCid"
+	var nft models.NftDataModel
+	query := "SELECT " + nftColumns + nftFrom +
+		` WHERE ((nft_data.cidv0 = $1 AND $1 <> '') OR nft_data.cidv1 = $2) AND nft_data.deleted_at IS NULL
+		ORDER BY nft_data.created_at, nft_data.id LIMIT 1;`
+
+	if err := scanNft(ur.db.QueryRow(ctx, query, cidv0, cidv1), &nft); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nft, tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+		}
+		return nft, tvoerrors.Wrap(op, err)
+	}
+
+	attributes, err := ur.attributesByNftIds(ctx, []int64{nft.ID})
+	if err != nil {
+		return nft, tvoerrors.Wrap(op, err)
+	}
+	nft.Attributes = attributes[nft.ID]
+
+	return nft, nil
+}
+
+// CidReferenced checks if a not deleted nft refers to the CID as its content or metadata.
+func (ur *NftDataRepository) CidReferenced(ctx context.Context, cid string) (bool, error) {
+	const op = "postgresql.NftDataRepository.CidReferenced"
+
+	// ревизии живых токенов тоже держат контент, к ним можно откатиться
+	query := `SELECT EXISTS(SELECT id FROM nft_data WHERE (cidv0 = $1 OR cidv1 = $1 OR metadata_cid = $1)
+		AND deleted_at IS NULL) OR EXISTS(SELECT r.id FROM nft_data_revisions r JOIN nft_data n ON n.id = r.nft_id
+		WHERE (r.cidv0 = $1 OR r.cidv1 = $1 OR r.metadata_cid = $1) AND n.deleted_at IS NULL);`
+	var exists bool
+	if err := ur.db.QueryRow(ctx, query, cid).Scan(&exists); err != nil {
+		return false, tvoerrors.Wrap(op, err)
+	}
+	return exists, nil
+}
+
+// CollectionCids returns the content and metadata CIDs of live tokens of the collection in token order.
+// Every CID is returned once.
+func (ur *NftDataRepository) CollectionCids(ctx context.Context, collectionId int64) ([]string, error) {
+	const op = "postgresql.NftDataRepository.CollectionCids"
+
+	query := `SELECT cid FROM (
+			SELECT DISTINCT ON (cid) cid, token_id, kind FROM (
+				SELECT cidv0 AS cid, token_id, 0 AS kind FROM nft_data
+				WHERE collection_id = $1 AND deleted_at IS NULL
+				UNION ALL
+				SELECT metadata_cid, token_id, 1 FROM nft_data
+				WHERE collection_id = $1 AND deleted_at IS NULL AND metadata_cid <> ''
+			) cids ORDER BY cid, token_id, kind
+		) first ORDER BY token_id, kind;`
+	rows, err := ur.db.Query(ctx, query, collectionId)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	defer rows.Close()
+
+	cids := []string{}
+	for rows.Next() {
+		var cid string
+		if err = rows.Scan(&cid); err != nil {
+			return nil, tvoerrors.Wrap(op, err)
+		}
+		cids = append(cids, cid)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	return cids, nil
+}
+
+// ContentCids returns the content and metadata CIDs of all live tokens, every CID once.
+func (ur *NftDataRepository) ContentCids(ctx context.Context) ([]string, error) {
+	const op = "postgresql.NftDataRepository.ContentCids"
+
+	query := `SELECT cidv0 FROM nft_data WHERE deleted_at IS NULL AND cidv0 <> ''
+		UNION
+		SELECT metadata_cid FROM nft_data WHERE deleted_at IS NULL AND metadata_cid <> ''
+		ORDER BY 1;`
+	rows, err := ur.db.Query(ctx, query)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	defer rows.Close()
+
+	cids := []string{}
+	for rows.Next() {
+		var cid string
+		if err = rows.Scan(&cid); err != nil {
+			return nil, tvoerrors.Wrap(op, err)
+		}
+		cids = append(cids, cid)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	return cids, nil
+}
+
+// MintEdition increases the minted supply of the token by amount.
+// Exceeding the max supply returns ErrConflict.
+func (ur *NftDataRepository) MintEdition(ctx context.Context, collectionId, tokenId, amount int64) (models.NftDataModel, error) {
+	const op = "postgresql.NftDataRepository.MintEdition"
+	var nft models.NftDataModel
+
+	tx, err := ur.db.Begin(ctx)
+	if err != nil {
+		return nft, tvoerrors.Wrap(op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	query := `SELECT id, max_supply, minted_supply FROM nft_data
+		WHERE collection_id IS NOT DISTINCT FROM $1::bigint AND token_id = $2 AND deleted_at IS NULL FOR UPDATE;`
+	if err = tx.QueryRow(ctx, query, nullableId(collectionId), tokenId).
+		Scan(&nft.ID, &nft.MaxSupply, &nft.MintedSupply); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nft, tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+		}
+		return nft, tvoerrors.Wrap(op, err)
+	}
+
+	if nft.MaxSupply != 0 && nft.MintedSupply+amount > nft.MaxSupply {
+		return nft, tvoerrors.Wrap(op+": max supply exceeded", tvoerrors.ErrConflict)
+	}
+
+	query = "UPDATE nft_data SET minted_supply = minted_supply + $1, updated_at = $2 WHERE id = $3;"
+	if _, err = tx.Exec(ctx, query, amount, time.Now().UTC(), nft.ID); err != nil {
+		return nft, tvoerrors.Wrap(op, err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nft, tvoerrors.Wrap(op, err)
+	}
+
+	return ur.ReadNftData(ctx, collectionId, tokenId)
+}
+
+// TokenIdExists checks if a nft data exists by its token iD inside the collection.
+func (ur *NftDataRepository) TokenIdExists(ctx context.Context, collectionId, tokenId int64) (bool, error) {
 	const op = "postgresql.NftDataRepository.TokenIdExists"
 
-	query := "SELECT EXISTS(SELECT id FROM nft_data WHERE token_id = $1);"
+	query := `SELECT EXISTS(SELECT id FROM nft_data
+		WHERE collection_id IS NOT DISTINCT FROM $1::bigint AND token_id = $2 AND deleted_at IS NULL);`
 	var exists bool
-	err := ur.db.QueryRow(ctx, query, tokenId).Scan(&exists)
+	err := ur.db.QueryRow(ctx, query, nullableId(collectionId), tokenId).Scan(&exists)
 	if err != nil {
 		return false, tvoerrors.Wrap(op, err)
 	}
 	return exists, nil
 }
+
+// attributesByNftIds loads attributes of the given nft rows grouped by nft id
+func (ur *NftDataRepository) attributesByNftIds(ctx context.Context, ids []int64) (map[int64][]models.NftAttribute, error) {
+	result := make(map[int64][]models.NftAttribute, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	query := `SELECT id, nft_id, trait_type, display_type, value_type, COALESCE(string_value, ''),
+		COALESCE(numeric_value, 0)::float8 FROM nft_attributes WHERE nft_id = ANY($1) ORDER BY id;`
+
+	rows, err := ur.db.Query(ctx, query, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var attribute models.NftAttribute
+		if err = rows.Scan(&attribute.ID, &attribute.NftID, &attribute.TraitType, &attribute.DisplayType,
+			&attribute.ValueType, &attribute.StringValue, &attribute.NumericValue); err != nil {
+			return nil, err
+		}
+		result[attribute.NftID] = append(result[attribute.NftID], attribute)
+	}
+
+	return result, rows.Err()
+}
+
+// scanNft reads a row selected with nftColumns followed by the extra columns
+func scanNft(row pgx.Row, nft *models.NftDataModel, extra ...interface{}) error {
+	return row.Scan(append([]interface{}{&nft.ID, &nft.TokenId, &nft.Name, &nft.Description, &nft.CidV0, &nft.CidV1,
+		&nft.MetadataCid, &nft.CollectionId, &nft.Collection, &nft.TokenStandard, &nft.CollectionIpnsName,
+		&nft.MaxSupply, &nft.MintedSupply, &nft.CreatorId, &nft.FileName, &nft.CreatedAt}, extra...)...)
+}
+
+// nullableId converts an optional reference to a query parameter, zero id is stored as NULL
+func nullableId(id int64) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// scanRevision reads a row selected with revisionColumns
+func scanRevision(row pgx.Row, revision *models.NftRevision) error {
+	var attributes []byte
+	if err := row.Scan(&revision.ID, &revision.NftID, &revision.Revision, &revision.AuthorId, &revision.Name,
+		&revision.Description,
+		&revision.CidV0, &revision.CidV1, &revision.FileName, &revision.MetadataCid, &attributes, &revision.RollbackOf,
+		&revision.CreatedAt); err != nil {
+		return err
+	}
+	return json.Unmarshal(attributes, &revision.Attributes)
+}
+
+// insertRevision records the current state of the nft as its next revision inside the transaction
+func insertRevision(ctx context.Context, tx pgx.Tx, nft *models.NftDataModel, change models.NftChange) error {
+	traits := make([]models.NftMetadataTrait, 0, len(nft.Attributes))
+	for _, attribute := range nft.Attributes {
+		traits = append(traits, attribute.Trait())
+	}
+	attributes, err := json.Marshal(traits)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO nft_data_revisions (nft_id, revision, author_id, name, description, cidv0, cidv1,
+		file_name, metadata_cid, attributes, rollback_of)
+		SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3, $4, $5, $6, $7, $8, $9, $10 FROM nft_data_revisions
+		WHERE nft_id = $1;`
+	_, err = tx.Exec(ctx, query, nft.ID, nullableId(change.AuthorId), nft.Name, nft.Description, nft.CidV0,
+		nft.CidV1, nft.FileName, nft.MetadataCid, attributes, nullableId(int64(change.RollbackOf)))
+	return err
+}
+
+// refreshSearchVector recomputes the full-text search vector of the nft from its name, description,
+// file name and string attributes. The russian config stems latin words with the english stemmer,
+// the english config is added so that english stop words and stems match english queries.
+func refreshSearchVector(ctx context.Context, tx pgx.Tx, nftId int64) error {
+	query := `UPDATE nft_data SET search_vector =
+			setweight(to_tsvector('russian', COALESCE(name, '')), 'A') ||
+			setweight(to_tsvector('english', COALESCE(name, '')), 'A') ||
+			setweight(to_tsvector('russian', COALESCE(content, '')), 'B') ||
+			setweight(to_tsvector('english', COALESCE(content, '')), 'B') ||
+			setweight(to_tsvector('simple', COALESCE(file_name, '')), 'C') ||
+			setweight(to_tsvector('russian', COALESCE((SELECT string_agg(concat_ws(' ', a.trait_type, a.string_value), ' ')
+				FROM nft_attributes a WHERE a.nft_id = nft_data.id), '')), 'C')
+		WHERE id = $1;`
+	_, err := tx.Exec(ctx, query, nftId)
+	return err
+}
+
+// insertAttributes saves attributes of the nft inside the transaction
+func insertAttributes(ctx context.Context, tx pgx.Tx, nftId int64, attributes []models.NftAttribute) error {
+	query := `INSERT INTO nft_attributes (nft_id, trait_type, display_type, value_type, string_value, numeric_value)
+		VALUES ($1, $2, $3, $4, $5, $6);`
+
+	for _, attribute := range attributes {
+		var stringValue, numericValue interface{}
+		if attribute.ValueType == models.AttributeNumber {
+			numericValue = attribute.NumericValue
+		} else {
+			stringValue = attribute.StringValue
+		}
+
+		if _, err := tx.Exec(ctx, query, nftId, attribute.TraitType, attribute.DisplayType, attribute.ValueType,
+			stringValue, numericValue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listConditions builds the WHERE conditions of nft list queries except the cursor.
+// Placeholders continue the numbering of args.
+func listConditions(filter *models.NftFilter, args []interface{}) ([]string, []interface{}) {
+	conditions := []string{"nft_data.deleted_at IS NULL"}
+
+	placeholder := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if filter.CollectionId != 0 {
+		conditions = append(conditions, "nft_data.collection_id = "+placeholder(filter.CollectionId))
+	}
+	if filter.CreatorId != 0 {
+		conditions = append(conditions, "nft_data.creator_id = "+placeholder(filter.CreatorId))
+	}
+	if !filter.CreatedFrom.IsZero() {
+		conditions = append(conditions, "nft_data.created_at >= "+placeholder(filter.CreatedFrom))
+	}
+	if !filter.CreatedTo.IsZero() {
+		conditions = append(conditions, "nft_data.created_at < "+placeholder(filter.CreatedTo))
+	}
+	if filter.Available {
+		conditions = append(conditions, "(nft_data.max_supply = 0 OR nft_data.minted_supply < nft_data.max_supply)")
+	}
+
+	traits, args := traitConditions(filter.Traits, args)
+	return append(conditions, traits...), args
+}
+
+// traitConditions builds EXISTS conditions over nft_attributes for the trait filters.
+// Placeholders continue the numbering of args.
+func traitConditions(filters []models.TraitFilter, args []interface{}) ([]string, []interface{}) {
+	operators := map[string]string{
+		models.TraitGt:  ">",
+		models.TraitGte: ">=",
+		models.TraitLt:  "<",
+		models.TraitLte: "<=",
+	}
+
+	placeholder := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	conditions := make([]string, 0, len(filters))
+	for _, filter := range filters {
+		trait := placeholder(filter.TraitType)
+
+		var match string
+		if filter.Op == models.TraitEq {
+			// значение совпадает со строковым либо, если это число, с числовым атрибутом
+			numbers := []float64{}
+			for _, value := range filter.Values {
+				if number, err := strconv.ParseFloat(value, 64); err == nil {
+					numbers = append(numbers, number)
+				}
+			}
+			match = fmt.Sprintf("(a.string_value = ANY(%s) OR a.numeric_value = ANY(%s::numeric[]))",
+				placeholder(filter.Values), placeholder(numbers))
+		} else {
+			number, _ := strconv.ParseFloat(filter.Values[0], 64)
+			match = fmt.Sprintf("a.numeric_value %s %s", operators[filter.Op], placeholder(number))
+		}
+
+		conditions = append(conditions, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM nft_attributes a WHERE a.nft_id = nft_data.id AND a.trait_type = %s AND %s)",
+			trait, match))
+	}
+
+	return conditions, args
+}
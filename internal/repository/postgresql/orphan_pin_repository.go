@@ -0,0 +1,146 @@
+package postgresql
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"main/internal/models"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// orphanPinColumns are the columns of an orphan, $1 is the grace period in seconds
+const orphanPinColumns = `id, cid, first_seen_at, first_seen_at <= now() - make_interval(secs => $1)`
+
+// OrphanPinRepository tracks unreferenced pins of the storage in PostgreSQL.
+type OrphanPinRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewOrphanPinRepository creates a new instance of OrphanPinRepository with the given PostgreSQL connection pool.
+func NewOrphanPinRepository(db *pgxpool.Pool) *OrphanPinRepository {
+	return &OrphanPinRepository{
+		db: db,
+	}
+}
+
+// ReferencedCids returns every CID referenced by the database, every CID once. Deleted tokens keep
+// their content referenced since they can be restored, pin requests keep the requested CIDs.
+func (opr *OrphanPinRepository) ReferencedCids(ctx context.Context) ([]string, error) {
+	const op = "postgresql.OrphanPinRepository.ReferencedCids"
+
+	query := `SELECT cid FROM (
+			SELECT unnest(ARRAY[cidv0, cidv1, metadata_cid]) AS cid FROM nft_data
+			UNION SELECT unnest(ARRAY[cidv0, cidv1, metadata_cid]) FROM nft_data_revisions
+			UNION SELECT cover_cid FROM collections
+			UNION SELECT cid FROM pins
+			UNION SELECT root_cid FROM directory_uploads
+			UNION SELECT cid FROM directory_upload_files
+			UNION SELECT value FROM ipns_records
+		) referenced WHERE cid IS NOT NULL AND cid <> '' ORDER BY 1;`
+	rows, err := opr.db.Query(ctx, query)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	defer rows.Close()
+
+	cids := []string{}
+	for rows.Next() {
+		var cid string
+		if err = rows.Scan(&cid); err != nil {
+			return nil, tvoerrors.Wrap(op, err)
+		}
+		cids = append(cids, cid)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	return cids, nil
+}
+
+// TrackOrphans replaces the tracked orphans with the given CIDs. CIDs tracked before keep
+// the time they were first seen, the others are forgotten. It returns the tracked orphans,
+// those orphaned longer than gracePeriod are marked expired.
+func (opr *OrphanPinRepository) TrackOrphans(ctx context.Context, cids []string,
+	gracePeriod time.Duration) ([]models.OrphanPin, error) {
+	const op = "postgresql.OrphanPinRepository.TrackOrphans"
+
+	if cids == nil {
+		// NULL array would keep every tracked orphan
+		cids = []string{}
+	}
+
+	tx, err := opr.db.Begin(ctx)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err = tx.Exec(ctx, `DELETE FROM orphan_pins WHERE cid <> ALL($1::varchar[]);`, cids); err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	query := `INSERT INTO orphan_pins (cid) SELECT unnest($1::varchar[]) ON CONFLICT (cid) DO NOTHING;`
+	if _, err = tx.Exec(ctx, query, cids); err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+
+	query = `SELECT ` + orphanPinColumns + ` FROM orphan_pins ORDER BY first_seen_at, id;`
+	rows, err := tx.Query(ctx, query, gracePeriod.Seconds())
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	orphans, err := scanOrphanPins(rows)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	return orphans, nil
+}
+
+// DeleteOrphan stops tracking the CID, e.g. after it was unpinned.
+func (opr *OrphanPinRepository) DeleteOrphan(ctx context.Context, cid string) error {
+	const op = "postgresql.OrphanPinRepository.DeleteOrphan"
+
+	if _, err := opr.db.Exec(ctx, `DELETE FROM orphan_pins WHERE cid = $1;`, cid); err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+	return nil
+}
+
+// Orphans retrieves the tracked orphans, the longest orphaned first. Those orphaned longer
+// than gracePeriod are marked expired.
+func (opr *OrphanPinRepository) Orphans(ctx context.Context, gracePeriod time.Duration,
+	limit int) ([]models.OrphanPin, error) {
+	const op = "postgresql.OrphanPinRepository.Orphans"
+
+	query := `SELECT ` + orphanPinColumns + ` FROM orphan_pins ORDER BY first_seen_at, id LIMIT $2;`
+	rows, err := opr.db.Query(ctx, query, gracePeriod.Seconds(), limit)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	orphans, err := scanOrphanPins(rows)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	return orphans, nil
+}
+
+// scanOrphanPins reads the orphan rows and closes them
+func scanOrphanPins(rows pgx.Rows) ([]models.OrphanPin, error) {
+	defer rows.Close()
+
+	orphans := []models.OrphanPin{}
+	for rows.Next() {
+		var orphan models.OrphanPin
+		if err := rows.Scan(&orphan.ID, &orphan.Cid, &orphan.FirstSeenAt, &orphan.Expired); err != nil {
+			return nil, err
+		}
+		orphans = append(orphans, orphan)
+	}
+	return orphans, rows.Err()
+}
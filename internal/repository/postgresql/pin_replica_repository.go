@@ -0,0 +1,119 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"main/internal/models"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+const pinReplicaColumns = `r.id, r.pin_id, p.cid, p.name, r.target, r.remote_request_id, r.status, r.attempts,
+	r.error, r.pinned_at, r.next_attempt_at, r.created_at, r.updated_at`
+
+// PinReplicaRepository handles tracking of pin copies on remote pinning services in PostgreSQL.
+type PinReplicaRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPinReplicaRepository creates a new instance of PinReplicaRepository with the given PostgreSQL connection pool.
+func NewPinReplicaRepository(db *pgxpool.Pool) *PinReplicaRepository {
+	return &PinReplicaRepository{
+		db: db,
+	}
+}
+
+// CreateReplicas queues copies of the pin on the targets. Targets the pin is already copied to are skipped.
+func (rr *PinReplicaRepository) CreateReplicas(ctx context.Context, pinId int64, targets []string) error {
+	const op = "postgresql.PinReplicaRepository.CreateReplicas"
+
+	query := `INSERT INTO pin_replicas (pin_id, target) SELECT $1, unnest($2::varchar[])
+		ON CONFLICT (pin_id, target) DO NOTHING;`
+	if _, err := rr.db.Exec(ctx, query, pinId, targets); err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+	return nil
+}
+
+// ClaimReplicas takes up to limit due copies which are queued or wait for the remote service
+// and postpones them by lease, so that other replicators skip them while they are processed.
+func (rr *PinReplicaRepository) ClaimReplicas(ctx context.Context, limit int,
+	lease time.Duration) ([]models.PinReplica, error) {
+	const op = "postgresql.PinReplicaRepository.ClaimReplicas"
+
+	query := `UPDATE pin_replicas r SET next_attempt_at = now() + make_interval(secs => $1), updated_at = now()
+		FROM pins p
+		WHERE p.id = r.pin_id AND r.id IN (SELECT id FROM pin_replicas
+			WHERE status = ANY($2) AND next_attempt_at <= now()
+			ORDER BY next_attempt_at, id LIMIT $3 FOR UPDATE SKIP LOCKED)
+		RETURNING ` + pinReplicaColumns
+	statuses := []string{models.PinStatusQueued, models.PinStatusPinning}
+	replicas, err := rr.queryReplicas(ctx, query, lease.Seconds(), statuses, limit)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	return replicas, nil
+}
+
+// UpdateReplica saves the state of the copy. A queued or pinning copy is processed again after retryAfter,
+// the pinned time is kept while the copy stays pinned.
+func (rr *PinReplicaRepository) UpdateReplica(ctx context.Context, replica *models.PinReplica,
+	retryAfter time.Duration) error {
+	const op = "postgresql.PinReplicaRepository.UpdateReplica"
+
+	query := `UPDATE pin_replicas SET remote_request_id = $1, status = $2, attempts = $3, error = $4, updated_at = now(),
+		next_attempt_at = now() + make_interval(secs => $5),
+		pinned_at = CASE WHEN $2 = 'pinned' THEN COALESCE(pinned_at, now()) END
+		WHERE id = $6
+		RETURNING pinned_at, next_attempt_at, updated_at`
+	var pinnedAt *time.Time
+	if err := rr.db.QueryRow(ctx, query, replica.RemoteRequestId, replica.Status, replica.Attempts, replica.Error,
+		retryAfter.Seconds(), replica.ID).Scan(&pinnedAt, &replica.NextAttemptAt, &replica.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+		}
+		return tvoerrors.Wrap(op, err)
+	}
+	replica.PinnedAt = timeOrZero(pinnedAt)
+	return nil
+}
+
+// ReplicasByPinId retrieves all copies of the pin ordered by target.
+func (rr *PinReplicaRepository) ReplicasByPinId(ctx context.Context, pinId int64) ([]models.PinReplica, error) {
+	const op = "postgresql.PinReplicaRepository.ReplicasByPinId"
+
+	query := "SELECT " + pinReplicaColumns + ` FROM pin_replicas r JOIN pins p ON p.id = r.pin_id
+		WHERE r.pin_id = $1 ORDER BY r.target;`
+	replicas, err := rr.queryReplicas(ctx, query, pinId)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	return replicas, nil
+}
+
+func (rr *PinReplicaRepository) queryReplicas(ctx context.Context, query string,
+	args ...interface{}) ([]models.PinReplica, error) {
+	rows, err := rr.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	replicas := []models.PinReplica{}
+	for rows.Next() {
+		var replica models.PinReplica
+		var pinnedAt *time.Time
+		if err = rows.Scan(&replica.ID, &replica.PinID, &replica.Cid, &replica.Name, &replica.Target,
+			&replica.RemoteRequestId, &replica.Status, &replica.Attempts, &replica.Error, &pinnedAt,
+			&replica.NextAttemptAt, &replica.CreatedAt, &replica.UpdatedAt); err != nil {
+			return nil, err
+		}
+		replica.PinnedAt = timeOrZero(pinnedAt)
+		replicas = append(replicas, replica)
+	}
+	return replicas, rows.Err()
+}
@@ -0,0 +1,362 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"main/internal/models"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+const pinColumns = `id, request_id::text, cid, name, origins, meta, COALESCE(requester_id, 0), status, attempts,
+	error, pinned_at, next_attempt_at, created_at, updated_at`
+
+// PinRepository handles pin request tracking in PostgreSQL.
+type PinRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPinRepository creates a new instance of PinRepository with the given PostgreSQL connection pool.
+func NewPinRepository(db *pgxpool.Pool) *PinRepository {
+	return &PinRepository{
+		db: db,
+	}
+}
+
+// CreatePin saves a new queued pin request and assigns its request id.
+func (pr *PinRepository) CreatePin(ctx context.Context, pin *models.Pin) error {
+	if err := insertPin(ctx, pr.db, pin); err != nil {
+		return tvoerrors.Wrap("postgresql.PinRepository.CreatePin", err)
+	}
+	return nil
+}
+
+// ReplacePin removes the pin request oldId and saves the new one in its place.
+func (pr *PinRepository) ReplacePin(ctx context.Context, oldId int64, pin *models.Pin) error {
+	const op = "postgresql.PinRepository.ReplacePin"
+
+	tx, err := pr.db.Begin(ctx)
+	if err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	result, err := tx.Exec(ctx, "DELETE FROM pins WHERE id = $1;", oldId)
+	if err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+	if result.RowsAffected() == 0 {
+		return tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+	}
+	if err = insertPin(ctx, tx, pin); err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+	return nil
+}
+
+// DeletePin removes the pin request.
+func (pr *PinRepository) DeletePin(ctx context.Context, id int64) error {
+	const op = "postgresql.PinRepository.DeletePin"
+
+	result, err := pr.db.Exec(ctx, "DELETE FROM pins WHERE id = $1;", id)
+	if err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+	if result.RowsAffected() == 0 {
+		return tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+	}
+	return nil
+}
+
+// PinCountByCid counts pin requests of the CID in any status.
+func (pr *PinRepository) PinCountByCid(ctx context.Context, cid string) (int64, error) {
+	const op = "postgresql.PinRepository.PinCountByCid"
+
+	var count int64
+	if err := pr.db.QueryRow(ctx, "SELECT count(*) FROM pins WHERE cid = $1;", cid).Scan(&count); err != nil {
+		return 0, tvoerrors.Wrap(op, err)
+	}
+	return count, nil
+}
+
+// ListPins takes a page of pins matching the filter from new to old together with the number of all matching pins.
+func (pr *PinRepository) ListPins(ctx context.Context, filter *models.PinFilter) ([]models.Pin, int, error) {
+	const op = "postgresql.PinRepository.ListPins"
+
+	conditions, args, err := pinConditions(filter)
+	if err != nil {
+		return nil, 0, tvoerrors.Wrap(op, err)
+	}
+	where := " WHERE " + strings.Join(conditions, " AND ")
+
+	var count int
+	if err = pr.db.QueryRow(ctx, "SELECT count(*) FROM pins"+where, args...).Scan(&count); err != nil {
+		return nil, 0, tvoerrors.Wrap(op, err)
+	}
+
+	args = append(args, filter.Limit)
+	query := "SELECT " + pinColumns + " FROM pins" + where +
+		fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d;", len(args))
+	pins, err := pr.queryPins(ctx, query, args...)
+	if err != nil {
+		return nil, 0, tvoerrors.Wrap(op, err)
+	}
+	return pins, count, nil
+}
+
+// PinByRequestId retrieves a pin request by its request id.
+func (pr *PinRepository) PinByRequestId(ctx context.Context, requestId string) (*models.Pin, error) {
+	const op = "postgresql.PinRepository.PinByRequestId"
+
+	if _, err := uuid.Parse(requestId); err != nil {
+		return nil, tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+	}
+
+	var pin models.Pin
+	query := "SELECT " + pinColumns + " FROM pins WHERE request_id = $1;"
+	if err := scanPin(pr.db.QueryRow(ctx, query, requestId), &pin); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+		}
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	return &pin, nil
+}
+
+// ClaimPins moves up to limit due queued pins to the pinning status and counts the attempt.
+// Rows locked by another worker are skipped, so several instances may share the queue.
+func (pr *PinRepository) ClaimPins(ctx context.Context, limit int) ([]models.Pin, error) {
+	const op = "postgresql.PinRepository.ClaimPins"
+
+	query := `UPDATE pins SET status = $1, attempts = attempts + 1, updated_at = now()
+		WHERE id IN (SELECT id FROM pins WHERE status = $2 AND next_attempt_at <= now()
+			ORDER BY next_attempt_at, id LIMIT $3 FOR UPDATE SKIP LOCKED)
+		RETURNING ` + pinColumns
+	pins, err := pr.queryPins(ctx, query, models.PinStatusPinning, models.PinStatusQueued, limit)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	return pins, nil
+}
+
+// UpdatePin saves the status and error of the pin. A queued pin is retried after retryAfter,
+// the pinned time is kept while the pin stays pinned.
+func (pr *PinRepository) UpdatePin(ctx context.Context, pin *models.Pin, retryAfter time.Duration) error {
+	const op = "postgresql.PinRepository.UpdatePin"
+
+	query := `UPDATE pins SET status = $1, error = $2, updated_at = now(),
+		next_attempt_at = now() + make_interval(secs => $3),
+		pinned_at = CASE WHEN $1 = 'pinned' THEN COALESCE(pinned_at, now()) END
+		WHERE id = $4
+		RETURNING pinned_at, next_attempt_at, updated_at`
+	var pinnedAt *time.Time
+	if err := pr.db.QueryRow(ctx, query, pin.Status, pin.Error, retryAfter.Seconds(), pin.ID).
+		Scan(&pinnedAt, &pin.NextAttemptAt, &pin.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+		}
+		return tvoerrors.Wrap(op, err)
+	}
+	pin.PinnedAt = timeOrZero(pinnedAt)
+	return nil
+}
+
+// PinsByStatus takes all pins in any of the statuses.
+func (pr *PinRepository) PinsByStatus(ctx context.Context, statuses ...string) ([]models.Pin, error) {
+	const op = "postgresql.PinRepository.PinsByStatus"
+
+	query := "SELECT " + pinColumns + " FROM pins WHERE status = ANY($1) ORDER BY id;"
+	pins, err := pr.queryPins(ctx, query, statuses)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	return pins, nil
+}
+
+// RequeueStalePins returns to the queue pins left in the pinning status longer than olderThan,
+// e.g. when the worker was stopped in the middle of an attempt.
+func (pr *PinRepository) RequeueStalePins(ctx context.Context, olderThan time.Duration) (int64, error) {
+	const op = "postgresql.PinRepository.RequeueStalePins"
+
+	query := `UPDATE pins SET status = $1, next_attempt_at = now(), updated_at = now()
+		WHERE status = $2 AND updated_at < now() - make_interval(secs => $3);`
+	result, err := pr.db.Exec(ctx, query, models.PinStatusQueued, models.PinStatusPinning, olderThan.Seconds())
+	if err != nil {
+		return 0, tvoerrors.Wrap(op, err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// RequeuePin queues the pin again from scratch: the attempts and the error of the previous pinning are reset.
+func (pr *PinRepository) RequeuePin(ctx context.Context, pin *models.Pin) error {
+	const op = "postgresql.PinRepository.RequeuePin"
+
+	query := `UPDATE pins SET status = $1, error = '', attempts = 0, pinned_at = NULL,
+		next_attempt_at = now(), updated_at = now()
+		WHERE id = $2
+		RETURNING next_attempt_at, updated_at`
+	if err := pr.db.QueryRow(ctx, query, models.PinStatusQueued, pin.ID).
+		Scan(&pin.NextAttemptAt, &pin.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+		}
+		return tvoerrors.Wrap(op, err)
+	}
+	pin.Status, pin.Error, pin.Attempts, pin.PinnedAt = models.PinStatusQueued, "", 0, time.Time{}
+	return nil
+}
+
+// DeleteUserPins removes the pin requests the user made for any of the CIDs, so that the reconciler
+// does not pin them again, and returns their number.
+func (pr *PinRepository) DeleteUserPins(ctx context.Context, requesterId int64, cids []string) (int64, error) {
+	const op = "postgresql.PinRepository.DeleteUserPins"
+
+	result, err := pr.db.Exec(ctx, "DELETE FROM pins WHERE requester_id = $1 AND cid = ANY($2);", requesterId, cids)
+	if err != nil {
+		return 0, tvoerrors.Wrap(op, err)
+	}
+	return result.RowsAffected(), nil
+}
+
+func (pr *PinRepository) queryPins(ctx context.Context, query string, args ...interface{}) ([]models.Pin, error) {
+	rows, err := pr.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pins := []models.Pin{}
+	for rows.Next() {
+		var pin models.Pin
+		if err = scanPin(rows, &pin); err != nil {
+			return nil, err
+		}
+		pins = append(pins, pin)
+	}
+	return pins, rows.Err()
+}
+
+// scanPin reads a row selected with pinColumns
+func scanPin(row pgx.Row, pin *models.Pin) error {
+	var origins, meta []byte
+	var pinnedAt *time.Time
+	if err := row.Scan(&pin.ID, &pin.RequestId, &pin.Cid, &pin.Name, &origins, &meta, &pin.RequesterId, &pin.Status,
+		&pin.Attempts, &pin.Error, &pinnedAt, &pin.NextAttemptAt, &pin.CreatedAt, &pin.UpdatedAt); err != nil {
+		return err
+	}
+	pin.PinnedAt = timeOrZero(pinnedAt)
+
+	if err := json.Unmarshal(origins, &pin.Origins); err != nil {
+		return err
+	}
+	return json.Unmarshal(meta, &pin.Meta)
+}
+
+// rowQuerier is implemented by both the pool and a transaction
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// insertPin saves a new queued pin request and assigns its request id
+func insertPin(ctx context.Context, db rowQuerier, pin *models.Pin) error {
+	origins, err := json.Marshal(nonNil(pin.Origins))
+	if err != nil {
+		return err
+	}
+	meta, err := json.Marshal(nonNilMap(pin.Meta))
+	if err != nil {
+		return err
+	}
+
+	pin.RequestId = uuid.NewString()
+	pin.Status = models.PinStatusQueued
+
+	query := `INSERT INTO pins (request_id, cid, name, origins, meta, requester_id, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, next_attempt_at, created_at, updated_at`
+	return db.QueryRow(ctx, query, pin.RequestId, pin.Cid, pin.Name, origins, meta, nullableId(pin.RequesterId),
+		pin.Status).Scan(&pin.ID, &pin.NextAttemptAt, &pin.CreatedAt, &pin.UpdatedAt)
+}
+
+// pinConditions builds the WHERE conditions of the pin list query
+func pinConditions(filter *models.PinFilter) ([]string, []interface{}, error) {
+	var args []interface{}
+	placeholder := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	conditions := []string{"requester_id = " + placeholder(filter.RequesterId)}
+	if len(filter.Cids) > 0 {
+		conditions = append(conditions, "cid = ANY("+placeholder(filter.Cids)+")")
+	}
+	if len(filter.Statuses) > 0 {
+		conditions = append(conditions, "status = ANY("+placeholder(filter.Statuses)+")")
+	}
+	if filter.Name != "" {
+		pattern := "%" + escapeLike(filter.Name) + "%"
+		switch filter.Match {
+		case models.PinMatchIExact:
+			conditions = append(conditions, "lower(name) = lower("+placeholder(filter.Name)+")")
+		case models.PinMatchPartial:
+			conditions = append(conditions, "name LIKE "+placeholder(pattern))
+		case models.PinMatchIPartial:
+			conditions = append(conditions, "name ILIKE "+placeholder(pattern))
+		default:
+			conditions = append(conditions, "name = "+placeholder(filter.Name))
+		}
+	}
+	if !filter.Before.IsZero() {
+		conditions = append(conditions, "created_at < "+placeholder(filter.Before))
+	}
+	if !filter.After.IsZero() {
+		conditions = append(conditions, "created_at > "+placeholder(filter.After))
+	}
+	if len(filter.Meta) > 0 {
+		meta, err := json.Marshal(filter.Meta)
+		if err != nil {
+			return nil, nil, err
+		}
+		conditions = append(conditions, "meta @> "+placeholder(meta)+"::jsonb")
+	}
+	return conditions, args, nil
+}
+
+// escapeLike escapes the LIKE wildcards, so that the value is matched literally
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func nonNilMap(values map[string]string) map[string]string {
+	if values == nil {
+		return map[string]string{}
+	}
+	return values
+}
+
+// timeOrZero converts a nullable timestamp, NULL is read as zero time
+func timeOrZero(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
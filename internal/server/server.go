@@ -12,6 +12,7 @@ import (
 	slogfiber "github.com/samber/slog-fiber"
 
 	"main/internal/handlers"
+	coreconfig "main/tools/pkg/core_config"
 	httpmiddlewares "main/tools/pkg/http_middlewares"
 	httputils "main/tools/pkg/http_utils"
 	"main/tools/pkg/logger"
@@ -19,24 +20,29 @@ import (
 	tvomodels "main/tools/pkg/tvo_models"
 )
 
-func NewServer() *fiber.App {
+func NewServer(cfg *coreconfig.App) *fiber.App {
 	app := fiber.New(fiber.Config{
 		StreamRequestBody: true,
-		WriteTimeout:      time.Second * 15,
-		ReadTimeout:       time.Second * 15,
-		IdleTimeout:       time.Second * 20,
-		CaseSensitive:     true,
-		StrictRouting:     false,
-		ServerHeader:      "Apache 2.0",
-		AppName:           "API Gateway",
-		BodyLimit:         20 * 1024 * 1024,
+		// multipart формы не разбираются заранее, чтобы загрузки шли потоком в хранилище
+		DisablePreParseMultipartForm: true,
+		WriteTimeout:                 time.Second * 15,
+		ReadTimeout:                  cfg.ReadTimeout,
+		IdleTimeout:                  time.Second * 20,
+		CaseSensitive:                true,
+		StrictRouting:                false,
+		ServerHeader:                 "Apache 2.0",
+		AppName:                      "API Gateway",
+		BodyLimit:                    cfg.BodyLimit,
 	})
 
 	return app
 }
 
 func AddRoutes(app *fiber.App, authHandlers *handlers.AuthHandlers, kuboHandlers *handlers.KuboHandlers,
-	nftHandlers *handlers.NftHandlers, logger *logger.Logger) {
+	nftHandlers *handlers.NftHandlers, collectionHandlers *handlers.CollectionHandlers,
+	pinningServiceHandlers *handlers.PinningServiceHandlers, gatewayHandlers *handlers.GatewayHandlers,
+	integrityHandlers *handlers.IntegrityHandlers, mfsHandlers *handlers.MfsHandlers, nodeHandlers *handlers.NodeHandlers,
+	gcHandlers *handlers.GcHandlers, logger *logger.Logger) {
 	app.Use(healthcheck.New())
 
 	v1Router := app.Group("/v1", slogfiber.NewWithConfig(logger.Logger, slogfiber.Config{
@@ -54,7 +60,8 @@ func AddRoutes(app *fiber.App, authHandlers *handlers.AuthHandlers, kuboHandlers
 		WithTraceID:        true,
 	}), recover.New())
 
-	addRoutesV1(v1Router, authHandlers, kuboHandlers, nftHandlers, logger)
+	addRoutesV1(v1Router, authHandlers, kuboHandlers, nftHandlers, collectionHandlers, pinningServiceHandlers,
+		gatewayHandlers, integrityHandlers, mfsHandlers, nodeHandlers, gcHandlers, logger)
 }
 
 // checkAuthToken утилита для проверки токена
@@ -85,7 +92,10 @@ func checkAuthToken(logger *logger.Logger) httpmiddlewares.CheckTokenCallback {
 
 // addRoutesV1 добавляем роутинг для версии API v1
 func addRoutesV1(v1Router fiber.Router, authHandlers *handlers.AuthHandlers, kuboHandlers *handlers.KuboHandlers,
-	nftHandlers *handlers.NftHandlers, logger *logger.Logger) fiber.Router {
+	nftHandlers *handlers.NftHandlers, collectionHandlers *handlers.CollectionHandlers,
+	pinningServiceHandlers *handlers.PinningServiceHandlers, gatewayHandlers *handlers.GatewayHandlers,
+	integrityHandlers *handlers.IntegrityHandlers, mfsHandlers *handlers.MfsHandlers, nodeHandlers *handlers.NodeHandlers,
+	gcHandlers *handlers.GcHandlers, logger *logger.Logger) fiber.Router {
 	authMiddleware := httpmiddlewares.NewAuthMiddleware(checkAuthToken(logger), false, logger)
 	//guestMiddleware := httpmiddlewares.NewAuthMiddleware(checkAuthToken(logger), true, logger)
 
@@ -109,18 +119,76 @@ func addRoutesV1(v1Router fiber.Router, authHandlers *handlers.AuthHandlers, kub
 	authProtected.Post("/reset_token/", httputils.FiberJSONWrapper(authHandlers.ResetToken))
 
 	// методы сервиса API
-	api := v1Router.Group("/api")
-	api.Get("/pins", handlers.ListPinsHandler)
+	// параметр cid_base задает основание multibase для CID в ответах
+	api := v1Router.Group("/api", handlers.CidBase)
+	api.Get("/pins", kuboHandlers.ListPinsHandler)
+	api.Get("/nft/search", httputils.FiberJSONWrapper(nftHandlers.SearchNft))
 	api.Get("/nft/:id", httputils.FiberJSONWrapper(nftHandlers.ReadNft))
-	api.Get("/nft/all/:limit", httputils.FiberJSONWrapper(nftHandlers.ReadAllNft))
+	api.Get("/nft/:id/metadata", httputils.FiberJSONWrapper(nftHandlers.ReadNftMetadata))
+	api.Get("/nft/:id/revisions", httputils.FiberJSONWrapper(nftHandlers.ReadNftRevisions))
+	api.Get("/nft/:id/car", nftHandlers.ExportNftCar)
+	api.Get("/nft/:id/content", nftHandlers.ReadNftContent)
+	api.Get("/nft", httputils.FiberJSONWrapper(nftHandlers.ReadAllNft))
+	api.Get("/collections", httputils.FiberJSONWrapper(collectionHandlers.ReadAllCollections))
+	api.Get("/collections/:slug", httputils.FiberJSONWrapper(collectionHandlers.ReadCollection))
+	api.Get("/collections/:slug/ipns", httputils.FiberJSONWrapper(collectionHandlers.ReadCollectionIpns))
+	api.Get("/collections/:slug/nft", httputils.FiberJSONWrapper(nftHandlers.ReadAllNft))
+	api.Get("/collections/:slug/nft/search", httputils.FiberJSONWrapper(nftHandlers.SearchNft))
+	api.Get("/collections/:slug/nft/:id", httputils.FiberJSONWrapper(nftHandlers.ReadNft))
+	api.Get("/collections/:slug/nft/:id/metadata", httputils.FiberJSONWrapper(nftHandlers.ReadNftMetadata))
+	api.Get("/collections/:slug/nft/:id/revisions", httputils.FiberJSONWrapper(nftHandlers.ReadNftRevisions))
+	api.Get("/collections/:slug/nft/:id/car", nftHandlers.ExportNftCar)
+	api.Get("/collections/:slug/nft/:id/content", nftHandlers.ReadNftContent)
+	api.Get("/directories/:cid", kuboHandlers.DirectoryHandler)
+
+	// встроенный шлюз, ссылки вида <host>/v1/ipfs/<cid>/1.json
+	v1Router.Get("/ipfs/:cid", gatewayHandlers.ServeIpfs)
+	v1Router.Get("/ipfs/:cid/*", gatewayHandlers.ServeIpfs)
+
+	// метрики узла хранилища в формате Prometheus
+	v1Router.Get("/metrics", nodeHandlers.Metrics)
+
+	// IPFS Pinning Service API, адрес сервиса для клиентов: <host>/v1/psa
+	psa := v1Router.Group("/psa", pinningServiceHandlers.Authenticate(authMiddleware))
+	psa.Get("/pins", pinningServiceHandlers.ListPins)
+	psa.Post("/pins", pinningServiceHandlers.AddPin)
+	psa.Get("/pins/:requestid", pinningServiceHandlers.GetPin)
+	psa.Post("/pins/:requestid", pinningServiceHandlers.ReplacePin)
+	psa.Delete("/pins/:requestid", pinningServiceHandlers.RemovePin)
 
 	apiProtected := v1Router.Group("", authMiddleware)
 	api.Post("/nft_data", httputils.FiberJSONWrapper(nftHandlers.CreateNftData))
-
-	apiProtected.Post("/files", handlers.UploadFileHandler)
+	api.Post("/cid/preview", httputils.FiberJSONWrapper(nftHandlers.PreviewCid))
+	api.Post("/collections", httputils.FiberJSONWrapper(collectionHandlers.CreateCollection))
+	api.Patch("/collections/:slug", httputils.FiberJSONWrapper(collectionHandlers.UpdateCollection))
+	api.Delete("/collections/:slug", httputils.FiberJSONWrapper(collectionHandlers.DeleteCollection))
+	api.Get("/collections/:slug/car", nftHandlers.ExportCollectionCar)
+	api.Post("/collections/:slug/ipns", collectionHandlers.PublishCollectionIpns)
+	api.Post("/collections/:slug/nft/:id/mint", httputils.FiberJSONWrapper(nftHandlers.MintEdition))
+	api.Patch("/nft/:id", httputils.FiberJSONWrapper(nftHandlers.UpdateNftData))
+	api.Delete("/nft/:id", httputils.FiberJSONWrapper(nftHandlers.DeleteNftData))
+	api.Post("/nft/:id/restore", httputils.FiberJSONWrapper(nftHandlers.RestoreNftData))
+	api.Patch("/collections/:slug/nft/:id", httputils.FiberJSONWrapper(nftHandlers.UpdateNftData))
+	api.Delete("/collections/:slug/nft/:id", httputils.FiberJSONWrapper(nftHandlers.DeleteNftData))
+	api.Post("/collections/:slug/nft/:id/restore", httputils.FiberJSONWrapper(nftHandlers.RestoreNftData))
+	api.Post("/nft/:id/revisions/:revision/rollback", httputils.FiberJSONWrapper(nftHandlers.RollbackNftData))
+	api.Post("/collections/:slug/nft/:id/revisions/:revision/rollback",
+		httputils.FiberJSONWrapper(nftHandlers.RollbackNftData))
+	api.Get("/integrity/failures", httputils.FiberJSONWrapper(integrityHandlers.ReadReport))
+	api.Get("/mfs/ls", httputils.FiberJSONWrapper(mfsHandlers.ListMfs))
+	api.Get("/mfs/stat", httputils.FiberJSONWrapper(mfsHandlers.StatMfs))
+	api.Get("/node", httputils.FiberJSONWrapper(nodeHandlers.ReadNode))
+	api.Get("/nodes", httputils.FiberJSONWrapper(nodeHandlers.ReadNodes))
+	api.Get("/gc", httputils.FiberJSONWrapper(gcHandlers.ReadGc))
+	api.Post("/gc", httputils.FiberJSONWrapper(gcHandlers.RunGc))
+
+	apiProtected.Post("/files", kuboHandlers.UploadFileHandler)
+	apiProtected.Post("/car", kuboHandlers.ImportCarHandler)
+	apiProtected.Post("/directories", kuboHandlers.UploadDirectoryHandler)
 	// Маршруты для управления закреплением (pin)
-	apiProtected.Post("/pins/:cid", handlers.PinCidHandler)
-	apiProtected.Delete("/pins/:cid", handlers.UnpinCidHandler)
+	apiProtected.Post("/pins/:cid", kuboHandlers.PinCidHandler)
+	apiProtected.Get("/pins/requests/:requestid", kuboHandlers.PinRequestHandler)
+	apiProtected.Delete("/pins/:cid", kuboHandlers.UnpinCidHandler)
 
 	return v1Router
 }
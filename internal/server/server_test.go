@@ -0,0 +1,115 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main/internal/config"
+	"main/internal/handlers"
+	"main/internal/mfs"
+	"main/internal/service"
+	"main/internal/storage"
+	"main/tools/pkg/constants"
+	coreconfig "main/tools/pkg/core_config"
+	"main/tools/pkg/logger"
+	tvomodels "main/tools/pkg/tvo_models"
+)
+
+// benchmarkFileSize размер файла для бенчмарка загрузки
+const benchmarkFileSize = 100 << 20
+
+// zeroReader бесконечный источник нулевых байт без аллокаций
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+// newDiscardKubo поднимает фейковый /api/v0/add, который вычитывает файл и отбрасывает его
+func newDiscardKubo(tb testing.TB) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reader, err := r.MultipartReader()
+		if err != nil {
+			tb.Errorf("MultipartReader() error = %v", err)
+			return
+		}
+		part, err := reader.NextPart()
+		if err != nil {
+			tb.Errorf("NextPart() error = %v", err)
+			return
+		}
+		n, _ := io.Copy(io.Discard, part)
+		_, _ = fmt.Fprintf(w, `{"Name":%q,"Hash":"QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o","Size":"%d"}`,
+			part.FileName(), n)
+	}))
+}
+
+// uploadRequest собирает multipart запрос с файлом size байт, тело формируется потоком
+func uploadRequest(target string, size int64) *http.Request {
+	var head bytes.Buffer
+	writer := multipart.NewWriter(&head)
+	_, _ = writer.CreateFormFile("file", "big.bin")
+	prefix := append([]byte(nil), head.Bytes()...)
+	head.Reset()
+	_ = writer.Close()
+
+	body := io.MultiReader(bytes.NewReader(prefix), io.LimitReader(zeroReader{}, size), bytes.NewReader(head.Bytes()))
+	req := httptest.NewRequest(fiber.MethodPost, target, body)
+	req.ContentLength = int64(len(prefix)) + size + int64(head.Len())
+	req.Header.Set(fiber.HeaderContentType, writer.FormDataContentType())
+	return req
+}
+
+// BenchmarkUploadFile загрузка 100 МБ через Fiber в Kubo с поднятым BodyLimit. app.Test сам держит
+// запрос в памяти, поэтому эталоном служит маршрут, который лишь вычитывает тело: загрузка
+// в Kubo не должна заметно добавлять к нему аллокаций, то есть тело не буферизуется
+func BenchmarkUploadFile(b *testing.B) {
+	srv := newDiscardKubo(b)
+	defer srv.Close()
+
+	log := &logger.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	s := storage.NewKuboStorage(service.NewKuboClient(&config.IPFS{APIURL: srv.URL}))
+	h := handlers.NewKuboHandlers(log, s, nil, nil, nil, nil, nil, mfs.NewMirror(s, &config.MFS{}, log), nil,
+		"https://ipfs.io/ipfs/%s")
+
+	app := NewServer(&coreconfig.App{BodyLimit: 128 << 20, ReadTimeout: time.Minute})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(constants.TOKEN_DATA_KEY, tvomodels.TokenData{UserID: 1})
+		return c.Next()
+	})
+	app.Post("/v1/drain", func(c *fiber.Ctx) error {
+		if _, err := io.Copy(io.Discard, c.Context().RequestBodyStream()); err != nil {
+			return err
+		}
+		return c.SendStatus(fiber.StatusCreated)
+	})
+	app.Post("/v1/files", h.UploadFileHandler)
+
+	for name, target := range map[string]string{"drain": "/v1/drain", "upload": "/v1/files"} {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(benchmarkFileSize)
+			for i := 0; i < b.N; i++ {
+				resp, err := app.Test(uploadRequest(target, benchmarkFileSize), -1)
+				if err != nil {
+					b.Fatal(err)
+				}
+				_, _ = io.Copy(io.Discard, resp.Body)
+				_ = resp.Body.Close()
+				if resp.StatusCode != fiber.StatusCreated {
+					b.Fatalf("status = %d, expected %d", resp.StatusCode, fiber.StatusCreated)
+				}
+			}
+		})
+	}
+}
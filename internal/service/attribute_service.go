@@ -0,0 +1,142 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"main/internal/dto"
+	"main/internal/models"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// traitQueryPrefix префикс параметров фильтрации по атрибутам, например trait.level.gte=5
+const traitQueryPrefix = "trait."
+
+// display_type, допустимые в метаданных OpenSea, все кроме пустого требуют числового значения
+var numericDisplayTypes = map[string]bool{
+	"number":           true,
+	"boost_number":     true,
+	"boost_percentage": true,
+	"date":             true,
+}
+
+// ParseNftAttributes разбирает и проверяет атрибуты токена, переданные JSON массивом
+func ParseNftAttributes(raw string) ([]models.NftAttribute, error) {
+	const op = "service.ParseNftAttributes"
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var items []dto.NftAttribute
+	decoder := json.NewDecoder(bytes.NewBufferString(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&items); err != nil {
+		return nil, tvoerrors.Wrap(op, tvoerrors.ErrInvalidRequestData)
+	}
+
+	attributes := make([]models.NftAttribute, 0, len(items))
+	for _, item := range items {
+		attribute := models.NftAttribute{
+			TraitType:   strings.TrimSpace(item.TraitType),
+			DisplayType: item.DisplayType,
+		}
+		if attribute.TraitType == "" {
+			return nil, tvoerrors.Wrap(op+": empty trait_type", tvoerrors.ErrInvalidRequestData)
+		}
+
+		switch value := item.Value.(type) {
+		case json.Number:
+			number, err := value.Float64()
+			if err != nil {
+				return nil, tvoerrors.Wrap(op, tvoerrors.ErrInvalidRequestData)
+			}
+			attribute.ValueType = models.AttributeNumber
+			attribute.NumericValue = number
+		case string:
+			if numericDisplayTypes[attribute.DisplayType] {
+				return nil, tvoerrors.Wrap(op+": numeric display_type with string value", tvoerrors.ErrInvalidRequestData)
+			}
+			attribute.ValueType = models.AttributeString
+			attribute.StringValue = value
+		default:
+			return nil, tvoerrors.Wrap(op+": value must be a string or a number", tvoerrors.ErrInvalidRequestData)
+		}
+
+		if attribute.DisplayType != "" && !numericDisplayTypes[attribute.DisplayType] {
+			return nil, tvoerrors.Wrap(op+": unknown display_type", tvoerrors.ErrInvalidRequestData)
+		}
+
+		attributes = append(attributes, attribute)
+	}
+
+	return attributes, nil
+}
+
+// AttributesToDto преобразует атрибуты для ответа API
+func AttributesToDto(attributes []models.NftAttribute) []dto.NftAttribute {
+	result := make([]dto.NftAttribute, 0, len(attributes))
+	for _, attribute := range attributes {
+		result = append(result, dto.NftAttribute{
+			TraitType:   attribute.TraitType,
+			DisplayType: attribute.DisplayType,
+			Value:       attribute.Value(),
+		})
+	}
+	return result
+}
+
+// AttributesToTraits преобразует атрибуты в формат метаданных ERC-721
+func AttributesToTraits(attributes []models.NftAttribute) []models.NftMetadataTrait {
+	traits := make([]models.NftMetadataTrait, 0, len(attributes))
+	for _, attribute := range attributes {
+		traits = append(traits, attribute.Trait())
+	}
+	return traits
+}
+
+// ParseTraitFilters собирает фильтры из параметров вида trait.<name>=<value> и trait.<name>.<op>=<value>.
+// Несколько значений одного равенства объединяются через ИЛИ.
+func ParseTraitFilters(query url.Values) ([]models.TraitFilter, error) {
+	const op = "service.ParseTraitFilters"
+
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		if strings.HasPrefix(key, traitQueryPrefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	filters := make([]models.TraitFilter, 0, len(keys))
+	for _, key := range keys {
+		traitType, operator := strings.TrimPrefix(key, traitQueryPrefix), models.TraitEq
+		if i := strings.LastIndex(traitType, "."); i > 0 {
+			switch suffix := traitType[i+1:]; suffix {
+			case models.TraitEq, models.TraitGt, models.TraitGte, models.TraitLt, models.TraitLte:
+				traitType, operator = traitType[:i], suffix
+			}
+		}
+		if traitType == "" {
+			return nil, tvoerrors.Wrap(op, tvoerrors.ErrInvalidRequestData)
+		}
+
+		if operator == models.TraitEq {
+			filters = append(filters, models.TraitFilter{TraitType: traitType, Op: operator, Values: query[key]})
+			continue
+		}
+
+		// у сравнений каждое значение - отдельное условие
+		for _, value := range query[key] {
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return nil, tvoerrors.Wrap(op+": non-numeric range value", tvoerrors.ErrInvalidRequestData)
+			}
+			filters = append(filters, models.TraitFilter{TraitType: traitType, Op: operator, Values: []string{value}})
+		}
+	}
+
+	return filters, nil
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"main/internal/models"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+func TestParseNftAttributes(t *testing.T) {
+	attributes, err := ParseNftAttributes(`[{"trait_type":"Background","value":"Blue"},
+		{"trait_type":"level","value":5,"display_type":"number"}]`)
+	if err != nil {
+		t.Fatalf("ParseNftAttributes() error = %v", err)
+	}
+
+	expected := []models.NftAttribute{
+		{TraitType: "Background", ValueType: models.AttributeString, StringValue: "Blue"},
+		{TraitType: "level", DisplayType: "number", ValueType: models.AttributeNumber, NumericValue: 5},
+	}
+	if !reflect.DeepEqual(attributes, expected) {
+		t.Errorf("ParseNftAttributes() = %+v, expected %+v", attributes, expected)
+	}
+
+	invalid := []string{
+		`{"trait_type":"level"}`,
+		`[{"value":"Blue"}]`,
+		`[{"trait_type":"level","value":true}]`,
+		`[{"trait_type":"level","value":"high","display_type":"number"}]`,
+		`[{"trait_type":"level","value":5,"display_type":"stars"}]`,
+	}
+	for _, raw := range invalid {
+		if _, err = ParseNftAttributes(raw); !errors.Is(err, tvoerrors.ErrInvalidRequestData) {
+			t.Errorf("ParseNftAttributes(%s) error = %v, expected ErrInvalidRequestData", raw, err)
+		}
+	}
+}
+
+func TestParseTraitFilters(t *testing.T) {
+	query, _ := url.ParseQuery("trait.Background=Blue&trait.Background=Red&trait.level.gte=5&trait.eye.color=green&limit=10")
+
+	filters, err := ParseTraitFilters(query)
+	if err != nil {
+		t.Fatalf("ParseTraitFilters() error = %v", err)
+	}
+
+	expected := []models.TraitFilter{
+		{TraitType: "Background", Op: models.TraitEq, Values: []string{"Blue", "Red"}},
+		{TraitType: "eye.color", Op: models.TraitEq, Values: []string{"green"}},
+		{TraitType: "level", Op: models.TraitGte, Values: []string{"5"}},
+	}
+	if !reflect.DeepEqual(filters, expected) {
+		t.Errorf("ParseTraitFilters() = %+v, expected %+v", filters, expected)
+	}
+
+	query, _ = url.ParseQuery("trait.level.lt=high")
+	if _, err = ParseTraitFilters(query); !errors.Is(err, tvoerrors.ErrInvalidRequestData) {
+		t.Errorf("ParseTraitFilters() error = %v, expected ErrInvalidRequestData", err)
+	}
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+
+	"main/internal/dto"
+	"main/internal/lib/cids"
+	"main/internal/models"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+var (
+	// slugRegexp адрес коллекции в URL: строчные латинские буквы, цифры и дефисы
+	slugRegexp = regexp.MustCompile(`^[a-z0-9](?:[a-z0-9-]{1,62}[a-z0-9])$`)
+	// contractAddressRegexp адрес контракта EVM
+	contractAddressRegexp = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+)
+
+// ValidateCollection проверяет поля коллекции перед сохранением.
+// CID обложки принимается в любой форме и приводится к каноническому виду.
+func ValidateCollection(collection *models.Collection) error {
+	const op = "service.ValidateCollection"
+
+	if !slugRegexp.MatchString(collection.Slug) {
+		return tvoerrors.Wrap(op+": invalid slug", tvoerrors.ErrInvalidRequestData)
+	}
+	if strings.TrimSpace(collection.Name) == "" {
+		return tvoerrors.Wrap(op+": empty name", tvoerrors.ErrInvalidRequestData)
+	}
+	if collection.ContractAddress != "" && !contractAddressRegexp.MatchString(collection.ContractAddress) {
+		return tvoerrors.Wrap(op+": invalid contract address", tvoerrors.ErrInvalidRequestData)
+	}
+	if collection.TokenStandard != models.TokenStandardERC721 && collection.TokenStandard != models.TokenStandardERC1155 {
+		return tvoerrors.Wrap(op+": unknown token standard", tvoerrors.ErrInvalidRequestData)
+	}
+	if collection.ChainId < 0 {
+		return tvoerrors.Wrap(op+": invalid chain id", tvoerrors.ErrInvalidRequestData)
+	}
+	if collection.CoverCid != "" {
+		cover, err := cids.Parse(collection.CoverCid)
+		if err != nil {
+			return tvoerrors.Wrap(op+": invalid cover cid", tvoerrors.ErrInvalidRequestData)
+		}
+		collection.CoverCid = cover.String()
+	}
+	return nil
+}
+
+// ApplyCollectionUpdate переносит в коллекцию заданные в запросе поля
+func ApplyCollectionUpdate(collection *models.Collection, request *dto.UpdateCollectionRequest) {
+	if request.Name != nil {
+		collection.Name = *request.Name
+	}
+	if request.Symbol != nil {
+		collection.Symbol = *request.Symbol
+	}
+	if request.Description != nil {
+		collection.Description = *request.Description
+	}
+	if request.CoverCid != nil {
+		collection.CoverCid = *request.CoverCid
+	}
+	if request.ContractAddress != nil {
+		collection.ContractAddress = *request.ContractAddress
+	}
+	if request.ChainId != nil {
+		collection.ChainId = *request.ChainId
+	}
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"main/internal/dto"
+	"main/internal/models"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+func TestValidateCollection(t *testing.T) {
+	const address = "0x1A92f7381B9F03921564a437210bB9396471050C"
+	erc721, erc1155 := models.TokenStandardERC721, models.TokenStandardERC1155
+
+	tests := []struct {
+		slug, name, address, standard string
+		valid                         bool
+	}{
+		{"cool-cats", "Cool Cats", "", erc721, true},
+		{"cool-cats", "Cool Cats", address, erc721, true},
+		{"cool-cats", "Cool Cats", "", erc1155, true},
+		{"cc", "Cool Cats", "", erc721, false},
+		{"Cool-Cats", "Cool Cats", "", erc721, false},
+		{"cool-cats-", "Cool Cats", "", erc721, false},
+		{"cool-cats", " ", "", erc721, false},
+		{"cool-cats", "Cool Cats", "0x1A92", erc721, false},
+		{"cool-cats", "Cool Cats", "", "erc20", false},
+	}
+
+	for _, test := range tests {
+		collection := models.Collection{Slug: test.slug, Name: test.name, ContractAddress: test.address,
+			TokenStandard: test.standard}
+		err := ValidateCollection(&collection)
+		if test.valid && err != nil {
+			t.Errorf("ValidateCollection(%+v) error = %v", collection, err)
+		}
+		if !test.valid && !errors.Is(err, tvoerrors.ErrInvalidRequestData) {
+			t.Errorf("ValidateCollection(%+v) error = %v, expected ErrInvalidRequestData", collection, err)
+		}
+	}
+}
+
+func TestApplyCollectionUpdate(t *testing.T) {
+	collection := models.Collection{TokenStandard: models.TokenStandardERC721, Slug: "cool-cats", Name: "Cool Cats", Symbol: "COOL"}
+	name := "Cooler Cats"
+	ApplyCollectionUpdate(&collection, &dto.UpdateCollectionRequest{Name: &name})
+
+	if collection.Name != name || collection.Symbol != "COOL" {
+		t.Errorf("ApplyCollectionUpdate() = %+v", collection)
+	}
+}
+
+func TestValidateCollectionCoverCid(t *testing.T) {
+	tests := []struct {
+		cover, expected string
+		valid           bool
+	}{
+		{"", "", true},
+		{"QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG", "QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG", true},
+		{"ipfs://bafybeiezfsxd3jlaivqftqlqbmw6kzfgpfnmdyvyexkfrwvlh4pxp2gh5q",
+			"bafybeiezfsxd3jlaivqftqlqbmw6kzfgpfnmdyvyexkfrwvlh4pxp2gh5q", true},
+		{"not-a-cid", "", false},
+	}
+
+	for _, test := range tests {
+		collection := models.Collection{Slug: "cool-cats", Name: "Cool Cats", CoverCid: test.cover,
+			TokenStandard: models.TokenStandardERC721}
+		err := ValidateCollection(&collection)
+		if test.valid && (err != nil || collection.CoverCid != test.expected) {
+			t.Errorf("ValidateCollection(%q) = %q, %v, expected %q", test.cover, collection.CoverCid, err, test.expected)
+		}
+		if !test.valid && !errors.Is(err, tvoerrors.ErrInvalidRequestData) {
+			t.Errorf("ValidateCollection(%q) error = %v, expected ErrInvalidRequestData", test.cover, err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// MaxDirectoryFiles ограничивает число файлов в одной загрузке директории
+const MaxDirectoryFiles = 10000
+
+// DirectoryEntry файл загружаемой директории. Open вызывается один раз, когда файл передается в хранилище.
+type DirectoryEntry struct {
+	Path string
+	Open func() (io.ReadCloser, error)
+}
+
+// CleanUploadPath приводит относительный путь файла из формы к виду images/1.png.
+// Абсолютные пути, выход за пределы директории и пустые имена считаются ошибкой.
+func CleanUploadPath(raw string) (string, error) {
+	cleaned := path.Clean(strings.ReplaceAll(raw, "\\", "/"))
+	if raw == "" || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "/") ||
+		strings.HasPrefix(cleaned, "../") {
+		return "", tvoerrors.Wrap("service.CleanUploadPath", tvoerrors.ErrInvalidRequestData)
+	}
+	return cleaned, nil
+}
+
+// ValidateDirectoryPaths проверяет, что пути файлов не повторяются и ни один файл
+// не совпадает с директорией другого файла (a.json и a.json/1.png)
+func ValidateDirectoryPaths(paths []string) error {
+	const op = "service.ValidateDirectoryPaths"
+
+	if len(paths) == 0 || len(paths) > MaxDirectoryFiles {
+		return tvoerrors.Wrap(op, tvoerrors.ErrInvalidRequestData)
+	}
+
+	files := make(map[string]struct{}, len(paths))
+	for _, p := range paths {
+		if _, ok := files[p]; ok {
+			return tvoerrors.Wrap(op, tvoerrors.ErrInvalidRequestData)
+		}
+		files[p] = struct{}{}
+	}
+	for _, p := range paths {
+		for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+			if _, ok := files[dir]; ok {
+				return tvoerrors.Wrap(op, tvoerrors.ErrInvalidRequestData)
+			}
+		}
+	}
+	return nil
+}
+
+// TokenIdFromPath определяет токен файла по имени без расширения: 1.json и images/1.png относятся к токену 1.
+// Имя в 64 шестнадцатеричных символа разбирается по шаблону {id} ERC-1155. Номер записывается
+// без ведущих нулей, иначе ссылка ipfs://<root>/<id>.json на файл не указывает.
+func TokenIdFromPath(p string) (int64, bool) {
+	base := path.Base(p)
+	name := strings.TrimSuffix(base, path.Ext(base))
+	if name == "" || strings.IndexFunc(name, isNotHexDigit) >= 0 {
+		return 0, false
+	}
+
+	tokenId, err := ParseTokenId(name)
+	if err != nil || (len(name) != 64 && strconv.FormatInt(tokenId, 10) != name) {
+		return 0, false
+	}
+	return tokenId, true
+}
+
+func isNotHexDigit(r rune) bool {
+	return !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f' || r >= 'A' && r <= 'F')
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+func TestCleanUploadPath(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected string
+		fail     bool
+	}{
+		{raw: "1.json", expected: "1.json"},
+		{raw: "./images//1.png", expected: "images/1.png"},
+		{raw: `images\1.png`, expected: "images/1.png"},
+		{raw: "images/../1.png", expected: "1.png"},
+		{raw: "", fail: true},
+		{raw: ".", fail: true},
+		{raw: "/etc/passwd", fail: true},
+		{raw: "../1.png", fail: true},
+		{raw: "images/../../1.png", fail: true},
+	}
+
+	for _, test := range tests {
+		result, err := CleanUploadPath(test.raw)
+		if test.fail {
+			if !errors.Is(err, tvoerrors.ErrInvalidRequestData) {
+				t.Errorf("CleanUploadPath(%q) error = %v, expected invalid request data", test.raw, err)
+			}
+			continue
+		}
+		if err != nil || result != test.expected {
+			t.Errorf("CleanUploadPath(%q) = %q, %v, expected %q", test.raw, result, err, test.expected)
+		}
+	}
+}
+
+func TestValidateDirectoryPaths(t *testing.T) {
+	tests := []struct {
+		paths []string
+		fail  bool
+	}{
+		{paths: []string{"1.json", "1.png", "images/2.png"}},
+		{paths: nil, fail: true},
+		{paths: []string{"1.json", "1.json"}, fail: true},
+		{paths: []string{"images", "images/1.png"}, fail: true},
+		{paths: []string{"a/b", "a/b/c/1.png"}, fail: true},
+	}
+
+	for _, test := range tests {
+		if err := ValidateDirectoryPaths(test.paths); (err != nil) != test.fail {
+			t.Errorf("ValidateDirectoryPaths(%v) error = %v, expected failure %v", test.paths, err, test.fail)
+		}
+	}
+}
+
+func TestTokenIdFromPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected int64
+		ok       bool
+	}{
+		{"1.json", 1, true},
+		{"images/42.png", 42, true},
+		{"0", 0, true},
+		{"000000000000000000000000000000000000000000000000000000000004cce0.json", 314592, true},
+		{"01.json", 0, false},
+		{"+1.json", 0, false},
+		{"cover.png", 0, false},
+		{"1a.json", 0, false},
+		{".json", 0, false},
+		{"1/cover.png", 0, false},
+	}
+
+	for _, test := range tests {
+		result, ok := TokenIdFromPath(test.path)
+		if result != test.expected || ok != test.ok {
+			t.Errorf("TokenIdFromPath(%q) = %d, %v, expected %d, %v", test.path, result, ok, test.expected, test.ok)
+		}
+	}
+}
@@ -3,156 +3,662 @@ package service
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/ipfs/go-cid"
 	"io"
-	"main/internal/models"
 	"mime/multipart"
+	"net"
 	"net/http"
+	"net/textproto"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"main/internal/config"
+	"main/internal/models"
+	tvoerrors "main/tools/pkg/tvo_errors"
 )
 
-const kuboApiBaseUrl = "http://127.0.0.1:5001/api/v0"
-const KuboGatewayUrlTemplate = "http://%s.ipfs.localhost:8080/"
+// defaultKuboTimeout используется, если в конфиге не задан таймаут вызова
+const defaultKuboTimeout = 30 * time.Second
 
-// AddFileToIPFS загружает файл в узел Kubo и возвращает информацию о нем.
-func AddFileToIPFS(fileHeader *multipart.FileHeader) (*models.AddResponse, string, string, error) {
-	file, err := fileHeader.Open()
-	if err != nil {
-		return nil, "", "", fmt.Errorf("не удалось открыть файл: %w", err)
+// defaultAddTimeout используется, если в конфиге не задан таймаут загрузки
+const defaultAddTimeout = 10 * time.Minute
+
+// defaultPinTimeout используется, если в конфиге не задан таймаут закрепления
+const defaultPinTimeout = 10 * time.Minute
+
+// addBufferSize размер буфера копирования при потоковой загрузке
+const addBufferSize = 64 * 1024
+
+// KuboClient клиент для работы с Kubo RPC API.
+// Один экземпляр разделяется между всеми обработчиками и переиспользует соединения.
+type KuboClient struct {
+	apiURL     string
+	timeout    time.Duration
+	addTimeout time.Duration
+	pinTimeout time.Duration
+	authHeader string
+	client     *http.Client
+}
+
+// NewKuboClient создает клиента Kubo на основе конфига приложения
+func NewKuboClient(cfg *config.IPFS) *KuboClient {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultKuboTimeout
+	}
+	addTimeout := cfg.AddTimeout
+	if addTimeout <= 0 {
+		addTimeout = defaultAddTimeout
+	}
+	pinTimeout := cfg.PinTimeout
+	if pinTimeout <= 0 {
+		pinTimeout = defaultPinTimeout
 	}
-	defer file.Close()
 
-	var requestBody bytes.Buffer
-	writer := multipart.NewWriter(&requestBody)
-	part, err := writer.CreateFormFile("file", fileHeader.Filename)
-	if err != nil {
-		return nil, "", "", fmt.Errorf("не удалось создать form-file: %w", err)
+	// общий транспорт для всех запросов к узлу
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   5 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   20,
+		IdleConnTimeout:       90 * time.Second,
+		ExpectContinueTimeout: time.Second,
 	}
-	if _, err := io.Copy(part, file); err != nil {
-		return nil, "", "", fmt.Errorf("не удалось скопировать данные файла: %w", err)
+
+	return &KuboClient{
+		apiURL:     strings.TrimRight(cfg.APIURL, "/"),
+		timeout:    timeout,
+		addTimeout: addTimeout,
+		pinTimeout: pinTimeout,
+		authHeader: kuboAuthHeader(cfg),
+		client:     &http.Client{Transport: transport},
+	}
+}
+
+// kuboAuthHeader формирует заголовок Authorization для API.Authorizations.
+// Bearer токен имеет приоритет над Basic авторизацией.
+func kuboAuthHeader(cfg *config.IPFS) string {
+	switch {
+	case cfg.AuthToken != "":
+		return "Bearer " + cfg.AuthToken
+	case cfg.AuthUsername != "":
+		credentials := cfg.AuthUsername + ":" + cfg.AuthPassword
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+	default:
+		return ""
+	}
+}
+
+// newRequest создает POST запрос к RPC методу Kubo
+func (k *KuboClient) newRequest(ctx context.Context, method string, args url.Values, body io.Reader) (*http.Request, error) {
+	endpoint := k.apiURL + "/" + method
+	if len(args) > 0 {
+		endpoint += "?" + args.Encode()
 	}
-	writer.Close()
 
-	// Документация на Kubo RPC API подтверждает использование этого эндпоинта
+	// Kubo RPC API принимает только POST запросы
 	// Источник: https://docs.ipfs.tech/reference/kubo/rpc/
-	req, err := http.NewRequest("POST", kuboApiBaseUrl+"/add", &requestBody)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, body)
 	if err != nil {
-		return nil, "", "", fmt.Errorf("не удалось создать запрос к Kubo: %w", err)
+		return nil, err
+	}
+	if k.authHeader != "" {
+		req.Header.Set("Authorization", k.authHeader)
+	}
+	return req, nil
+}
+
+// call выполняет RPC метод с таймаутом и декодирует JSON ответ в out
+func (k *KuboClient) call(ctx context.Context, method string, args url.Values, body io.Reader, contentType string,
+	out interface{}) error {
+	return k.callWithTimeout(ctx, k.timeout, method, args, body, contentType, out)
+}
+
+// callWithTimeout выполняет RPC метод с заданным таймаутом
+func (k *KuboClient) callWithTimeout(ctx context.Context, timeout time.Duration, method string, args url.Values,
+	body io.Reader, contentType string, out interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := k.newRequest(ctx, method, args, body)
+	if err != nil {
+		return fmt.Errorf("не удалось создать запрос к Kubo (%s): %w", method, err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
 	}
-	req.Header.Set("Content-Type", writer.FormDataContentType())
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := k.client.Do(req)
 	if err != nil {
-		return nil, "", "", fmt.Errorf("ошибка при выполнении запроса к Kubo: %w", err)
+		return fmt.Errorf("ошибка при выполнении запроса к Kubo (%s): %w", method, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, "", "", fmt.Errorf("Kubo API вернул ошибку: %s, тело ответа: %s", resp.Status, string(bodyBytes))
+		bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("Kubo API (%s) вернул ошибку: %s, тело ответа: %s", method, resp.Status, string(bodyBytes))
 	}
 
-	var addResp models.AddResponse
-	if err := json.NewDecoder(resp.Body).Decode(&addResp); err != nil {
-		return nil, "", "", fmt.Errorf("не удалось декодировать ответ от Kubo: %w", err)
+	if out == nil {
+		return nil
+	}
+	dec := json.NewDecoder(resp.Body)
+	if stream, ok := out.(jsonStream); ok {
+		err = stream(dec)
+	} else {
+		err = dec.Decode(out)
 	}
-
-	// --- Начало новой логики ---
-	// Декодируем полученный CIDv0 (начинается с "Qm")
-	// Источник: https://pkg.go.dev/github.com/ipfs/go-cid#Decode
-	cidV0, err := cid.Decode(addResp.Hash)
 	if err != nil {
-		return nil, "", "", fmt.Errorf("не удалось декодировать CID: %w", err)
+		return fmt.Errorf("не удалось декодировать ответ от Kubo (%s): %w", method, err)
 	}
+	return nil
+}
 
-	cidV1 := cid.NewCidV1(cid.DagProtobuf, cidV0.Hash())
-	gatewayURL := fmt.Sprintf(KuboGatewayUrlTemplate, cidV1.String())
+// jsonStream декодирует ответ из нескольких JSON объектов подряд
+type jsonStream func(dec *json.Decoder) error
 
-	return &addResp, cidV1.String(), gatewayURL, nil
+// stream выполняет RPC метод и возвращает тело ответа без декодирования
+func (k *KuboClient) stream(ctx context.Context, method string, args url.Values) (io.ReadCloser, error) {
+	return k.streamWithTimeout(ctx, k.timeout, method, args)
 }
 
-// PinCID закрепляет (pins) CID на узле Kubo.
-func PinCID(cid string) (*models.PinResponse, error) {
-	// Эндпоинт для закрепления: /api/v0/pin/add
-	// Источник: https://github.com/ipfs/kubo
-	url := fmt.Sprintf("%s/pin/add?arg=%s", kuboApiBaseUrl, cid)
-	req, err := http.NewRequest("POST", url, nil)
+// streamWithTimeout выполняет RPC метод с заданным таймаутом, который действует до закрытия тела ответа
+func (k *KuboClient) streamWithTimeout(ctx context.Context, timeout time.Duration, method string,
+	args url.Values) (io.ReadCloser, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+
+	req, err := k.newRequest(ctx, method, args, nil)
 	if err != nil {
-		return nil, fmt.Errorf("не удалось создать запрос на закрепление: %w", err)
+		cancel()
+		return nil, fmt.Errorf("не удалось создать запрос к Kubo (%s): %w", method, err)
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := k.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("ошибка при выполнении запроса на закрепление: %w", err)
+		cancel()
+		return nil, fmt.Errorf("ошибка при выполнении запроса к Kubo (%s): %w", method, err)
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Kubo API (pin) вернул ошибку: %s", resp.Status)
+		defer cancel()
+		defer resp.Body.Close()
+		bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("Kubo API (%s) вернул ошибку: %s, тело ответа: %s", method, resp.Status, string(bodyBytes))
 	}
 
-	var pinResp models.PinResponse
-	if err := json.NewDecoder(resp.Body).Decode(&pinResp); err != nil {
-		return nil, fmt.Errorf("не удалось декодировать ответ от Kubo (pin): %w", err)
+	return &cancelReadCloser{ReadCloser: resp.Body, cancel: cancel}, nil
+}
+
+// cancelReadCloser отменяет контекст запроса при закрытии тела ответа
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close закрывает тело ответа и освобождает контекст
+func (r *cancelReadCloser) Close() error {
+	defer r.cancel()
+	return r.ReadCloser.Close()
+}
+
+// Add потоково загружает содержимое reader в узел Kubo под именем name.
+func (k *KuboClient) Add(ctx context.Context, name string, r io.Reader) (*models.AddResponse, error) {
+	var addResp models.AddResponse
+	if err := k.upload(ctx, "add", nil, name, r, &addResp); err != nil {
+		return nil, err
 	}
-	return &pinResp, nil
+	return &addResp, nil
 }
 
-// UnpinCID открепляет (unpins) CID с узла Kubo.
-func UnpinCID(cid string) (*models.PinResponse, error) {
-	// Эндпоинт для открепления: /api/v0/pin/rm
-	// Источник: https://github.com/ipfs/kubo
-	url := fmt.Sprintf("%s/pin/rm?arg=%s", kuboApiBaseUrl, cid)
-	req, err := http.NewRequest("POST", url, nil)
+// AddOnlyHash вычисляет CID, который получит содержимое reader при загрузке, ничего не сохраняя в узле.
+// Узел применяет те же настройки импорта, что и при Add, поэтому CID совпадают.
+func (k *KuboClient) AddOnlyHash(ctx context.Context, name string, r io.Reader) (*models.AddResponse, error) {
+	var addResp models.AddResponse
+	if err := k.upload(ctx, "add", url.Values{"only-hash": {"true"}}, name, r, &addResp); err != nil {
+		return nil, err
+	}
+	return &addResp, nil
+}
+
+// DagImport загружает CAR файл (CARv1 или CARv2) в узел Kubo и закрепляет его корни.
+// Возвращает корни архива, ошибка закрепления любого корня считается ошибкой импорта.
+func (k *KuboClient) DagImport(ctx context.Context, r io.Reader) ([]string, error) {
+	// Эндпоинт для импорта: /api/v0/dag/import, ответ содержит по объекту на каждый корень
+	var roots []string
+	decode := jsonStream(func(dec *json.Decoder) error {
+		for {
+			var line models.DagImportResponse
+			if err := dec.Decode(&line); errors.Is(err, io.EOF) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			if line.Root == nil {
+				continue
+			}
+			if line.Root.PinErrorMsg != "" {
+				return fmt.Errorf("не удалось закрепить корень %s: %s", line.Root.Cid.Link, line.Root.PinErrorMsg)
+			}
+			roots = append(roots, line.Root.Cid.Link)
+		}
+	})
+
+	args := url.Values{"pin-roots": {"true"}}
+	if err := k.upload(ctx, "dag/import", args, "import.car", r, decode); err != nil {
+		return nil, err
+	}
+	return roots, nil
+}
+
+// DagExport возвращает DAG с корнем cid в виде CARv1 файла.
+// Выгрузка может быть долгой, поэтому действует таймаут загрузки.
+func (k *KuboClient) DagExport(ctx context.Context, cid string) (io.ReadCloser, error) {
+	return k.streamWithTimeout(ctx, k.addTimeout, "dag/export", url.Values{"arg": {cid}})
+}
+
+// upload потоково передает содержимое reader методу Kubo как multipart файл name.
+func (k *KuboClient) upload(ctx context.Context, method string, args url.Values, name string, r io.Reader,
+	out interface{}) error {
+	return k.uploadParts(ctx, method, args, func(writer *multipart.Writer) error {
+		part, err := writer.CreateFormFile("file", name)
+		if err != nil {
+			return err
+		}
+		_, err = io.CopyBuffer(part, r, make([]byte, addBufferSize))
+		return err
+	}, out)
+}
+
+// uploadParts передает методу Kubo multipart тело, части которого записывает write.
+// Тело формируется в отдельной горутине и передается в запрос через io.Pipe,
+// поэтому в памяти находится только буфер копирования, а не все файлы.
+func (k *KuboClient) uploadParts(ctx context.Context, method string, args url.Values,
+	write func(writer *multipart.Writer) error, out interface{}) error {
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		err := write(writer)
+		if err == nil {
+			err = writer.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	err := k.callWithTimeout(ctx, k.addTimeout, method, args, pr, writer.FormDataContentType(), out)
+
+	// останавливаем горутину, если Kubo не дочитал тело, и дожидаемся ее,
+	// чтобы файлы не читались после возврата из метода
+	pr.CloseWithError(io.ErrClosedPipe)
+	<-done
+
+	return err
+}
+
+// AddDirectory потоково загружает файлы в узел Kubo одной директорией (wrap-with-directory),
+// относительные пути файлов сохраняются. Возвращает ответы add по всем файлам и поддиректориям,
+// корневая директория идет в ответе с пустым именем.
+func (k *KuboClient) AddDirectory(ctx context.Context, entries []DirectoryEntry) ([]models.AddResponse, error) {
+	// узел разбирает тело как обход дерева: директория передается до своих файлов, а ее содержимое
+	// идет подряд, что дает сортировка путей
+	sorted := slices.Clone(entries)
+	slices.SortFunc(sorted, func(a, b DirectoryEntry) int { return strings.Compare(a.Path, b.Path) })
+
+	var added []models.AddResponse
+	decode := jsonStream(func(dec *json.Decoder) error {
+		for {
+			var line models.AddResponse
+			if err := dec.Decode(&line); errors.Is(err, io.EOF) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			added = append(added, line)
+		}
+	})
+
+	write := func(writer *multipart.Writer) error {
+		written := map[string]bool{}
+		buf := make([]byte, addBufferSize)
+		for _, entry := range sorted {
+			if err := writeParentDirs(writer, entry.Path, written); err != nil {
+				return err
+			}
+			part, err := writer.CreatePart(kuboPartHeader(entry.Path, "application/octet-stream"))
+			if err != nil {
+				return err
+			}
+			file, err := entry.Open()
+			if err != nil {
+				return err
+			}
+			_, err = io.CopyBuffer(part, file, buf)
+			_ = file.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	args := url.Values{"wrap-with-directory": {"true"}}
+	if err := k.uploadParts(ctx, "add", args, write, decode); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// writeParentDirs записывает части директорий пути p, которые еще не были записаны
+func writeParentDirs(writer *multipart.Writer, p string, written map[string]bool) error {
+	var dirs []string
+	for dir := path.Dir(p); dir != "." && !written[dir]; dir = path.Dir(dir) {
+		dirs = append(dirs, dir)
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if _, err := writer.CreatePart(kuboPartHeader(dirs[i], "application/x-directory")); err != nil {
+			return err
+		}
+		written[dirs[i]] = true
+	}
+	return nil
+}
+
+// kuboPartHeader формирует заголовок части файла или директории, Kubo ожидает путь в url-кодировке
+func kuboPartHeader(p, contentType string) textproto.MIMEHeader {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, url.QueryEscape(p)))
+	header.Set("Content-Type", contentType)
+	return header
+}
+
+// Cat возвращает содержимое файла по CID. Выгрузка большого файла может быть долгой, поэтому
+// действует таймаут загрузки, он действует до закрытия reader.
+func (k *KuboClient) Cat(ctx context.Context, cid string) (io.ReadCloser, error) {
+	return k.streamWithTimeout(ctx, k.addTimeout, "cat", url.Values{"arg": {cid}})
+}
+
+// CatRange возвращает length байт файла по CID начиная со смещения offset, отрицательная длина
+// читает файл до конца. Как и в Cat, действует таймаут загрузки.
+func (k *KuboClient) CatRange(ctx context.Context, cid string, offset, length int64) (io.ReadCloser, error) {
+	args := url.Values{"arg": {cid}, "offset": {strconv.FormatInt(offset, 10)}}
+	if length >= 0 {
+		args.Set("length", strconv.FormatInt(length, 10))
+	}
+	return k.streamWithTimeout(ctx, k.addTimeout, "cat", args)
+}
+
+// maxBlockSize ограничивает размер блока, Kubo не передает блоки больше 2 MiB
+const maxBlockSize = 2 << 20
+
+// BlockGet возвращает блок по CID только из локального хранилища узла, без поиска в сети.
+// Отсутствие блока на узле возвращается как tvoerrors.ErrNotFound.
+func (k *KuboClient) BlockGet(ctx context.Context, cid string) ([]byte, error) {
+	const op = "service.KuboClient.BlockGet"
+
+	// Эндпоинт для получения блока: /api/v0/block/get
+	body, err := k.stream(ctx, "block/get", url.Values{"arg": {cid}, "offline": {"true"}})
 	if err != nil {
-		return nil, fmt.Errorf("не удалось создать запрос на открепление: %w", err)
+		if strings.Contains(err.Error(), "not found") {
+			return nil, tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+		}
+		return nil, err
 	}
+	defer body.Close()
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	block, err := io.ReadAll(io.LimitReader(body, maxBlockSize+1))
 	if err != nil {
-		return nil, fmt.Errorf("ошибка при выполнении запроса на открепление: %w", err)
+		return nil, fmt.Errorf("не удалось прочитать блок %s: %w", cid, err)
 	}
-	defer resp.Body.Close()
+	if len(block) > maxBlockSize {
+		return nil, fmt.Errorf("блок %s больше %d байт", cid, maxBlockSize)
+	}
+	return block, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Kubo API (unpin) вернул ошибку: %s", resp.Status)
+// Resolve разрешает IPFS путь /ipfs/<cid>/<path> в путь /ipfs/<cid> объекта.
+// Отсутствие объекта по пути возвращается как tvoerrors.ErrNotFound.
+func (k *KuboClient) Resolve(ctx context.Context, ipfsPath string) (string, error) {
+	// Эндпоинт для разрешения пути: /api/v0/resolve
+	var resolveResp models.ResolveResponse
+	if err := k.call(ctx, "resolve", url.Values{"arg": {ipfsPath}}, nil, "", &resolveResp); err != nil {
+		// Kubo отвечает на отсутствующую ссылку ошибкой 500
+		if strings.Contains(err.Error(), "no link named") {
+			return "", tvoerrors.Wrap("service.KuboClient.Resolve", tvoerrors.ErrNotFound)
+		}
+		return "", err
+	}
+	return resolveResp.Path, nil
+}
+
+// Pin закрепляет (pins) CID на узле Kubo.
+// Узел может скачивать DAG из сети, поэтому действует отдельный таймаут закрепления.
+func (k *KuboClient) Pin(ctx context.Context, cid string) (*models.PinResponse, error) {
+	// Эндпоинт для закрепления: /api/v0/pin/add
+	var pinResp models.PinResponse
+	if err := k.callWithTimeout(ctx, k.pinTimeout, "pin/add", url.Values{"arg": {cid}}, nil, "", &pinResp); err != nil {
+		return nil, err
 	}
+	return &pinResp, nil
+}
 
+// Unpin открепляет (unpins) CID с узла Kubo.
+func (k *KuboClient) Unpin(ctx context.Context, cid string) (*models.PinResponse, error) {
+	// Эндпоинт для открепления: /api/v0/pin/rm
 	var unpinResp models.PinResponse
-	if err := json.NewDecoder(resp.Body).Decode(&unpinResp); err != nil {
-		return nil, fmt.Errorf("не удалось декодировать ответ от Kubo (unpin): %w", err)
+	if err := k.call(ctx, "pin/rm", url.Values{"arg": {cid}}, nil, "", &unpinResp); err != nil {
+		return nil, err
 	}
 	return &unpinResp, nil
 }
 
-// ListPinnedCIDs возвращает список всех закрепленных CID.
-func ListPinnedCIDs() (*models.PinLsResponse, error) {
+// ListPins возвращает список рекурсивно закрепленных CID. Косвенно закрепленные блоки
+// не перечисляются: их держат корни, а список всех блоков репозитория может быть очень большим.
+func (k *KuboClient) ListPins(ctx context.Context) (*models.PinLsResponse, error) {
 	// Эндпоинт для получения списка закрепленных объектов: /api/v0/pin/ls
-	// Источник: https://github.com/ipfs/kubo
-	url := fmt.Sprintf("%s/pin/ls", kuboApiBaseUrl)
-	req, err := http.NewRequest("POST", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("не удалось создать запрос на получение списка: %w", err)
+	var lsResp models.PinLsResponse
+	if err := k.call(ctx, "pin/ls", url.Values{"type": {"recursive"}}, nil, "", &lsResp); err != nil {
+		return nil, err
+	}
+	return &lsResp, nil
+}
+
+// ID возвращает идентификатор узла Kubo и его адреса.
+func (k *KuboClient) ID(ctx context.Context) (*models.IdResponse, error) {
+	// Эндпоинт для получения информации об узле: /api/v0/id
+	var idResp models.IdResponse
+	if err := k.call(ctx, "id", nil, nil, "", &idResp); err != nil {
+		return nil, err
 	}
+	return &idResp, nil
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка при выполнении запроса на получение списка: %w", err)
+// KeyGen создает ключ IPNS с именем name. Возвращает имя IPNS ключа в base36.
+func (k *KuboClient) KeyGen(ctx context.Context, name string) (*models.KeyResponse, error) {
+	// Эндпоинт для создания ключа: /api/v0/key/gen
+	var keyResp models.KeyResponse
+	args := url.Values{"arg": {name}, "type": {"ed25519"}, "ipns-base": {"base36"}}
+	if err := k.call(ctx, "key/gen", args, nil, "", &keyResp); err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
+	return &keyResp, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Kubo API (ls) вернул ошибку: %s", resp.Status)
+// KeyList возвращает ключи IPNS узла.
+func (k *KuboClient) KeyList(ctx context.Context) (*models.KeyListResponse, error) {
+	// Эндпоинт для получения списка ключей: /api/v0/key/list
+	var listResp models.KeyListResponse
+	if err := k.call(ctx, "key/list", url.Values{"ipns-base": {"base36"}}, nil, "", &listResp); err != nil {
+		return nil, err
 	}
+	return &listResp, nil
+}
 
-	var lsResp models.PinLsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&lsResp); err != nil {
-		return nil, fmt.Errorf("не удалось декодировать ответ от Kubo (ls): %w", err)
+// KeyImport импортирует ключ IPNS с именем name, key - закрытый ключ libp2p в protobuf.
+// Возвращает имя IPNS ключа в base36.
+func (k *KuboClient) KeyImport(ctx context.Context, name string, key []byte) (*models.KeyResponse, error) {
+	// Эндпоинт для импорта ключа: /api/v0/key/import
+	var keyResp models.KeyResponse
+	args := url.Values{"arg": {name}, "ipns-base": {"base36"}}
+	if err := k.upload(ctx, "key/import", args, name+".key", bytes.NewReader(key), &keyResp); err != nil {
+		return nil, err
+	}
+	return &keyResp, nil
+}
+
+// NamePublish публикует запись IPNS ключа key, указывающую на ipfsPath, со сроком действия lifetime.
+// Запись рассылается в сеть, поэтому действует таймаут закрепления.
+func (k *KuboClient) NamePublish(ctx context.Context, key, ipfsPath string,
+	lifetime time.Duration) (*models.NamePublishResponse, error) {
+	// Эндпоинт для публикации записи: /api/v0/name/publish
+	var publishResp models.NamePublishResponse
+	args := url.Values{
+		"arg":           {ipfsPath},
+		"key":           {key},
+		"lifetime":      {lifetime.String()},
+		"allow-offline": {"true"},
+		"ipns-base":     {"base36"},
+	}
+	if err := k.callWithTimeout(ctx, k.pinTimeout, "name/publish", args, nil, "", &publishResp); err != nil {
+		return nil, err
+	}
+	return &publishResp, nil
+}
+
+// FilesCp копирует объект src (/ipfs/<cid> или путь MFS) в путь MFS dst, недостающие директории создаются.
+func (k *KuboClient) FilesCp(ctx context.Context, src, dst string) error {
+	// Эндпоинт для копирования в MFS: /api/v0/files/cp
+	return k.call(ctx, "files/cp", url.Values{"arg": {src, dst}, "parents": {"true"}}, nil, "", nil)
+}
+
+// FilesRm удаляет файл или директорию MFS вместе с содержимым.
+// Отсутствие пути возвращается как tvoerrors.ErrNotFound.
+func (k *KuboClient) FilesRm(ctx context.Context, path string) error {
+	const op = "service.KuboClient.FilesRm"
+
+	// Эндпоинт для удаления из MFS: /api/v0/files/rm
+	err := k.call(ctx, "files/rm", url.Values{"arg": {path}, "recursive": {"true"}, "force": {"true"}}, nil, "", nil)
+	if err != nil && strings.Contains(err.Error(), "does not exist") {
+		return tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+	}
+	return err
+}
+
+// FilesLs возвращает элементы директории MFS с их CID и размерами.
+// Отсутствие пути возвращается как tvoerrors.ErrNotFound.
+func (k *KuboClient) FilesLs(ctx context.Context, path string) (*models.FilesLsResponse, error) {
+	const op = "service.KuboClient.FilesLs"
+
+	// Эндпоинт для просмотра директории MFS: /api/v0/files/ls
+	var lsResp models.FilesLsResponse
+	if err := k.call(ctx, "files/ls", url.Values{"arg": {path}, "long": {"true"}}, nil, "", &lsResp); err != nil {
+		if strings.Contains(err.Error(), "does not exist") {
+			return nil, tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+		}
+		return nil, err
 	}
 	return &lsResp, nil
 }
+
+// FilesStat возвращает CID и размер файла или директории MFS.
+// Отсутствие пути возвращается как tvoerrors.ErrNotFound.
+func (k *KuboClient) FilesStat(ctx context.Context, path string) (*models.FilesStatResponse, error) {
+	const op = "service.KuboClient.FilesStat"
+
+	// Эндпоинт для описания объекта MFS: /api/v0/files/stat
+	var statResp models.FilesStatResponse
+	if err := k.call(ctx, "files/stat", url.Values{"arg": {path}}, nil, "", &statResp); err != nil {
+		if strings.Contains(err.Error(), "does not exist") {
+			return nil, tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+		}
+		return nil, err
+	}
+	return &statResp, nil
+}
+
+// RepoStat возвращает размер репозитория узла и его предел StorageMax.
+// Объекты не подсчитываются: для этого узлу пришлось бы обойти весь репозиторий.
+func (k *KuboClient) RepoStat(ctx context.Context) (*models.RepoStatResponse, error) {
+	// Эндпоинт для статистики репозитория: /api/v0/repo/stat
+	var statResp models.RepoStatResponse
+	if err := k.call(ctx, "repo/stat", url.Values{"size-only": {"true"}}, nil, "", &statResp); err != nil {
+		return nil, err
+	}
+	return &statResp, nil
+}
+
+// StatsBw возвращает объем и скорость обмена данными узла.
+func (k *KuboClient) StatsBw(ctx context.Context) (*models.StatsBwResponse, error) {
+	// Эндпоинт для статистики трафика: /api/v0/stats/bw
+	var bwResp models.StatsBwResponse
+	if err := k.call(ctx, "stats/bw", nil, nil, "", &bwResp); err != nil {
+		return nil, err
+	}
+	return &bwResp, nil
+}
+
+// SwarmPeers возвращает пиров, к которым подключен узел.
+func (k *KuboClient) SwarmPeers(ctx context.Context) (*models.SwarmPeersResponse, error) {
+	// Эндпоинт для списка подключенных пиров: /api/v0/swarm/peers
+	var peersResp models.SwarmPeersResponse
+	if err := k.call(ctx, "swarm/peers", nil, nil, "", &peersResp); err != nil {
+		return nil, err
+	}
+	return &peersResp, nil
+}
+
+// Version возвращает версию Kubo и репозитория узла.
+func (k *KuboClient) Version(ctx context.Context) (*models.VersionResponse, error) {
+	// Эндпоинт для версии узла: /api/v0/version
+	var versionResp models.VersionResponse
+	if err := k.call(ctx, "version", nil, nil, "", &versionResp); err != nil {
+		return nil, err
+	}
+	return &versionResp, nil
+}
+
+// RepoGC удаляет из репозитория блоки, не удерживаемые закреплениями и MFS, и возвращает число
+// удаленных блоков. Сборка мусора может быть долгой, поэтому действует таймаут закрепления.
+func (k *KuboClient) RepoGC(ctx context.Context) (int, error) {
+	// Эндпоинт для сборки мусора: /api/v0/repo/gc, ответ содержит по объекту на каждый удаленный блок
+	removed := 0
+	decode := jsonStream(func(dec *json.Decoder) error {
+		for {
+			var line models.RepoGcResponse
+			if err := dec.Decode(&line); errors.Is(err, io.EOF) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			if line.Error != "" {
+				return fmt.Errorf("сборка мусора прервана: %s", line.Error)
+			}
+			removed++
+		}
+	})
+
+	args := url.Values{"stream-errors": {"true"}}
+	if err := k.callWithTimeout(ctx, k.pinTimeout, "repo/gc", args, nil, "", decode); err != nil {
+		return removed, err
+	}
+	return removed, nil
+}
+
+// SwarmConnect подключает узел Kubo к пирам по их multiaddr.
+func (k *KuboClient) SwarmConnect(ctx context.Context, addrs []string) error {
+	// Эндпоинт для подключения к пирам: /api/v0/swarm/connect
+	return k.call(ctx, "swarm/connect", url.Values{"arg": addrs}, nil, "", nil)
+}
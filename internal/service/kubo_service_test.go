@@ -0,0 +1,412 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"main/internal/config"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+func TestKuboClientAuthorization(t *testing.T) {
+	tests := []struct {
+		cfg      config.IPFS
+		expected string
+	}{
+		{config.IPFS{}, ""},
+		{config.IPFS{AuthUsername: "user", AuthPassword: "pass"}, "Basic dXNlcjpwYXNz"},
+		{config.IPFS{AuthToken: "secret", AuthUsername: "user"}, "Bearer secret"},
+	}
+
+	for _, test := range tests {
+		var got string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = r.Header.Get("Authorization")
+			if r.URL.Path != "/api/v0/pin/add" || r.URL.Query().Get("arg") != "QmTest" {
+				t.Errorf("unexpected request %s", r.URL)
+			}
+			_, _ = w.Write([]byte(`{"Pins":["QmTest"]}`))
+		}))
+
+		test.cfg.APIURL = srv.URL + "/api/v0/"
+		resp, err := NewKuboClient(&test.cfg).Pin(context.Background(), "QmTest")
+		srv.Close()
+
+		if err != nil {
+			t.Fatalf("Pin() error = %v", err)
+		}
+		if len(resp.Pins) != 1 || resp.Pins[0] != "QmTest" {
+			t.Errorf("Pin() = %v, expected [QmTest]", resp.Pins)
+		}
+		if got != test.expected {
+			t.Errorf("Authorization = %q, expected %q", got, test.expected)
+		}
+	}
+}
+
+func TestKuboClientTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	client := NewKuboClient(&config.IPFS{APIURL: srv.URL, Timeout: 50 * time.Millisecond})
+	if _, err := client.ListPins(context.Background()); err == nil {
+		t.Error("ListPins() expected timeout error")
+	}
+}
+
+func TestKuboClientCatOutlivesCallTimeout(t *testing.T) {
+	// узел отдает файл дольше таймаута одного вызова
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, chunk := range []string{"slow ", "body"} {
+			_, _ = w.Write([]byte(chunk))
+			w.(http.Flusher).Flush()
+			time.Sleep(100 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+
+	client := NewKuboClient(&config.IPFS{APIURL: srv.URL, Timeout: 50 * time.Millisecond})
+	reads := map[string]func() (io.ReadCloser, error){
+		"Cat":      func() (io.ReadCloser, error) { return client.Cat(context.Background(), "QmTest") },
+		"CatRange": func() (io.ReadCloser, error) { return client.CatRange(context.Background(), "QmTest", 0, -1) },
+	}
+	for name, read := range reads {
+		body, err := read()
+		if err != nil {
+			t.Fatalf("%s() error = %v", name, err)
+		}
+		data, err := io.ReadAll(body)
+		body.Close()
+		if err != nil || string(data) != "slow body" {
+			t.Errorf("%s() = %q, %v, expected the whole body", name, data, err)
+		}
+	}
+}
+
+// benchmarkFileSize размер файла для бенчмарков загрузки
+const benchmarkFileSize = 100 << 20
+
+// zeroReader бесконечный источник нулевых байт без аллокаций
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+// newDiscardKubo поднимает фейковый /api/v0/add, который вычитывает файл и отбрасывает его
+func newDiscardKubo(tb testing.TB) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reader, err := r.MultipartReader()
+		if err != nil {
+			tb.Errorf("MultipartReader() error = %v", err)
+			return
+		}
+		part, err := reader.NextPart()
+		if err != nil {
+			tb.Errorf("NextPart() error = %v", err)
+			return
+		}
+		n, _ := io.Copy(io.Discard, part)
+		_, _ = fmt.Fprintf(w, `{"Name":%q,"Hash":"QmTest","Size":"%d"}`, part.FileName(), n)
+	}))
+}
+
+func TestKuboClientAddStreams(t *testing.T) {
+	srv := newDiscardKubo(t)
+	defer srv.Close()
+
+	client := NewKuboClient(&config.IPFS{APIURL: srv.URL})
+	resp, err := client.Add(context.Background(), "big.bin", io.LimitReader(zeroReader{}, 5<<20))
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if resp.Name != "big.bin" || resp.Size != strconv.Itoa(5<<20) {
+		t.Errorf("Add() = %+v", resp)
+	}
+}
+
+func TestKuboClientDagImport(t *testing.T) {
+	tests := []struct {
+		response string
+		expected []string
+		fail     bool
+	}{
+		{
+			response: `{"Root":{"Cid":{"/":"bafyroot1"},"PinErrorMsg":""}}` + "\n" +
+				`{"Root":{"Cid":{"/":"bafyroot2"},"PinErrorMsg":""}}` + "\n" +
+				`{"Stats":{"BlockCount":3,"BlockBytesCount":42}}`,
+			expected: []string{"bafyroot1", "bafyroot2"},
+		},
+		{
+			response: `{"Root":{"Cid":{"/":"bafyroot1"},"PinErrorMsg":"block was not found locally"}}`,
+			fail:     true,
+		},
+	}
+
+	for _, test := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/dag/import" || r.URL.Query().Get("pin-roots") != "true" {
+				t.Errorf("unexpected request %s", r.URL)
+			}
+			file, _, err := r.FormFile("file")
+			if err != nil {
+				t.Errorf("FormFile() error = %v", err)
+				return
+			}
+			if body, _ := io.ReadAll(file); string(body) != "car" {
+				t.Errorf("imported body = %q, expected car", body)
+			}
+			_, _ = w.Write([]byte(test.response))
+		}))
+
+		roots, err := NewKuboClient(&config.IPFS{APIURL: srv.URL}).DagImport(context.Background(),
+			bytes.NewReader([]byte("car")))
+		srv.Close()
+
+		if test.fail {
+			if err == nil {
+				t.Errorf("DagImport() expected pin error")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("DagImport() error = %v", err)
+		}
+		if fmt.Sprint(roots) != fmt.Sprint(test.expected) {
+			t.Errorf("DagImport() = %v, expected %v", roots, test.expected)
+		}
+	}
+}
+
+func TestKuboClientDagExport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/dag/export" || r.URL.Query().Get("arg") != "bafyroot" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte("car"))
+	}))
+	defer srv.Close()
+
+	body, err := NewKuboClient(&config.IPFS{APIURL: srv.URL}).DagExport(context.Background(), "bafyroot")
+	if err != nil {
+		t.Fatalf("DagExport() error = %v", err)
+	}
+	defer body.Close()
+	if data, _ := io.ReadAll(body); string(data) != "car" {
+		t.Errorf("DagExport() = %q, expected car", data)
+	}
+}
+
+func TestKuboClientAddDirectory(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/add" || r.URL.Query().Get("wrap-with-directory") != "true" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		reader, err := r.MultipartReader()
+		if err != nil {
+			t.Errorf("MultipartReader() error = %v", err)
+			return
+		}
+
+		// узел отвечает строкой на каждую часть и корнем с пустым именем
+		var parts []string
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				break
+			}
+			_, params, _ := mime.ParseMediaType(part.Header.Get("Content-Disposition"))
+			name, _ := url.QueryUnescape(params["filename"])
+			body, _ := io.ReadAll(part)
+			parts = append(parts, fmt.Sprintf("%s:%s:%s", name, part.Header.Get("Content-Type"), body))
+			_, _ = fmt.Fprintf(w, `{"Name":%q,"Hash":"bafy%d","Size":"%d"}`+"\n", name, len(parts), len(body))
+		}
+		_, _ = w.Write([]byte(`{"Name":"","Hash":"bafyroot","Size":"100"}`))
+
+		expected := []string{
+			"1.json:application/octet-stream:one",
+			"images:application/x-directory:",
+			"images/1 cover.png:application/octet-stream:png",
+			"images/thumbs:application/x-directory:",
+			"images/thumbs/1.png:application/octet-stream:thumb",
+		}
+		if fmt.Sprint(parts) != fmt.Sprint(expected) {
+			t.Errorf("parts = %q, expected %q", parts, expected)
+		}
+	}))
+	defer srv.Close()
+
+	entry := func(p, content string) DirectoryEntry {
+		return DirectoryEntry{Path: p, Open: func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader([]byte(content))), nil
+		}}
+	}
+	entries := []DirectoryEntry{entry("images/thumbs/1.png", "thumb"), entry("1.json", "one"),
+		entry("images/1 cover.png", "png")}
+
+	added, err := NewKuboClient(&config.IPFS{APIURL: srv.URL}).AddDirectory(context.Background(), entries)
+	if err != nil {
+		t.Fatalf("AddDirectory() error = %v", err)
+	}
+	if len(added) != 6 || added[5].Name != "" || added[5].Hash != "bafyroot" || added[2].Name != "images/1 cover.png" {
+		t.Errorf("AddDirectory() = %+v", added)
+	}
+}
+
+// BenchmarkKuboAddStreaming потоковая загрузка через io.Pipe
+func BenchmarkKuboAddStreaming(b *testing.B) {
+	srv := newDiscardKubo(b)
+	defer srv.Close()
+	client := NewKuboClient(&config.IPFS{APIURL: srv.URL})
+
+	b.ReportAllocs()
+	b.SetBytes(benchmarkFileSize)
+	for i := 0; i < b.N; i++ {
+		if _, err := client.Add(context.Background(), "big.bin", io.LimitReader(zeroReader{}, benchmarkFileSize)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkKuboAddBuffered прежняя схема: файл целиком копируется в bytes.Buffer перед запросом
+func BenchmarkKuboAddBuffered(b *testing.B) {
+	srv := newDiscardKubo(b)
+	defer srv.Close()
+	client := NewKuboClient(&config.IPFS{APIURL: srv.URL})
+
+	b.ReportAllocs()
+	b.SetBytes(benchmarkFileSize)
+	for i := 0; i < b.N; i++ {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		part, _ := writer.CreateFormFile("file", "big.bin")
+		if _, err := io.Copy(part, io.LimitReader(zeroReader{}, benchmarkFileSize)); err != nil {
+			b.Fatal(err)
+		}
+		writer.Close()
+
+		if err := client.call(context.Background(), "add", nil, &body, writer.FormDataContentType(), nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestKuboClientBlockGet(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/block/get" || r.URL.Query().Get("offline") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.URL.Query().Get("arg") == "bafylarge" {
+			_, _ = w.Write(make([]byte, maxBlockSize+1))
+			return
+		}
+		if r.URL.Query().Get("arg") != "bafyblock" {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"Message":"block was not found locally (offline): ipld: could not find node","Code":0}`))
+			return
+		}
+		_, _ = w.Write([]byte("block"))
+	}))
+	defer srv.Close()
+	client := NewKuboClient(&config.IPFS{APIURL: srv.URL})
+
+	block, err := client.BlockGet(context.Background(), "bafyblock")
+	if err != nil || string(block) != "block" {
+		t.Errorf("BlockGet() = %q, %v, expected block", block, err)
+	}
+	if _, err = client.BlockGet(context.Background(), "bafymissing"); !errors.Is(err, tvoerrors.ErrNotFound) {
+		t.Errorf("BlockGet() of a missing block error = %v, expected not found", err)
+	}
+	if _, err = client.BlockGet(context.Background(), "bafylarge"); err == nil {
+		t.Errorf("BlockGet() of a block over %d bytes expected an error", maxBlockSize)
+	}
+}
+
+func TestKuboClientNodeStats(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repo/stat":
+			if r.URL.Query().Get("size-only") != "true" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"RepoSize":900,"StorageMax":1000,"RepoPath":"/data/ipfs","Version":"fs-repo@16"}`))
+		case "/stats/bw":
+			_, _ = w.Write([]byte(`{"TotalIn":10,"TotalOut":20,"RateIn":1.5,"RateOut":2.5}`))
+		case "/swarm/peers":
+			_, _ = w.Write([]byte(`{"Peers":[{"Addr":"/ip4/1.2.3.4/tcp/4001","Peer":"12D3KooWA"},{"Addr":"/ip4/5.6.7.8/tcp/4001","Peer":"12D3KooWB"}]}`))
+		case "/version":
+			_, _ = w.Write([]byte(`{"Version":"0.34.1","Repo":"16","System":"amd64/linux","Golang":"go1.24.2"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	client := NewKuboClient(&config.IPFS{APIURL: srv.URL})
+	ctx := context.Background()
+
+	repo, err := client.RepoStat(ctx)
+	if err != nil || repo.RepoSize != 900 || repo.StorageMax != 1000 || repo.RepoPath != "/data/ipfs" {
+		t.Errorf("RepoStat() = %+v, %v", repo, err)
+	}
+	bw, err := client.StatsBw(ctx)
+	if err != nil || bw.TotalIn != 10 || bw.TotalOut != 20 || bw.RateOut != 2.5 {
+		t.Errorf("StatsBw() = %+v, %v", bw, err)
+	}
+	peers, err := client.SwarmPeers(ctx)
+	if err != nil || len(peers.Peers) != 2 || peers.Peers[1].Peer != "12D3KooWB" {
+		t.Errorf("SwarmPeers() = %+v, %v", peers, err)
+	}
+	version, err := client.Version(ctx)
+	if err != nil || version.Version != "0.34.1" || version.Repo != "16" {
+		t.Errorf("Version() = %+v, %v", version, err)
+	}
+}
+
+func TestKuboClientRepoGC(t *testing.T) {
+	failing := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/pin/ls" && r.URL.Query().Get("type") == "recursive":
+			_, _ = w.Write([]byte(`{"Keys":{"bafyroot":{"Type":"recursive"}}}`))
+		case r.URL.Path == "/repo/gc" && r.URL.Query().Get("stream-errors") == "true":
+			_, _ = w.Write([]byte(`{"Key":{"/":"bafyone"}}` + "\n" + `{"Key":{"/":"bafytwo"}}` + "\n"))
+			if failing {
+				_, _ = w.Write([]byte(`{"Error":"could not remove bafythree"}` + "\n"))
+			}
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+	client := NewKuboClient(&config.IPFS{APIURL: srv.URL})
+
+	pins, err := client.ListPins(context.Background())
+	if err != nil || len(pins.Keys) != 1 {
+		t.Errorf("ListPins() = %+v, %v, expected the recursive pin", pins, err)
+	}
+	removed, err := client.RepoGC(context.Background())
+	if err != nil || removed != 2 {
+		t.Errorf("RepoGC() = %d, %v, expected 2 removed blocks", removed, err)
+	}
+	failing = true
+	if _, err = client.RepoGC(context.Background()); err == nil {
+		t.Error("RepoGC() expected the streamed error")
+	}
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"main/internal/models"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// IpfsURI возвращает ссылку вида ipfs://<cid>, которую понимают кошельки и маркетплейсы
+func IpfsURI(cid string) string {
+	return "ipfs://" + cid
+}
+
+// TokenURI возвращает адрес метаданных токена относительно базового URI из конфига
+func TokenURI(baseURI string, tokenId int64) string {
+	return strings.TrimRight(baseURI, "/") + "/" + strconv.FormatInt(tokenId, 10) + "/metadata"
+}
+
+// CollectionTokenURI возвращает адрес метаданных токена коллекции slug
+func CollectionTokenURI(baseURI, slug string, tokenId int64) string {
+	return TokenURI(strings.TrimRight(baseURI, "/")+"/"+slug+"/nft", tokenId)
+}
+
+// EditionIdPlaceholder подстановка номера токена в URI метаданных ERC-1155
+const EditionIdPlaceholder = "{id}"
+
+// EditionHexId возвращает номер токена в виде, которого требует ERC-1155 для {id}:
+// 64 символа в нижнем регистре без префикса 0x
+func EditionHexId(tokenId int64) string {
+	return fmt.Sprintf("%064x", tokenId)
+}
+
+// EditionURI возвращает шаблон uri() контракта ERC-1155 для коллекции slug
+func EditionURI(baseURI, slug string) string {
+	return strings.TrimRight(baseURI, "/") + "/" + slug + "/nft/" + EditionIdPlaceholder + "/metadata"
+}
+
+// ParseTokenId разбирает номер токена из пути: десятичный либо 64-символьный hex из шаблона ERC-1155
+func ParseTokenId(raw string) (int64, error) {
+	base := 10
+	if len(raw) == 64 {
+		raw = strings.TrimLeft(raw, "0")
+		if raw == "" {
+			return 0, nil
+		}
+		base = 16
+	}
+
+	tokenId, err := strconv.ParseInt(raw, base, 64)
+	if err != nil || tokenId < 0 {
+		return 0, tvoerrors.Wrap("service.ParseTokenId", tvoerrors.ErrInvalidRequestData)
+	}
+	return tokenId, nil
+}
+
+// EditionMaxSupply проверяет размер тиража для стандарта коллекции.
+// Токен ERC-721 всегда уникален, для ERC-1155 ноль означает неограниченный тираж.
+func EditionMaxSupply(standard string, maxSupply int64) (int64, error) {
+	const op = "service.EditionMaxSupply"
+
+	if maxSupply < 0 {
+		return 0, tvoerrors.Wrap(op, tvoerrors.ErrInvalidRequestData)
+	}
+	if standard == models.TokenStandardERC1155 {
+		return maxSupply, nil
+	}
+	if maxSupply > 1 {
+		return 0, tvoerrors.Wrap(op+": erc721 token can't have editions", tvoerrors.ErrInvalidRequestData)
+	}
+	return 1, nil
+}
+
+// BuildNftMetadata формирует документ метаданных ERC-721 для изображения с CID imageCid.
+// Если имя не задано, используется номер токена.
+func BuildNftMetadata(tokenId int64, name, description, imageCid string,
+	attributes []models.NftMetadataTrait) *models.NftMetadata {
+	if name == "" {
+		name = fmt.Sprintf("#%d", tokenId)
+	}
+	if attributes == nil {
+		attributes = []models.NftMetadataTrait{}
+	}
+
+	return &models.NftMetadata{
+		Name:        name,
+		Description: description,
+		Image:       IpfsURI(imageCid),
+		Attributes:  attributes,
+	}
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"main/internal/models"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+func TestBuildNftMetadata(t *testing.T) {
+	metadata := BuildNftMetadata(7, "", "About this token", "bafyimage", nil)
+
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	expected := `{"name":"#7","description":"About this token","image":"ipfs://bafyimage","attributes":[]}`
+	if string(data) != expected {
+		t.Errorf("BuildNftMetadata() = %s, expected %s", data, expected)
+	}
+}
+
+func TestTokenURI(t *testing.T) {
+	tests := []struct {
+		base     string
+		expected string
+	}{
+		{"https://nft.example.com/v1/api/nft", "https://nft.example.com/v1/api/nft/42/metadata"},
+		{"https://nft.example.com/v1/api/nft/", "https://nft.example.com/v1/api/nft/42/metadata"},
+	}
+
+	for _, test := range tests {
+		if result := TokenURI(test.base, 42); result != test.expected {
+			t.Errorf("TokenURI(%q) = %q, expected %q", test.base, result, test.expected)
+		}
+	}
+}
+
+func TestCollectionTokenURI(t *testing.T) {
+	expected := "https://nft.example.com/v1/api/collections/cool-cats/nft/42/metadata"
+	if result := CollectionTokenURI("https://nft.example.com/v1/api/collections/", "cool-cats", 42); result != expected {
+		t.Errorf("CollectionTokenURI() = %q, expected %q", result, expected)
+	}
+}
+
+func TestEditionHexId(t *testing.T) {
+	expected := "000000000000000000000000000000000000000000000000000000000004cce0"
+	if result := EditionHexId(314592); result != expected {
+		t.Errorf("EditionHexId() = %q, expected %q", result, expected)
+	}
+
+	uri := EditionURI("https://nft.example.com/v1/api/collections", "cool-cats")
+	if uri != "https://nft.example.com/v1/api/collections/cool-cats/nft/{id}/metadata" {
+		t.Errorf("EditionURI() = %q", uri)
+	}
+}
+
+func TestParseTokenId(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected int64
+		valid    bool
+	}{
+		{"314592", 314592, true},
+		{"000000000000000000000000000000000000000000000000000000000004cce0", 314592, true},
+		{"0000000000000000000000000000000000000000000000000000000000000000", 0, true},
+		{"00000000000000000000000000000000000000000000000000000000000004CG", 0, false},
+		{"1000000000000000000000000000000000000000000000000000000000000000", 0, false},
+		{"-1", 0, false},
+		{"abc", 0, false},
+	}
+
+	for _, test := range tests {
+		result, err := ParseTokenId(test.raw)
+		if test.valid && (err != nil || result != test.expected) {
+			t.Errorf("ParseTokenId(%q) = %d, %v, expected %d", test.raw, result, err, test.expected)
+		}
+		if !test.valid && !errors.Is(err, tvoerrors.ErrInvalidRequestData) {
+			t.Errorf("ParseTokenId(%q) error = %v, expected ErrInvalidRequestData", test.raw, err)
+		}
+	}
+}
+
+func TestEditionMaxSupply(t *testing.T) {
+	tests := []struct {
+		standard  string
+		maxSupply int64
+		expected  int64
+		valid     bool
+	}{
+		{models.TokenStandardERC721, 0, 1, true},
+		{models.TokenStandardERC721, 1, 1, true},
+		{models.TokenStandardERC721, 5, 0, false},
+		{models.TokenStandardERC1155, 0, 0, true},
+		{models.TokenStandardERC1155, 100, 100, true},
+		{models.TokenStandardERC1155, -1, 0, false},
+	}
+
+	for _, test := range tests {
+		result, err := EditionMaxSupply(test.standard, test.maxSupply)
+		if test.valid && (err != nil || result != test.expected) {
+			t.Errorf("EditionMaxSupply(%s, %d) = %d, %v, expected %d", test.standard, test.maxSupply, result, err,
+				test.expected)
+		}
+		if !test.valid && !errors.Is(err, tvoerrors.ErrInvalidRequestData) {
+			t.Errorf("EditionMaxSupply(%s, %d) error = %v, expected ErrInvalidRequestData", test.standard,
+				test.maxSupply, err)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"time"
+
+	"main/internal/models"
+	tvoerrors "main/tools/pkg/tvo_errors"
+	tvomodels "main/tools/pkg/tvo_models"
+)
+
+// порядок сортировки списка токенов
+const (
+	SortCreatedAtDesc = "created_at:desc"
+	SortCreatedAtAsc  = "created_at:asc"
+)
+
+// dateLayout формат даты без времени в фильтрах created_from и created_to
+const dateLayout = "2006-01-02"
+
+// ParseLimit разбирает размер страницы: пустое значение дает tvomodels.DefaultLimit,
+// значения больше tvomodels.MaxLimit ограничиваются им
+func ParseLimit(raw string) (int, error) {
+	if raw == "" {
+		return tvomodels.DefaultLimit, nil
+	}
+
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit <= 0 {
+		return 0, tvoerrors.Wrap("service.ParseLimit", tvoerrors.ErrInvalidRequestData)
+	}
+	return min(limit, tvomodels.MaxLimit), nil
+}
+
+// EncodeNftCursor упаковывает позицию в непрозрачную строку для next_cursor
+func EncodeNftCursor(cursor models.NftCursor) string {
+	cursor.CreatedAt = cursor.CreatedAt.UTC()
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeNftCursor разбирает строку, полученную от EncodeNftCursor
+func DecodeNftCursor(raw string) (*models.NftCursor, error) {
+	const op = "service.DecodeNftCursor"
+
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, tvoerrors.ErrInvalidRequestData)
+	}
+
+	var cursor models.NftCursor
+	if err = json.Unmarshal(data, &cursor); err != nil || cursor.ID <= 0 || cursor.CreatedAt.IsZero() {
+		return nil, tvoerrors.Wrap(op, tvoerrors.ErrInvalidRequestData)
+	}
+	return &cursor, nil
+}
+
+// ParseNftListQuery разбирает параметры списка токенов: limit, cursor, sort, creator,
+// created_from, created_to, available и фильтры по атрибутам.
+// Коллекцию вызывающий код определяет сам, так как для этого нужен поиск по slug.
+func ParseNftListQuery(query url.Values) (*models.NftFilter, int, error) {
+	const op = "service.ParseNftListQuery"
+
+	limit, err := ParseLimit(query.Get("limit"))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	filter := &models.NftFilter{
+		Available: query.Get("available") == "true",
+	}
+
+	switch query.Get("sort") {
+	case "", SortCreatedAtDesc:
+	case SortCreatedAtAsc:
+		filter.Ascending = true
+	default:
+		return nil, 0, tvoerrors.Wrap(op+": unknown sort", tvoerrors.ErrInvalidRequestData)
+	}
+
+	if raw := query.Get("cursor"); raw != "" {
+		if filter.After, err = DecodeNftCursor(raw); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if raw := query.Get("creator"); raw != "" {
+		filter.CreatorId, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil || filter.CreatorId <= 0 {
+			return nil, 0, tvoerrors.Wrap(op+": invalid creator", tvoerrors.ErrInvalidRequestData)
+		}
+	}
+
+	if filter.CreatedFrom, err = parseTimeBound(query.Get("created_from"), false); err != nil {
+		return nil, 0, err
+	}
+	if filter.CreatedTo, err = parseTimeBound(query.Get("created_to"), true); err != nil {
+		return nil, 0, err
+	}
+
+	if filter.Traits, err = ParseTraitFilters(query); err != nil {
+		return nil, 0, err
+	}
+
+	return filter, limit, nil
+}
+
+// NextNftPage обрезает выборку, запрошенную с запасом в одну запись, до limit
+// и возвращает курсор следующей страницы, если она есть
+func NextNftPage(nfts []models.NftDataModel, limit int) ([]models.NftDataModel, string) {
+	if len(nfts) <= limit {
+		return nfts, ""
+	}
+
+	nfts = nfts[:limit]
+	last := nfts[len(nfts)-1]
+	return nfts, EncodeNftCursor(models.NftCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+}
+
+// parseTimeBound разбирает границу периода в формате RFC 3339 либо даты.
+// Дата в верхней границе включает весь день.
+func parseTimeBound(raw string, upper bool) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t.UTC(), nil
+	}
+
+	t, err := time.Parse(dateLayout, raw)
+	if err != nil {
+		return time.Time{}, tvoerrors.Wrap("service.parseTimeBound", tvoerrors.ErrInvalidRequestData)
+	}
+	if upper {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"main/internal/models"
+	tvoerrors "main/tools/pkg/tvo_errors"
+	tvomodels "main/tools/pkg/tvo_models"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected int
+		valid    bool
+	}{
+		{"", tvomodels.DefaultLimit, true},
+		{"5", 5, true},
+		{"1000000", tvomodels.MaxLimit, true},
+		{"0", 0, false},
+		{"-3", 0, false},
+		{"ten", 0, false},
+	}
+
+	for _, test := range tests {
+		result, err := ParseLimit(test.raw)
+		if test.valid && (err != nil || result != test.expected) {
+			t.Errorf("ParseLimit(%q) = %d, %v, expected %d", test.raw, result, err, test.expected)
+		}
+		if !test.valid && !errors.Is(err, tvoerrors.ErrInvalidRequestData) {
+			t.Errorf("ParseLimit(%q) error = %v, expected ErrInvalidRequestData", test.raw, err)
+		}
+	}
+}
+
+func TestNftCursor(t *testing.T) {
+	cursor := models.NftCursor{CreatedAt: time.Date(2025, 7, 1, 12, 30, 0, 123456000, time.UTC), ID: 42}
+
+	decoded, err := DecodeNftCursor(EncodeNftCursor(cursor))
+	if err != nil {
+		t.Fatalf("DecodeNftCursor() error = %v", err)
+	}
+	if !decoded.CreatedAt.Equal(cursor.CreatedAt) || decoded.ID != cursor.ID {
+		t.Errorf("DecodeNftCursor() = %+v, expected %+v", decoded, cursor)
+	}
+
+	for _, raw := range []string{"!!!", "e30", "bm90IGpzb24"} {
+		if _, err = DecodeNftCursor(raw); !errors.Is(err, tvoerrors.ErrInvalidRequestData) {
+			t.Errorf("DecodeNftCursor(%q) error = %v, expected ErrInvalidRequestData", raw, err)
+		}
+	}
+}
+
+func TestParseNftListQuery(t *testing.T) {
+	query, _ := url.ParseQuery("limit=20&sort=created_at:asc&creator=7&created_from=2025-07-01" +
+		"&created_to=2025-07-31&trait.level.gte=5")
+
+	filter, limit, err := ParseNftListQuery(query)
+	if err != nil {
+		t.Fatalf("ParseNftListQuery() error = %v", err)
+	}
+	if limit != 20 || !filter.Ascending || filter.CreatorId != 7 || len(filter.Traits) != 1 {
+		t.Errorf("ParseNftListQuery() = %+v, %d", filter, limit)
+	}
+	if !filter.CreatedFrom.Equal(time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)) ||
+		!filter.CreatedTo.Equal(time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("ParseNftListQuery() period = %s - %s", filter.CreatedFrom, filter.CreatedTo)
+	}
+
+	for _, raw := range []string{"sort=token_id", "cursor=abc", "creator=me", "created_from=yesterday"} {
+		query, _ = url.ParseQuery(raw)
+		if _, _, err = ParseNftListQuery(query); !errors.Is(err, tvoerrors.ErrInvalidRequestData) {
+			t.Errorf("ParseNftListQuery(%q) error = %v, expected ErrInvalidRequestData", raw, err)
+		}
+	}
+}
+
+func TestNextNftPage(t *testing.T) {
+	created := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	nfts := []models.NftDataModel{{ID: 3, CreatedAt: created}, {ID: 2, CreatedAt: created}, {ID: 1, CreatedAt: created}}
+
+	page, next := NextNftPage(nfts, 2)
+	if len(page) != 2 || next == "" {
+		t.Fatalf("NextNftPage() = %d items, next %q", len(page), next)
+	}
+	if cursor, _ := DecodeNftCursor(next); cursor == nil || cursor.ID != 2 {
+		t.Errorf("NextNftPage() cursor = %+v, expected id 2", cursor)
+	}
+
+	if page, next = NextNftPage(nfts, 3); len(page) != 3 || next != "" {
+		t.Errorf("NextNftPage() last page = %d items, next %q", len(page), next)
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"html"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"main/internal/models"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// MaxSearchQueryLength максимальная длина поискового запроса в символах
+const MaxSearchQueryLength = 256
+
+// EncodeNftSearchCursor упаковывает позицию в выдаче поиска в непрозрачную строку для next_cursor
+func EncodeNftSearchCursor(cursor models.NftSearchCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeNftSearchCursor разбирает строку, полученную от EncodeNftSearchCursor
+func DecodeNftSearchCursor(raw string) (*models.NftSearchCursor, error) {
+	const op = "service.DecodeNftSearchCursor"
+
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, tvoerrors.ErrInvalidRequestData)
+	}
+
+	var cursor models.NftSearchCursor
+	if err = json.Unmarshal(data, &cursor); err != nil || cursor.ID <= 0 || cursor.RankKey < 0 {
+		return nil, tvoerrors.Wrap(op, tvoerrors.ErrInvalidRequestData)
+	}
+	return &cursor, nil
+}
+
+// ParseNftSearchQuery разбирает параметры поиска: обязательный q, limit, cursor и те же фильтры,
+// что и у списка токенов. Выдача всегда сортируется по релевантности, поэтому sort не принимается.
+func ParseNftSearchQuery(query url.Values) (*models.NftSearch, int, error) {
+	const op = "service.ParseNftSearchQuery"
+
+	text := strings.TrimSpace(query.Get("q"))
+	if text == "" || utf8.RuneCountInString(text) > MaxSearchQueryLength {
+		return nil, 0, tvoerrors.Wrap(op+": invalid q", tvoerrors.ErrInvalidRequestData)
+	}
+	if query.Has("sort") {
+		return nil, 0, tvoerrors.Wrap(op+": search is sorted by rank", tvoerrors.ErrInvalidRequestData)
+	}
+
+	// курсор поиска отличается от курсора списка и разбирается отдельно
+	listQuery := url.Values{}
+	for key, values := range query {
+		if key != "cursor" {
+			listQuery[key] = values
+		}
+	}
+	filter, limit, err := ParseNftListQuery(listQuery)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	search := &models.NftSearch{
+		Text:   text,
+		Filter: *filter,
+	}
+	if raw := query.Get("cursor"); raw != "" {
+		if search.After, err = DecodeNftSearchCursor(raw); err != nil {
+			return nil, 0, err
+		}
+	}
+	return search, limit, nil
+}
+
+// NextNftSearchPage обрезает выдачу, запрошенную с запасом в одну запись, до limit
+// и возвращает курсор следующей страницы, если она есть
+func NextNftSearchPage(hits []models.NftSearchHit, limit int) ([]models.NftSearchHit, string) {
+	if len(hits) <= limit {
+		return hits, ""
+	}
+
+	hits = hits[:limit]
+	last := hits[len(hits)-1]
+	return hits, EncodeNftSearchCursor(models.NftSearchCursor{RankKey: last.RankKey, ID: last.Nft.ID})
+}
+
+// HighlightSnippet экранирует фрагмент текста из БД как HTML и заменяет маркеры
+// найденных слов на теги <b>, так что пользовательская разметка не попадает в ответ
+func HighlightSnippet(snippet string) string {
+	return strings.NewReplacer(
+		models.SnippetStartSel, "<b>",
+		models.SnippetStopSel, "</b>",
+	).Replace(html.EscapeString(snippet))
+}
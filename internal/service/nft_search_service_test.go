@@ -0,0 +1,149 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"main/internal/models"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+func TestNftSearchCursor(t *testing.T) {
+	cursor := models.NftSearchCursor{RankKey: 123457, ID: 42}
+
+	decoded, err := DecodeNftSearchCursor(EncodeNftSearchCursor(cursor))
+	if err != nil {
+		t.Fatalf("DecodeNftSearchCursor() error = %v", err)
+	}
+	if *decoded != cursor {
+		t.Errorf("DecodeNftSearchCursor() = %+v, expected %+v", decoded, cursor)
+	}
+
+	// курсор списка без id, курсор с отрицательным рангом и курсор с дробным рангом прежнего формата
+	invalid := []string{"!!!", "e30", EncodeNftSearchCursor(models.NftSearchCursor{RankKey: -1, ID: 1}),
+		base64.RawURLEncoding.EncodeToString([]byte(`{"r":0.5,"id":1}`))}
+	for _, raw := range invalid {
+		if _, err = DecodeNftSearchCursor(raw); !errors.Is(err, tvoerrors.ErrInvalidRequestData) {
+			t.Errorf("DecodeNftSearchCursor(%q) error = %v, expected ErrInvalidRequestData", raw, err)
+		}
+	}
+}
+
+func TestParseNftSearchQuery(t *testing.T) {
+	cursor := EncodeNftSearchCursor(models.NftSearchCursor{RankKey: 500000, ID: 9})
+	query, _ := url.ParseQuery("q=+red+cat+&limit=5&creator=7&trait.level.gte=5&cursor=" + cursor)
+
+	search, limit, err := ParseNftSearchQuery(query)
+	if err != nil {
+		t.Fatalf("ParseNftSearchQuery() error = %v", err)
+	}
+	if search.Text != "red cat" || limit != 5 || search.Filter.CreatorId != 7 || len(search.Filter.Traits) != 1 {
+		t.Errorf("ParseNftSearchQuery() = %+v, %d", search, limit)
+	}
+	if search.After == nil || search.After.ID != 9 || search.Filter.After != nil {
+		t.Errorf("ParseNftSearchQuery() cursor = %+v, filter cursor = %+v", search.After, search.Filter.After)
+	}
+
+	invalid := []string{
+		"",
+		"q=++",
+		"q=" + strings.Repeat("a", MaxSearchQueryLength+1),
+		"q=cat&sort=created_at:asc",
+		"q=cat&limit=0",
+		"q=cat&cursor=e30",
+	}
+	for _, raw := range invalid {
+		query, _ = url.ParseQuery(raw)
+		if _, _, err = ParseNftSearchQuery(query); !errors.Is(err, tvoerrors.ErrInvalidRequestData) {
+			t.Errorf("ParseNftSearchQuery(%q) error = %v, expected ErrInvalidRequestData", raw, err)
+		}
+	}
+}
+
+func TestNextNftSearchPage(t *testing.T) {
+	hits := []models.NftSearchHit{
+		{Nft: models.NftDataModel{ID: 3}, RankKey: 900000},
+		{Nft: models.NftDataModel{ID: 2}, RankKey: 500000},
+		{Nft: models.NftDataModel{ID: 1}, RankKey: 500000},
+	}
+
+	page, next := NextNftSearchPage(hits, 2)
+	if len(page) != 2 {
+		t.Fatalf("NextNftSearchPage() returned %d hits, expected 2", len(page))
+	}
+	cursor, err := DecodeNftSearchCursor(next)
+	if err != nil || cursor.ID != 2 || cursor.RankKey != 500000 {
+		t.Errorf("NextNftSearchPage() cursor = %+v, %v", cursor, err)
+	}
+	if rank := page[1].Rank(); rank != 0.5 {
+		t.Errorf("Rank() = %v, expected 0.5", rank)
+	}
+
+	if _, next = NextNftSearchPage(hits, 3); next != "" {
+		t.Errorf("NextNftSearchPage() on the last page cursor = %q, expected empty", next)
+	}
+}
+
+func TestNftSearchPagesWithEqualRanks(t *testing.T) {
+	// выдача в порядке (rank key, id) по убыванию, равные ранги приходятся на границы страниц
+	var all []models.NftSearchHit
+	for id := int64(10); id > 0; id-- {
+		all = append(all, models.NftSearchHit{Nft: models.NftDataModel{ID: id}, RankKey: 100000 * (id / 4)})
+	}
+	// search повторяет условие SearchNftData: строки строго после курсора
+	search := func(after *models.NftSearchCursor, limit int) []models.NftSearchHit {
+		var hits []models.NftSearchHit
+		for _, hit := range all {
+			if after == nil || hit.RankKey < after.RankKey || (hit.RankKey == after.RankKey && hit.Nft.ID < after.ID) {
+				hits = append(hits, hit)
+			}
+		}
+		return hits[:min(len(hits), limit)]
+	}
+
+	var seen []int64
+	var after *models.NftSearchCursor
+	for range len(all) {
+		page, next := NextNftSearchPage(search(after, 4), 3)
+		for _, hit := range page {
+			seen = append(seen, hit.Nft.ID)
+		}
+		if next == "" {
+			break
+		}
+		var err error
+		if after, err = DecodeNftSearchCursor(next); err != nil {
+			t.Fatalf("DecodeNftSearchCursor() error = %v", err)
+		}
+	}
+
+	if len(seen) != len(all) {
+		t.Fatalf("pages returned ids %v, expected every hit once", seen)
+	}
+	for i, id := range seen {
+		if id != all[i].Nft.ID {
+			t.Errorf("pages returned ids %v, expected every hit once in order", seen)
+			break
+		}
+	}
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	tests := []struct {
+		snippet  string
+		expected string
+	}{
+		{"plain text", "plain text"},
+		{"a \x02cat\x03 on the \x02mat\x03", "a <b>cat</b> on the <b>mat</b>"},
+		{"<script>\x02alert\x03</script>", "&lt;script&gt;<b>alert</b>&lt;/script&gt;"},
+	}
+
+	for _, test := range tests {
+		if result := HighlightSnippet(test.snippet); result != test.expected {
+			t.Errorf("HighlightSnippet(%q) = %q, expected %q", test.snippet, result, test.expected)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"encoding/json"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"main/internal/lib/cids"
+	"main/internal/models"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// ограничения IPFS Pinning Service API
+const (
+	PinListDefaultLimit = 10
+	PinListMaxLimit     = 1000
+	MaxPinNameLength    = 255
+	MaxPinOrigins       = 20
+	MaxPinCidFilter     = 10
+	MaxPinMetaKeys      = 1000
+)
+
+// ParsePinQuery разбирает параметры списка закреплений GET /pins: cid, name, match, status,
+// before, after, limit и meta. По умолчанию отдаются только закрепленные CID.
+func ParsePinQuery(query url.Values) (*models.PinFilter, error) {
+	const op = "service.ParsePinQuery"
+
+	filter := &models.PinFilter{
+		Name:     query.Get("name"),
+		Match:    models.PinMatchExact,
+		Statuses: []string{models.PinStatusPinned},
+		Limit:    PinListDefaultLimit,
+	}
+
+	if raw := query.Get("cid"); raw != "" {
+		values := strings.Split(raw, ",")
+		if len(values) > MaxPinCidFilter {
+			return nil, tvoerrors.Wrap(op+": too many cids", tvoerrors.ErrInvalidRequestData)
+		}
+		for _, value := range values {
+			c, err := cids.Parse(value)
+			if err != nil {
+				return nil, tvoerrors.Wrap(op+": invalid cid", tvoerrors.ErrInvalidRequestData)
+			}
+			// закрепление могли запросить в другой версии CID, поэтому ищем по всем его формам
+			for _, form := range cids.Forms(c) {
+				if !slices.Contains(filter.Cids, form) {
+					filter.Cids = append(filter.Cids, form)
+				}
+			}
+		}
+	}
+
+	if utf8.RuneCountInString(filter.Name) > MaxPinNameLength {
+		return nil, tvoerrors.Wrap(op+": name is too long", tvoerrors.ErrInvalidRequestData)
+	}
+	if raw := query.Get("match"); raw != "" {
+		switch raw {
+		case models.PinMatchExact, models.PinMatchIExact, models.PinMatchPartial, models.PinMatchIPartial:
+			filter.Match = raw
+		default:
+			return nil, tvoerrors.Wrap(op+": unknown match", tvoerrors.ErrInvalidRequestData)
+		}
+	}
+
+	if raw := query.Get("status"); raw != "" {
+		filter.Statuses = nil
+		for _, status := range strings.Split(raw, ",") {
+			switch status {
+			case models.PinStatusQueued, models.PinStatusPinning, models.PinStatusPinned, models.PinStatusFailed:
+				filter.Statuses = append(filter.Statuses, status)
+			default:
+				return nil, tvoerrors.Wrap(op+": unknown status", tvoerrors.ErrInvalidRequestData)
+			}
+		}
+	}
+
+	var err error
+	if filter.Before, err = parsePinTime(query.Get("before")); err != nil {
+		return nil, tvoerrors.Wrap(op+": invalid before", err)
+	}
+	if filter.After, err = parsePinTime(query.Get("after")); err != nil {
+		return nil, tvoerrors.Wrap(op+": invalid after", err)
+	}
+
+	if raw := query.Get("limit"); raw != "" {
+		filter.Limit, err = strconv.Atoi(raw)
+		if err != nil || filter.Limit < 1 || filter.Limit > PinListMaxLimit {
+			return nil, tvoerrors.Wrap(op+": invalid limit", tvoerrors.ErrInvalidRequestData)
+		}
+	}
+
+	if raw := query.Get("meta"); raw != "" {
+		if err = json.Unmarshal([]byte(raw), &filter.Meta); err != nil {
+			return nil, tvoerrors.Wrap(op+": meta must be a JSON object of strings", tvoerrors.ErrInvalidRequestData)
+		}
+	}
+
+	return filter, nil
+}
+
+// RemotePinToModel проверяет объект Pin из запроса и преобразует его в запрос на закрепление.
+// CID приводится к каноническому строковому виду.
+func RemotePinToModel(pin *models.RemotePin) (*models.Pin, error) {
+	const op = "service.RemotePinToModel"
+
+	c, err := cids.Parse(pin.Cid)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op+": invalid cid", tvoerrors.ErrInvalidRequestData)
+	}
+	if utf8.RuneCountInString(pin.Name) > MaxPinNameLength {
+		return nil, tvoerrors.Wrap(op+": name is too long", tvoerrors.ErrInvalidRequestData)
+	}
+	if len(pin.Origins) > MaxPinOrigins {
+		return nil, tvoerrors.Wrap(op+": too many origins", tvoerrors.ErrInvalidRequestData)
+	}
+	for _, origin := range pin.Origins {
+		if !strings.HasPrefix(origin, "/") {
+			return nil, tvoerrors.Wrap(op+": origin must be a multiaddr", tvoerrors.ErrInvalidRequestData)
+		}
+	}
+	if len(pin.Meta) > MaxPinMetaKeys {
+		return nil, tvoerrors.Wrap(op+": too many meta keys", tvoerrors.ErrInvalidRequestData)
+	}
+
+	return &models.Pin{
+		Cid:     c.String(),
+		Name:    pin.Name,
+		Origins: pin.Origins,
+		Meta:    pin.Meta,
+	}, nil
+}
+
+// PinToRemoteStatus преобразует запрос на закрепление в объект PinStatus.
+// delegates адреса узла, который хранит содержимое. Причина неудачной попытки передается в info.
+func PinToRemoteStatus(pin *models.Pin, delegates []string) models.RemotePinStatus {
+	status := models.RemotePinStatus{
+		RequestId: pin.RequestId,
+		Status:    pin.Status,
+		Created:   pin.CreatedAt.UTC(),
+		Pin: models.RemotePin{
+			Cid:     pin.Cid,
+			Name:    pin.Name,
+			Origins: pin.Origins,
+			Meta:    pin.Meta,
+		},
+		Delegates: delegates,
+		Info:      map[string]string{},
+	}
+	if pin.Error != "" {
+		status.Info["status_details"] = pin.Error
+	}
+	return status
+}
+
+// parsePinTime разбирает время в формате RFC 3339, пустое значение дает нулевое время
+func parsePinTime(raw string) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, tvoerrors.ErrInvalidRequestData
+	}
+	return t.UTC(), nil
+}
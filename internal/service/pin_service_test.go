@@ -0,0 +1,115 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"main/internal/models"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+const testCidV1 = "bafybeihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku"
+
+func TestParsePinQuery(t *testing.T) {
+	filter, err := ParsePinQuery(url.Values{})
+	if err != nil {
+		t.Fatalf("ParsePinQuery() error = %v", err)
+	}
+	if filter.Limit != PinListDefaultLimit || filter.Match != models.PinMatchExact ||
+		!reflect.DeepEqual(filter.Statuses, []string{models.PinStatusPinned}) {
+		t.Errorf("ParsePinQuery() defaults = %+v", filter)
+	}
+
+	// каждый CID дополняется другой его версией, повторяющиеся формы не дублируются
+	query, _ := url.ParseQuery("cid=" + testCidV1 + ",QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn" +
+		",QmdfTbBqBPQ7VNxZEYEj14VmRuZBkqFbiwReogJgS1zR1n" +
+		"&name=cat&match=ipartial&status=queued,failed&before=2025-07-02T00:00:00Z&after=2025-07-01T00:00:00%2B03:00" +
+		`&limit=1000&meta={"app_id":"99"}`)
+	filter, err = ParsePinQuery(query)
+	if err != nil {
+		t.Fatalf("ParsePinQuery() error = %v", err)
+	}
+	expected := &models.PinFilter{
+		Cids: []string{testCidV1, "QmdfTbBqBPQ7VNxZEYEj14VmRuZBkqFbiwReogJgS1zR1n",
+			"QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn",
+			"bafybeiczsscdsbs7ffqz55asqdf3smv6klcw3gofszvwlyarci47bgf354"},
+		Name:     "cat",
+		Match:    models.PinMatchIPartial,
+		Statuses: []string{models.PinStatusQueued, models.PinStatusFailed},
+		Before:   time.Date(2025, 7, 2, 0, 0, 0, 0, time.UTC),
+		After:    time.Date(2025, 6, 30, 21, 0, 0, 0, time.UTC),
+		Meta:     map[string]string{"app_id": "99"},
+		Limit:    1000,
+	}
+	if !reflect.DeepEqual(filter, expected) {
+		t.Errorf("ParsePinQuery() = %+v, expected %+v", filter, expected)
+	}
+
+	invalid := []string{
+		"cid=nope",
+		"cid=" + strings.Repeat(testCidV1+",", MaxPinCidFilter) + testCidV1,
+		"name=" + strings.Repeat("a", MaxPinNameLength+1),
+		"match=fuzzy",
+		"status=pinned,lost",
+		"before=yesterday",
+		"after=2025-07-01",
+		"limit=0",
+		"limit=1001",
+		`meta=["a"]`,
+		`meta={"a":1}`,
+	}
+	for _, raw := range invalid {
+		query, _ = url.ParseQuery(raw)
+		if _, err = ParsePinQuery(query); !errors.Is(err, tvoerrors.ErrInvalidRequestData) {
+			t.Errorf("ParsePinQuery(%q) error = %v, expected ErrInvalidRequestData", raw, err)
+		}
+	}
+}
+
+func TestRemotePinToModel(t *testing.T) {
+	pin, err := RemotePinToModel(&models.RemotePin{
+		Cid:     testCidV1,
+		Name:    "cat.png",
+		Origins: []string{"/ip4/203.0.113.1/tcp/4001/p2p/QmPeer"},
+		Meta:    map[string]string{"app_id": "99"},
+	})
+	if err != nil {
+		t.Fatalf("RemotePinToModel() error = %v", err)
+	}
+	if pin.Cid != testCidV1 || pin.Name != "cat.png" || len(pin.Origins) != 1 || pin.Meta["app_id"] != "99" {
+		t.Errorf("RemotePinToModel() = %+v", pin)
+	}
+
+	invalid := []models.RemotePin{
+		{},
+		{Cid: "nope"},
+		{Cid: testCidV1, Name: strings.Repeat("a", MaxPinNameLength+1)},
+		{Cid: testCidV1, Origins: []string{"203.0.113.1:4001"}},
+		{Cid: testCidV1, Origins: make([]string, MaxPinOrigins+1)},
+	}
+	for _, remote := range invalid {
+		if _, err = RemotePinToModel(&remote); !errors.Is(err, tvoerrors.ErrInvalidRequestData) {
+			t.Errorf("RemotePinToModel(%+v) error = %v, expected ErrInvalidRequestData", remote, err)
+		}
+	}
+}
+
+func TestPinToRemoteStatus(t *testing.T) {
+	pin := &models.Pin{
+		RequestId: "5c3a1f8e-3b7d-4c39-9f4e-2d7f0f2c4a11",
+		Cid:       testCidV1,
+		Status:    models.PinStatusQueued,
+		Error:     "timeout",
+		CreatedAt: time.Date(2025, 7, 1, 15, 0, 0, 0, time.FixedZone("MSK", 3*3600)),
+	}
+
+	status := PinToRemoteStatus(pin, []string{"/ip4/127.0.0.1/tcp/4001/p2p/QmNode"})
+	if status.RequestId != pin.RequestId || status.Pin.Cid != testCidV1 || len(status.Delegates) != 1 ||
+		status.Info["status_details"] != "timeout" || status.Created.Location() != time.UTC {
+		t.Errorf("PinToRemoteStatus() = %+v", status)
+	}
+}
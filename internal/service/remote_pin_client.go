@@ -0,0 +1,114 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"main/internal/config"
+	"main/internal/models"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// defaultRemotePinTimeout используется, если в конфиге не задан таймаут вызова удаленного сервиса
+const defaultRemotePinTimeout = 30 * time.Second
+
+// RemotePinClient клиент удаленного сервиса закреплений с IPFS Pinning Service API.
+// Источник: https://ipfs.github.io/pinning-services-api-spec/
+type RemotePinClient struct {
+	name     string
+	endpoint string
+	token    string
+	client   *http.Client
+}
+
+// NewRemotePinClient создает клиента удаленного сервиса закреплений
+func NewRemotePinClient(target *config.RemoteTarget, timeout time.Duration) *RemotePinClient {
+	if timeout <= 0 {
+		timeout = defaultRemotePinTimeout
+	}
+	return &RemotePinClient{
+		name:     target.Name,
+		endpoint: strings.TrimRight(target.Endpoint, "/"),
+		token:    target.Token,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// Name имя сервиса из конфига
+func (r *RemotePinClient) Name() string {
+	return r.name
+}
+
+// AddPin создает запрос на закрепление на удаленном сервисе
+func (r *RemotePinClient) AddPin(ctx context.Context, pin *models.RemotePin) (*models.RemotePinStatus, error) {
+	var status models.RemotePinStatus
+	if err := r.call(ctx, http.MethodPost, "/pins", pin, http.StatusAccepted, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// GetPin возвращает состояние запроса на закрепление. Неизвестный сервису запрос дает ErrNotFound.
+func (r *RemotePinClient) GetPin(ctx context.Context, requestId string) (*models.RemotePinStatus, error) {
+	var status models.RemotePinStatus
+	if err := r.call(ctx, http.MethodGet, "/pins/"+requestId, nil, http.StatusOK, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// call выполняет запрос к сервису и декодирует JSON ответ в out
+func (r *RemotePinClient) call(ctx context.Context, method, path string, in interface{}, expected int,
+	out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, r.endpoint+path, body)
+	if err != nil {
+		return fmt.Errorf("не удалось создать запрос к сервису %s: %w", r.name, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ошибка при выполнении запроса к сервису %s: %w", r.name, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return tvoerrors.Wrap("сервис "+r.name+": "+path, tvoerrors.ErrNotFound)
+	case resp.StatusCode != expected && resp.StatusCode != http.StatusOK:
+		// причина ошибки передается объектом Failure
+		var failure models.RemotePinFailure
+		bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(bodyBytes, &failure) == nil && failure.Error.Reason != "" {
+			return fmt.Errorf("сервис %s вернул ошибку: %s, %s: %s", r.name, resp.Status, failure.Error.Reason,
+				failure.Error.Details)
+		}
+		return fmt.Errorf("сервис %s вернул ошибку: %s, тело ответа: %s", r.name, resp.Status, string(bodyBytes))
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("не удалось декодировать ответ сервиса %s: %w", r.name, err)
+	}
+	return nil
+}
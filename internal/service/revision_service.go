@@ -0,0 +1,91 @@
+package service
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"main/internal/dto"
+	"main/internal/models"
+)
+
+// поля токена, изменения которых видны в истории
+const (
+	RevisionFieldName        = "name"
+	RevisionFieldDescription = "description"
+	RevisionFieldAttributes  = "attributes"
+	RevisionFieldContent     = "content"
+	RevisionFieldMetadata    = "metadata"
+)
+
+// TraitsToAttributes разбирает атрибуты, сохраненные в ревизии в формате метаданных
+func TraitsToAttributes(traits []models.NftMetadataTrait) ([]models.NftAttribute, error) {
+	if len(traits) == 0 {
+		return nil, nil
+	}
+	raw, err := json.Marshal(traits)
+	if err != nil {
+		return nil, err
+	}
+	return ParseNftAttributes(string(raw))
+}
+
+// RevisionChanges возвращает поля, которые изменились в ревизии по сравнению с предыдущей.
+// Для первой ревизии previous равен nil и изменившимися считаются все заполненные поля.
+func RevisionChanges(previous, current *models.NftRevision) []string {
+	if previous == nil {
+		previous = &models.NftRevision{}
+	}
+
+	changes := []string{}
+	if previous.Name != current.Name {
+		changes = append(changes, RevisionFieldName)
+	}
+	if previous.Description != current.Description {
+		changes = append(changes, RevisionFieldDescription)
+	}
+	if !reflect.DeepEqual(traitsOrEmpty(previous.Attributes), traitsOrEmpty(current.Attributes)) {
+		changes = append(changes, RevisionFieldAttributes)
+	}
+	if previous.CidV0 != current.CidV0 || previous.CidV1 != current.CidV1 {
+		changes = append(changes, RevisionFieldContent)
+	}
+	if previous.MetadataCid != current.MetadataCid {
+		changes = append(changes, RevisionFieldMetadata)
+	}
+	return changes
+}
+
+// RevisionsToDto преобразует историю, отсортированную от новых ревизий к старым, в ответ API
+func RevisionsToDto(revisions []models.NftRevision) []dto.NftRevision {
+	result := make([]dto.NftRevision, 0, len(revisions))
+	for i := range revisions {
+		var previous *models.NftRevision
+		if i+1 < len(revisions) {
+			previous = &revisions[i+1]
+		}
+
+		revision := &revisions[i]
+		result = append(result, dto.NftRevision{
+			Revision:    revision.Revision,
+			AuthorId:    revision.AuthorId,
+			CreatedAt:   revision.CreatedAt,
+			Name:        revision.Name,
+			Description: revision.Description,
+			CidV0:       revision.CidV0,
+			CidV1:       revision.CidV1,
+			FileName:    revision.FileName,
+			MetadataCid: revision.MetadataCid,
+			Attributes:  traitsOrEmpty(revision.Attributes),
+			RollbackOf:  revision.RollbackOf,
+			Changes:     RevisionChanges(previous, revision),
+		})
+	}
+	return result
+}
+
+func traitsOrEmpty(traits []models.NftMetadataTrait) []models.NftMetadataTrait {
+	if traits == nil {
+		return []models.NftMetadataTrait{}
+	}
+	return traits
+}
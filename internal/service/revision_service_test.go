@@ -0,0 +1,51 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"main/internal/models"
+)
+
+func TestRevisionChanges(t *testing.T) {
+	first := models.NftRevision{Revision: 1, Description: "About", CidV0: "Qm1", CidV1: "bafy1", MetadataCid: "QmM1"}
+	second := first
+	second.Revision = 2
+	second.Description = "About this token"
+	second.MetadataCid = "QmM2"
+	third := second
+	third.Revision = 3
+	third.Attributes = []models.NftMetadataTrait{{TraitType: "level", Value: float64(5)}}
+
+	tests := []struct {
+		previous *models.NftRevision
+		current  *models.NftRevision
+		expected []string
+	}{
+		{nil, &first, []string{RevisionFieldDescription, RevisionFieldContent, RevisionFieldMetadata}},
+		{&first, &second, []string{RevisionFieldDescription, RevisionFieldMetadata}},
+		{&second, &third, []string{RevisionFieldAttributes}},
+		{&third, &third, []string{}},
+	}
+
+	for _, test := range tests {
+		if result := RevisionChanges(test.previous, test.current); !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("RevisionChanges(%d) = %v, expected %v", test.current.Revision, result, test.expected)
+		}
+	}
+}
+
+func TestTraitsToAttributes(t *testing.T) {
+	attributes := []models.NftAttribute{
+		{TraitType: "Background", ValueType: models.AttributeString, StringValue: "Blue"},
+		{TraitType: "level", DisplayType: "number", ValueType: models.AttributeNumber, NumericValue: 5},
+	}
+
+	result, err := TraitsToAttributes(AttributesToTraits(attributes))
+	if err != nil {
+		t.Fatalf("TraitsToAttributes() error = %v", err)
+	}
+	if !reflect.DeepEqual(result, attributes) {
+		t.Errorf("TraitsToAttributes() = %+v, expected %+v", result, attributes)
+	}
+}
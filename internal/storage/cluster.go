@@ -0,0 +1,498 @@
+package storage
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+
+	"main/internal/cluster"
+	"main/internal/service"
+	"main/tools/pkg/logger"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// ClusterStorage stores content on several Kubo nodes. Content is added to a healthy node
+// and pinned on the number of nodes set by the registry, reads fail over to the next node.
+// IPNS keys and the MFS tree are local to a node: new keys are imported into every healthy node,
+// and the content is copied into MFS of the nodes keeping its blocks.
+type ClusterStorage struct {
+	nodes  *cluster.Registry
+	logger *logger.Logger
+}
+
+// NewClusterStorage creates a storage on top of the node registry.
+func NewClusterStorage(nodes *cluster.Registry, logger *logger.Logger) *ClusterStorage {
+	return &ClusterStorage{
+		nodes:  nodes,
+		logger: logger,
+	}
+}
+
+// Add uploads the content to a healthy node and replicates its pin.
+func (s *ClusterStorage) Add(ctx context.Context, name string, r io.Reader) (*Object, error) {
+	node := s.nodes.Pick()
+	object, err := s.kubo(node).Add(ctx, name, r)
+	if err != nil {
+		s.nodes.Fail(node, err)
+		return nil, err
+	}
+	s.replicate(ctx, node, object.Cid)
+	return object, nil
+}
+
+// Hash computes the CID on a healthy node, the nodes share the import settings.
+func (s *ClusterStorage) Hash(ctx context.Context, name string, r io.Reader) (*Object, error) {
+	node := s.nodes.Pick()
+	object, err := s.kubo(node).Hash(ctx, name, r)
+	if err != nil {
+		s.nodes.Fail(node, err)
+		return nil, err
+	}
+	return object, nil
+}
+
+// AddDirectory uploads the files to a healthy node and replicates the pin of the root.
+func (s *ClusterStorage) AddDirectory(ctx context.Context, files []service.DirectoryEntry) (*Object, []Object, error) {
+	node := s.nodes.Pick()
+	root, children, err := s.kubo(node).AddDirectory(ctx, files)
+	if err != nil {
+		s.nodes.Fail(node, err)
+		return nil, nil, err
+	}
+	s.replicate(ctx, node, root.Cid)
+	return root, children, nil
+}
+
+// Import imports the CAR file on a healthy node and replicates the pins of its roots.
+func (s *ClusterStorage) Import(ctx context.Context, r io.Reader) ([]cid.Cid, error) {
+	node := s.nodes.Pick()
+	roots, err := s.kubo(node).Import(ctx, r)
+	if err != nil {
+		s.nodes.Fail(node, err)
+		return nil, err
+	}
+	s.replicate(ctx, node, roots...)
+	return roots, nil
+}
+
+// Pin pins the CID on the number of healthy nodes set by the registry.
+// It fails only when no node pinned the CID.
+func (s *ClusterStorage) Pin(ctx context.Context, c cid.Cid) error {
+	targets := s.targets(s.nodes.Replicas(), nil)
+	if len(targets) == 0 {
+		// the health state may be stale, so all nodes are tried
+		targets = s.nodes.Nodes()[:s.nodes.Replicas()]
+	}
+
+	errs := s.each(targets, func(node *cluster.Node) error {
+		return s.kubo(node).Pin(ctx, c)
+	})
+	if len(errs) == len(targets) {
+		return errs[0]
+	}
+	if len(errs) > 0 {
+		s.logger.Warn("CID is pinned on fewer nodes than required", "cid", c,
+			"pinned", len(targets)-len(errs), "replicas", s.nodes.Replicas(), "error", errs[0])
+	}
+	return nil
+}
+
+// Unpin removes the pin from all healthy nodes. The pins left on unavailable nodes are found
+// as orphans once they are back. It fails only when no node removed the pin.
+func (s *ClusterStorage) Unpin(ctx context.Context, c cid.Cid) error {
+	targets := s.healthy()
+	errs := s.each(targets, func(node *cluster.Node) error {
+		return s.kubo(node).Unpin(ctx, c)
+	})
+	if len(errs) == len(targets) {
+		return errs[0]
+	}
+	return nil
+}
+
+// List returns the CIDs pinned on any healthy node, every CID once.
+func (s *ClusterStorage) List(ctx context.Context) ([]cid.Cid, error) {
+	targets := s.healthy()
+	var mu sync.Mutex
+	seen := make(map[cid.Cid]struct{})
+	cids := []cid.Cid{}
+	errs := s.each(targets, func(node *cluster.Node) error {
+		pinned, err := s.kubo(node).List(ctx)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, c := range pinned {
+			if _, ok := seen[c]; !ok {
+				seen[c] = struct{}{}
+				cids = append(cids, c)
+			}
+		}
+		return nil
+	})
+	if len(errs) == len(targets) {
+		return nil, errs[0]
+	}
+	return cids, nil
+}
+
+// Cat streams the content from the first node returning it.
+func (s *ClusterStorage) Cat(ctx context.Context, c cid.Cid) (body io.ReadCloser, err error) {
+	err = s.read(ctx, func(node *KuboStorage) (err error) {
+		body, err = node.Cat(ctx, c)
+		return err
+	})
+	return body, err
+}
+
+// Size returns the size of the content from the first node knowing it.
+func (s *ClusterStorage) Size(ctx context.Context, c cid.Cid) (size int64, err error) {
+	err = s.read(ctx, func(node *KuboStorage) (err error) {
+		size, err = node.Size(ctx, c)
+		return err
+	})
+	return size, err
+}
+
+// CatRange streams a part of the content from the first node returning it.
+func (s *ClusterStorage) CatRange(ctx context.Context, c cid.Cid, offset, length int64) (body io.ReadCloser, err error) {
+	err = s.read(ctx, func(node *KuboStorage) (err error) {
+		body, err = node.CatRange(ctx, c, offset, length)
+		return err
+	})
+	return body, err
+}
+
+// Export streams the DAG from the first node returning it.
+func (s *ClusterStorage) Export(ctx context.Context, c cid.Cid) (body io.ReadCloser, err error) {
+	err = s.read(ctx, func(node *KuboStorage) (err error) {
+		body, err = node.Export(ctx, c)
+		return err
+	})
+	return body, err
+}
+
+// Resolve resolves the path under the root on the first node able to.
+func (s *ClusterStorage) Resolve(ctx context.Context, root cid.Cid, path string) (resolved cid.Cid, err error) {
+	err = s.read(ctx, func(node *KuboStorage) (err error) {
+		resolved, err = node.Resolve(ctx, root, path)
+		return err
+	})
+	return resolved, err
+}
+
+// Block returns the block from the first node keeping it.
+func (s *ClusterStorage) Block(ctx context.Context, c cid.Cid) (block []byte, err error) {
+	err = s.read(ctx, func(node *KuboStorage) (err error) {
+		block, err = node.Block(ctx, c)
+		return err
+	})
+	return block, err
+}
+
+// Connect connects all healthy nodes to the peers providing the content.
+func (s *ClusterStorage) Connect(ctx context.Context, addrs []string) error {
+	targets := s.nodes.Healthy()
+	errs := s.each(targets, func(node *cluster.Node) error {
+		return s.kubo(node).Connect(ctx, addrs)
+	})
+	if len(targets) > 0 && len(errs) == len(targets) {
+		return errs[0]
+	}
+	return nil
+}
+
+// CollectGarbage runs the garbage collection on all healthy nodes and sums the removed blocks.
+func (s *ClusterStorage) CollectGarbage(ctx context.Context) (int, error) {
+	var mu sync.Mutex
+	total := 0
+	errs := s.each(s.nodes.Healthy(), func(node *cluster.Node) error {
+		removed, err := s.kubo(node).CollectGarbage(ctx)
+		mu.Lock()
+		total += removed
+		mu.Unlock()
+		return err
+	})
+	if len(errs) > 0 {
+		return total, errs[0]
+	}
+	return total, nil
+}
+
+// Key returns the IPNS name of the key from the first node keeping it. A missing key is generated here
+// and imported into the healthy nodes, so the record can be published from any of them. It is done only
+// when every node answered without the key: a node which did not answer may keep it, and a new key
+// would change the IPNS name.
+func (s *ClusterStorage) Key(ctx context.Context, keyName string) (name string, err error) {
+	const op = "storage.ClusterStorage.Key"
+
+	err = s.read(ctx, func(node *KuboStorage) (err error) {
+		name, err = node.findKey(ctx, keyName)
+		return err
+	})
+	if !errors.Is(err, tvoerrors.ErrNotFound) {
+		return name, err
+	}
+
+	key, err := newKey()
+	if err != nil {
+		return "", tvoerrors.Wrap(op, err)
+	}
+	targets := s.healthy()
+	var mu sync.Mutex
+	errs := s.each(targets, func(node *cluster.Node) error {
+		resp, err := node.Client.KeyImport(ctx, keyName, key)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		name = resp.Id
+		mu.Unlock()
+		return nil
+	})
+	if len(errs) == len(targets) {
+		return "", tvoerrors.Wrap(op, errs[0])
+	}
+	if len(errs) > 0 {
+		s.logger.Warn("IPNS key is imported into fewer nodes than are healthy", "key", keyName,
+			"imported", len(targets)-len(errs), "error", errs[0])
+	}
+	return name, nil
+}
+
+// Publish publishes the IPNS record from the first node keeping the key.
+func (s *ClusterStorage) Publish(ctx context.Context, keyName string, c cid.Cid,
+	lifetime time.Duration) (name string, err error) {
+	err = s.read(ctx, func(node *KuboStorage) (err error) {
+		if _, err = node.findKey(ctx, keyName); err != nil {
+			return err
+		}
+		name, err = node.Publish(ctx, keyName, c, lifetime)
+		return err
+	})
+	return name, err
+}
+
+// CopyFile copies the content into MFS of the healthy nodes keeping its blocks, so no node fetches
+// the content from another one and keeps a copy the replica count does not know about.
+// ErrNotFound is returned when no node keeps the content.
+func (s *ClusterStorage) CopyFile(ctx context.Context, c cid.Cid, mfsPath string) error {
+	const op = "storage.ClusterStorage.CopyFile"
+
+	targets := s.healthy()
+	var mu sync.Mutex
+	copied := 0
+	errs := s.each(targets, func(node *cluster.Node) error {
+		kubo := s.kubo(node)
+		if _, err := kubo.Block(ctx, c); errors.Is(err, tvoerrors.ErrNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := kubo.CopyFile(ctx, c, mfsPath); err != nil {
+			return err
+		}
+		mu.Lock()
+		copied++
+		mu.Unlock()
+		return nil
+	})
+	if copied == 0 && len(errs) > 0 {
+		return errs[0]
+	}
+	if copied == 0 {
+		return tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+	}
+	if len(errs) > 0 {
+		s.logger.Warn("Content is copied into MFS of fewer nodes than keep it", "cid", c, "path", mfsPath,
+			"copied", copied, "error", errs[0])
+	}
+	return nil
+}
+
+// ListFiles merges the MFS directory of the healthy nodes, an entry kept on several nodes is listed once.
+// ErrNotFound is returned when no node has the directory.
+func (s *ClusterStorage) ListFiles(ctx context.Context, mfsPath string) ([]FileInfo, error) {
+	const op = "storage.ClusterStorage.ListFiles"
+
+	targets := s.healthy()
+	var mu sync.Mutex
+	listed := make(map[*cluster.Node][]FileInfo, len(targets))
+	errs := s.each(targets, func(node *cluster.Node) error {
+		files, err := s.kubo(node).ListFiles(ctx, mfsPath)
+		if errors.Is(err, tvoerrors.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		listed[node] = files
+		mu.Unlock()
+		return nil
+	})
+	if len(listed) == 0 && len(errs) > 0 {
+		return nil, errs[0]
+	}
+	if len(listed) == 0 {
+		return nil, tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+	}
+
+	// the entries keep the order of the nodes, so the listing does not change between requests
+	seen := make(map[string]struct{})
+	files := []FileInfo{}
+	for _, node := range targets {
+		for _, file := range listed[node] {
+			if _, ok := seen[file.Name]; !ok {
+				seen[file.Name] = struct{}{}
+				files = append(files, file)
+			}
+		}
+	}
+	return files, nil
+}
+
+// StatFile describes the MFS entry from the first node having it.
+func (s *ClusterStorage) StatFile(ctx context.Context, mfsPath string) (info *FileInfo, err error) {
+	err = s.read(ctx, func(node *KuboStorage) (err error) {
+		info, err = node.StatFile(ctx, mfsPath)
+		return err
+	})
+	return info, err
+}
+
+// RemoveFile removes the MFS entry from all healthy nodes. It fails only when no node removed it,
+// ErrNotFound is returned when no node has the entry.
+func (s *ClusterStorage) RemoveFile(ctx context.Context, mfsPath string) error {
+	const op = "storage.ClusterStorage.RemoveFile"
+
+	var mu sync.Mutex
+	removed := 0
+	errs := s.each(s.healthy(), func(node *cluster.Node) error {
+		err := s.kubo(node).RemoveFile(ctx, mfsPath)
+		if errors.Is(err, tvoerrors.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		removed++
+		mu.Unlock()
+		return nil
+	})
+	if removed == 0 && len(errs) > 0 {
+		return errs[0]
+	}
+	if removed == 0 {
+		return tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+	}
+	return nil
+}
+
+// replicate pins the CIDs added to the source node on the other healthy nodes until the registry
+// replica count is reached. Replication failures are logged, the content is stored on the source node anyway.
+func (s *ClusterStorage) replicate(ctx context.Context, source *cluster.Node, cids ...cid.Cid) {
+	targets := s.targets(s.nodes.Replicas()-1, source)
+	addrs := source.Addresses()
+
+	s.each(targets, func(node *cluster.Node) error {
+		// the replica fetches the blocks from the source node directly
+		if len(addrs) > 0 {
+			_ = node.Client.SwarmConnect(ctx, addrs)
+		}
+		for _, c := range cids {
+			if err := s.kubo(node).Pin(ctx, c); err != nil {
+				s.logger.Warn("Error replicating pin", "cid", c, "node", node.Name, "error", err)
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// targets returns up to n healthy nodes other than exclude
+func (s *ClusterStorage) targets(n int, exclude *cluster.Node) []*cluster.Node {
+	var targets []*cluster.Node
+	for _, node := range s.nodes.Healthy() {
+		if node != exclude && len(targets) < n {
+			targets = append(targets, node)
+		}
+	}
+	return targets
+}
+
+// each calls fn for the nodes concurrently and returns the errors. Nodes which did not respond
+// are marked down in the registry.
+func (s *ClusterStorage) each(nodes []*cluster.Node, fn func(node *cluster.Node) error) []error {
+	var mu sync.Mutex
+	var errs []error
+	var wg sync.WaitGroup
+	for _, node := range nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := fn(node); err != nil {
+				s.nodes.Fail(node, err)
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+// read calls fn for the nodes one by one, healthy nodes first, until it succeeds.
+// ErrNotFound is returned only when every node answered without the content.
+func (s *ClusterStorage) read(ctx context.Context, fn func(node *KuboStorage) error) error {
+	var err, nodeErr error
+	for _, node := range s.nodes.Nodes() {
+		if err = fn(s.kubo(node)); err == nil {
+			return nil
+		}
+		if s.nodes.Fail(node, err) {
+			nodeErr = err
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+	}
+	// a node which did not answer may keep the content
+	if nodeErr != nil {
+		return nodeErr
+	}
+	return err
+}
+
+// healthy returns the healthy nodes, or all nodes when none is known to be healthy,
+// since the health state may be stale
+func (s *ClusterStorage) healthy() []*cluster.Node {
+	if nodes := s.nodes.Healthy(); len(nodes) > 0 {
+		return nodes
+	}
+	return s.nodes.Nodes()
+}
+
+// kubo returns the storage of the node
+func (s *ClusterStorage) kubo(node *cluster.Node) *KuboStorage {
+	return NewKuboStorage(node.Client)
+}
+
+// newKey generates an ed25519 IPNS key in the libp2p protobuf encoding /api/v0/key/import expects
+func newKey() ([]byte, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	// PrivateKey{Type: Ed25519, Data: key}: field 1 varint 1, field 2 of 64 bytes
+	return append([]byte{0x08, 0x01, 0x12, ed25519.PrivateKeySize}, key...), nil
+}
@@ -0,0 +1,449 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+
+	"main/internal/cluster"
+	"main/internal/config"
+	"main/tools/pkg/logger"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// fakeNetwork lets the fake nodes fetch the content of each other while pinning, like bitswap does.
+type fakeNetwork struct {
+	mu    sync.Mutex
+	nodes []*fakeKubo
+}
+
+// fetch returns the content from any running node keeping it
+func (n *fakeNetwork) fetch(c string) ([]byte, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, node := range n.nodes {
+		if data, ok := node.content(c); ok && !node.down {
+			return data, true
+		}
+	}
+	return nil, false
+}
+
+// fakeKubo is a minimal Kubo node keeping the pinned content in memory.
+type fakeKubo struct {
+	name    string
+	network *fakeNetwork
+	srv     *httptest.Server
+	down    bool
+
+	mu        sync.Mutex
+	blocks    map[string][]byte
+	pins      map[string]bool
+	keys      map[string]string
+	files     map[string]string
+	connected []string
+}
+
+func newFakeKubo(t *testing.T, network *fakeNetwork, name string) *fakeKubo {
+	t.Helper()
+
+	node := &fakeKubo{name: name, network: network, blocks: map[string][]byte{}, pins: map[string]bool{},
+		keys: map[string]string{}, files: map[string]string{}}
+	node.srv = httptest.NewServer(node)
+	t.Cleanup(node.srv.Close)
+
+	network.mu.Lock()
+	network.nodes = append(network.nodes, node)
+	network.mu.Unlock()
+	return node
+}
+
+// stop shuts the node down, its API refuses connections afterwards
+func (k *fakeKubo) stop() {
+	k.network.mu.Lock()
+	k.down = true
+	k.network.mu.Unlock()
+	k.srv.Close()
+}
+
+func (k *fakeKubo) content(c string) ([]byte, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	data, ok := k.blocks[c]
+	return data, ok
+}
+
+func (k *fakeKubo) pinned(c cid.Cid) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.pins[c.String()]
+}
+
+func (k *fakeKubo) file(p string) (string, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	c, ok := k.files[p]
+	return c, ok
+}
+
+func (k *fakeKubo) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	arg := r.URL.Query().Get("arg")
+	switch r.URL.Path {
+	case "/id":
+		writeJSON(w, map[string]interface{}{"ID": k.name, "Addresses": []string{"/ip4/127.0.0.1/tcp/4001/p2p/" + k.name}})
+	case "/add":
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		hash, _ := mh.Sum(data, mh.SHA2_256, -1)
+		c := cid.NewCidV0(hash).String()
+		k.mu.Lock()
+		k.blocks[c], k.pins[c] = data, true
+		k.mu.Unlock()
+		writeJSON(w, map[string]string{"Name": header.Filename, "Hash": c, "Size": strconv.Itoa(len(data))})
+	case "/pin/add":
+		data, ok := k.content(arg)
+		if !ok {
+			if data, ok = k.network.fetch(arg); !ok {
+				writeKuboError(w, "failed to fetch "+arg)
+				return
+			}
+		}
+		k.mu.Lock()
+		k.blocks[arg], k.pins[arg] = data, true
+		k.mu.Unlock()
+		writeJSON(w, map[string][]string{"Pins": {arg}})
+	case "/pin/rm":
+		k.mu.Lock()
+		pinned := k.pins[arg]
+		delete(k.pins, arg)
+		k.mu.Unlock()
+		if !pinned {
+			writeKuboError(w, "not pinned or pinned indirectly")
+			return
+		}
+		writeJSON(w, map[string][]string{"Pins": {arg}})
+	case "/pin/ls":
+		keys := map[string]map[string]string{}
+		k.mu.Lock()
+		for c := range k.pins {
+			keys[c] = map[string]string{"Type": "recursive"}
+		}
+		k.mu.Unlock()
+		writeJSON(w, map[string]interface{}{"Keys": keys})
+	case "/cat":
+		data, ok := k.content(arg)
+		if !ok {
+			writeKuboError(w, "block was not found locally (offline)")
+			return
+		}
+		_, _ = w.Write(data)
+	case "/block/get":
+		data, ok := k.content(arg)
+		if !ok {
+			writeKuboError(w, "block was not found locally (offline)")
+			return
+		}
+		_, _ = w.Write(data)
+	case "/key/list":
+		keys := []map[string]string{}
+		k.mu.Lock()
+		for name, id := range k.keys {
+			keys = append(keys, map[string]string{"Name": name, "Id": id})
+		}
+		k.mu.Unlock()
+		writeJSON(w, map[string]interface{}{"Keys": keys})
+	case "/key/import":
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		hash, _ := mh.Sum(data, mh.SHA2_256, -1)
+		id := cid.NewCidV1(cid.Libp2pKey, hash).String()
+		k.mu.Lock()
+		k.keys[arg] = id
+		k.mu.Unlock()
+		writeJSON(w, map[string]string{"Name": arg, "Id": id})
+	case "/name/publish":
+		k.mu.Lock()
+		id, ok := k.keys[r.URL.Query().Get("key")]
+		k.mu.Unlock()
+		if !ok {
+			writeKuboError(w, "no key by the given name was found")
+			return
+		}
+		writeJSON(w, map[string]string{"Name": id, "Value": arg})
+	case "/files/cp":
+		args := r.URL.Query()["arg"]
+		c := strings.TrimPrefix(args[0], "/ipfs/")
+		if _, ok := k.content(c); !ok {
+			// the node fetches the content from the network like bitswap does
+			data, ok := k.network.fetch(c)
+			if !ok {
+				writeKuboError(w, "failed to fetch "+c)
+				return
+			}
+			k.mu.Lock()
+			k.blocks[c] = data
+			k.mu.Unlock()
+		}
+		k.mu.Lock()
+		k.files[args[1]] = c
+		k.mu.Unlock()
+		writeJSON(w, map[string]interface{}{})
+	case "/files/stat":
+		c, ok := k.file(arg)
+		if !ok {
+			writeKuboError(w, "file does not exist")
+			return
+		}
+		data, _ := k.content(c)
+		writeJSON(w, map[string]interface{}{"Hash": c, "Size": len(data), "Type": "file"})
+	case "/files/ls":
+		entries := []map[string]interface{}{}
+		k.mu.Lock()
+		for p, c := range k.files {
+			if path.Dir(p) == arg {
+				entries = append(entries, map[string]interface{}{"Name": path.Base(p), "Hash": c, "Type": 0})
+			}
+		}
+		k.mu.Unlock()
+		if len(entries) == 0 {
+			writeKuboError(w, "file does not exist")
+			return
+		}
+		writeJSON(w, map[string]interface{}{"Entries": entries})
+	case "/files/rm":
+		if _, ok := k.file(arg); !ok {
+			writeKuboError(w, "file does not exist")
+			return
+		}
+		k.mu.Lock()
+		delete(k.files, arg)
+		k.mu.Unlock()
+		writeJSON(w, map[string]interface{}{})
+	case "/swarm/connect":
+		k.mu.Lock()
+		k.connected = append(k.connected, r.URL.Query()["arg"]...)
+		k.mu.Unlock()
+		writeJSON(w, map[string]interface{}{})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func writeKuboError(w http.ResponseWriter, message string) {
+	w.WriteHeader(http.StatusInternalServerError)
+	writeJSON(w, map[string]interface{}{"Message": message, "Code": 0})
+}
+
+// newTestCluster starts count fake nodes and checks them with the registry
+func newTestCluster(t *testing.T, count, replicas int) ([]*fakeKubo, *cluster.Registry, *ClusterStorage) {
+	t.Helper()
+
+	network := &fakeNetwork{}
+	nodes := make([]*fakeKubo, 0, count)
+	urls := make([]string, 0, count)
+	for i := range count {
+		node := newFakeKubo(t, network, "12D3KooWNode"+strconv.Itoa(i))
+		nodes, urls = append(nodes, node), append(urls, node.srv.URL)
+	}
+
+	log := &logger.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	registry := cluster.NewRegistry(&config.IPFS{NodeURLs: urls, Replicas: replicas}, log)
+	registry.Check(context.Background())
+	return nodes, registry, NewClusterStorage(registry, log)
+}
+
+// pinCount returns the number of nodes pinning the CID
+func pinCount(nodes []*fakeKubo, c cid.Cid) int {
+	count := 0
+	for _, node := range nodes {
+		if node.pinned(c) {
+			count++
+		}
+	}
+	return count
+}
+
+func TestClusterStorageAddReplicates(t *testing.T) {
+	nodes, _, s := newTestCluster(t, 3, 2)
+	ctx := context.Background()
+
+	object, err := s.Add(ctx, "1.png", strings.NewReader("token image"))
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if count := pinCount(nodes, object.Cid); count != 2 {
+		t.Errorf("CID is pinned on %d nodes, expected 2", count)
+	}
+	// the replica connects to the node the content was added to
+	if len(nodes[1].connected) != 1 || nodes[1].connected[0] != "/ip4/127.0.0.1/tcp/4001/p2p/12D3KooWNode0" {
+		t.Errorf("replica connected to %v", nodes[1].connected)
+	}
+
+	// the next upload goes to the next healthy node
+	second, err := s.Add(ctx, "2.png", strings.NewReader("second image"))
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if _, ok := nodes[1].content(second.Cid.String()); !ok || pinCount(nodes, second.Cid) != 2 {
+		t.Errorf("second upload is not added to the second node and replicated")
+	}
+
+	cids, err := s.List(ctx)
+	if err != nil || len(cids) != 2 {
+		t.Errorf("List() = %v, %v, expected both uploads once", cids, err)
+	}
+}
+
+func TestClusterStorageFailover(t *testing.T) {
+	nodes, registry, s := newTestCluster(t, 3, 2)
+	ctx := context.Background()
+
+	object, err := s.Add(ctx, "1.png", strings.NewReader("token image"))
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	nodes[0].stop()
+
+	// the read fails over to the replica and the stopped node is marked down
+	body, err := s.Cat(ctx, object.Cid)
+	if err != nil {
+		t.Fatalf("Cat() error = %v", err)
+	}
+	data, _ := io.ReadAll(body)
+	body.Close()
+	if string(data) != "token image" {
+		t.Errorf("Cat() = %q", data)
+	}
+	if states := registry.States(); states[0].Healthy || states[0].Error == "" || !states[1].Healthy {
+		t.Errorf("unexpected node states %+v", states)
+	}
+
+	// uploads go to the healthy nodes only and are replicated between them
+	for i := range 2 {
+		added, err := s.Add(ctx, "upload", strings.NewReader("upload "+strconv.Itoa(i)))
+		if err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		if !nodes[1].pinned(added.Cid) || !nodes[2].pinned(added.Cid) {
+			t.Errorf("upload %d is not pinned on both healthy nodes", i)
+		}
+	}
+
+	// pins restore the replica count on the healthy nodes
+	if err = s.Pin(ctx, object.Cid); err != nil {
+		t.Fatalf("Pin() error = %v", err)
+	}
+	if !nodes[1].pinned(object.Cid) || !nodes[2].pinned(object.Cid) {
+		t.Error("Pin() did not replicate the CID to the healthy nodes")
+	}
+	if _, err = s.List(ctx); err != nil {
+		t.Errorf("List() error = %v with a node down", err)
+	}
+	if err = s.Unpin(ctx, object.Cid); err != nil || pinCount(nodes[1:], object.Cid) != 0 {
+		t.Errorf("Unpin() error = %v, expected the CID unpinned from the healthy nodes", err)
+	}
+}
+
+func TestClusterStorageAllNodesDown(t *testing.T) {
+	nodes, _, s := newTestCluster(t, 2, 2)
+	for _, node := range nodes {
+		node.stop()
+	}
+
+	if _, err := s.Add(context.Background(), "1.png", strings.NewReader("token image")); err == nil {
+		t.Error("Add() expected an error with all nodes down")
+	}
+	if _, err := s.List(context.Background()); err == nil {
+		t.Error("List() expected an error with all nodes down")
+	}
+}
+
+func TestClusterStorageFilesAndKeys(t *testing.T) {
+	nodes, _, s := newTestCluster(t, 3, 2)
+	ctx := context.Background()
+
+	object, err := s.Add(ctx, "1.png", strings.NewReader("token image"))
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	name, err := s.Key(ctx, "collection-1")
+	if err != nil {
+		t.Fatalf("Key() error = %v", err)
+	}
+
+	// the content is copied into MFS of the nodes keeping it, the third node does not fetch it
+	if err = s.CopyFile(ctx, object.Cid, "/tvo/1.png"); err != nil {
+		t.Fatalf("CopyFile() error = %v", err)
+	}
+	if _, ok := nodes[2].file("/tvo/1.png"); ok {
+		t.Error("CopyFile() copied the content into MFS of the node not keeping it")
+	}
+	if _, ok := nodes[2].content(object.Cid.String()); ok {
+		t.Error("CopyFile() made the node not keeping the content fetch it")
+	}
+	hash, _ := mh.Sum([]byte("missing"), mh.SHA2_256, -1)
+	missing := cid.NewCidV1(cid.Raw, hash)
+	if err = s.CopyFile(ctx, missing, "/tvo/2.png"); !errors.Is(err, tvoerrors.ErrNotFound) {
+		t.Errorf("CopyFile() of missing content error = %v, expected ErrNotFound", err)
+	}
+
+	nodes[0].stop()
+
+	// the MFS tree and the IPNS key are served by the healthy nodes
+	files, err := s.ListFiles(ctx, "/tvo")
+	if err != nil || len(files) != 1 || files[0].Name != "1.png" || files[0].Cid != object.Cid {
+		t.Errorf("ListFiles() = %+v, %v, expected the copied file once", files, err)
+	}
+	if info, err := s.StatFile(ctx, "/tvo/1.png"); err != nil || info.Cid != object.Cid {
+		t.Errorf("StatFile() = %+v, %v", info, err)
+	}
+	if again, err := s.Key(ctx, "collection-1"); err != nil || again != name {
+		t.Errorf("Key() = %q, %v, expected %q", again, err, name)
+	}
+	if published, err := s.Publish(ctx, "collection-1", object.Cid, time.Hour); err != nil || published != name {
+		t.Errorf("Publish() = %q, %v, expected %q", published, err, name)
+	}
+	// the stopped node may keep a key the others do not have, so no new key is made
+	if _, err = s.Key(ctx, "collection-2"); err == nil || errors.Is(err, tvoerrors.ErrNotFound) {
+		t.Errorf("Key() of a new key with a node down error = %v, expected the node error", err)
+	}
+
+	if err = s.RemoveFile(ctx, "/tvo/1.png"); err != nil {
+		t.Fatalf("RemoveFile() error = %v", err)
+	}
+	if _, ok := nodes[1].file("/tvo/1.png"); ok {
+		t.Error("RemoveFile() kept the file on a healthy node")
+	}
+	if _, err = s.ListFiles(ctx, "/tvo"); !errors.Is(err, tvoerrors.ErrNotFound) {
+		t.Errorf("ListFiles() of the emptied directory error = %v, expected ErrNotFound", err)
+	}
+	if err = s.RemoveFile(ctx, "/tvo/1.png"); !errors.Is(err, tvoerrors.ErrNotFound) {
+		t.Errorf("RemoveFile() of a removed file error = %v, expected ErrNotFound", err)
+	}
+}
@@ -0,0 +1,338 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ipfs/go-cid"
+
+	"main/internal/models"
+	"main/internal/service"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// KuboStorage stores content on a Kubo node.
+type KuboStorage struct {
+	kubo *service.KuboClient
+}
+
+// NewKuboStorage creates a storage on top of the Kubo client.
+func NewKuboStorage(kubo *service.KuboClient) *KuboStorage {
+	return &KuboStorage{kubo: kubo}
+}
+
+// Add uploads the content with /api/v0/add, which pins it by default.
+func (s *KuboStorage) Add(ctx context.Context, name string, r io.Reader) (*Object, error) {
+	const op = "storage.KuboStorage.Add"
+
+	resp, err := s.kubo.Add(ctx, name, r)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+
+	object, err := addedObject(resp)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	return object, nil
+}
+
+// Hash computes the CID with /api/v0/add and only-hash, so the import settings of the node apply.
+func (s *KuboStorage) Hash(ctx context.Context, name string, r io.Reader) (*Object, error) {
+	const op = "storage.KuboStorage.Hash"
+
+	resp, err := s.kubo.AddOnlyHash(ctx, name, r)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+
+	object, err := addedObject(resp)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	return object, nil
+}
+
+// AddDirectory uploads the files with /api/v0/add and wrap-with-directory, the root is pinned by default.
+func (s *KuboStorage) AddDirectory(ctx context.Context, files []service.DirectoryEntry) (*Object, []Object, error) {
+	const op = "storage.KuboStorage.AddDirectory"
+
+	added, err := s.kubo.AddDirectory(ctx, files)
+	if err != nil {
+		return nil, nil, tvoerrors.Wrap(op, err)
+	}
+
+	var root *Object
+	children := make([]Object, 0, len(added))
+	for i := range added {
+		object, err := addedObject(&added[i])
+		if err != nil {
+			return nil, nil, tvoerrors.Wrap(op, err)
+		}
+		// the wrapping directory is reported without a name
+		if object.Name == "" {
+			root = object
+			continue
+		}
+		children = append(children, *object)
+	}
+	if root == nil {
+		return nil, nil, tvoerrors.Wrap(op, errors.New("kubo did not report the root directory"))
+	}
+	return root, children, nil
+}
+
+// addedObject converts a line of the /api/v0/add response
+func addedObject(resp *models.AddResponse) (*Object, error) {
+	c, err := cid.Decode(resp.Hash)
+	if err != nil {
+		return nil, err
+	}
+	size, _ := strconv.ParseInt(resp.Size, 10, 64)
+
+	return &Object{Name: resp.Name, Cid: c, Size: size}, nil
+}
+
+// Pin pins the CID on the node.
+func (s *KuboStorage) Pin(ctx context.Context, c cid.Cid) error {
+	if _, err := s.kubo.Pin(ctx, c.String()); err != nil {
+		return tvoerrors.Wrap("storage.KuboStorage.Pin", err)
+	}
+	return nil
+}
+
+// Connect connects the node to the peers providing the content.
+func (s *KuboStorage) Connect(ctx context.Context, addrs []string) error {
+	if err := s.kubo.SwarmConnect(ctx, addrs); err != nil {
+		return tvoerrors.Wrap("storage.KuboStorage.Connect", err)
+	}
+	return nil
+}
+
+// Unpin removes the pin from the node.
+func (s *KuboStorage) Unpin(ctx context.Context, c cid.Cid) error {
+	if _, err := s.kubo.Unpin(ctx, c.String()); err != nil {
+		return tvoerrors.Wrap("storage.KuboStorage.Unpin", err)
+	}
+	return nil
+}
+
+// List returns the recursively pinned CIDs of the node.
+func (s *KuboStorage) List(ctx context.Context) ([]cid.Cid, error) {
+	const op = "storage.KuboStorage.List"
+
+	resp, err := s.kubo.ListPins(ctx)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+
+	cids := make([]cid.Cid, 0, len(resp.Keys))
+	for key := range resp.Keys {
+		c, err := cid.Decode(key)
+		if err != nil {
+			return nil, tvoerrors.Wrap(op, err)
+		}
+		cids = append(cids, c)
+	}
+	return cids, nil
+}
+
+// Cat streams the content from the node.
+func (s *KuboStorage) Cat(ctx context.Context, c cid.Cid) (io.ReadCloser, error) {
+	body, err := s.kubo.Cat(ctx, c.String())
+	if err != nil {
+		return nil, tvoerrors.Wrap("storage.KuboStorage.Cat", err)
+	}
+	return body, nil
+}
+
+// Size returns the size of the file with /api/v0/files/stat on its /ipfs path.
+func (s *KuboStorage) Size(ctx context.Context, c cid.Cid) (int64, error) {
+	resp, err := s.kubo.FilesStat(ctx, "/ipfs/"+c.String())
+	if err != nil {
+		return 0, tvoerrors.Wrap("storage.KuboStorage.Size", err)
+	}
+	return resp.Size, nil
+}
+
+// CatRange streams a part of the content from the node, cat reads only the blocks of the range.
+func (s *KuboStorage) CatRange(ctx context.Context, c cid.Cid, offset, length int64) (io.ReadCloser, error) {
+	body, err := s.kubo.CatRange(ctx, c.String(), offset, length)
+	if err != nil {
+		return nil, tvoerrors.Wrap("storage.KuboStorage.CatRange", err)
+	}
+	return body, nil
+}
+
+// Import imports a CAR file with /api/v0/dag/import and pins its roots.
+func (s *KuboStorage) Import(ctx context.Context, r io.Reader) ([]cid.Cid, error) {
+	const op = "storage.KuboStorage.Import"
+
+	roots, err := s.kubo.DagImport(ctx, r)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+
+	cids := make([]cid.Cid, 0, len(roots))
+	for _, root := range roots {
+		c, err := cid.Decode(root)
+		if err != nil {
+			return nil, tvoerrors.Wrap(op, err)
+		}
+		cids = append(cids, c)
+	}
+	return cids, nil
+}
+
+// Export streams the DAG from the node with /api/v0/dag/export.
+func (s *KuboStorage) Export(ctx context.Context, c cid.Cid) (io.ReadCloser, error) {
+	body, err := s.kubo.DagExport(ctx, c.String())
+	if err != nil {
+		return nil, tvoerrors.Wrap("storage.KuboStorage.Export", err)
+	}
+	return body, nil
+}
+
+// Resolve resolves the path under the root with /api/v0/resolve.
+func (s *KuboStorage) Resolve(ctx context.Context, root cid.Cid, path string) (cid.Cid, error) {
+	const op = "storage.KuboStorage.Resolve"
+
+	resolved, err := s.kubo.Resolve(ctx, "/ipfs/"+root.String()+"/"+path)
+	if err != nil {
+		return cid.Undef, tvoerrors.Wrap(op, err)
+	}
+	c, err := cid.Decode(strings.TrimPrefix(resolved, "/ipfs/"))
+	if err != nil {
+		return cid.Undef, tvoerrors.Wrap(op, err)
+	}
+	return c, nil
+}
+
+// Block returns a block kept by the node with /api/v0/block/get, the network is not searched.
+func (s *KuboStorage) Block(ctx context.Context, c cid.Cid) ([]byte, error) {
+	block, err := s.kubo.BlockGet(ctx, c.String())
+	if err != nil {
+		return nil, tvoerrors.Wrap("storage.KuboStorage.Block", err)
+	}
+	return block, nil
+}
+
+// Key returns the IPNS name of the node key, the key is generated with /api/v0/key/gen when missing.
+func (s *KuboStorage) Key(ctx context.Context, keyName string) (string, error) {
+	const op = "storage.KuboStorage.Key"
+
+	name, err := s.findKey(ctx, keyName)
+	if !errors.Is(err, tvoerrors.ErrNotFound) {
+		return name, err
+	}
+
+	key, err := s.kubo.KeyGen(ctx, keyName)
+	if err != nil {
+		return "", tvoerrors.Wrap(op, err)
+	}
+	return key.Id, nil
+}
+
+// findKey returns the IPNS name of the node key with /api/v0/key/list, ErrNotFound when the node has no such key.
+func (s *KuboStorage) findKey(ctx context.Context, keyName string) (string, error) {
+	const op = "storage.KuboStorage.findKey"
+
+	keys, err := s.kubo.KeyList(ctx)
+	if err != nil {
+		return "", tvoerrors.Wrap(op, err)
+	}
+	for _, key := range keys.Keys {
+		if key.Name == keyName {
+			return key.Id, nil
+		}
+	}
+	return "", tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+}
+
+// Publish publishes the IPNS record of the key with /api/v0/name/publish.
+func (s *KuboStorage) Publish(ctx context.Context, keyName string, c cid.Cid, lifetime time.Duration) (string, error) {
+	resp, err := s.kubo.NamePublish(ctx, keyName, "/ipfs/"+c.String(), lifetime)
+	if err != nil {
+		return "", tvoerrors.Wrap("storage.KuboStorage.Publish", err)
+	}
+	return resp.Name, nil
+}
+
+// CopyFile copies the content into MFS with /api/v0/files/cp. An existing entry is removed first,
+// since files/cp does not overwrite.
+func (s *KuboStorage) CopyFile(ctx context.Context, c cid.Cid, mfsPath string) error {
+	const op = "storage.KuboStorage.CopyFile"
+
+	if _, err := s.kubo.FilesStat(ctx, mfsPath); err == nil {
+		if err = s.kubo.FilesRm(ctx, mfsPath); err != nil {
+			return tvoerrors.Wrap(op, err)
+		}
+	} else if !errors.Is(err, tvoerrors.ErrNotFound) {
+		return tvoerrors.Wrap(op, err)
+	}
+
+	if err := s.kubo.FilesCp(ctx, "/ipfs/"+c.String(), mfsPath); err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+	return nil
+}
+
+// ListFiles lists the MFS directory with /api/v0/files/ls.
+func (s *KuboStorage) ListFiles(ctx context.Context, mfsPath string) ([]FileInfo, error) {
+	const op = "storage.KuboStorage.ListFiles"
+
+	resp, err := s.kubo.FilesLs(ctx, mfsPath)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	files := make([]FileInfo, 0, len(resp.Entries))
+	for _, entry := range resp.Entries {
+		c, err := cid.Decode(entry.Hash)
+		if err != nil {
+			return nil, tvoerrors.Wrap(op, err)
+		}
+		files = append(files, FileInfo{Name: entry.Name, Cid: c, Size: entry.Size, Directory: entry.Type == 1})
+	}
+	return files, nil
+}
+
+// StatFile describes the MFS entry with /api/v0/files/stat.
+func (s *KuboStorage) StatFile(ctx context.Context, mfsPath string) (*FileInfo, error) {
+	const op = "storage.KuboStorage.StatFile"
+
+	resp, err := s.kubo.FilesStat(ctx, mfsPath)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	c, err := cid.Decode(resp.Hash)
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	info := &FileInfo{Name: path.Base(mfsPath), Cid: c, Size: resp.Size, Directory: resp.Type == "directory"}
+	if info.Directory {
+		info.Size = resp.CumulativeSize
+	}
+	return info, nil
+}
+
+// RemoveFile removes the MFS entry with /api/v0/files/rm.
+func (s *KuboStorage) RemoveFile(ctx context.Context, mfsPath string) error {
+	if err := s.kubo.FilesRm(ctx, mfsPath); err != nil {
+		return tvoerrors.Wrap("storage.KuboStorage.RemoveFile", err)
+	}
+	return nil
+}
+
+// CollectGarbage runs /api/v0/repo/gc on the node.
+func (s *KuboStorage) CollectGarbage(ctx context.Context) (int, error) {
+	removed, err := s.kubo.RepoGC(ctx)
+	if err != nil {
+		return removed, tvoerrors.Wrap("storage.KuboStorage.CollectGarbage", err)
+	}
+	return removed, nil
+}
@@ -0,0 +1,218 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/ipfs/go-cid"
+
+	"main/internal/lib/cids"
+	"main/internal/lib/unixfs"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// directories of the local storage
+const (
+	objectsDir = "objects"
+	pinsDir    = "pins"
+	tmpDir     = "tmp"
+)
+
+// LocalStorage keeps content in a directory, named by the CID Kubo would compute in its CIDv1 form,
+// so content is found by both CID versions. There is no garbage collector, so unpinned content
+// is removed immediately.
+type LocalStorage struct {
+	root string
+}
+
+// NewLocalStorage creates the local storage and its directories.
+func NewLocalStorage(root string) (*LocalStorage, error) {
+	const op = "storage.NewLocalStorage"
+
+	for _, dir := range []string{objectsDir, pinsDir, tmpDir} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			return nil, tvoerrors.Wrap(op, err)
+		}
+	}
+	s := &LocalStorage{root: root}
+	for _, dir := range []string{objectsDir, pinsDir} {
+		if err := s.renameLegacy(dir); err != nil {
+			return nil, tvoerrors.Wrap(op, err)
+		}
+	}
+	return s, nil
+}
+
+// Add writes the content to a temporary file while computing its CID and
+// moves the file to its content address.
+func (s *LocalStorage) Add(ctx context.Context, name string, r io.Reader) (*Object, error) {
+	const op = "storage.LocalStorage.Add"
+
+	tmp, err := os.CreateTemp(filepath.Join(s.root, tmpDir), "add-*")
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	res, err := unixfs.Sum(io.TeeReader(r, tmp))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+
+	if err = os.Rename(tmp.Name(), s.objectPath(res.Cid)); err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	if err = s.Pin(ctx, res.Cid); err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+
+	return &Object{Name: name, Cid: res.Cid, Size: int64(res.DagSize)}, nil
+}
+
+// Pin marks stored content as pinned.
+func (s *LocalStorage) Pin(_ context.Context, c cid.Cid) error {
+	const op = "storage.LocalStorage.Pin"
+
+	if _, err := os.Stat(s.objectPath(c)); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+		}
+		return tvoerrors.Wrap(op, err)
+	}
+
+	if err := os.WriteFile(s.pinPath(c), nil, 0o644); err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+	return nil
+}
+
+// Unpin removes the pin together with the content.
+func (s *LocalStorage) Unpin(_ context.Context, c cid.Cid) error {
+	const op = "storage.LocalStorage.Unpin"
+
+	if err := os.Remove(s.pinPath(c)); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+		}
+		return tvoerrors.Wrap(op, err)
+	}
+
+	if err := os.Remove(s.objectPath(c)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return tvoerrors.Wrap(op, err)
+	}
+	return nil
+}
+
+// List returns CIDs of all pinned content.
+func (s *LocalStorage) List(_ context.Context) ([]cid.Cid, error) {
+	const op = "storage.LocalStorage.List"
+
+	entries, err := os.ReadDir(filepath.Join(s.root, pinsDir))
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+
+	cids := make([]cid.Cid, 0, len(entries))
+	for _, entry := range entries {
+		c, err := cid.Decode(entry.Name())
+		if err != nil {
+			// skip foreign files in the pins directory
+			continue
+		}
+		cids = append(cids, c)
+	}
+	return cids, nil
+}
+
+// Cat opens the stored content.
+func (s *LocalStorage) Cat(_ context.Context, c cid.Cid) (io.ReadCloser, error) {
+	const op = "storage.LocalStorage.Cat"
+
+	f, err := os.Open(s.objectPath(c))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+		}
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	return f, nil
+}
+
+// Size returns the size of the stored content.
+func (s *LocalStorage) Size(_ context.Context, c cid.Cid) (int64, error) {
+	const op = "storage.LocalStorage.Size"
+
+	info, err := os.Stat(s.objectPath(c))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, tvoerrors.Wrap(op, tvoerrors.ErrNotFound)
+		}
+		return 0, tvoerrors.Wrap(op, err)
+	}
+	return info.Size(), nil
+}
+
+// CatRange opens the stored content at the offset.
+func (s *LocalStorage) CatRange(ctx context.Context, c cid.Cid, offset, length int64) (io.ReadCloser, error) {
+	r, err := s.Cat(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	// Cat opens the file itself
+	f := r.(*os.File)
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		_ = f.Close()
+		return nil, tvoerrors.Wrap("storage.LocalStorage.CatRange", err)
+	}
+	if length < 0 {
+		return f, nil
+	}
+	return &limitedReadCloser{Reader: io.LimitReader(f, length), Closer: f}, nil
+}
+
+// limitedReadCloser reads a part of the file and closes the whole file.
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// renameLegacy renames the files of the directory named by CIDv0, as content was stored
+// before, to their CIDv1 names.
+func (s *LocalStorage) renameLegacy(dir string) error {
+	entries, err := os.ReadDir(filepath.Join(s.root, dir))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		c, err := cid.Decode(entry.Name())
+		if err != nil || contentKey(c) == entry.Name() {
+			continue
+		}
+		err = os.Rename(filepath.Join(s.root, dir, entry.Name()), filepath.Join(s.root, dir, contentKey(c)))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *LocalStorage) objectPath(c cid.Cid) string {
+	return filepath.Join(s.root, objectsDir, contentKey(c))
+}
+
+func (s *LocalStorage) pinPath(c cid.Cid) string {
+	return filepath.Join(s.root, pinsDir, contentKey(c))
+}
+
+// contentKey returns the name content is stored under by the local and S3 storages.
+// CIDv0 and CIDv1 of the same content share the name.
+func contentKey(c cid.Cid) string {
+	return cids.V1(c).String()
+}
@@ -0,0 +1,342 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ipfs/go-cid"
+
+	"main/internal/config"
+	"main/internal/lib/cids"
+	"main/internal/lib/unixfs"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// key prefixes inside the bucket
+const (
+	s3ObjectsPrefix = "objects/"
+	s3PinsPrefix    = "pins/"
+)
+
+// sha256 of an empty payload
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Storage keeps content in an S3-compatible bucket (AWS S3, MinIO) using
+// path-style requests signed with AWS Signature Version 4.
+// Like the local storage, content is keyed by its CIDv1 and unpinned content is removed immediately.
+// Content added before the keys were normalized is still found under its CIDv0.
+type S3Storage struct {
+	endpoint  string
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+}
+
+// listBucketResult is the ListObjectsV2 response.
+type listBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// NewS3Storage creates the S3 storage from the config.
+func NewS3Storage(cfg *config.Storage) (*S3Storage, error) {
+	if cfg.S3Endpoint == "" || cfg.S3Bucket == "" {
+		return nil, tvoerrors.Wrap("storage.NewS3Storage", tvoerrors.ErrInvalidRequestData)
+	}
+
+	return &S3Storage{
+		endpoint:  strings.TrimRight(cfg.S3Endpoint, "/"),
+		region:    cfg.S3Region,
+		bucket:    cfg.S3Bucket,
+		accessKey: cfg.S3AccessKey,
+		secretKey: cfg.S3SecretKey,
+		client:    &http.Client{},
+	}, nil
+}
+
+// Add spools the content to a temporary file to compute its CID and uploads it.
+func (s *S3Storage) Add(ctx context.Context, name string, r io.Reader) (*Object, error) {
+	const op = "storage.S3Storage.Add"
+
+	tmp, err := os.CreateTemp("", "s3-add-*")
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	res, err := unixfs.Sum(io.TeeReader(r, tmp))
+	if err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	if _, err = tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+
+	if err = s.put(ctx, s3ObjectsPrefix+contentKey(res.Cid), tmp, int64(res.FileSize)); err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+	if err = s.put(ctx, s3PinsPrefix+contentKey(res.Cid), nil, 0); err != nil {
+		return nil, tvoerrors.Wrap(op, err)
+	}
+
+	return &Object{Name: name, Cid: res.Cid, Size: int64(res.DagSize)}, nil
+}
+
+// Pin marks stored content as pinned.
+func (s *S3Storage) Pin(ctx context.Context, c cid.Cid) error {
+	const op = "storage.S3Storage.Pin"
+
+	if _, err := s.find(ctx, s3ObjectsPrefix, c); err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+	if err := s.put(ctx, s3PinsPrefix+contentKey(c), nil, 0); err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+	return nil
+}
+
+// Unpin removes the pin together with the content.
+func (s *S3Storage) Unpin(ctx context.Context, c cid.Cid) error {
+	const op = "storage.S3Storage.Unpin"
+
+	if _, err := s.find(ctx, s3PinsPrefix, c); err != nil {
+		return tvoerrors.Wrap(op, err)
+	}
+
+	// the pin and the content may be kept under both keys
+	keys := append(s.keys(s3PinsPrefix, c), s.keys(s3ObjectsPrefix, c)...)
+	for _, key := range keys {
+		resp, err := s.do(ctx, http.MethodDelete, key, nil, nil, 0)
+		if errors.Is(err, tvoerrors.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return tvoerrors.Wrap(op, err)
+		}
+		resp.Body.Close()
+	}
+	return nil
+}
+
+// List returns CIDs of all pinned content.
+func (s *S3Storage) List(ctx context.Context) ([]cid.Cid, error) {
+	const op = "storage.S3Storage.List"
+
+	var pinned []cid.Cid
+	seen := make(map[string]struct{})
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {s3PinsPrefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil, 0)
+		if err != nil {
+			return nil, tvoerrors.Wrap(op, err)
+		}
+
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, tvoerrors.Wrap(op, err)
+		}
+
+		for _, item := range result.Contents {
+			c, err := cid.Decode(strings.TrimPrefix(item.Key, s3PinsPrefix))
+			if err != nil {
+				continue
+			}
+			// a pin may be kept under both keys, it is listed by its CIDv1 once
+			c = cids.V1(c)
+			if _, ok := seen[c.KeyString()]; !ok {
+				seen[c.KeyString()] = struct{}{}
+				pinned = append(pinned, c)
+			}
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return pinned, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// Cat streams the stored content.
+func (s *S3Storage) Cat(ctx context.Context, c cid.Cid) (io.ReadCloser, error) {
+	var err error
+	for _, key := range s.keys(s3ObjectsPrefix, c) {
+		var resp *http.Response
+		if resp, err = s.do(ctx, http.MethodGet, key, nil, nil, 0); err == nil {
+			return resp.Body, nil
+		}
+		if !errors.Is(err, tvoerrors.ErrNotFound) {
+			break
+		}
+	}
+	return nil, tvoerrors.Wrap("storage.S3Storage.Cat", err)
+}
+
+// keys returns the keys the content may be stored under with the prefix: its CIDv1
+// and the CIDv0 used by the storage before.
+func (s *S3Storage) keys(prefix string, c cid.Cid) []string {
+	keys := []string{prefix + contentKey(c)}
+	if v0, ok := cids.V0(c); ok {
+		keys = append(keys, prefix+v0.String())
+	}
+	return keys
+}
+
+// find returns the key the content is stored under with the prefix, ErrNotFound when there is none.
+func (s *S3Storage) find(ctx context.Context, prefix string, c cid.Cid) (string, error) {
+	var err error
+	for _, key := range s.keys(prefix, c) {
+		var resp *http.Response
+		if resp, err = s.do(ctx, http.MethodHead, key, nil, nil, 0); err == nil {
+			resp.Body.Close()
+			return key, nil
+		}
+		if !errors.Is(err, tvoerrors.ErrNotFound) {
+			break
+		}
+	}
+	return "", err
+}
+
+// put uploads an object of the known size.
+func (s *S3Storage) put(ctx context.Context, key string, body io.Reader, size int64) error {
+	resp, err := s.do(ctx, http.MethodPut, key, nil, body, size)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// do sends a signed request and converts error statuses to errors.
+func (s *S3Storage) do(ctx context.Context, method, key string, query url.Values, body io.Reader,
+	size int64) (*http.Response, error) {
+	endpoint := fmt.Sprintf("%s/%s/%s", s.endpoint, s.bucket, key)
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+
+	payloadHash := emptyPayloadHash
+	if body != nil {
+		payloadHash = "UNSIGNED-PAYLOAD"
+	}
+	s.sign(req, payloadHash, time.Now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, tvoerrors.ErrNotFound
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		defer resp.Body.Close()
+		bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("S3 %s %s returned %s: %s", method, key, resp.Status, string(bodyBytes))
+	}
+	return resp, nil
+}
+
+// sign adds AWS Signature Version 4 headers to the request.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func (s *S3Storage) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	scope := date + "/" + s.region + "/s3/aws4_request"
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+// canonicalQuery encodes query parameters sorted by key as RFC 3986 requires.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, uriEncode(k)+"="+uriEncode(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func uriEncode(v string) string {
+	return strings.ReplaceAll(url.QueryEscape(v), "+", "%20")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ipfs/go-cid"
+
+	"main/internal/cluster"
+	"main/internal/config"
+	"main/internal/lib/cids"
+	"main/internal/lib/unixfs"
+	"main/internal/service"
+	"main/tools/pkg/logger"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// supported backends
+const (
+	BackendKubo  = "kubo"
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+var ErrUnknownBackend = errors.New("unknown storage backend")
+
+// Object describes content added to a storage.
+type Object struct {
+	Name string
+	Cid  cid.Cid
+	Size int64 // cumulative DAG size, same as the Size field of Kubo add
+}
+
+// Storage is a content-addressed storage backend.
+type Storage interface {
+	// Add stores and pins the content, returning its CID
+	Add(ctx context.Context, name string, r io.Reader) (*Object, error)
+	// Pin keeps the content with the given CID in the storage
+	Pin(ctx context.Context, c cid.Cid) error
+	// Unpin releases the content with the given CID
+	Unpin(ctx context.Context, c cid.Cid) error
+	// List returns CIDs of all pinned content
+	List(ctx context.Context) ([]cid.Cid, error)
+	// Cat returns the content with the given CID
+	Cat(ctx context.Context, c cid.Cid) (io.ReadCloser, error)
+}
+
+// RangeStorage is implemented by storages that read a part of the content without fetching the rest.
+type RangeStorage interface {
+	// Size returns the size of the content with the given CID, ErrNotFound when there is none
+	Size(ctx context.Context, c cid.Cid) (int64, error)
+	// CatRange returns length bytes of the content from the offset, a negative length reads to the end
+	CatRange(ctx context.Context, c cid.Cid, offset, length int64) (io.ReadCloser, error)
+}
+
+// Connector is implemented by storages that fetch content from the network
+// and can connect to the peers known to provide it.
+type Connector interface {
+	Connect(ctx context.Context, addrs []string) error
+}
+
+// DagStorage is implemented by storages that move whole DAGs as CAR files.
+type DagStorage interface {
+	// Import adds the blocks of a CARv1 or CARv2 file, pins its roots and returns them
+	Import(ctx context.Context, r io.Reader) ([]cid.Cid, error)
+	// Export returns the DAG of the CID as a CARv1 file
+	Export(ctx context.Context, c cid.Cid) (io.ReadCloser, error)
+}
+
+// BlockStorage is implemented by storages that return the raw blocks of DAGs.
+type BlockStorage interface {
+	// Block returns the block of the CID kept by the storage, ErrNotFound when there is none
+	Block(ctx context.Context, c cid.Cid) ([]byte, error)
+}
+
+// Resolver is implemented by storages that resolve paths inside directories.
+type Resolver interface {
+	// Resolve returns the CID of the object at the path under the root, ErrNotFound when there is none
+	Resolve(ctx context.Context, root cid.Cid, path string) (cid.Cid, error)
+}
+
+// NameStorage is implemented by storages that publish IPNS records.
+type NameStorage interface {
+	// Key returns the IPNS name of the key, the key is generated when the storage has none
+	Key(ctx context.Context, keyName string) (string, error)
+	// Publish points the IPNS name of the key to the CID for the lifetime of the record
+	Publish(ctx context.Context, keyName string, c cid.Cid, lifetime time.Duration) (string, error)
+}
+
+// FileInfo describes a file or a directory of a mutable file tree.
+type FileInfo struct {
+	Name      string
+	Cid       cid.Cid
+	Size      int64 // file size, cumulative DAG size for directories
+	Directory bool
+}
+
+// FilesStorage is implemented by storages with a mutable file tree, such as Kubo MFS.
+type FilesStorage interface {
+	// CopyFile places the content of the CID at the path replacing the previous one, parent directories are created
+	CopyFile(ctx context.Context, c cid.Cid, path string) error
+	// ListFiles returns the entries of the directory at the path, ErrNotFound when there is none
+	ListFiles(ctx context.Context, path string) ([]FileInfo, error)
+	// StatFile describes the file or the directory at the path, ErrNotFound when there is none
+	StatFile(ctx context.Context, path string) (*FileInfo, error)
+	// RemoveFile removes the file or the directory at the path with its content, ErrNotFound when there is none
+	RemoveFile(ctx context.Context, path string) error
+}
+
+// GarbageCollector is implemented by storages that keep unpinned content until a garbage collection.
+type GarbageCollector interface {
+	// CollectGarbage removes the content which is no longer pinned and returns the number of removed blocks
+	CollectGarbage(ctx context.Context) (int, error)
+}
+
+// Hasher is implemented by storages that compute CIDs with their own import settings.
+type Hasher interface {
+	// Hash returns the object the content would be added as, nothing is stored
+	Hash(ctx context.Context, name string, r io.Reader) (*Object, error)
+}
+
+// DirectoryStorage is implemented by storages that add several files as one directory.
+type DirectoryStorage interface {
+	// AddDirectory stores and pins the files wrapped into a directory, keeping their relative paths.
+	// It returns the root directory and the files and subdirectories named by their paths.
+	AddDirectory(ctx context.Context, files []service.DirectoryEntry) (*Object, []Object, error)
+}
+
+// New creates the storage backend selected in the config. Kubo content is spread over
+// the registry nodes when there are several of them.
+func New(cfg *config.Storage, nodes *cluster.Registry, logger *logger.Logger) (Storage, error) {
+	switch cfg.Backend {
+	case BackendKubo, "":
+		if nodes.Len() > 1 {
+			return NewClusterStorage(nodes, logger), nil
+		}
+		return NewKuboStorage(nodes.Primary().Client), nil
+	case BackendLocal:
+		return NewLocalStorage(cfg.LocalPath)
+	case BackendS3:
+		return NewS3Storage(cfg)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBackend, cfg.Backend)
+	}
+}
+
+// CidV1 returns the CIDv1 form of the object CID, raw leaves and dag-cbor keep their codec.
+func (o *Object) CidV1() cid.Cid {
+	return cids.V1(o.Cid)
+}
+
+// CidV0 returns the CIDv0 form of the object CID. Content without one, such as a raw leaf,
+// keeps the CID returned by the storage.
+func (o *Object) CidV0() cid.Cid {
+	if v0, ok := cids.V0(o.Cid); ok {
+		return v0
+	}
+	return o.Cid
+}
+
+// Hash returns the object the content would be added to the storage as, without storing it.
+// Storages without their own hashing get the CID of the default Kubo import, as they use it for Add.
+func Hash(ctx context.Context, s Storage, name string, r io.Reader) (*Object, error) {
+	if hasher, ok := s.(Hasher); ok {
+		return hasher.Hash(ctx, name, r)
+	}
+
+	res, err := unixfs.Sum(r)
+	if err != nil {
+		return nil, tvoerrors.Wrap("storage.Hash", err)
+	}
+	return &Object{Name: name, Cid: res.Cid, Size: int64(res.DagSize)}, nil
+}
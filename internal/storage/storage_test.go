@@ -0,0 +1,335 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+
+	"main/internal/config"
+	"main/internal/lib/cids"
+	"main/internal/service"
+	tvoerrors "main/tools/pkg/tvo_errors"
+)
+
+// fakeS3 is a minimal in-memory stand-in for MinIO serving a single bucket.
+type fakeS3 struct {
+	t       *testing.T
+	mu      sync.Mutex
+	bucket  string
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=minio/") ||
+		r.Header.Get("X-Amz-Date") == "" || r.Header.Get("X-Amz-Content-Sha256") == "" {
+		f.t.Errorf("unsigned request %s %s", r.Method, r.URL)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+f.bucket+"/")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		var result listBucketResult
+		keys := make([]string, 0, len(f.objects))
+		for k := range f.objects {
+			if strings.HasPrefix(k, r.URL.Query().Get("prefix")) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			result.Contents = append(result.Contents, struct {
+				Key string `xml:"Key"`
+			}{Key: k})
+		}
+		_ = xml.NewEncoder(w).Encode(result)
+	case r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[key] = data
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(data)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func testStorage(t *testing.T, s Storage) {
+	ctx := context.Background()
+	content := []byte("hello world")
+
+	obj, err := s.Add(ctx, "hello.txt", bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if obj.Cid.String() != "Qmf412jQZiuVUtdgnB36FXFX7xg5V6KEbSJ4dpQuhkLyfD" {
+		t.Errorf("Add() cid = %s", obj.Cid)
+	}
+
+	r, err := s.Cat(ctx, obj.Cid)
+	if err != nil {
+		t.Fatalf("Cat() error = %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if !bytes.Equal(data, content) {
+		t.Errorf("Cat() = %q, expected %q", data, content)
+	}
+
+	// the content is found by its CIDv1 as well
+	r, err = s.Cat(ctx, cids.V1(obj.Cid))
+	if err != nil {
+		t.Fatalf("Cat() of CIDv1 error = %v", err)
+	}
+	data, _ = io.ReadAll(r)
+	r.Close()
+	if !bytes.Equal(data, content) {
+		t.Errorf("Cat() of CIDv1 = %q, expected %q", data, content)
+	}
+	if err = s.Pin(ctx, cids.V1(obj.Cid)); err != nil {
+		t.Errorf("Pin() of CIDv1 error = %v", err)
+	}
+
+	if ranges, ok := s.(RangeStorage); ok {
+		if size, err := ranges.Size(ctx, obj.Cid); err != nil || size != int64(len(content)) {
+			t.Errorf("Size() = %d, %v, expected %d", size, err, len(content))
+		}
+		for _, test := range []struct {
+			offset, length int64
+			expected       string
+		}{
+			{0, 5, "hello"},
+			{6, -1, "world"},
+			{6, 100, "world"},
+		} {
+			r, err := ranges.CatRange(ctx, obj.Cid, test.offset, test.length)
+			if err != nil {
+				t.Fatalf("CatRange(%d, %d) error = %v", test.offset, test.length, err)
+			}
+			data, _ = io.ReadAll(r)
+			r.Close()
+			if string(data) != test.expected {
+				t.Errorf("CatRange(%d, %d) = %q, expected %q", test.offset, test.length, data, test.expected)
+			}
+		}
+	}
+
+	pins, err := s.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(pins) != 1 || !pins[0].Equals(cids.V1(obj.Cid)) {
+		t.Errorf("List() = %v, expected [%s]", pins, cids.V1(obj.Cid))
+	}
+
+	if err = s.Unpin(ctx, obj.Cid); err != nil {
+		t.Fatalf("Unpin() error = %v", err)
+	}
+	if err = s.Pin(ctx, obj.Cid); !errors.Is(err, tvoerrors.ErrNotFound) {
+		t.Errorf("Pin() after Unpin error = %v, expected ErrNotFound", err)
+	}
+	if _, err = s.Cat(ctx, obj.Cid); !errors.Is(err, tvoerrors.ErrNotFound) {
+		t.Errorf("Cat() after Unpin error = %v, expected ErrNotFound", err)
+	}
+	if pins, _ = s.List(ctx); len(pins) != 0 {
+		t.Errorf("List() after Unpin = %v, expected empty", pins)
+	}
+}
+
+func TestLocalStorage(t *testing.T) {
+	s, err := New(&config.Storage{Backend: BackendLocal, LocalPath: t.TempDir()}, nil, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	testStorage(t, s)
+}
+
+func TestLocalStorageLegacyNames(t *testing.T) {
+	root := t.TempDir()
+	const v0 = "Qmf412jQZiuVUtdgnB36FXFX7xg5V6KEbSJ4dpQuhkLyfD"
+	for _, dir := range []string{objectsDir, pinsDir} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = os.WriteFile(filepath.Join(root, objectsDir, v0), []byte("hello world"), 0o644)
+	_ = os.WriteFile(filepath.Join(root, pinsDir, v0), nil, 0o644)
+
+	// content stored under CIDv0 names before is renamed and found by both CIDs
+	s, err := NewLocalStorage(root)
+	if err != nil {
+		t.Fatalf("NewLocalStorage() error = %v", err)
+	}
+	c, _ := cid.Decode(v0)
+	for _, form := range []cid.Cid{c, cids.V1(c)} {
+		r, err := s.Cat(context.Background(), form)
+		if err != nil {
+			t.Fatalf("Cat(%s) error = %v", form, err)
+		}
+		r.Close()
+	}
+	if err = s.Unpin(context.Background(), cids.V1(c)); err != nil {
+		t.Errorf("Unpin() error = %v", err)
+	}
+}
+
+func TestS3Storage(t *testing.T) {
+	srv := httptest.NewServer(&fakeS3{t: t, bucket: "nft", objects: map[string][]byte{}})
+	defer srv.Close()
+
+	s, err := New(&config.Storage{
+		Backend:     BackendS3,
+		S3Endpoint:  srv.URL,
+		S3Region:    "us-east-1",
+		S3Bucket:    "nft",
+		S3AccessKey: "minio",
+		S3SecretKey: "minio123",
+	}, nil, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	testStorage(t, s)
+}
+
+func TestNewUnknownBackend(t *testing.T) {
+	if _, err := New(&config.Storage{Backend: "ftp"}, nil, nil); !errors.Is(err, ErrUnknownBackend) {
+		t.Errorf("New() error = %v, expected ErrUnknownBackend", err)
+	}
+}
+
+func TestKuboStorageKey(t *testing.T) {
+	var generated []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/key/list":
+			_, _ = w.Write([]byte(`{"Keys":[{"Name":"self","Id":"k51self"},{"Name":"existing","Id":"k51existing"}]}`))
+		case "/key/gen":
+			generated = append(generated, r.URL.Query().Get("arg"))
+			_, _ = w.Write([]byte(`{"Name":"` + r.URL.Query().Get("arg") + `","Id":"k51generated"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	s := NewKuboStorage(service.NewKuboClient(&config.IPFS{APIURL: srv.URL}))
+
+	tests := []struct {
+		keyName  string
+		expected string
+	}{
+		{"existing", "k51existing"},
+		{"created", "k51generated"},
+	}
+	for _, test := range tests {
+		name, err := s.Key(context.Background(), test.keyName)
+		if err != nil || name != test.expected {
+			t.Errorf("Key(%s) = %q, %v, expected %s", test.keyName, name, err, test.expected)
+		}
+	}
+	if len(generated) != 1 || generated[0] != "created" {
+		t.Errorf("generated keys = %q, expected only created", generated)
+	}
+}
+
+func TestKuboStorageCopyFile(t *testing.T) {
+	var calls []string
+	existing := map[string]bool{"/nft-service/cool-cats/1/metadata.json": true}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		args := r.URL.Query()["arg"]
+		calls = append(calls, r.URL.Path+" "+strings.Join(args, " "))
+		switch r.URL.Path {
+		case "/files/stat":
+			if !existing[args[0]] {
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte(`{"Message":"file does not exist","Code":0}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"Hash":"bafkqaaa","Size":0,"Type":"file"}`))
+		case "/files/rm", "/files/cp":
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	s := NewKuboStorage(service.NewKuboClient(&config.IPFS{APIURL: srv.URL}))
+
+	c := cid.MustParse("Qmf412jQZiuVUtdgnB36FXFX7xg5V6KEbSJ4dpQuhkLyfD")
+	for _, p := range []string{"/nft-service/cool-cats/1/metadata.json", "/nft-service/cool-cats/2/metadata.json"} {
+		if err := s.CopyFile(context.Background(), c, p); err != nil {
+			t.Fatalf("CopyFile(%s) error = %v", p, err)
+		}
+	}
+
+	// the existing file is removed first, since files/cp does not replace it
+	expected := []string{
+		"/files/stat /nft-service/cool-cats/1/metadata.json",
+		"/files/rm /nft-service/cool-cats/1/metadata.json",
+		"/files/cp /ipfs/" + c.String() + " /nft-service/cool-cats/1/metadata.json",
+		"/files/stat /nft-service/cool-cats/2/metadata.json",
+		"/files/cp /ipfs/" + c.String() + " /nft-service/cool-cats/2/metadata.json",
+	}
+	if strings.Join(calls, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("calls = %q, expected %q", calls, expected)
+	}
+}
+
+func TestHash(t *testing.T) {
+	ctx := context.Background()
+
+	// storages without their own hashing get the CID their Add would return, nothing is stored
+	local, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStorage() error = %v", err)
+	}
+	obj, err := Hash(ctx, local, "hello.txt", strings.NewReader("hello world"))
+	if err != nil || obj.Cid.String() != "Qmf412jQZiuVUtdgnB36FXFX7xg5V6KEbSJ4dpQuhkLyfD" || obj.Name != "hello.txt" {
+		t.Errorf("Hash() = %+v, %v", obj, err)
+	}
+	if pins, _ := local.List(ctx); len(pins) != 0 {
+		t.Errorf("Hash() stored the content: %v", pins)
+	}
+
+	// Kubo hashes with the import settings of the node, e.g. CIDv1 raw leaves
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/add" || r.URL.Query().Get("only-hash") != "true" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		_, _ = w.Write([]byte(`{"Name":"hello.txt","Hash":"bafkreifzjut3te2nhyekklss27nh3k72ysco7y32koao5eei66wof36n5e","Size":"11"}`))
+	}))
+	defer srv.Close()
+	kubo := NewKuboStorage(service.NewKuboClient(&config.IPFS{APIURL: srv.URL}))
+
+	obj, err = Hash(ctx, kubo, "hello.txt", strings.NewReader("hello world"))
+	if err != nil || obj.Cid.Type() != cid.Raw || obj.Size != 11 {
+		t.Errorf("Hash() = %+v, %v", obj, err)
+	}
+}
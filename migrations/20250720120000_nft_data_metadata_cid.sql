@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE nft_data
+    ADD COLUMN IF NOT EXISTS metadata_cid varchar default '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE nft_data
+    DROP COLUMN IF EXISTS metadata_cid;
+-- +goose StatementEnd
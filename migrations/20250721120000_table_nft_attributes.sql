@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS nft_attributes
+(
+    id            bigserial
+        constraint nft_attributes_pk primary key,
+    nft_id        bigint  not null,
+    trait_type    varchar not null,
+    display_type  varchar default '',
+    value_type    varchar not null,
+    string_value  varchar,
+    numeric_value numeric,
+    created_at    timestamp default now(),
+    FOREIGN KEY (nft_id) REFERENCES nft_data (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS nft_attributes_nft_id_idx ON nft_attributes (nft_id);
+CREATE INDEX IF NOT EXISTS nft_attributes_string_idx ON nft_attributes (trait_type, string_value);
+CREATE INDEX IF NOT EXISTS nft_attributes_numeric_idx ON nft_attributes (trait_type, numeric_value);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS nft_attributes;
+-- +goose StatementEnd
@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS collections
+(
+    id               bigserial
+        constraint collections_pk primary key,
+    slug             varchar not null
+        constraint collections_slug_unique unique,
+    name             varchar not null,
+    symbol           varchar   default '',
+    description      text      default '',
+    cover_cid        varchar   default '',
+    contract_address varchar   default '',
+    chain_id         bigint    default 0,
+    owner_id         bigint,
+    created_at       timestamp default now(),
+    updated_at       timestamp,
+    deleted_at       timestamp,
+    FOREIGN KEY (owner_id) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS collections_contract_unique
+    ON collections (chain_id, lower(contract_address)) WHERE contract_address <> '';
+
+ALTER TABLE nft_data
+    ADD COLUMN IF NOT EXISTS collection_id bigint REFERENCES collections (id) ON DELETE RESTRICT;
+
+-- token_id уникален внутри коллекции, токены без коллекции остаются в общем пространстве
+CREATE UNIQUE INDEX IF NOT EXISTS nft_data_collection_token_unique
+    ON nft_data (collection_id, token_id) WHERE collection_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS nft_data_token_unique
+    ON nft_data (token_id) WHERE collection_id IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS nft_data_token_unique;
+DROP INDEX IF EXISTS nft_data_collection_token_unique;
+ALTER TABLE nft_data
+    DROP COLUMN IF EXISTS collection_id;
+DROP TABLE IF EXISTS collections;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE collections
+    ADD COLUMN IF NOT EXISTS token_standard varchar NOT NULL DEFAULT 'erc721';
+
+-- max_supply = 0 означает неограниченный тираж
+ALTER TABLE nft_data
+    ADD COLUMN IF NOT EXISTS max_supply    bigint NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS minted_supply bigint NOT NULL DEFAULT 0,
+    ADD CONSTRAINT nft_data_supply_check CHECK (minted_supply >= 0 AND (max_supply = 0 OR minted_supply <= max_supply));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE nft_data
+    DROP CONSTRAINT IF EXISTS nft_data_supply_check,
+    DROP COLUMN IF EXISTS minted_supply,
+    DROP COLUMN IF EXISTS max_supply;
+ALTER TABLE collections
+    DROP COLUMN IF EXISTS token_standard;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- удаленные токены не занимают token_id, его можно выпустить заново
+DROP INDEX IF EXISTS nft_data_collection_token_unique;
+DROP INDEX IF EXISTS nft_data_token_unique;
+CREATE UNIQUE INDEX IF NOT EXISTS nft_data_collection_token_unique
+    ON nft_data (collection_id, token_id) WHERE collection_id IS NOT NULL AND deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS nft_data_token_unique
+    ON nft_data (token_id) WHERE collection_id IS NULL AND deleted_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS nft_data_collection_token_unique;
+DROP INDEX IF EXISTS nft_data_token_unique;
+CREATE UNIQUE INDEX IF NOT EXISTS nft_data_collection_token_unique
+    ON nft_data (collection_id, token_id) WHERE collection_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS nft_data_token_unique
+    ON nft_data (token_id) WHERE collection_id IS NULL;
+-- +goose StatementEnd
@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS nft_data_revisions
+(
+    id           bigserial
+        constraint nft_data_revisions_pk primary key,
+    nft_id       bigint  not null,
+    revision     integer not null,
+    author_id    bigint,
+    description  text    default '',
+    cidv0        varchar default '',
+    cidv1        varchar default '',
+    metadata_cid varchar default '',
+    attributes   jsonb   not null default '[]',
+    rollback_of  integer,
+    created_at   timestamp default now(),
+    FOREIGN KEY (nft_id) REFERENCES nft_data (id) ON DELETE CASCADE,
+    FOREIGN KEY (author_id) REFERENCES users (id) ON DELETE SET NULL,
+    constraint nft_data_revisions_unique unique (nft_id, revision)
+);
+
+-- текущее состояние существующих токенов становится первой ревизией
+INSERT INTO nft_data_revisions (nft_id, revision, description, cidv0, cidv1, metadata_cid, attributes, created_at)
+SELECT n.id, 1, n.content, n.cidv0, n.cidv1, n.metadata_cid,
+       COALESCE((SELECT jsonb_agg(jsonb_strip_nulls(jsonb_build_object(
+               'trait_type', a.trait_type,
+               'display_type', NULLIF(a.display_type, ''),
+               'value', CASE WHEN a.value_type = 'number' THEN to_jsonb(a.numeric_value)
+                             ELSE to_jsonb(a.string_value) END)) ORDER BY a.id)
+                 FROM nft_attributes a WHERE a.nft_id = n.id), '[]'),
+       COALESCE(n.updated_at, n.created_at, now())
+FROM nft_data n;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS nft_data_revisions;
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE nft_data
+    ADD COLUMN IF NOT EXISTS creator_id bigint REFERENCES users (id) ON DELETE SET NULL;
+
+-- автором существующих токенов считаем автора первой ревизии
+UPDATE nft_data n
+SET creator_id = r.author_id
+FROM nft_data_revisions r
+WHERE r.nft_id = n.id AND r.revision = 1 AND n.creator_id IS NULL;
+
+UPDATE nft_data SET created_at = now() WHERE created_at IS NULL;
+ALTER TABLE nft_data
+    ALTER COLUMN created_at SET NOT NULL;
+
+-- индексы для постраничного вывода по (created_at, id)
+CREATE INDEX IF NOT EXISTS nft_data_created_idx ON nft_data (created_at, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS nft_data_collection_created_idx
+    ON nft_data (collection_id, created_at, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS nft_data_creator_created_idx
+    ON nft_data (creator_id, created_at, id) WHERE deleted_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS nft_data_creator_created_idx;
+DROP INDEX IF EXISTS nft_data_collection_created_idx;
+DROP INDEX IF EXISTS nft_data_created_idx;
+ALTER TABLE nft_data
+    ALTER COLUMN created_at DROP NOT NULL;
+ALTER TABLE nft_data
+    DROP COLUMN IF EXISTS creator_id;
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE nft_data
+    ADD COLUMN IF NOT EXISTS name          varchar default '',
+    ADD COLUMN IF NOT EXISTS search_vector tsvector;
+ALTER TABLE nft_data_revisions
+    ADD COLUMN IF NOT EXISTS name varchar default '';
+
+-- то же выражение, что и в postgresql.refreshSearchVector
+UPDATE nft_data
+SET search_vector =
+        setweight(to_tsvector('russian', COALESCE(name, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(name, '')), 'A') ||
+        setweight(to_tsvector('russian', COALESCE(content, '')), 'B') ||
+        setweight(to_tsvector('english', COALESCE(content, '')), 'B') ||
+        setweight(to_tsvector('simple', COALESCE(file_name, '')), 'C') ||
+        setweight(to_tsvector('russian', COALESCE((SELECT string_agg(concat_ws(' ', a.trait_type, a.string_value), ' ')
+                                                   FROM nft_attributes a WHERE a.nft_id = nft_data.id), '')), 'C');
+
+CREATE INDEX IF NOT EXISTS nft_data_search_idx ON nft_data USING GIN (search_vector);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS nft_data_search_idx;
+ALTER TABLE nft_data_revisions
+    DROP COLUMN IF EXISTS name;
+ALTER TABLE nft_data
+    DROP COLUMN IF EXISTS search_vector,
+    DROP COLUMN IF EXISTS name;
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS pins
+(
+    id              bigserial
+        constraint pins_pk primary key,
+    request_id      uuid    not null
+        constraint pins_request_id_unique unique,
+    cid             varchar not null,
+    requester_id    bigint,
+    status          varchar not null default 'queued'
+        constraint pins_status_check check (status IN ('queued', 'pinning', 'pinned', 'failed')),
+    attempts        integer not null default 0,
+    error           text    not null default '',
+    next_attempt_at timestamp        default now(),
+    pinned_at       timestamp,
+    created_at      timestamp        default now(),
+    updated_at      timestamp        default now(),
+    FOREIGN KEY (requester_id) REFERENCES users (id) ON DELETE SET NULL
+);
+
+-- очередь воркера: закрепления, ожидающие очередной попытки
+CREATE INDEX IF NOT EXISTS pins_queue_idx ON pins (next_attempt_at, id) WHERE status = 'queued';
+CREATE INDEX IF NOT EXISTS pins_status_idx ON pins (status);
+CREATE INDEX IF NOT EXISTS pins_cid_idx ON pins (cid);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS pins;
+-- +goose StatementEnd
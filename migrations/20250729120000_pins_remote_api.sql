@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- поля объекта Pin из IPFS Pinning Service API
+ALTER TABLE pins
+    ADD COLUMN IF NOT EXISTS name    varchar not null default '',
+    ADD COLUMN IF NOT EXISTS origins jsonb   not null default '[]',
+    ADD COLUMN IF NOT EXISTS meta    jsonb   not null default '{}';
+
+-- список закреплений пользователя от новых к старым
+CREATE INDEX IF NOT EXISTS pins_requester_created_idx ON pins (requester_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS pins_meta_idx ON pins USING GIN (meta jsonb_path_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS pins_meta_idx;
+DROP INDEX IF EXISTS pins_requester_created_idx;
+ALTER TABLE pins
+    DROP COLUMN IF EXISTS meta,
+    DROP COLUMN IF EXISTS origins,
+    DROP COLUMN IF EXISTS name;
+-- +goose StatementEnd
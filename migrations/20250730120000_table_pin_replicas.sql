@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+-- копии закреплений на удаленных сервисах закреплений, по одной строке на сервис
+CREATE TABLE IF NOT EXISTS pin_replicas
+(
+    id                bigserial
+        constraint pin_replicas_pk primary key,
+    pin_id            bigint  not null,
+    target            varchar not null,
+    -- requestid закрепления на удаленном сервисе
+    remote_request_id varchar not null default '',
+    status            varchar not null default 'queued'
+        constraint pin_replicas_status_check check (status IN ('queued', 'pinning', 'pinned', 'failed')),
+    attempts          integer not null default 0,
+    error             text    not null default '',
+    next_attempt_at   timestamp        default now(),
+    pinned_at         timestamp,
+    created_at        timestamp        default now(),
+    updated_at        timestamp        default now(),
+    constraint pin_replicas_pin_target_unique unique (pin_id, target),
+    FOREIGN KEY (pin_id) REFERENCES pins (id) ON DELETE CASCADE
+);
+
+-- очередь репликатора: новые копии и копии, ожидающие подтверждения сервиса
+CREATE INDEX IF NOT EXISTS pin_replicas_queue_idx ON pin_replicas (next_attempt_at, id)
+    WHERE status IN ('queued', 'pinning');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS pin_replicas;
+-- +goose StatementEnd
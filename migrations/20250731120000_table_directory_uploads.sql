@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+-- директории, загруженные одним корневым CID (ipfs://<root>/1.json)
+CREATE TABLE IF NOT EXISTS directory_uploads
+(
+    id            bigserial
+        constraint directory_uploads_pk primary key,
+    root_cid      varchar not null,
+    size          bigint  not null default 0,
+    collection_id bigint,
+    uploader_id   bigint,
+    created_at    timestamp        default now(),
+    FOREIGN KEY (collection_id) REFERENCES collections (id) ON DELETE CASCADE,
+    FOREIGN KEY (uploader_id) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS directory_uploads_root_cid_idx ON directory_uploads (root_cid);
+
+-- файлы и поддиректории загрузки, token_id задан у файлов, названных по номеру токена
+CREATE TABLE IF NOT EXISTS directory_upload_files
+(
+    id        bigserial
+        constraint directory_upload_files_pk primary key,
+    upload_id bigint  not null,
+    path      varchar not null,
+    cid       varchar not null,
+    size      bigint  not null default 0,
+    directory boolean not null default false,
+    token_id  bigint,
+    constraint directory_upload_files_path_unique unique (upload_id, path),
+    FOREIGN KEY (upload_id) REFERENCES directory_uploads (id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS directory_upload_files;
+DROP TABLE IF EXISTS directory_uploads;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- последняя проверка целостности каждого CID: содержимое пересчитывается и сравнивается с CID
+CREATE TABLE IF NOT EXISTS content_checks
+(
+    id         bigserial
+        constraint content_checks_pk primary key,
+    cid        varchar not null
+        constraint content_checks_cid_unique unique,
+    status     varchar not null,
+    error      varchar not null default '',
+    source     varchar not null,
+    checked_at timestamp        default now(),
+    failed_at  timestamp
+);
+
+CREATE INDEX IF NOT EXISTS content_checks_failed_idx ON content_checks (failed_at) WHERE status <> 'ok';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS content_checks;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- изменяемые указатели коллекций: запись IPNS ключа коллекции и CID, на который она указывает
+CREATE TABLE IF NOT EXISTS ipns_records
+(
+    id            bigserial
+        constraint ipns_records_pk primary key,
+    collection_id bigint    not null
+        constraint ipns_records_collection_unique unique,
+    key_name      varchar   not null,
+    name          varchar   not null,
+    value         varchar   not null,
+    sequence      bigint    not null default 0,
+    published_at  timestamp not null default now(),
+    expires_at    timestamp not null,
+    created_at    timestamp          default now(),
+    FOREIGN KEY (collection_id) REFERENCES collections (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS ipns_records_expires_at_idx ON ipns_records (expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS ipns_records;
+-- +goose StatementEnd
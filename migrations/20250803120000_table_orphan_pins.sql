@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- закрепления хранилища, на которые не ссылается ни одна запись базы, first_seen_at отсчитывает срок до удаления
+CREATE TABLE IF NOT EXISTS orphan_pins
+(
+    id            bigserial
+        constraint orphan_pins_pk primary key,
+    cid           varchar   not null
+        constraint orphan_pins_cid_unique unique,
+    first_seen_at timestamp not null default now()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS orphan_pins;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- индексы для поиска уже загруженного содержимого перед созданием токена
+CREATE INDEX IF NOT EXISTS nft_data_cidv0_idx ON nft_data (cidv0) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS nft_data_cidv1_idx ON nft_data (cidv1) WHERE deleted_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS nft_data_cidv1_idx;
+DROP INDEX IF EXISTS nft_data_cidv0_idx;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- имя файла содержимого в ревизии, чтобы откат возвращал его вместе с CID
+ALTER TABLE nft_data_revisions ADD COLUMN IF NOT EXISTS file_name varchar default '';
+UPDATE nft_data_revisions SET file_name = COALESCE(nft_data.file_name, '')
+FROM nft_data WHERE nft_data.id = nft_data_revisions.nft_id AND nft_data_revisions.cidv0 = nft_data.cidv0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE nft_data_revisions DROP COLUMN IF EXISTS file_name;
+-- +goose StatementEnd
@@ -11,6 +11,10 @@ import (
 type App struct {
 	Addr  string `envconfig:"APP_ADDR" required:"false" default:"0.0.0.0:9000"` // URL of the application
 	Debug bool   `envconfig:"APP_DEBUG" default:"false"`
+	// BodyLimit maximum request body size in bytes
+	BodyLimit int `envconfig:"APP_BODY_LIMIT" default:"20971520"`
+	// ReadTimeout maximum time to read a request including its body, large uploads over slow links need more
+	ReadTimeout time.Duration `envconfig:"APP_READ_TIMEOUT" default:"5m"`
 }
 
 // Logging конфиг для создания логгера